@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	"io"
+)
+
+const authzUsage = `usage: backend authz <eval|partial> [flags]
+
+  eval     evaluates the query of the input against the policies and explains denials
+  partial  partially evaluates the query with the unknowns and prints the filters and the SQL condition`
+
+// runAuthz runs the authz subcommand with the arguments after "authz"
+func runAuthz(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(authzUsage)
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("authz "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	policies := flags.String("policies", "internal/authorization/policies", "comma separated Rego files or directories")
+	input := flags.String("input", "internal/authorization/example.json", "JSON file with the query, input and unknowns, see example.json")
+	query := flags.String("query", "", "query which replaces the query of the input file")
+	unknowns := flags.String("unknowns", "", "comma separated unknowns which replace the unknowns of the input file")
+	explain := flags.String("explain", authorization.ExplainFails, "trace printed for denials of eval: off, fails, notes or full")
+	dialect := flags.String("dialect", storageSQLite, "SQL dialect of the condition printed by partial: sqlite or postgres")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	req, err := authorization.ReadRequest(*input)
+	if err != nil {
+		return err
+	}
+	if *query != "" {
+		req.Query = *query
+	}
+	if *unknowns != "" {
+		req.Unknowns = splitList(*unknowns)
+	}
+
+	switch command {
+	case "eval":
+		return authzEval(ctx, out, splitList(*policies), req, *explain)
+	case "partial":
+		return authzPartial(ctx, out, splitList(*policies), req, *dialect)
+	default:
+		return fmt.Errorf("unknown authz command %q\n%s", command, authzUsage)
+	}
+}
+
+func authzEval(ctx context.Context, out io.Writer, policies []string, req authorization.Request, explain string) error {
+	evaluation, err := authorization.Evaluate(ctx, policies, req, explain)
+	if err != nil {
+		return err
+	}
+
+	results, err := json.MarshalIndent(evaluation.Results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "allowed: %t\nresults: %s\n", evaluation.Allowed, results)
+
+	if !evaluation.Allowed && len(evaluation.Trace) > 0 {
+		fmt.Fprintln(out, "explanation:")
+		evaluation.Explain(out)
+	}
+	return nil
+}
+
+func authzPartial(ctx context.Context, out io.Writer, policies []string, req authorization.Request, dialect string) error {
+	var sqlDialect authorization.Dialect
+	switch dialect {
+	case storageSQLite:
+		sqlDialect = authorization.SQLiteDialect{}
+	case storagePostgres:
+		sqlDialect = authorization.PostgresDialect{}
+	default:
+		return fmt.Errorf("unknown dialect %q, use %s or %s", dialect, storageSQLite, storagePostgres)
+	}
+
+	evaluation, err := authorization.PartialEvaluate(ctx, policies, req)
+	if errors.Is(err, authorization.AccessDeniedError) {
+		fmt.Fprintln(out, "denied: no value of the unknowns is allowed")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "queries:")
+	for _, q := range evaluation.Queries.Queries {
+		fmt.Fprintf(out, "  %s\n", q)
+	}
+
+	fmt.Fprintln(out, "filters:")
+	for i, filter := range evaluation.Filters {
+		fmt.Fprintf(out, "  %d: %+v\n", i, filter)
+	}
+
+	sql, args, err := authorization.NewSQLCompiler(sqlDialect, nil).Compile(authorization.FilterOf(evaluation.Filters))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "sql: %s\nargs: %v\n", sql, args)
+	return nil
+}
@@ -1,5 +1,597 @@
 package main
 
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/gql"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rest"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rpc"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/ws"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	storageSQLite   = "sqlite"
+	storagePostgres = "postgres"
+	storageMemory   = "memory"
+)
+
+const (
+	decisionLogStdout = "stdout"
+	decisionLogFile   = "file"
+	decisionLogSQLite = "sqlite"
+	decisionLogMemory = "memory"
+)
+
+type config struct {
+	listen              string
+	grpcListen          string
+	metricsListen       string
+	eventsHeartbeat     time.Duration
+	eventsReplay        int
+	wsConnections       int
+	wsUserConnections   int
+	wsPingInterval      time.Duration
+	userDirectory       string
+	storage             string
+	sqlitePath          string
+	postgresDSN         string
+	eventLogPath        string
+	userHeader          string
+	rolesHeader         string
+	admins              string
+	moderators          string
+	policies            string
+	bundle              string
+	bundleKey           string
+	bundleKeyID         string
+	bundleKeyAlgorithm  string
+	bundleInterval      time.Duration
+	partialCacheSize    int
+	partialCacheTTL     time.Duration
+	decisionLogs        string
+	decisionLogPath     string
+	decisionLogFile     string
+	decisionLogMaxBytes int64
+	decisionLogBackups  int
+	decisionLogMemory   int
+	decisionLogMask     string
+	decisionLogHashKey  string
+	wordList            string
+	denyList            string
+	maxLinks            int
+	reports             int
+	rateLimit           bool
+	rateLimits          string
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "authz" {
+		if err := runAuthz(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var cfg config
+	flag.StringVar(&cfg.listen, "listen", ":8080", "address of the HTTP server")
+	flag.StringVar(&cfg.grpcListen, "grpc-listen", ":9090", "address of the gRPC server, the HTTP server serves it as JSON on /v1/, empty disables it")
+	flag.StringVar(&cfg.metricsListen, "metrics-listen", "localhost:9100", "address of the HTTP server of the Prometheus metrics on /metrics, empty disables it")
+	flag.DurationVar(&cfg.eventsHeartbeat, "events-heartbeat", sse.DefaultHeartbeat, "interval of the heartbeats of the /events stream")
+	flag.IntVar(&cfg.eventsReplay, "events-replay-buffer", sse.DefaultReplayBufferSize, "count of /events messages which are kept for clients which resume with a Last-Event-ID")
+	flag.IntVar(&cfg.wsConnections, "ws-max-connections", ws.DefaultLimits().Connections, "count of open /ws connections, 0 disables the limit")
+	flag.IntVar(&cfg.wsUserConnections, "ws-max-connections-per-user", ws.DefaultLimits().ConnectionsPerUser, "count of open /ws connections of a single user, 0 disables the limit")
+	flag.DurationVar(&cfg.wsPingInterval, "ws-ping-interval", ws.DefaultLimits().PingInterval, "interval of the pings of the /ws connections, connections without a pong for two intervals are closed")
+	flag.StringVar(&cfg.userDirectory, "user-directory", "", "JSON file which maps the user ids to the display names of the GraphQL API, the ids are shown if it is empty")
+	flag.StringVar(&cfg.storage, "storage", storageSQLite, "storage backend: sqlite, postgres or memory")
+	flag.StringVar(&cfg.sqlitePath, "sqlite-path", "opinions.db", "location of the SQLite database")
+	flag.StringVar(&cfg.postgresDSN, "postgres-dsn", "", "connection string of the PostgreSQL database")
+	flag.StringVar(&cfg.eventLogPath, "event-log-path", "events.db", "location of the SQLite event log, only used for sqlite storage")
+	flag.StringVar(&cfg.userHeader, "user-header", "X-User-Id", "header which carries the id of the authenticated user")
+	flag.StringVar(&cfg.rolesHeader, "roles-header", "", "header which carries the comma separated roles of the authenticated user, only set it behind a proxy which strips the header of clients")
+	flag.StringVar(&cfg.admins, "admins", "", "comma separated ids of the users which have the admin role")
+	flag.StringVar(&cfg.moderators, "moderators", "", "comma separated ids of the users which have the moderator role")
+	flag.StringVar(&cfg.policies, "policies", "", "directory of the Rego policies which authorize the requests, the built-in rules are used if it is empty")
+	flag.StringVar(&cfg.bundle, "policy-bundle", "", "tar.gz file, bundle directory or http(s) URL of an OPA bundle which authorizes the requests instead of --policies")
+	flag.StringVar(&cfg.bundleKey, "policy-bundle-key", "", "file with the PEM encoded public key which verifies the bundle signatures, unsigned bundles are accepted if it is empty")
+	flag.StringVar(&cfg.bundleKeyID, "policy-bundle-key-id", "default", "key id of the bundle signatures")
+	flag.StringVar(&cfg.bundleKeyAlgorithm, "policy-bundle-key-algorithm", "RS256", "signing algorithm of the bundle signatures")
+	flag.DurationVar(&cfg.bundleInterval, "policy-bundle-interval", time.Minute, "interval in which new revisions of the bundle are loaded")
+	flag.IntVar(&cfg.partialCacheSize, "partial-eval-cache-size", infrastructure.DefaultPartialEvalCacheSize, "count of cached opinion visibilities of the Rego policies, 0 disables the cache")
+	flag.DurationVar(&cfg.partialCacheTTL, "partial-eval-cache-ttl", infrastructure.DefaultPartialEvalCacheTTL, "time a cached opinion visibility is used")
+	flag.StringVar(&cfg.decisionLogs, "decision-log", decisionLogSQLite, "comma separated sinks of the policy decisions: stdout, file, sqlite or memory, the sqlite or memory sink is queried by GET /decisions")
+	flag.StringVar(&cfg.decisionLogPath, "decision-log-path", "decisions.db", "location of the SQLite decision log")
+	flag.StringVar(&cfg.decisionLogFile, "decision-log-file", "decisions.log", "location of the rotated JSON decision log file")
+	flag.Int64Var(&cfg.decisionLogMaxBytes, "decision-log-file-max-bytes", 10<<20, "size of the decision log file which triggers the rotation")
+	flag.IntVar(&cfg.decisionLogBackups, "decision-log-file-backups", 5, "count of rotated decision log files which are kept")
+	flag.IntVar(&cfg.decisionLogMemory, "decision-log-memory-size", infrastructure.DefaultDecisionLogMemorySize, "count of the newest decisions which are kept by the memory decision log")
+	flag.StringVar(&cfg.decisionLogMask, "decision-log-mask", "", "comma separated decision fields which are masked: user, roles, opinion or owner")
+	flag.StringVar(&cfg.decisionLogHashKey, "decision-log-hash-key", "", "file with the secret which keys the input hashes of the decisions, without it the masked request is hashed")
+	flag.StringVar(&cfg.wordList, "moderation-word-list", "", "file with one word per line, opinions containing a word are rejected")
+	flag.StringVar(&cfg.denyList, "moderation-deny-list", "", "file with one regular expression per line, matching opinions are rejected")
+	flag.IntVar(&cfg.maxLinks, "moderation-max-links", 1, "opinions with more links are held for review")
+	flag.IntVar(&cfg.reports, "report-threshold", application.DefaultReportThreshold, "count of open reports which hides an opinion until an admin resolved them, 0 disables it")
+	flag.BoolVar(&cfg.rateLimit, "rate-limit", true, "throttle the commands of every user with the default rate limits")
+	flag.StringVar(&cfg.rateLimits, "rate-limits", "", "comma separated action=burst/refill pairs which override the default rate limits, e.g. CreateOpinion=5/1m")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cfg config) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics, err := infrastructure.NewMetrics(registry)
+	if err != nil {
+		return err
+	}
+
+	repo, eventLog, err := newStorage(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	repo = infrastructure.NewInstrumentedRepository(repo, metrics)
+
+	bus := infrastructure.NewEventBus(eventLog, func(event application.Event, err error) {
+		log.Printf("event %d could not be projected: %s", event.Sequence, err)
+	})
+
+	opinionList := projections.NewOpinionListProjection()
+	projector := projections.NewProjector(eventLog, opinionList, projections.NewUserActivityProjection())
+	if err := projector.Rebuild(ctx); err != nil {
+		return fmt.Errorf("projections could not be rebuilt: %w", err)
+	}
+	bus.Subscribe(projector)
+
+	// the hub reads the vote tallies of the opinion list, so it is subscribed after the projector
+	hub := sse.NewHub(opinionList, cfg.eventsReplay)
+	bus.Subscribe(hub)
+
+	broadcast := infrastructure.NewEventBroadcast()
+	bus.Subscribe(broadcast)
+
+	moderation, err := newModeration(cfg)
+	if err != nil {
+		return err
+	}
+
+	pep, err := newPolicyEnforcementPoint(ctx, cfg, registry)
+	if err != nil {
+		return err
+	}
+
+	options := []application.Option{
+		application.WithModeration(moderation),
+		application.WithReportThreshold(cfg.reports),
+		application.WithPublishErrorHandler(func(event application.Event, err error) {
+			log.Printf("%s event of opinion %s could not be published: %s", event.Type, event.Opinion.ID, err)
+		}),
+	}
+
+	loggers, decisions, err := newDecisionLoggers(cfg)
+	if err != nil {
+		return err
+	}
+	pep = infrastructure.NewInstrumentedPolicyEnforcementPoint(pep, metrics)
+	if len(loggers) > 0 {
+		logOptions := []application.DecisionLogOption{
+			application.MaskDecisionFields(splitList(cfg.decisionLogMask)...),
+			application.OnDecisionLogError(func(decision application.Decision, err error) {
+				log.Printf("decision of %s for %s could not be logged: %s", decision.User, decision.Action, err)
+			}),
+		}
+		if cfg.decisionLogHashKey != "" {
+			key, err := os.ReadFile(cfg.decisionLogHashKey)
+			if err != nil {
+				return err
+			}
+			logOptions = append(logOptions, application.HashDecisionInputs(bytes.TrimSpace(key)))
+		}
+		pep = application.NewDecisionLoggingPolicyEnforcementPoint(pep, loggers, infrastructure.SystemTimeService{}, logOptions...)
+	}
+	if decisions != nil {
+		options = append(options, application.WithDecisionLog(decisions))
+	}
+
+	// the Rego policies decide which opinions are listed, the built-in rules use the default visibility of the service.
+	// The decorators of the policy enforcement point are VisibilityPolicies as well, so these decisions are logged.
+	if visibility, ok := pep.(application.VisibilityPolicy); ok {
+		options = append(options, application.WithVisibilityPolicy(visibility))
+	}
+
+	service := application.NewOpinionService(
+		pep,
+		repo,
+		bus,
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+		options...,
+	)
+
+	if cfg.rateLimit {
+		limits := application.DefaultRateLimits()
+		if err := application.ParseRateLimits(cfg.rateLimits, limits); err != nil {
+			return fmt.Errorf("invalid rate limits: %w", err)
+		}
+		service, err = application.NewRateLimitedService(service, infrastructure.NewRateLimitStoreMemory(), limits, infrastructure.SystemTimeService{})
+		if err != nil {
+			return err
+		}
+	}
+	// the rate limited calls are recorded with their code, so the service is instrumented last
+	service = infrastructure.NewInstrumentedService(service, metrics)
+
+	errs := make(chan error, 3)
+
+	var gateway http.Handler
+	if cfg.grpcListen != "" {
+		grpcServer := rpc.NewServer(service, broadcast, newMetadataAuthenticator(cfg))
+		listener, err := net.Listen("tcp", cfg.grpcListen)
+		if err != nil {
+			return err
+		}
+		go func() {
+			log.Printf("gRPC listening on %s", cfg.grpcListen)
+			errs <- grpcServer.Serve(listener)
+		}()
+		defer stopGracefully(grpcServer, 10*time.Second)
+
+		conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		gateway, err = rpc.NewGateway(ctx, conn, cfg.userHeader, cfg.rolesHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	votes := ws.NewHandler(service, hub, newAuthenticator(cfg), ws.Limits{
+		Connections:        cfg.wsConnections,
+		ConnectionsPerUser: cfg.wsUserConnections,
+		PingInterval:       cfg.wsPingInterval,
+	})
+
+	directory, err := newUserDirectory(cfg)
+	if err != nil {
+		return err
+	}
+	graphql := gql.NewHandler(service, broadcast, opinionList, directory, newAuthenticator(cfg))
+
+	handler, err := newHandler(service, cfg, sse.NewHandler(service, hub, newAuthenticator(cfg), cfg.eventsHeartbeat), votes, graphql, gateway)
+	if err != nil {
+		return err
+	}
+
+	go expireIdempotencyKeys(ctx, repo)
+
+	// the metrics are served apart from the API, so they are not exposed together with it
+	if cfg.metricsListen != "" {
+		metricsServer := newMetricsServer(cfg.metricsListen, registry)
+		go func() {
+			log.Printf("metrics listening on %s", cfg.metricsListen)
+			errs <- metricsServer.ListenAndServe()
+		}()
+		defer metricsServer.Close()
+	}
+
+	server := &http.Server{
+		Addr:              cfg.listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// the streams and the upgraded connections are not closed by Shutdown
+	server.RegisterOnShutdown(hub.Close)
+	server.RegisterOnShutdown(votes.Close)
+	server.RegisterOnShutdown(graphql.Close)
+
+	go func() {
+		log.Printf("listening on %s with %s storage", cfg.listen, cfg.storage)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newHandler serves the REST API, the live updates on /events, the voting WebSocket on /ws, the GraphQL API on /graphql
+// and the gRPC gateway on /v1/ if it is not nil
+func newHandler(service application.Service, cfg config, events http.Handler, votes http.Handler, graphql http.Handler, gateway http.Handler) (http.Handler, error) {
+	api, err := rest.NewHandler(service, newAuthenticator(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI spec could not be loaded: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", events)
+	mux.Handle("/ws", votes)
+	mux.Handle("/graphql", graphql)
+	if gateway != nil {
+		mux.Handle("/v1/", gateway)
+	}
+	mux.Handle("/", api)
+	return mux, nil
+}
+
+// newMetricsServer serves the Prometheus metrics of the registry on /metrics
+func newMetricsServer(addr string, registry *prometheus.Registry) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// stopGracefully waits for the running calls until the timeout, the open WatchOpinions streams are only closed by Stop
+func stopGracefully(server *grpc.Server, timeout time.Duration) {
+	timer := time.AfterFunc(timeout, server.Stop)
+	defer timer.Stop()
+	server.GracefulStop()
+}
+
+// expireIdempotencyKeys removes the expired idempotency keys every hour until the context is done
+func expireIdempotencyKeys(ctx context.Context, repo application.Repository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := repo.DeleteExpiredIdempotencyRecords(ctx, time.Now()); err != nil {
+				log.Printf("expired idempotency keys could not be removed: %s", err)
+			}
+		}
+	}
+}
+
+func newPolicyEnforcementPoint(ctx context.Context, cfg config, registerer prometheus.Registerer) (application.PolicyEnforcementPoint, error) {
+	if cfg.bundle != "" && cfg.policies != "" {
+		return nil, errors.New("--policies and --policy-bundle can not be combined")
+	}
+
+	if cfg.bundle != "" {
+		return newBundlePolicyEnforcementPoint(ctx, cfg, registerer)
+	}
+
+	if cfg.policies == "" {
+		return infrastructure.AuthenticatedUsersPolicyEnforcementPoint{}, nil
+	}
+
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(ctx, cfg.policies)
+	if err != nil {
+		return nil, fmt.Errorf("policies %s could not be loaded: %w", cfg.policies, err)
+	}
+	cache, err := newPartialEvalCache(cfg, registerer)
+	if err != nil {
+		return nil, err
+	}
+	return pep.CachePartialEvaluations(cache), nil
+}
+
+// newPartialEvalCache returns nil if the cache is disabled, the cache statistics are registered as Prometheus metrics
+func newPartialEvalCache(cfg config, registerer prometheus.Registerer) (*infrastructure.PartialEvalCache, error) {
+	if cfg.partialCacheSize <= 0 {
+		return nil, nil
+	}
+
+	cache := infrastructure.NewPartialEvalCache(cfg.partialCacheSize, cfg.partialCacheTTL, infrastructure.SystemTimeService{})
+	if err := registerer.Register(infrastructure.NewPartialEvalCacheCollector(cache)); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// newBundlePolicyEnforcementPoint activates the first bundle and loads new revisions in the background
+func newBundlePolicyEnforcementPoint(ctx context.Context, cfg config, registerer prometheus.Registerer) (application.PolicyEnforcementPoint, error) {
+	var verification *bundle.VerificationConfig
+	if cfg.bundleKey != "" {
+		key, err := os.ReadFile(cfg.bundleKey)
+		if err != nil {
+			return nil, err
+		}
+		verification = infrastructure.NewPolicyBundleVerification(cfg.bundleKeyID, cfg.bundleKeyAlgorithm, key)
+	}
+
+	pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(ctx, infrastructure.NewPolicyBundleSource(cfg.bundle, verification))
+	if err != nil {
+		return nil, fmt.Errorf("policy bundle %s could not be loaded: %w", cfg.bundle, err)
+	}
+	cache, err := newPartialEvalCache(cfg, registerer)
+	if err != nil {
+		return nil, err
+	}
+	pep.CachePartialEvaluations(cache)
+	log.Printf("policy bundle revision %s is active", pep.PolicyRevision())
+
+	go pep.Watch(ctx, cfg.bundleInterval, func(err error) {
+		log.Printf("policy bundle could not be reloaded, revision %s stays active: %s", pep.PolicyRevision(), err)
+	})
+	return pep, nil
+}
+
+// newDecisionLoggers opens the configured sinks, the sqlite or memory sink is returned as the queryable decision log
+func newDecisionLoggers(cfg config) (infrastructure.DecisionLoggers, application.DecisionLog, error) {
+	var loggers infrastructure.DecisionLoggers
+	var decisions application.DecisionLog
+
+	for _, sink := range splitList(cfg.decisionLogs) {
+		switch sink {
+		case decisionLogStdout:
+			loggers = append(loggers, infrastructure.NewDecisionLoggerJSON(os.Stdout))
+		case decisionLogFile:
+			logger, err := infrastructure.NewDecisionLoggerFile(cfg.decisionLogFile, cfg.decisionLogMaxBytes, cfg.decisionLogBackups)
+			if err != nil {
+				return nil, nil, err
+			}
+			loggers = append(loggers, logger)
+		case decisionLogSQLite:
+			store, err := infrastructure.NewDecisionLogSQLite(cfg.decisionLogPath)
+			if err != nil {
+				return nil, nil, err
+			}
+			loggers = append(loggers, store)
+			decisions = store
+		case decisionLogMemory:
+			store := infrastructure.NewDecisionLogMemory(cfg.decisionLogMemory)
+			loggers = append(loggers, store)
+			decisions = store
+		default:
+			return nil, nil, fmt.Errorf("unknown decision log %q", sink)
+		}
+	}
+	return loggers, decisions, nil
+}
+
+func newAuthenticator(cfg config) rest.HeaderAuthenticator {
+	return rest.HeaderAuthenticator{Header: cfg.userHeader, RolesHeader: cfg.rolesHeader, Roles: newRoles(cfg)}
+}
+
+// newMetadataAuthenticator reads the user of the gRPC calls from the metadata keys of the headers, the gateway forwards them
+func newMetadataAuthenticator(cfg config) rpc.MetadataAuthenticator {
+	return rpc.MetadataAuthenticator{Key: strings.ToLower(cfg.userHeader), RolesKey: strings.ToLower(cfg.rolesHeader), Roles: newRoles(cfg)}
+}
+
+func newRoles(cfg config) map[application.UserId][]application.Role {
+	roles := make(map[application.UserId][]application.Role)
+	for _, id := range splitList(cfg.moderators) {
+		roles[application.UserId(id)] = append(roles[application.UserId(id)], application.RoleModerator)
+	}
+	for _, id := range splitList(cfg.admins) {
+		roles[application.UserId(id)] = append(roles[application.UserId(id)], application.RoleAdmin)
+	}
+	return roles
+}
+
+func newModeration(cfg config) (application.ModerationCheck, error) {
+	checks := []application.ModerationCheck{
+		infrastructure.LinkSpamCheck{MaxLinks: cfg.maxLinks, MaxLinkRatio: 0.5},
+	}
+
+	if cfg.wordList != "" {
+		words, err := readList(cfg.wordList)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, infrastructure.NewWordListCheck(words, application.VerdictReject))
+	}
+
+	if cfg.denyList != "" {
+		patterns, err := readList(cfg.denyList)
+		if err != nil {
+			return nil, err
+		}
+		check, err := infrastructure.NewDenyListCheck(patterns, application.VerdictReject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny list %s: %w", cfg.denyList, err)
+		}
+		checks = append(checks, check)
+	}
+	return application.NewModerationPipeline(checks...), nil
+}
+
+// newUserDirectory reads the display names of the users, without a file every user is shown by the id
+func newUserDirectory(cfg config) (gql.UserDirectory, error) {
+	if cfg.userDirectory == "" {
+		return infrastructure.NewUserDirectoryMemory(nil), nil
+	}
+
+	f, err := os.Open(cfg.userDirectory)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	directory, err := infrastructure.ReadUserDirectory(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user directory %s: %w", cfg.userDirectory, err)
+	}
+	return directory, nil
+}
+
+func readList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return infrastructure.ReadList(f)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type eventLog interface {
+	infrastructure.EventStore
+	projections.EventLoader
+}
 
+func newStorage(ctx context.Context, cfg config) (application.Repository, eventLog, error) {
+	switch cfg.storage {
+	case storageMemory:
+		return infrastructure.NewOpinionsRepositoryMemory(), infrastructure.NewEventLogMemory(), nil
+	case storageSQLite:
+		repo, err := infrastructure.NewOpinionsRepositorySQLite(cfg.sqlitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		events, err := infrastructure.NewEventLogSQLite(cfg.eventLogPath)
+		return repo, events, err
+	case storagePostgres:
+		repo, err := infrastructure.NewOpinionsRepositoryPostgres(ctx, cfg.postgresDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		events, err := infrastructure.NewEventLogPostgres(ctx, cfg.postgresDSN)
+		return repo, events, err
+	default:
+		return nil, nil, fmt.Errorf("unknown storage %q", cfg.storage)
+	}
 }
@@ -0,0 +1,144 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/lineage"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Explain modes of Evaluate, they select the trace events which are returned
+const (
+	ExplainOff   = "off"
+	ExplainFails = "fails"
+	ExplainNotes = "notes"
+	ExplainFull  = "full"
+)
+
+var (
+	InvalidExplainModeError = errors.New("invalid explain mode")
+	MissingQueryError       = errors.New("request has no query")
+)
+
+// Request is a query with its input and the unknowns of a partial evaluation, see example.json
+type Request struct {
+	Query    string   `json:"query"`
+	Input    any      `json:"input"`
+	Unknowns []string `json:"unknowns"`
+}
+
+// ReadRequest decodes a Request from the JSON file
+func ReadRequest(path string) (Request, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Request{}, err
+	}
+	defer file.Close()
+	return DecodeRequest(file)
+}
+
+// DecodeRequest decodes a JSON encoded Request
+func DecodeRequest(r io.Reader) (Request, error) {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return Request{}, fmt.Errorf("request could not be decoded: %w", err)
+	}
+	if req.Query == "" {
+		return Request{}, MissingQueryError
+	}
+	return req, nil
+}
+
+// Evaluation is the result of Evaluate
+type Evaluation struct {
+	// Allowed is set if the query returned a result whose expressions are all true
+	Allowed bool
+	Results rego.ResultSet
+	// Trace contains the events of the explain mode, it is empty for ExplainOff
+	Trace []*topdown.Event
+}
+
+// Explain writes the trace with the locations of the policies
+func (e Evaluation) Explain(w io.Writer) {
+	topdown.PrettyTraceWithLocation(w, e.Trace)
+}
+
+// Evaluate evaluates the query of the request with its input against the policies of the paths, Rego test files are ignored
+func Evaluate(ctx context.Context, paths []string, req Request, explain string) (Evaluation, error) {
+	filter, err := explainFilter(explain)
+	if err != nil {
+		return Evaluation{}, err
+	}
+
+	tracer := topdown.NewBufferTracer()
+	results, err := rego.New(
+		rego.Query(req.Query),
+		rego.Input(req.Input),
+		rego.Load(paths, ignoreRegoTests),
+		rego.QueryTracer(tracer),
+	).Eval(ctx)
+	if err != nil {
+		return Evaluation{}, err
+	}
+
+	evaluation := Evaluation{
+		Allowed: results.Allowed(),
+		Results: results,
+	}
+	if filter != nil {
+		evaluation.Trace = filter(*tracer)
+	}
+	return evaluation, nil
+}
+
+func explainFilter(explain string) (func([]*topdown.Event) []*topdown.Event, error) {
+	switch explain {
+	case ExplainOff, "":
+		return nil, nil
+	case ExplainFails:
+		return lineage.Fails, nil
+	case ExplainNotes:
+		return lineage.Notes, nil
+	case ExplainFull:
+		return func(trace []*topdown.Event) []*topdown.Event { return trace }, nil
+	default:
+		return nil, fmt.Errorf("%w %q, use %s, %s, %s or %s", InvalidExplainModeError, explain, ExplainOff, ExplainFails, ExplainNotes, ExplainFull)
+	}
+}
+
+// PartialEvaluation is the result of PartialEvaluate
+type PartialEvaluation struct {
+	Queries *rego.PartialQueries
+	// Filters are the parsed Queries, see ParsePartialQueries
+	Filters [][]ParsedExpression
+}
+
+// PartialEvaluate evaluates the query of the request with its unknowns against the policies of the paths.
+// AccessDeniedError is returned with the queries if no input of the unknowns is allowed.
+func PartialEvaluate(ctx context.Context, paths []string, req Request) (PartialEvaluation, error) {
+	queries, err := rego.New(
+		rego.Query(req.Query),
+		rego.Input(req.Input),
+		rego.Unknowns(req.Unknowns),
+		rego.Load(paths, ignoreRegoTests),
+	).Partial(ctx)
+	if err != nil {
+		return PartialEvaluation{}, err
+	}
+
+	filters, err := ParsePartialQueries(*queries)
+	return PartialEvaluation{Queries: queries, Filters: filters}, err
+}
+
+// ignoreRegoTests is a loader.Filter which skips the Rego test files
+var ignoreRegoTests loader.Filter = func(_ string, info fs.FileInfo, _ int) bool {
+	return !info.IsDir() && strings.HasSuffix(info.Name(), "_test.rego")
+}
@@ -0,0 +1,128 @@
+package authorization_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func exampleRequest(t *testing.T) authorization.Request {
+	t.Helper()
+	req, err := authorization.ReadRequest("example.json")
+	if err != nil {
+		t.Fatalf("ReadRequest() returned error %s, but no error is expected", err)
+	}
+	return req
+}
+
+func TestDecodeRequest(t *testing.T) {
+	t.Parallel()
+	_, err := authorization.DecodeRequest(strings.NewReader(`{"input": {}}`))
+	assert.ErrorIs(t, err, authorization.MissingQueryError)
+
+	_, err = authorization.DecodeRequest(strings.NewReader(`{`))
+	assert.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		req         authorization.Request
+		explain     string
+		wantAllowed bool
+		wantTrace   bool
+		wantErr     bool
+	}{
+		{
+			name:        "Should allow the owner to delete an opinion",
+			req:         authorization.Request{Query: "data.site.opinions.allow", Input: map[string]any{"user": "123", "owner": "123", "action": "DeleteOpinion"}},
+			explain:     authorization.ExplainOff,
+			wantAllowed: true,
+		},
+		{
+			name:      "Should deny other users and explain the failed expressions",
+			req:       authorization.Request{Query: "data.site.opinions.allow", Input: map[string]any{"user": "456", "owner": "123", "action": "DeleteOpinion"}},
+			explain:   authorization.ExplainFails,
+			wantTrace: true,
+		},
+		{
+			name:        "Should trace every event in full mode",
+			req:         authorization.Request{Query: "data.site.opinions.allow", Input: map[string]any{"user": "123", "action": "CreateOpinion"}},
+			explain:     authorization.ExplainFull,
+			wantAllowed: true,
+			wantTrace:   true,
+		},
+		{
+			name:    "Should reject unknown explain modes",
+			req:     authorization.Request{Query: "data.site.opinions.allow"},
+			explain: "verbose",
+			wantErr: true,
+		},
+		{
+			name:    "Should reject invalid queries",
+			req:     authorization.Request{Query: "data.site.opinions.allow ==="},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := authorization.Evaluate(context.Background(), []string{policies}, tt.req, tt.explain)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantAllowed, got.Allowed)
+			if !tt.wantTrace {
+				assert.Empty(t, got.Trace)
+				return
+			}
+
+			var explained bytes.Buffer
+			got.Explain(&explained)
+			assert.NotEmpty(t, got.Trace)
+			assert.Contains(t, explained.String(), "opinions.rego")
+		})
+	}
+}
+
+func TestEvaluate_example(t *testing.T) {
+	t.Parallel()
+	req := exampleRequest(t)
+	req.Query = "data.site.opinions.allow"
+
+	got, err := authorization.Evaluate(context.Background(), []string{policies}, req, authorization.ExplainFails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, got.Allowed, "the user of the example does not own the opinion")
+	assert.NotEmpty(t, got.Trace)
+}
+
+func TestPartialEvaluate(t *testing.T) {
+	t.Parallel()
+	req := exampleRequest(t)
+
+	got, err := authorization.PartialEvaluate(context.Background(), []string{policies}, req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.ElementsMatch(t, [][]authorization.ParsedExpression{
+		{{FieldName: "status", Value: "published", Operator: "eq"}},
+		{{FieldName: "ownerId", Value: "123", Operator: "eq"}},
+	}, got.Filters)
+
+	req.Input = map[string]any{"user": "", "action": "ListOpinions"}
+	got, err = authorization.PartialEvaluate(context.Background(), []string{policies}, req)
+	assert.ErrorIs(t, err, authorization.AccessDeniedError)
+	assert.Empty(t, got.Queries.Queries)
+}
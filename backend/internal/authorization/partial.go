@@ -0,0 +1,165 @@
+// Package authorization evaluates the Rego policies of the site and turns the results of partial evaluations into filters.
+package authorization
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"strings"
+)
+
+var (
+	AccessDeniedError                 = errors.New("access denied")
+	InvalidPolicyQueryTermError       = errors.New("invalid policy term")
+	InvalidPolicyQueryExpressionError = errors.New("invalid policy expression")
+)
+
+// ParsePartialRawResult parses the JSON encoded result of a partial evaluation, see ParsePartialQueries
+func ParsePartialRawResult(result []byte) ([][]ParsedExpression, error) {
+	var r rego.PartialQueries
+	if err := json.Unmarshal(result, &r); err != nil {
+		return nil, err
+	}
+	return ParsePartialQueries(r)
+}
+
+// ParseCompileResponse parses the response of the compile API of an OPA server, it wraps the partial result in "result"
+// https://www.openpolicyagent.org/docs/latest/rest-api/#compile-api
+func ParseCompileResponse(response []byte) ([][]ParsedExpression, error) {
+	var r struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(response, &r); err != nil {
+		return nil, err
+	}
+	// a missing result means that no input of the unknowns is allowed
+	if len(r.Result) == 0 {
+		return nil, AccessDeniedError
+	}
+	return ParsePartialRawResult(r.Result)
+}
+
+// ParsePartialQueries returns the expressions of every query. The expressions of a query are combined with AND,
+// the queries are combined with OR. An unconditional result is returned as a single query without expressions.
+func ParsePartialQueries(r rego.PartialQueries) ([][]ParsedExpression, error) {
+	if err := IsAccessDenied(r); err != nil {
+		return nil, err
+	}
+
+	if IsUnconditional(r.Queries) {
+		return [][]ParsedExpression{{}}, nil
+	}
+
+	queries := make([][]ParsedExpression, 0, len(r.Queries))
+	for _, body := range r.Queries {
+		expressions := make([]ParsedExpression, 0, len(body))
+		for _, expr := range body {
+			parsed, err := ParseExpression(expr)
+			if err != nil {
+				return nil, err
+			}
+			expressions = append(expressions, parsed)
+		}
+		queries = append(queries, expressions)
+	}
+	return queries, nil
+}
+
+// IsUnconditional checks if the given queries contains an unconditional query.
+// Unconditional queries could be threaten like a super admin access
+// https://www.openpolicyagent.org/docs/latest/rest-api/#unconditional-results-from-partial-evaluation
+func IsUnconditional(queries []ast.Body) bool {
+	for _, q := range queries {
+		if len(q) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAccessDenied checks if the query response states access denied error
+// https://www.openpolicyagent.org/docs/latest/rest-api/#unconditional-results-from-partial-evaluation
+func IsAccessDenied(q rego.PartialQueries) error {
+	if len(q.Queries) == 0 {
+		return AccessDeniedError
+	}
+	return nil
+}
+
+// ParsedExpression is a comparison of a field with a value, it reads as "FieldName Operator Value"
+type ParsedExpression struct {
+	FieldName string
+	Value     any
+	Operator  string
+}
+
+// mirroredOperators keep the meaning of a comparison if its operands are swapped
+var mirroredOperators = map[string]string{
+	"lt":  "gt",
+	"lte": "gte",
+	"gt":  "lt",
+	"gte": "lte",
+}
+
+func ParseExpression(e *ast.Expr) (ParsedExpression, error) {
+	if !e.IsCall() {
+		return ParsedExpression{}, InvalidPolicyQueryExpressionError
+	}
+
+	pe := ParsedExpression{
+		Operator: e.Operator().String(),
+	}
+
+	if len(e.Operands()) != 2 {
+		return ParsedExpression{}, InvalidPolicyQueryExpressionError
+	}
+
+	// the value may be null, so it is tracked separately
+	var hasValue bool
+	for i, term := range e.Operands() {
+		isValue, err := pe.parseOperand(term, i == 0)
+		if err != nil {
+			return ParsedExpression{}, err
+		}
+		hasValue = hasValue || isValue
+	}
+
+	if !hasValue || pe.FieldName == "" {
+		return ParsedExpression{}, InvalidPolicyQueryExpressionError
+	}
+	return pe, nil
+}
+
+// parseOperand sets the value or the field of the operand and reports whether it is the value.
+// A value on the left side mirrors the operator, e.g. 5 < data.opinions.votes becomes votes > 5.
+func (pe *ParsedExpression) parseOperand(term *ast.Term, left bool) (bool, error) {
+	if !ast.IsConstant(term.Value) {
+		fieldName, err := ParseTerm(term.String())
+		if err != nil {
+			return false, err
+		}
+		pe.FieldName = fieldName
+		return false, nil
+	}
+
+	val, err := ast.JSON(term.Value)
+	if err != nil {
+		return false, err
+	}
+	pe.Value = val
+	if mirrored, ok := mirroredOperators[pe.Operator]; ok && left {
+		pe.Operator = mirrored
+	}
+	return true, nil
+}
+
+// ParseTerm returns the field of a reference to an unknown document, it has to be of the form data.<document>.<field>.
+// The document is not returned, the caller knows the table which the filter is compiled for.
+func ParseTerm(term string) (string, error) {
+	split := strings.Split(term, ".")
+	if len(split) != 3 || split[0] != "data" || split[1] == "" || split[2] == "" {
+		return "", InvalidPolicyQueryTermError
+	}
+	return split[2], nil
+}
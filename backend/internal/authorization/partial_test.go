@@ -0,0 +1,31 @@
+package authorization_test
+
+import (
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseTerm(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{term: "data.opinions.status", want: "status"},
+		{term: "data.opinions", wantErr: authorization.InvalidPolicyQueryTermError},
+		{term: "data.opinions.meta.status", wantErr: authorization.InvalidPolicyQueryTermError},
+		{term: "input.opinions.status", wantErr: authorization.InvalidPolicyQueryTermError},
+		{term: "data..status", wantErr: authorization.InvalidPolicyQueryTermError},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.term, func(t *testing.T) {
+			t.Parallel()
+			got, err := authorization.ParseTerm(tt.term)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
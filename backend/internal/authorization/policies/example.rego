@@ -1,12 +0,0 @@
-package site
-
-
-allow {
-    input.method = "GET"
-    input.path = ["opinions", opinion_id]
-    security_filter
-}
-
-security_filter{
-    data.opinions.ownerId == input.ownerId
-}
\ No newline at end of file
@@ -0,0 +1,102 @@
+package site.opinions
+
+import future.keywords.in
+
+# The input is the access request of the opinions service, e.g.
+# {"user": "123", "roles": ["user"], "action": "DeleteOpinion", "opinion": "789", "owner": "456"}
+# The opinion and its owner are only set for actions which address a single opinion, otherwise they are empty.
+# The owner and owner only actions are denied without an owner.
+
+default allow = false
+
+# user_actions are permitted to every authenticated user
+user_actions := {
+	"CreateOpinion",
+	"ListOpinions",
+	"ReportOpinion",
+	"CreateVote",
+	"UpdateVote",
+	"DeleteVote",
+}
+
+# owner_actions are permitted to the owner of the opinion and to the role which may perform them on any opinion
+owner_actions := {
+	"DeleteOpinion": "admin",
+	"HideOpinion": "moderator",
+}
+
+# owner_only_actions are only permitted to the owner of the opinion
+owner_only_actions := {"EditOpinion"}
+
+# role_actions are only permitted to the role
+role_actions := {
+	"ApproveOpinion": "moderator",
+	"RejectOpinion": "moderator",
+	"ViewUnpublishedOpinions": "moderator",
+	"ListReports": "moderator",
+	"ResolveReports": "moderator",
+	"BulkDeleteOpinions": "admin",
+	"ListDecisions": "admin",
+}
+
+roles[role] {
+	some role in input.roles
+}
+
+# admins have all capabilities of moderators
+roles["moderator"] {
+	"admin" in input.roles
+}
+
+authenticated {
+	input.user != ""
+}
+
+owns {
+	input.owner != ""
+	input.owner == input.user
+}
+
+allow {
+	authenticated
+	input.action in user_actions
+}
+
+allow {
+	authenticated
+	owner_actions[input.action]
+	owns
+}
+
+allow {
+	authenticated
+	roles[owner_actions[input.action]]
+}
+
+allow {
+	authenticated
+	input.action in owner_only_actions
+	owns
+}
+
+allow {
+	authenticated
+	roles[role_actions[input.action]]
+}
+
+# visible selects the opinions a user may list. The service evaluates it partially with data.opinions as unknown,
+# the remaining comparisons of data.opinions.id, data.opinions.ownerId and data.opinions.status become the filter of the list.
+visible {
+	authenticated
+	data.opinions.status == "published"
+}
+
+visible {
+	authenticated
+	data.opinions.ownerId == input.user
+}
+
+visible {
+	authenticated
+	roles[role_actions.ViewUnpublishedOpinions]
+}
@@ -0,0 +1,125 @@
+package site.opinions
+
+user := {"user": "123", "roles": ["user"], "owner": ""}
+
+moderator := {"user": "moderator", "roles": ["user", "moderator"], "owner": ""}
+
+admin := {"user": "admin", "roles": ["user", "admin"], "owner": ""}
+
+anonymous := {"user": "", "roles": [], "owner": ""}
+
+request(subject, action) := object.union(subject, {"action": action})
+
+request_on(subject, action, owner) := object.union(subject, {"action": action, "owner": owner})
+
+test_users_create_and_list_opinions {
+	allow with input as request(user, "CreateOpinion")
+	allow with input as request(user, "ListOpinions")
+	allow with input as request(user, "ReportOpinion")
+}
+
+test_users_vote {
+	allow with input as request(user, "CreateVote")
+	allow with input as request(user, "UpdateVote")
+	allow with input as request(user, "DeleteVote")
+}
+
+test_anonymous_users_are_denied {
+	not allow with input as request(anonymous, "ListOpinions")
+	not allow with input as request(anonymous, "CreateOpinion")
+	not allow with input as request(anonymous, "CreateVote")
+	not allow with input as request_on(anonymous, "DeleteOpinion", "")
+}
+
+test_roles_without_user_are_denied {
+	not allow with input as request({"user": "", "roles": ["admin"], "owner": ""}, "BulkDeleteOpinions")
+}
+
+test_unknown_actions_are_denied {
+	not allow with input as request(user, "DropDatabase")
+	not allow with input as request(admin, "DropDatabase")
+}
+
+test_owners_delete_their_opinions {
+	allow with input as request_on(user, "DeleteOpinion", "123")
+	allow with input as request_on(user, "HideOpinion", "123")
+	allow with input as request_on(user, "EditOpinion", "123")
+}
+
+test_users_do_not_delete_foreign_opinions {
+	not allow with input as request_on(user, "DeleteOpinion", "456")
+	not allow with input as request_on(user, "HideOpinion", "456")
+	not allow with input as request_on(user, "EditOpinion", "456")
+}
+
+test_owner_actions_without_owner_are_denied {
+	not allow with input as request_on(user, "DeleteOpinion", "")
+	not allow with input as request_on(user, "HideOpinion", "")
+	not allow with input as request_on(user, "EditOpinion", "")
+}
+
+test_admins_do_not_edit_foreign_opinions {
+	not allow with input as request_on(admin, "EditOpinion", "456")
+}
+
+test_users_do_not_moderate {
+	not allow with input as request(user, "ApproveOpinion")
+	not allow with input as request(user, "RejectOpinion")
+	not allow with input as request(user, "ViewUnpublishedOpinions")
+	not allow with input as request(user, "ListReports")
+	not allow with input as request(user, "ResolveReports")
+	not allow with input as request(user, "BulkDeleteOpinions")
+	not allow with input as request(user, "ListDecisions")
+}
+
+test_moderators_moderate {
+	allow with input as request(moderator, "ApproveOpinion")
+	allow with input as request(moderator, "RejectOpinion")
+	allow with input as request(moderator, "ViewUnpublishedOpinions")
+	allow with input as request(moderator, "ListReports")
+	allow with input as request(moderator, "ResolveReports")
+	allow with input as request_on(moderator, "HideOpinion", "456")
+}
+
+test_moderators_do_not_delete_foreign_opinions {
+	not allow with input as request_on(moderator, "DeleteOpinion", "456")
+	not allow with input as request(moderator, "BulkDeleteOpinions")
+	not allow with input as request(moderator, "ListDecisions")
+}
+
+test_admins_override_the_owner {
+	allow with input as request_on(admin, "DeleteOpinion", "456")
+	allow with input as request_on(admin, "HideOpinion", "456")
+	allow with input as request(admin, "BulkDeleteOpinions")
+	allow with input as request(admin, "ListDecisions")
+}
+
+test_admins_moderate {
+	allow with input as request(admin, "ApproveOpinion")
+	allow with input as request(admin, "RejectOpinion")
+	allow with input as request(admin, "ViewUnpublishedOpinions")
+	allow with input as request(admin, "ListReports")
+	allow with input as request(admin, "ResolveReports")
+}
+
+test_admins_are_users {
+	allow with input as request(admin, "CreateOpinion")
+	allow with input as request(admin, "CreateVote")
+}
+
+pending := {"id": "789", "ownerId": "456", "status": "pending_review"}
+
+test_users_see_published_and_own_opinions {
+	visible with input as request(user, "ListOpinions") with data.opinions as object.union(pending, {"status": "published"})
+	visible with input as request(user, "ListOpinions") with data.opinions as object.union(pending, {"ownerId": "123"})
+	not visible with input as request(user, "ListOpinions") with data.opinions as pending
+}
+
+test_moderators_see_unpublished_opinions {
+	visible with input as request(moderator, "ListOpinions") with data.opinions as pending
+	visible with input as request(admin, "ListOpinions") with data.opinions as pending
+}
+
+test_anonymous_users_see_no_opinions {
+	not visible with input as request(anonymous, "ListOpinions") with data.opinions as object.union(pending, {"status": "published"})
+}
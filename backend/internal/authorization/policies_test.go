@@ -0,0 +1,35 @@
+package authorization_test
+
+import (
+	"context"
+	"github.com/open-policy-agent/opa/tester"
+	"testing"
+)
+
+const policies = "./policies"
+
+// TestPolicies runs the Rego unit tests of the policies
+func TestPolicies(t *testing.T) {
+	t.Parallel()
+
+	results, err := tester.Run(context.Background(), policies)
+	if err != nil {
+		t.Fatalf("policies could not be loaded: %s", err)
+	}
+
+	if len(results) == 0 {
+		t.Fatalf("no policy tests found in %s", policies)
+	}
+
+	for _, result := range results {
+		result := result
+		t.Run(result.Package+"."+result.Name, func(t *testing.T) {
+			if result.Error != nil {
+				t.Fatalf("policy test %s failed with error: %s", result.Location, result.Error)
+			}
+			if result.Fail {
+				t.Errorf("policy test %s failed at %s", result.Location, result.FailedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package authorization
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	UnsupportedOperatorError = errors.New("unsupported filter operator")
+	UnknownFilterFieldError  = errors.New("unknown filter field")
+	InvalidFilterValueError  = errors.New("invalid filter value")
+)
+
+// Filter is a tree of ParsedExpressions which are combined by And and Or
+type Filter interface {
+	filter()
+}
+
+// And matches if all filters match, an empty And matches every row
+type And []Filter
+
+// Or matches if one of the filters matches, an empty Or matches no row
+type Or []Filter
+
+func (ParsedExpression) filter() {}
+func (And) filter()              {}
+func (Or) filter()               {}
+
+// FilterOf combines the expressions of a query with And and the queries with Or, see ParsePartialQueries
+func FilterOf(queries [][]ParsedExpression) Filter {
+	or := make(Or, 0, len(queries))
+	for _, query := range queries {
+		and := make(And, 0, len(query))
+		for _, e := range query {
+			and = append(and, e)
+		}
+		or = append(or, and)
+	}
+	return or
+}
+
+// Dialect renders the parts of a SQL condition which differ between the databases
+type Dialect interface {
+	// Placeholder returns the placeholder of the n-th argument, the first argument is 1
+	Placeholder(n int) string
+	QuoteIdentifier(name string) string
+	Boolean(b bool) string
+}
+
+// SQLiteDialect uses ? placeholders and renders booleans as 1 and 0 like SQLite stores them
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+func (SQLiteDialect) QuoteIdentifier(name string) string { return quoteIdentifier(name) }
+
+func (SQLiteDialect) Boolean(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// PostgresDialect uses numbered placeholders, quoted identifiers are case-sensitive
+type PostgresDialect struct{}
+
+func (PostgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (PostgresDialect) QuoteIdentifier(name string) string { return quoteIdentifier(name) }
+
+func (PostgresDialect) Boolean(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// quoteIdentifier quotes the name with double quotes, quotes inside the name are doubled
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// NewSQLCompiler renders filters for the dialect. The columns map the field names of the expressions to the columns,
+// fields without a column are rejected. Without columns the field names are used as columns.
+func NewSQLCompiler(dialect Dialect, columns map[string]string) SQLCompiler {
+	return SQLCompiler{
+		dialect: dialect,
+		columns: columns,
+	}
+}
+
+// SQLCompiler renders filters as parameterized conditions of a WHERE clause.
+// Values of the policies are always passed as arguments, only quoted columns and known operators are part of the SQL.
+type SQLCompiler struct {
+	dialect Dialect
+	columns map[string]string
+}
+
+// Compile returns the condition and its arguments in the order of the placeholders
+func (c SQLCompiler) Compile(f Filter) (string, []any, error) {
+	comp := &compilation{SQLCompiler: c}
+	sql, err := comp.filter(f)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, comp.args, nil
+}
+
+// compilation collects the arguments of one Compile call
+type compilation struct {
+	SQLCompiler
+	args []any
+}
+
+func (c *compilation) filter(f Filter) (string, error) {
+	switch f := f.(type) {
+	case ParsedExpression:
+		return c.expression(f)
+	case And:
+		return c.combine([]Filter(f), " AND ", c.dialect.Boolean(true))
+	case Or:
+		return c.combine([]Filter(f), " OR ", c.dialect.Boolean(false))
+	default:
+		return "", fmt.Errorf("%w: unknown filter %T", InvalidPolicyQueryExpressionError, f)
+	}
+}
+
+// combine joins the filters with the operator, empty is rendered for no filters
+func (c *compilation) combine(filters []Filter, operator string, empty string) (string, error) {
+	if len(filters) == 0 {
+		return empty, nil
+	}
+
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		sql, err := c.filter(f)
+		if err != nil {
+			return "", err
+		}
+		if _, leaf := f.(ParsedExpression); !leaf {
+			sql = "(" + sql + ")"
+		}
+		parts = append(parts, sql)
+	}
+	return strings.Join(parts, operator), nil
+}
+
+// comparisons are the SQL operators of the Rego comparisons
+var comparisons = map[string]string{
+	"eq":    "=",
+	"equal": "=",
+	"neq":   "<>",
+	"lt":    "<",
+	"lte":   "<=",
+	"gt":    ">",
+	"gte":   ">=",
+}
+
+// memberOperator is the Rego operator of "field in [values]"
+const memberOperator = "internal.member_2"
+
+func (c *compilation) expression(e ParsedExpression) (string, error) {
+	column, err := c.column(e.FieldName)
+	if err != nil {
+		return "", err
+	}
+
+	if e.Operator == memberOperator {
+		return c.in(column, e.Value)
+	}
+
+	operator, ok := comparisons[e.Operator]
+	if !ok {
+		return "", fmt.Errorf("%w %q", UnsupportedOperatorError, e.Operator)
+	}
+
+	if e.Value == nil {
+		switch operator {
+		case "=":
+			return column + " IS NULL", nil
+		case "<>":
+			return column + " IS NOT NULL", nil
+		default:
+			return "", fmt.Errorf("%w: null can not be compared with %s", InvalidFilterValueError, e.Operator)
+		}
+	}
+
+	value, err := c.value(e.Value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", column, operator, value), nil
+}
+
+// in renders a membership test, an empty list matches no row
+func (c *compilation) in(column string, value any) (string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return "", fmt.Errorf("%w: %T is not a list", InvalidFilterValueError, value)
+	}
+	if len(list) == 0 {
+		return c.dialect.Boolean(false), nil
+	}
+
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		if v == nil {
+			return "", fmt.Errorf("%w: lists must not contain null", InvalidFilterValueError)
+		}
+		rendered, err := c.value(v)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, rendered)
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(values, ", ")), nil
+}
+
+// value renders booleans as literals and adds all other scalars as argument
+func (c *compilation) value(v any) (string, error) {
+	switch v := v.(type) {
+	case bool:
+		return c.dialect.Boolean(v), nil
+	case string, float64, int, int64:
+		return c.arg(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return c.arg(i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", InvalidFilterValueError, err)
+		}
+		return c.arg(f), nil
+	default:
+		return "", fmt.Errorf("%w: %T is not a scalar", InvalidFilterValueError, v)
+	}
+}
+
+func (c *compilation) arg(v any) string {
+	c.args = append(c.args, v)
+	return c.dialect.Placeholder(len(c.args))
+}
+
+func (c *compilation) column(field string) (string, error) {
+	column := field
+	if c.columns != nil {
+		mapped, ok := c.columns[field]
+		if !ok {
+			return "", fmt.Errorf("%w %q", UnknownFilterFieldError, field)
+		}
+		column = mapped
+	}
+
+	if column == "" || strings.ContainsRune(column, 0) {
+		return "", fmt.Errorf("%w %q", UnknownFilterFieldError, field)
+	}
+	return c.dialect.QuoteIdentifier(column), nil
+}
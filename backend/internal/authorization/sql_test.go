@@ -0,0 +1,267 @@
+package authorization_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSQLCompiler_Compile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		filter       authorization.Filter
+		columns      map[string]string
+		wantSQLite   string
+		wantPostgres string
+		wantArgs     []any
+		wantErr      error
+	}{
+		{
+			name: "Should combine the expressions with AND and the queries with OR",
+			filter: authorization.FilterOf([][]authorization.ParsedExpression{
+				{{FieldName: "ownerId", Value: "123", Operator: "eq"}, {FieldName: "votes", Value: json.Number("5"), Operator: "gte"}},
+				{{FieldName: "status", Value: "published", Operator: "neq"}},
+			}),
+			wantSQLite:   `("ownerId" = ? AND "votes" >= ?) OR ("status" <> ?)`,
+			wantPostgres: `("ownerId" = $1 AND "votes" >= $2) OR ("status" <> $3)`,
+			wantArgs:     []any{"123", int64(5), "published"},
+		},
+		{
+			name: "Should render nested trees",
+			filter: authorization.And{
+				authorization.ParsedExpression{FieldName: "a", Value: 1.5, Operator: "lt"},
+				authorization.Or{
+					authorization.ParsedExpression{FieldName: "b", Value: "x", Operator: "equal"},
+					authorization.And{
+						authorization.ParsedExpression{FieldName: "c", Value: "y", Operator: "gt"},
+						authorization.ParsedExpression{FieldName: "d", Value: "z", Operator: "lte"},
+					},
+				},
+			},
+			wantSQLite:   `"a" < ? AND ("b" = ? OR ("c" > ? AND "d" <= ?))`,
+			wantPostgres: `"a" < $1 AND ("b" = $2 OR ("c" > $3 AND "d" <= $4))`,
+			wantArgs:     []any{1.5, "x", "y", "z"},
+		},
+		{
+			name:         "Should map the fields to columns",
+			filter:       authorization.ParsedExpression{FieldName: "ownerId", Value: "123", Operator: "eq"},
+			columns:      map[string]string{"ownerId": "userid"},
+			wantSQLite:   `"userid" = ?`,
+			wantPostgres: `"userid" = $1`,
+			wantArgs:     []any{"123"},
+		},
+		{
+			name:    "Should reject fields without column",
+			filter:  authorization.ParsedExpression{FieldName: "statement", Value: "123", Operator: "eq"},
+			columns: map[string]string{"ownerId": "userid"},
+			wantErr: authorization.UnknownFilterFieldError,
+		},
+		{
+			name:         "Should quote the identifiers",
+			filter:       authorization.ParsedExpression{FieldName: `id" OR 1=1 --`, Value: "1", Operator: "eq"},
+			wantSQLite:   `"id"" OR 1=1 --" = ?`,
+			wantPostgres: `"id"" OR 1=1 --" = $1`,
+			wantArgs:     []any{"1"},
+		},
+		{
+			name:         "Should render IN lists",
+			filter:       authorization.ParsedExpression{FieldName: "status", Value: []any{"published", "hidden", true}, Operator: "internal.member_2"},
+			wantSQLite:   `"status" IN (?, ?, 1)`,
+			wantPostgres: `"status" IN ($1, $2, TRUE)`,
+			wantArgs:     []any{"published", "hidden"},
+		},
+		{
+			name:         "Should match no row for an empty IN list",
+			filter:       authorization.ParsedExpression{FieldName: "status", Value: []any{}, Operator: "internal.member_2"},
+			wantSQLite:   `0`,
+			wantPostgres: `FALSE`,
+		},
+		{
+			name:    "Should reject IN without list",
+			filter:  authorization.ParsedExpression{FieldName: "status", Value: "published", Operator: "internal.member_2"},
+			wantErr: authorization.InvalidFilterValueError,
+		},
+		{
+			name:    "Should reject null in IN lists",
+			filter:  authorization.ParsedExpression{FieldName: "status", Value: []any{nil}, Operator: "internal.member_2"},
+			wantErr: authorization.InvalidFilterValueError,
+		},
+		{
+			name: "Should render NULL checks",
+			filter: authorization.And{
+				authorization.ParsedExpression{FieldName: "deleted", Value: nil, Operator: "eq"},
+				authorization.ParsedExpression{FieldName: "owner", Value: nil, Operator: "neq"},
+			},
+			wantSQLite:   `"deleted" IS NULL AND "owner" IS NOT NULL`,
+			wantPostgres: `"deleted" IS NULL AND "owner" IS NOT NULL`,
+		},
+		{
+			name:    "Should reject ordering comparisons with null",
+			filter:  authorization.ParsedExpression{FieldName: "votes", Value: nil, Operator: "lt"},
+			wantErr: authorization.InvalidFilterValueError,
+		},
+		{
+			name:         "Should render boolean literals",
+			filter:       authorization.ParsedExpression{FieldName: "published", Value: false, Operator: "eq"},
+			wantSQLite:   `"published" = 0`,
+			wantPostgres: `"published" = FALSE`,
+		},
+		{
+			name:         "Should match every row for an unconditional filter",
+			filter:       authorization.FilterOf([][]authorization.ParsedExpression{{}}),
+			wantSQLite:   `(1)`,
+			wantPostgres: `(TRUE)`,
+		},
+		{
+			name:         "Should match no row without queries",
+			filter:       authorization.Or{},
+			wantSQLite:   `0`,
+			wantPostgres: `FALSE`,
+		},
+		{
+			name:    "Should reject unsupported operators",
+			filter:  authorization.ParsedExpression{FieldName: "statement", Value: "a", Operator: "startswith"},
+			wantErr: authorization.UnsupportedOperatorError,
+		},
+		{
+			name:    "Should reject objects",
+			filter:  authorization.ParsedExpression{FieldName: "owner", Value: map[string]any{"id": "1"}, Operator: "eq"},
+			wantErr: authorization.InvalidFilterValueError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			for dialect, want := range map[authorization.Dialect]string{
+				authorization.SQLiteDialect{}:   tt.wantSQLite,
+				authorization.PostgresDialect{}: tt.wantPostgres,
+			} {
+				sql, args, err := authorization.NewSQLCompiler(dialect, tt.columns).Compile(tt.filter)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+					continue
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, want, sql, "%T", dialect)
+				assert.Equal(t, tt.wantArgs, args, "%T", dialect)
+			}
+		})
+	}
+}
+
+func TestSQLCompiler_Compile_compile_response(t *testing.T) {
+	t.Parallel()
+	response, err := os.ReadFile("resp.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries, err := authorization.ParseCompileResponse(response)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, [][]authorization.ParsedExpression{{{FieldName: "allow", Value: true, Operator: "eq"}}}, queries)
+
+	sqlite, args, err := authorization.NewSQLCompiler(authorization.SQLiteDialect{}, nil).Compile(authorization.FilterOf(queries))
+	assert.NoError(t, err)
+	assert.Equal(t, `("allow" = 1)`, sqlite)
+	assert.Empty(t, args)
+
+	postgres, _, err := authorization.NewSQLCompiler(authorization.PostgresDialect{}, nil).Compile(authorization.FilterOf(queries))
+	assert.NoError(t, err)
+	assert.Equal(t, `("allow" = TRUE)`, postgres)
+
+	_, err = authorization.ParseCompileResponse([]byte(`{}`))
+	assert.ErrorIs(t, err, authorization.AccessDeniedError)
+}
+
+// fuzzColumns are the columns of the fuzz table, the fuzzed filters compare them
+var fuzzColumns = map[string]string{"owner": "ownerId", "status": "status", "votes": "votes"}
+
+func openFuzzTable(f *testing.F) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		f.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	f.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE opinions (ownerId TEXT, status TEXT, votes REAL); INSERT INTO opinions VALUES ('123', 'published', 3)`); err != nil {
+		f.Fatal(err)
+	}
+	return db
+}
+
+// FuzzSQLCompiler_Compile checks that values of the policies are only passed as arguments and that every compiled filter is valid SQL
+func FuzzSQLCompiler_Compile(f *testing.F) {
+	db := openFuzzTable(f)
+
+	f.Add("owner", "eq", "123", false)
+	f.Add("status", "neq", "' OR '1'='1", false)
+	f.Add("votes", "gte", "1; DROP TABLE opinions; --", true)
+	f.Add("owner", "internal.member_2", `") OR 1=1 --`, true)
+	f.Add("status", "lt", "$1 ? ?", false)
+
+	f.Fuzz(func(t *testing.T, field string, operator string, value string, list bool) {
+		var v any = value
+		if list {
+			v = []any{value, value + "'"}
+		}
+
+		condition, args, err := authorization.NewSQLCompiler(authorization.SQLiteDialect{}, fuzzColumns).Compile(authorization.Or{
+			authorization.And{authorization.ParsedExpression{FieldName: field, Value: v, Operator: operator}},
+			authorization.And{authorization.ParsedExpression{FieldName: "owner", Value: value, Operator: "neq"}},
+		})
+		if err != nil {
+			return
+		}
+
+		if strings.Count(condition, "?") != len(args) {
+			t.Fatalf("condition %q has %d placeholders for %d arguments", condition, strings.Count(condition, "?"), len(args))
+		}
+
+		var count int
+		if err := db.QueryRow("SELECT count(*) FROM opinions WHERE "+condition, args...).Scan(&count); err != nil {
+			t.Fatalf("condition %q is invalid: %s", condition, err)
+		}
+		if err := db.QueryRow("SELECT count(*) FROM opinions").Scan(&count); err != nil || count != 1 {
+			t.Fatalf("condition %q changed the table: %d rows, %v", condition, count, err)
+		}
+	})
+}
+
+// FuzzSQLiteDialect_QuoteIdentifier checks that every quoted identifier selects exactly the column with the name
+func FuzzSQLiteDialect_QuoteIdentifier(f *testing.F) {
+	db := openFuzzTable(f)
+
+	f.Add("ownerId")
+	f.Add(`a" FROM opinions; DROP TABLE opinions; --`)
+	f.Add(`""`)
+
+	f.Fuzz(func(t *testing.T, name string) {
+		if name == "" || strings.ContainsRune(name, 0) || !utf8Valid(name) {
+			return
+		}
+
+		quoted := authorization.SQLiteDialect{}.QuoteIdentifier(name)
+		var got string
+		if err := db.QueryRow("SELECT " + quoted + " FROM (SELECT 'ok' AS " + quoted + ")").Scan(&got); err != nil {
+			t.Fatalf("identifier %q quoted as %s is invalid: %s", name, quoted, err)
+		}
+		if got != "ok" {
+			t.Fatalf("identifier %q quoted as %s selected %q", name, quoted, got)
+		}
+	})
+}
+
+func utf8Valid(s string) bool {
+	return strings.ToValidUTF8(s, "") == s
+}
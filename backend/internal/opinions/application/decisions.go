@@ -0,0 +1,251 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"time"
+)
+
+// Decision is the recorded answer of the PolicyEnforcementPoint to an AccessRequest
+type Decision struct {
+	Time   time.Time
+	User   UserId
+	Roles  []Role
+	Action string
+	// Opinion and Owner describe the resource of the request, they are empty if the action does not address a single opinion
+	Opinion OpinionId
+	Owner   UserId
+	// InputHash is the HMAC-SHA-256 of the unmasked AccessRequest if a key is configured with HashDecisionInputs,
+	// otherwise the SHA-256 of the masked request, so masked values can not be guessed from the hash
+	InputHash string
+	Allowed   bool
+	// Reason is the error of a denied decision, it is empty for allowed decisions
+	Reason string
+	// PolicyRevision identifies the policies which made the decision, it is empty if the PolicyEnforcementPoint does not know it
+	PolicyRevision string
+	Latency        time.Duration
+}
+
+// VisibilityDecisionAction is the action of the decisions of a VisibilityPolicy about the opinions a user may list.
+// They are not denied if the user may list some opinions.
+const VisibilityDecisionAction = "OpinionVisibility"
+
+// MaskedValue replaces the values of masked decision fields
+const MaskedValue = "***"
+
+// Fields of a Decision which can be masked
+const (
+	DecisionFieldUser    = "user"
+	DecisionFieldRoles   = "roles"
+	DecisionFieldOpinion = "opinion"
+	DecisionFieldOwner   = "owner"
+)
+
+// Mask replaces the values of the given fields with MaskedValue, unknown fields are ignored
+func (d Decision) Mask(fields ...string) Decision {
+	for _, field := range fields {
+		switch field {
+		case DecisionFieldUser:
+			d.User = MaskedValue
+		case DecisionFieldRoles:
+			d.Roles = []Role{MaskedValue}
+		case DecisionFieldOpinion:
+			if d.Opinion != "" {
+				d.Opinion = MaskedValue
+			}
+		case DecisionFieldOwner:
+			if d.Owner != "" {
+				d.Owner = MaskedValue
+			}
+		}
+	}
+	return d
+}
+
+// DefaultDecisionLimit is the count of decisions which is listed if the filter has no limit
+const DefaultDecisionLimit = 100
+
+// maxDecisionLimit bounds the count of decisions which is listed at once
+const maxDecisionLimit = 1000
+
+// DecisionFilter selects decisions, empty fields match every decision
+type DecisionFilter struct {
+	User   UserId
+	Action string
+	// Allowed selects only allowed or only denied decisions if it is set
+	Allowed *bool
+	// From is inclusive, Before is exclusive
+	From   time.Time
+	Before time.Time
+	// Limit is the maximum count of listed decisions
+	Limit int
+}
+
+// Matches reports whether the decision is selected by the filter, the limit is not considered
+func (f DecisionFilter) Matches(d Decision) bool {
+	if f.User != "" && d.User != f.User {
+		return false
+	}
+	if f.Action != "" && d.Action != f.Action {
+		return false
+	}
+	if f.Allowed != nil && d.Allowed != *f.Allowed {
+		return false
+	}
+	if !f.From.IsZero() && d.Time.Before(f.From) {
+		return false
+	}
+	if !f.Before.IsZero() && !d.Time.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// DecisionLogger records decisions, e.g. as JSON lines or in a database
+type DecisionLogger interface {
+	LogDecision(ctx context.Context, decision Decision) error
+}
+
+// DecisionLog is a DecisionLogger which can be queried.
+// ListDecisions returns the newest decisions first and at most filter.Limit decisions.
+type DecisionLog interface {
+	DecisionLogger
+	ListDecisions(ctx context.Context, filter DecisionFilter) ([]Decision, error)
+}
+
+// PolicyRevisioner is implemented by PolicyEnforcementPoints which know the revision of their policies
+type PolicyRevisioner interface {
+	PolicyRevision() string
+}
+
+// DecisionLogOption changes the default configuration of the decision logging
+type DecisionLogOption func(p *decisionLoggingPolicyEnforcementPoint)
+
+// MaskDecisionFields masks the fields of every decision before it is logged, see Decision.Mask
+func MaskDecisionFields(fields ...string) DecisionLogOption {
+	return func(p *decisionLoggingPolicyEnforcementPoint) {
+		p.masked = append(p.masked, fields...)
+	}
+}
+
+// OnDecisionLogError is called with the decision if it could not be logged, without it the error is dropped
+func OnDecisionLogError(handler func(decision Decision, err error)) DecisionLogOption {
+	return func(p *decisionLoggingPolicyEnforcementPoint) {
+		p.onError = handler
+	}
+}
+
+// HashDecisionInputs keys the InputHash of the decisions with the secret, equal requests have equal hashes even if fields are masked
+func HashDecisionInputs(key []byte) DecisionLogOption {
+	return func(p *decisionLoggingPolicyEnforcementPoint) {
+		p.hashKey = key
+	}
+}
+
+// NewDecisionLoggingPolicyEnforcementPoint decorates the policy enforcement point and records every decision with the logger.
+// If the policy enforcement point is a VisibilityPolicy, the decorator is one as well and records its decisions as VisibilityDecisionAction.
+// A failed logging does not change the decision.
+func NewDecisionLoggingPolicyEnforcementPoint(next PolicyEnforcementPoint, logger DecisionLogger, timeService TimeService, options ...DecisionLogOption) PolicyEnforcementPoint {
+	p := &decisionLoggingPolicyEnforcementPoint{
+		next:        next,
+		logger:      logger,
+		timeService: timeService,
+		onError:     func(Decision, error) {},
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	if visibility, ok := next.(VisibilityPolicy); ok {
+		return &decisionLoggingVisibilityPolicy{decisionLoggingPolicyEnforcementPoint: p, visibility: visibility}
+	}
+	return p
+}
+
+type decisionLoggingPolicyEnforcementPoint struct {
+	next        PolicyEnforcementPoint
+	logger      DecisionLogger
+	timeService TimeService
+	masked      []string
+	hashKey     []byte
+	onError     func(decision Decision, err error)
+}
+
+func (p *decisionLoggingPolicyEnforcementPoint) RequestAccessForUser(ctx context.Context, request AccessRequest) error {
+	start := p.timeService.CurrentTime()
+	err := p.next.RequestAccessForUser(ctx, request)
+	p.log(ctx, request, request.Action, start, err)
+	return err
+}
+
+// log records the decision about the request, it is allowed if err is nil
+func (p *decisionLoggingPolicyEnforcementPoint) log(ctx context.Context, request AccessRequest, action string, start time.Time, err error) {
+	decision := Decision{
+		Time:           start,
+		User:           request.User,
+		Roles:          request.Roles,
+		Action:         action,
+		Opinion:        request.Opinion,
+		Owner:          request.Owner,
+		Allowed:        err == nil,
+		PolicyRevision: p.PolicyRevision(),
+		Latency:        p.timeService.CurrentTime().Sub(start),
+	}
+	if err != nil {
+		decision.Reason = err.Error()
+	}
+
+	decision = decision.Mask(p.masked...)
+	decision.InputHash = p.hashInput(request, decision)
+	if logErr := p.logger.LogDecision(ctx, decision); logErr != nil {
+		p.onError(decision, logErr)
+	}
+}
+
+// hashInput returns the keyed hash of the request or, without a key, the hash of the request as it is logged
+func (p *decisionLoggingPolicyEnforcementPoint) hashInput(request AccessRequest, masked Decision) string {
+	if len(p.hashKey) > 0 {
+		return hashAccessRequest(hmac.New(sha256.New, p.hashKey), request)
+	}
+	return hashAccessRequest(sha256.New(), AccessRequest{
+		User:    masked.User,
+		Roles:   masked.Roles,
+		Action:  request.Action,
+		Opinion: masked.Opinion,
+		Owner:   masked.Owner,
+	})
+}
+
+// PolicyRevision returns the revision of the decorated policy enforcement point
+func (p *decisionLoggingPolicyEnforcementPoint) PolicyRevision() string {
+	if r, ok := p.next.(PolicyRevisioner); ok {
+		return r.PolicyRevision()
+	}
+	return ""
+}
+
+// hashAccessRequest returns the hex encoded hash of the JSON encoded request
+func hashAccessRequest(h hash.Hash, request AccessRequest) string {
+	// the request only consists of strings, encoding it can not fail
+	encoded, _ := json.Marshal(request)
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// decisionLoggingVisibilityPolicy also records the decisions of the decorated VisibilityPolicy
+type decisionLoggingVisibilityPolicy struct {
+	*decisionLoggingPolicyEnforcementPoint
+	visibility VisibilityPolicy
+}
+
+func (p *decisionLoggingVisibilityPolicy) OpinionVisibility(ctx context.Context, request AccessRequest) (OpinionVisibility, error) {
+	start := p.timeService.CurrentTime()
+	visibility, err := p.visibility.OpinionVisibility(ctx, request)
+	p.log(ctx, request, VisibilityDecisionAction, start, err)
+	return visibility, err
+}
@@ -0,0 +1,283 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	mock_application "github.com/fwiedmann/site/backend/internal/opinions/application/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// revisionedPolicyEnforcementPoint adds a policy revision to the mocked policy enforcement point
+type revisionedPolicyEnforcementPoint struct {
+	application.PolicyEnforcementPoint
+	revision string
+}
+
+func (p revisionedPolicyEnforcementPoint) PolicyRevision() string {
+	return p.revision
+}
+
+func TestDecisionLoggingPolicyEnforcementPoint(t *testing.T) {
+	t.Parallel()
+	logError := errors.New("log error")
+	testDate := time.Now()
+	request := application.AccessRequest{
+		User:    "1",
+		Roles:   []application.Role{application.RoleUser},
+		Action:  application.ActionDeleteOpinion,
+		Opinion: "187",
+		Owner:   "2",
+	}
+
+	tests := []struct {
+		name         string
+		pepError     error
+		logError     error
+		options      []application.DecisionLogOption
+		wantDecision application.Decision
+	}{
+		{
+			name: "Should log allowed decisions",
+			wantDecision: application.Decision{
+				User:    "1",
+				Roles:   []application.Role{application.RoleUser},
+				Action:  application.ActionDeleteOpinion,
+				Opinion: "187",
+				Owner:   "2",
+				Allowed: true,
+			},
+		},
+		{
+			name:     "Should log denied decisions with the reason",
+			pepError: application.AccessDeniedError,
+			wantDecision: application.Decision{
+				User:    "1",
+				Roles:   []application.Role{application.RoleUser},
+				Action:  application.ActionDeleteOpinion,
+				Opinion: "187",
+				Owner:   "2",
+				Reason:  "forbidden: access denied",
+			},
+		},
+		{
+			name:    "Should mask the fields",
+			options: []application.DecisionLogOption{application.MaskDecisionFields(application.DecisionFieldOwner, application.DecisionFieldRoles)},
+			wantDecision: application.Decision{
+				User:    "1",
+				Roles:   []application.Role{application.MaskedValue},
+				Action:  application.ActionDeleteOpinion,
+				Opinion: "187",
+				Owner:   application.MaskedValue,
+				Allowed: true,
+			},
+		},
+		{
+			name:     "Should keep the decision if it could not be logged",
+			pepError: application.AccessDeniedError,
+			logError: logError,
+			wantDecision: application.Decision{
+				User:    "1",
+				Roles:   []application.Role{application.RoleUser},
+				Action:  application.ActionDeleteOpinion,
+				Opinion: "187",
+				Owner:   "2",
+				Reason:  "forbidden: access denied",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			gomock.InOrder(
+				timeService.EXPECT().CurrentTime().Return(testDate),
+				timeService.EXPECT().CurrentTime().Return(testDate.Add(time.Millisecond)),
+			)
+
+			next := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			next.EXPECT().RequestAccessForUser(gomock.Any(), request).Return(tt.pepError)
+
+			var logged application.Decision
+			log := mock_application.NewMockDecisionLog(ctrl)
+			log.EXPECT().LogDecision(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, d application.Decision) error {
+				logged = d
+				return tt.logError
+			})
+
+			var handled error
+			options := append(tt.options, application.OnDecisionLogError(func(_ application.Decision, err error) {
+				handled = err
+			}))
+
+			pep := application.NewDecisionLoggingPolicyEnforcementPoint(revisionedPolicyEnforcementPoint{PolicyEnforcementPoint: next, revision: "rev"}, log, timeService, options...)
+			err := pep.RequestAccessForUser(context.Background(), request)
+
+			assert.ErrorIs(t, err, tt.pepError)
+			assert.ErrorIs(t, handled, tt.logError)
+
+			assert.Len(t, logged.InputHash, 64)
+			tt.wantDecision.Time = testDate
+			tt.wantDecision.Latency = time.Millisecond
+			tt.wantDecision.PolicyRevision = "rev"
+			tt.wantDecision.InputHash = logged.InputHash
+			assert.Equal(t, tt.wantDecision, logged)
+		})
+	}
+}
+
+func TestDecisionLoggingPolicyEnforcementPoint_input_hash(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	timeService := mock_application.NewMockTimeService(ctrl)
+	timeService.EXPECT().CurrentTime().Return(time.Now()).AnyTimes()
+
+	next := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+	next.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	var hashes []string
+	log := mock_application.NewMockDecisionLog(ctrl)
+	log.EXPECT().LogDecision(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, d application.Decision) error {
+		hashes = append(hashes, d.InputHash)
+		return nil
+	}).Times(6)
+
+	masked := application.NewDecisionLoggingPolicyEnforcementPoint(next, log, timeService, application.MaskDecisionFields(application.DecisionFieldUser))
+	keyed := application.NewDecisionLoggingPolicyEnforcementPoint(next, log, timeService, application.MaskDecisionFields(application.DecisionFieldUser), application.HashDecisionInputs([]byte("secret")))
+	for _, pep := range []application.PolicyEnforcementPoint{masked, keyed} {
+		for _, user := range []application.UserId{"1", "1", "2"} {
+			assert.NoError(t, pep.RequestAccessForUser(context.Background(), application.AccessRequest{User: user, Action: application.ActionListOpinions}))
+		}
+	}
+
+	assert.Equal(t, hashes[0], hashes[1])
+	assert.Equal(t, hashes[0], hashes[2], "without a key masked fields are not part of the hash")
+	assert.Equal(t, hashes[3], hashes[4])
+	assert.NotEqual(t, hashes[3], hashes[5], "with a key masked fields are part of the hash")
+	assert.NotEqual(t, hashes[0], hashes[3])
+}
+
+// visibilityPolicyEnforcementPoint adds a visibility to the mocked policy enforcement point
+type visibilityPolicyEnforcementPoint struct {
+	application.PolicyEnforcementPoint
+	err error
+}
+
+func (p visibilityPolicyEnforcementPoint) OpinionVisibility(context.Context, application.AccessRequest) (application.OpinionVisibility, error) {
+	return application.AllOpinionsVisible, p.err
+}
+
+func TestDecisionLoggingPolicyEnforcementPoint_visibility(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	timeService := mock_application.NewMockTimeService(ctrl)
+	timeService.EXPECT().CurrentTime().Return(time.Now()).AnyTimes()
+
+	var logged []application.Decision
+	log := mock_application.NewMockDecisionLog(ctrl)
+	log.EXPECT().LogDecision(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, d application.Decision) error {
+		logged = append(logged, d)
+		return nil
+	}).Times(2)
+
+	request := application.AccessRequest{User: "1", Action: application.ActionListOpinions}
+	for _, err := range []error{nil, application.AccessDeniedError} {
+		pep := application.NewDecisionLoggingPolicyEnforcementPoint(visibilityPolicyEnforcementPoint{PolicyEnforcementPoint: mock_application.NewMockPolicyEnforcementPoint(ctrl), err: err}, log, timeService)
+		visibility, ok := pep.(application.VisibilityPolicy)
+		if !assert.True(t, ok, "the decorator of a VisibilityPolicy is a VisibilityPolicy") {
+			return
+		}
+		_, gotErr := visibility.OpinionVisibility(context.Background(), request)
+		assert.ErrorIs(t, gotErr, err)
+	}
+
+	assert.Equal(t, application.VisibilityDecisionAction, logged[0].Action)
+	assert.True(t, logged[0].Allowed)
+	assert.Equal(t, application.VisibilityDecisionAction, logged[1].Action)
+	assert.False(t, logged[1].Allowed)
+	assert.Equal(t, "forbidden: access denied", logged[1].Reason)
+
+	_, ok := application.NewDecisionLoggingPolicyEnforcementPoint(mock_application.NewMockPolicyEnforcementPoint(ctrl), log, timeService).(application.VisibilityPolicy)
+	assert.False(t, ok)
+}
+
+func TestService_ListDecisionsQuery(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	testDate := time.Now()
+	decisions := []application.Decision{{User: "2", Action: application.ActionCreateOpinion, Allowed: true}}
+
+	tests := []struct {
+		name       string
+		pepError   error
+		withLog    bool
+		filter     application.DecisionFilter
+		wantFilter application.DecisionFilter
+		wantErr    error
+	}{
+		{
+			name:     "Should throw error because user is not permitted",
+			pepError: application.AccessDeniedError,
+			withLog:  true,
+			wantErr:  application.AccessDeniedError,
+		},
+		{
+			name:    "Should throw error because no decision log is configured",
+			wantErr: application.DecisionLogUnavailableError,
+		},
+		{
+			name:    "Should throw error because time range is invalid",
+			withLog: true,
+			filter:  application.DecisionFilter{From: testDate, Before: testDate.Add(-time.Minute)},
+			wantErr: application.InvalidDecisionTimeRangeError,
+		},
+		{
+			name:       "Should use the default limit",
+			withLog:    true,
+			filter:     application.DecisionFilter{User: "2"},
+			wantFilter: application.DecisionFilter{User: "2", Limit: application.DefaultDecisionLimit},
+		},
+		{
+			name:       "Should bound the limit",
+			withLog:    true,
+			filter:     application.DecisionFilter{Limit: 100000},
+			wantFilter: application.DecisionFilter{Limit: 1000},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListDecisions)).Return(tt.pepError)
+
+			var options []application.Option
+			if tt.withLog {
+				log := mock_application.NewMockDecisionLog(ctrl)
+				log.EXPECT().ListDecisions(gomock.Any(), tt.wantFilter).Return(decisions, nil).MaxTimes(1)
+				options = append(options, application.WithDecisionLog(log))
+			}
+
+			s := application.NewOpinionService(pep, mock_application.NewMockRepository(ctrl), mock_application.NewMockEventPublisher(ctrl),
+				mock_application.NewMockIdService(ctrl), mock_application.NewMockTimeService(ctrl), options...)
+			got, err := s.ListDecisionsQuery(context.Background(), application.AuthenticatedUser{Id: testUserId}, tt.filter)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, decisions, got)
+		})
+	}
+}
@@ -8,19 +8,89 @@ type OpinionId string
 // UserId unique identifier for an user in the system
 type UserId string
 
+// OpinionStatus is the moderation state of an opinion
+type OpinionStatus string
+
+const (
+	// OpinionPublished opinions are visible to every user
+	OpinionPublished OpinionStatus = "published"
+	// OpinionPendingReview opinions wait for an admin and are only visible to the owner and admins
+	OpinionPendingReview OpinionStatus = "pending_review"
+	// OpinionRejected opinions were declined by an admin and are only visible to the owner and admins
+	OpinionRejected OpinionStatus = "rejected"
+	// OpinionHidden opinions were reported too often and are only visible to the owner and admins until the reports are resolved
+	OpinionHidden OpinionStatus = "hidden"
+)
+
 // Opinion is submitted by an authenticated user.
-// Only the owner or the admin is able to delete the opinion.
+// Only the owner or an admin is able to delete the opinion.
 // If a User gets deleted in the system, all related opinions should be deleted too.
 type Opinion struct {
 	ID        OpinionId
 	Owner     UserId
 	CreatedAt time.Time
 	Statement string
+	Status    OpinionStatus
+	// Version starts at 1 and is incremented by every update, it detects concurrent updates
+	Version int64
+}
+
+// Cursor returns the position of the opinion in the lists
+func (o Opinion) Cursor() OpinionCursor {
+	return OpinionCursor{CreatedAt: o.CreatedAt, ID: o.ID}
+}
+
+// OpinionCursor is a position in the lists, which are ordered by creation time and id.
+// It stays valid after its opinion was deleted, the zero value is the position before the first opinion.
+type OpinionCursor struct {
+	CreatedAt time.Time
+	ID        OpinionId
+}
+
+// Precedes reports whether the opinion follows the cursor in the lists
+func (c OpinionCursor) Precedes(o Opinion) bool {
+	if o.CreatedAt.Equal(c.CreatedAt) {
+		return o.ID > c.ID
+	}
+	return o.CreatedAt.After(c.CreatedAt)
+}
+
+// OpinionPage selects at most Limit opinions which follow the After cursor
+type OpinionPage struct {
+	After OpinionCursor
+	Limit int
+}
+
+// OpinionList is a page of the opinions which are visible to the user
+type OpinionList struct {
+	Opinions []Opinion
+	// TotalCount is the count of all opinions which are visible to the user
+	TotalCount  int
+	HasNextPage bool
 }
 
 // OpinionCreateDTO holds required information to perform a create action on a opinion
 type OpinionCreateDTO struct {
 	Statement string
+	// IdempotencyKey is optional, a repeated command with the same key returns the opinion of the first command
+	IdempotencyKey string
+}
+
+// IdempotencyRecord remembers the result of a create command for the key of the user until it expires
+type IdempotencyRecord struct {
+	User UserId
+	Key  string
+	// RequestHash identifies the payload of the command, the key must not be reused for another payload
+	RequestHash string
+	Opinion     Opinion
+	ExpiresAt   time.Time
+}
+
+// OpinionUpdateDTO holds required information to perform an edit action on a opinion
+type OpinionUpdateDTO struct {
+	Statement string
+	// Version is the version the user has seen, zero skips the check
+	Version int64
 }
 
 // Vote represents a users agreement or disagreement on the given opinion.
@@ -31,17 +101,137 @@ type Vote struct {
 	Voter     UserId
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// Version is incremented by every change of the agreement, a new vote has version 1
+	Version int64
+}
+
+// VoteCount is the tally of the votes on an opinion
+type VoteCount struct {
+	Agreements    int
+	Disagreements int
 }
 
 // VoteCreateAndUpdateDTO holds required information to perform a create or update action on a vote
 type VoteCreateAndUpdateDTO struct {
 	Agreement bool
 	Opinion   OpinionId
+	// Version is the version the user has seen, it is only checked on update and zero skips the check
+	Version int64
 }
 
+// ReportReason is the category a user chooses when flagging an opinion
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonAbuse          ReportReason = "abuse"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonOther          ReportReason = "other"
+)
+
+// Valid reports whether the reason is one of the known categories
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReportReasonSpam, ReportReasonAbuse, ReportReasonMisinformation, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReportResolution is the decision of an admin about the reports of an opinion
+type ReportResolution string
+
+const (
+	// ReportDismissed keeps the opinion, a hidden opinion gets published again
+	ReportDismissed ReportResolution = "dismissed"
+	// ReportUpheld rejects the opinion
+	ReportUpheld ReportResolution = "upheld"
+)
+
+// Valid reports whether the resolution is one of the known decisions
+func (r ReportResolution) Valid() bool {
+	return r == ReportDismissed || r == ReportUpheld
+}
+
+// Report flags an opinion as inappropriate, every user can report an opinion once.
+// A Report is open until an admin resolved it.
+type Report struct {
+	Opinion    OpinionId
+	Reporter   UserId
+	Reason     ReportReason
+	CreatedAt  time.Time
+	Resolution ReportResolution
+	ResolvedAt time.Time
+}
+
+// ReportCreateDTO holds required information to report an opinion
+type ReportCreateDTO struct {
+	Opinion OpinionId
+	Reason  ReportReason
+}
+
+// OpinionFilter selects the opinions of a bulk command, zero fields match every opinion
+type OpinionFilter struct {
+	Owner UserId
+	// CreatedFrom is inclusive
+	CreatedFrom time.Time
+	// CreatedBefore is exclusive
+	CreatedBefore time.Time
+}
+
+// Empty reports whether the filter matches every opinion
+func (f OpinionFilter) Empty() bool {
+	return f.Owner == "" && f.CreatedFrom.IsZero() && f.CreatedBefore.IsZero()
+}
+
+// Matches reports whether the opinion is selected by the filter
+func (f OpinionFilter) Matches(o Opinion) bool {
+	if f.Owner != "" && f.Owner != o.Owner {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && o.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+	return f.CreatedBefore.IsZero() || o.CreatedAt.Before(f.CreatedBefore)
+}
+
+// Role grants capabilities to a user, the policies decide which actions a role may perform
+type Role string
+
+const (
+	// RoleUser is granted to every authenticated user
+	RoleUser Role = "user"
+	// RoleModerator may review and hide the opinions of all users
+	RoleModerator Role = "moderator"
+	// RoleAdmin may additionally delete the opinions of all users
+	RoleAdmin Role = "admin"
+)
+
 // AuthenticatedUser is capable to perform actions on opinions and votes
 type AuthenticatedUser struct {
-	Id UserId
+	Id    UserId
+	Roles []Role
+}
+
+// HasRole reports whether the role was granted to the user
+func (a AuthenticatedUser) HasRole(role Role) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessRequest is the input of the PolicyEnforcementPoint
+type AccessRequest struct {
+	User   UserId
+	Roles  []Role
+	Action string
+	// Opinion the action is performed on, it is empty if the action does not address a single opinion
+	Opinion OpinionId
+	// Owner of the opinion if the permission may depend on it, e.g. for DeleteOpinion and HideOpinion, it is empty otherwise
+	Owner UserId
 }
 
 // AuthorizedUser represents a user identity which is permitted to perform the action on the given resource
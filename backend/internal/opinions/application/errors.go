@@ -0,0 +1,198 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorCode classifies an Error independent of the transport which renders it
+type ErrorCode string
+
+const (
+	// CodeValidation is used if the input of a command is invalid
+	CodeValidation ErrorCode = "validation"
+	// CodeNotFound is used if a referenced opinion or vote does not exist
+	CodeNotFound ErrorCode = "not_found"
+	// CodeConflict is used if the command collides with the stored state
+	CodeConflict ErrorCode = "conflict"
+	// CodeForbidden is used if the user is not permitted to perform the action
+	CodeForbidden ErrorCode = "forbidden"
+	// CodeUnauthenticated is used if the user identity is missing
+	CodeUnauthenticated ErrorCode = "unauthenticated"
+	// CodeRateLimited is used if the user performed an action too often, the Error carries the RetryAfter
+	CodeRateLimited ErrorCode = "rate_limited"
+	// CodeInternal is used for all failures which are not caused by the user
+	CodeInternal ErrorCode = "internal"
+)
+
+var (
+	// EmptyOpinionStatementError can be returned during OpinionCreateDTO validation
+	EmptyOpinionStatementError = &Error{Code: CodeValidation, Message: "opinion statement is empty", Fields: []FieldError{{Field: "statement", Message: "must not be empty"}}}
+	// OpinionRejectedError is returned if the moderation declined the statement, the reasons are set as fields
+	OpinionRejectedError = &Error{Code: CodeValidation, Message: "opinion was rejected by the moderation"}
+	// EmptyOpinionFilterError is returned if a bulk command would affect all opinions
+	EmptyOpinionFilterError = &Error{Code: CodeValidation, Message: "opinion filter is empty", Fields: []FieldError{{Field: "filter", Message: "must contain an owner or a creation time range"}}}
+	// InvalidCreationTimeRangeError is returned if a filter selects a creation time range which ends before it starts
+	InvalidCreationTimeRangeError = &Error{Code: CodeValidation, Message: "creation time range is invalid", Fields: []FieldError{{Field: "createdBefore", Message: "must be after createdFrom"}}}
+	// InvalidDecisionTimeRangeError is returned if decisions are listed for a time range which ends before it starts
+	InvalidDecisionTimeRangeError = &Error{Code: CodeValidation, Message: "decision time range is invalid", Fields: []FieldError{{Field: "before", Message: "must be after from"}}}
+	// EmptyOpinionIdError can be returned if a command references an opinion without an id
+	EmptyOpinionIdError = &Error{Code: CodeValidation, Message: "opinion id is empty", Fields: []FieldError{{Field: "id", Message: "must not be empty"}}}
+
+	// UnauthenticatedError is returned if a command is called without a user identity
+	UnauthenticatedError = &Error{Code: CodeUnauthenticated, Message: "unauthenticated"}
+	// AccessDeniedError is returned by a PolicyEnforcementPoint if the user is not permitted to perform the action
+	AccessDeniedError = &Error{Code: CodeForbidden, Message: "access denied"}
+
+	// RateLimitedError matches every Error created by NewRateLimitedError
+	RateLimitedError = &Error{Code: CodeRateLimited, Message: "rate limit exceeded"}
+
+	// InvalidOpinionConditionError is returned if a VisibilityPolicy returned a condition which can not be applied to the opinions
+	InvalidOpinionConditionError = &Error{Code: CodeInternal, Message: "opinion condition is invalid"}
+
+	// DecisionLogUnavailableError is returned if decisions are listed but the service has no DecisionLog
+	DecisionLogUnavailableError = &Error{Code: CodeNotFound, Message: "decision log is not configured"}
+
+	// OpinionNotFoundError is returned by a Repository if the requested opinion does not exist
+	OpinionNotFoundError = &Error{Code: CodeNotFound, Message: "opinion not found"}
+	// OpinionAlreadyExistsError is returned by a Repository if an opinion with the same id is already stored
+	OpinionAlreadyExistsError = &Error{Code: CodeConflict, Message: "opinion already exists"}
+	// OpinionVersionConflictError is returned if the opinion was updated since the given version was read
+	OpinionVersionConflictError = &Error{Code: CodeConflict, Message: "opinion was changed by another request"}
+	// VoteNotFoundError is returned by a Repository if the user did not vote on the opinion
+	VoteNotFoundError = &Error{Code: CodeNotFound, Message: "vote not found"}
+	// VoteAlreadyExistsError is returned by a Repository if the user already voted on the opinion
+	VoteAlreadyExistsError = &Error{Code: CodeConflict, Message: "vote already exists"}
+	// VoteVersionConflictError is returned if the vote was updated since the given version was read
+	VoteVersionConflictError = &Error{Code: CodeConflict, Message: "vote was changed by another request"}
+	// ReportAlreadyExistsError is returned by a Repository if the user already reported the opinion
+	ReportAlreadyExistsError = &Error{Code: CodeConflict, Message: "report already exists"}
+	// ReportNotFoundError is returned by a Repository if the opinion has no open reports
+	ReportNotFoundError = &Error{Code: CodeNotFound, Message: "report not found"}
+
+	// IdempotencyRecordNotFoundError is returned by a Repository if the key is unknown or expired
+	IdempotencyRecordNotFoundError = &Error{Code: CodeNotFound, Message: "idempotency key not found"}
+	// IdempotencyRecordAlreadyExistsError is returned by a Repository if the key of the user is stored and not expired
+	IdempotencyRecordAlreadyExistsError = &Error{Code: CodeConflict, Message: "idempotency key already exists"}
+	// IdempotencyKeyReusedError is returned if a key is sent again with a different payload
+	IdempotencyKeyReusedError = &Error{Code: CodeConflict, Message: "idempotency key was used for a different request"}
+	// InvalidIdempotencyKeyError is returned if the key exceeds the storable length
+	InvalidIdempotencyKeyError = &Error{Code: CodeValidation, Message: "idempotency key is invalid", Fields: []FieldError{{Field: "idempotencyKey", Message: "must contain at most 255 characters"}}}
+
+	// InvalidReportReasonError is returned if a report does not use one of the known reasons
+	InvalidReportReasonError = &Error{Code: CodeValidation, Message: "report reason is invalid", Fields: []FieldError{{Field: "reason", Message: "must be one of spam, abuse, misinformation or other"}}}
+	// InvalidReportResolutionError is returned if reports are resolved with an unknown resolution
+	InvalidReportResolutionError = &Error{Code: CodeValidation, Message: "report resolution is invalid", Fields: []FieldError{{Field: "resolution", Message: "must be dismissed or upheld"}}}
+)
+
+// FieldError describes why a single input field is invalid
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error is returned by the service, repositories and policy enforcement points.
+// The Message is safe to be shown to the user, the Cause is only meant for logs.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+	Cause   error
+	// RetryAfter is the time until the action is permitted again, it is only set for CodeRateLimited
+	RetryAfter time.Duration
+}
+
+// NewError creates an Error with the given code and message
+func NewError(code ErrorCode, message string, cause error) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Cause:   cause,
+	}
+}
+
+// NewValidationError creates a validation Error for the given field errors
+func NewValidationError(fields ...FieldError) *Error {
+	return &Error{
+		Code:    CodeValidation,
+		Message: "validation failed",
+		Fields:  fields,
+	}
+}
+
+// NewRateLimitedError creates a RateLimitedError which permits the action again after the given duration
+func NewRateLimitedError(retryAfter time.Duration) *Error {
+	e := *RateLimitedError
+	e.RetryAfter = retryAfter
+	return &e
+}
+
+// InternalError wraps an unexpected failure. Errors which are already an Error are returned unchanged.
+func InternalError(cause error) error {
+	if cause == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(cause, &e) {
+		return cause
+	}
+	return NewError(CodeInternal, "internal error", cause)
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Code, e.Message)
+	for _, f := range e.Fields {
+		msg = fmt.Sprintf("%s, %s: %s", msg, f.Field, f.Message)
+	}
+	if e.Cause != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.Cause)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether the target is an Error with the same code and message,
+// so sentinel errors match even if a repository attached a cause.
+// A target with field errors only matches the same field errors, e.g. the errors of NewValidationError.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if e.Code != t.Code || e.Message != t.Message {
+		return false
+	}
+	if len(t.Fields) == 0 {
+		return true
+	}
+	if len(e.Fields) != len(t.Fields) {
+		return false
+	}
+	for i := range t.Fields {
+		if e.Fields[i] != t.Fields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WithCause returns a copy of the error which wraps the given cause
+func (e *Error) WithCause(cause error) *Error {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+// CodeOf returns the code of the first Error in the chain, other errors are internal
+func CodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,66 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	t.Parallel()
+	cause := errors.New("no rows")
+
+	wrapped := fmt.Errorf("repository failed: %w", application.OpinionNotFoundError.WithCause(cause))
+
+	assert.ErrorIs(t, wrapped, application.OpinionNotFoundError)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.NotErrorIs(t, wrapped, application.VoteNotFoundError)
+
+	empty := application.NewValidationError(application.FieldError{Field: "statement", Message: "must not be empty"})
+	assert.ErrorIs(t, fmt.Errorf("validation: %w", empty), application.NewValidationError(application.FieldError{Field: "statement", Message: "must not be empty"}))
+	assert.NotErrorIs(t, empty, application.NewValidationError(application.FieldError{Field: "body", Message: "must not be empty"}))
+
+	rejected := &application.Error{Code: application.CodeValidation, Message: application.OpinionRejectedError.Message, Fields: []application.FieldError{{Field: "statement", Message: "contains a blocked word"}}}
+	assert.ErrorIs(t, rejected, application.OpinionRejectedError)
+}
+
+func TestInternalError(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, application.InternalError(nil))
+	assert.Equal(t, application.VoteNotFoundError, application.InternalError(application.VoteNotFoundError))
+
+	err := application.InternalError(context.Canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, application.CodeInternal, application.CodeOf(err))
+}
+
+func TestCodeOf(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want application.ErrorCode
+	}{
+		{name: "validation", err: application.EmptyOpinionStatementError, want: application.CodeValidation},
+		{name: "not found", err: application.OpinionNotFoundError, want: application.CodeNotFound},
+		{name: "conflict", err: application.VoteAlreadyExistsError, want: application.CodeConflict},
+		{name: "forbidden", err: fmt.Errorf("pep: %w", application.AccessDeniedError), want: application.CodeForbidden},
+		{name: "unauthenticated", err: application.UnauthenticatedError, want: application.CodeUnauthenticated},
+		{name: "unknown errors are internal", err: errors.New("boom"), want: application.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, application.CodeOf(tt.err))
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	t.Parallel()
+	err := application.NewValidationError(application.FieldError{Field: "statement", Message: "too long"})
+	assert.Equal(t, "validation: validation failed, statement: too long", err.Error())
+}
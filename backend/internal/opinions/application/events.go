@@ -0,0 +1,70 @@
+package application
+
+import (
+	"context"
+	"time"
+)
+
+// EventType names a domain event which happened in the opinions module
+type EventType string
+
+const (
+	// EventOpinionCreated is published after an opinion was stored
+	EventOpinionCreated EventType = "OpinionCreated"
+	// EventOpinionEdited is published after the owner changed the statement of an opinion
+	EventOpinionEdited EventType = "OpinionEdited"
+	// EventOpinionApproved is published after an admin published an opinion which was held for review
+	EventOpinionApproved EventType = "OpinionApproved"
+	// EventOpinionRejected is published after an admin declined an opinion
+	EventOpinionRejected EventType = "OpinionRejected"
+	// EventOpinionReported is published after a user reported an opinion
+	EventOpinionReported EventType = "OpinionReported"
+	// EventOpinionHidden is published after an opinion crossed the report threshold
+	EventOpinionHidden EventType = "OpinionHidden"
+	// EventReportsResolved is published after an admin resolved all open reports of an opinion
+	EventReportsResolved EventType = "ReportsResolved"
+	// EventOpinionDeleted is published after an opinion was removed
+	EventOpinionDeleted EventType = "OpinionDeleted"
+	// EventVoteSubmitted is published after a user voted for the first time on an opinion
+	EventVoteSubmitted EventType = "VoteSubmitted"
+	// EventVoteUpdated is published after a user changed the agreement of an existing vote
+	EventVoteUpdated EventType = "VoteUpdated"
+	// EventVoteDeleted is published after a user withdrew the vote
+	EventVoteDeleted EventType = "VoteDeleted"
+)
+
+// Event is a fact about a state change of an opinion, a vote or a report.
+// Depending on the Type the Opinion, the Vote or the Report payload is set,
+// report events carry the Opinion and the Report.
+// For EventOpinionDeleted only the Opinion.ID is guaranteed to be set.
+type Event struct {
+	// Sequence is the position in the event log, it is assigned when the event gets appended
+	Sequence   uint64
+	Type       EventType
+	OccurredAt time.Time
+	Opinion    Opinion
+	Vote       Vote
+	Report     Report
+	// PreviousStatus is the status of the Opinion before an edit, a review or a report changed it, it is empty for the other events
+	PreviousStatus OpinionStatus
+}
+
+// PreviousOpinion returns the Opinion with the PreviousStatus, ok is false if the event did not change an opinion
+func (e Event) PreviousOpinion() (o Opinion, ok bool) {
+	if e.PreviousStatus == "" {
+		return Opinion{}, false
+	}
+	o = e.Opinion
+	o.Status = e.PreviousStatus
+	return o, true
+}
+
+// EventPublisher is used by the service to announce state changes to the query side
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// EventHandler consumes published events, e.g. to update a read model
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event Event) error
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/fwiedmann/site/backend/internal/opinions/application (interfaces: Repository,PolicyEnforcementPoint,IdService,TimeService)
+// Source: github.com/fwiedmann/site/backend/internal/opinions/application (interfaces: Service,Repository,PolicyEnforcementPoint,VisibilityPolicy,IdService,TimeService,EventPublisher,RateLimitStore,DecisionLog)
 
 // Package mock_application is a generated GoMock package.
 package mock_application
@@ -13,6 +13,312 @@ import (
 	gomock "github.com/golang/mock/gomock"
 )
 
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// ApproveOpinionCommand mocks base method.
+func (m *MockService) ApproveOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveOpinionCommand indicates an expected call of ApproveOpinionCommand.
+func (mr *MockServiceMockRecorder) ApproveOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveOpinionCommand", reflect.TypeOf((*MockService)(nil).ApproveOpinionCommand), arg0, arg1, arg2)
+}
+
+// BulkDeleteOpinionsCommand mocks base method.
+func (m *MockService) BulkDeleteOpinionsCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionFilter) ([]application.OpinionId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkDeleteOpinionsCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]application.OpinionId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkDeleteOpinionsCommand indicates an expected call of BulkDeleteOpinionsCommand.
+func (mr *MockServiceMockRecorder) BulkDeleteOpinionsCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkDeleteOpinionsCommand", reflect.TypeOf((*MockService)(nil).BulkDeleteOpinionsCommand), arg0, arg1, arg2)
+}
+
+// CountVotesQuery mocks base method.
+func (m *MockService) CountVotesQuery(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 []application.OpinionId) (map[application.OpinionId]application.VoteCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountVotesQuery", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[application.OpinionId]application.VoteCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountVotesQuery indicates an expected call of CountVotesQuery.
+func (mr *MockServiceMockRecorder) CountVotesQuery(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountVotesQuery", reflect.TypeOf((*MockService)(nil).CountVotesQuery), arg0, arg1, arg2)
+}
+
+// CreateOpinionCommand mocks base method.
+func (m *MockService) CreateOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionCreateDTO) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOpinionCommand indicates an expected call of CreateOpinionCommand.
+func (mr *MockServiceMockRecorder) CreateOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOpinionCommand", reflect.TypeOf((*MockService)(nil).CreateOpinionCommand), arg0, arg1, arg2)
+}
+
+// CreateVoteCommand mocks base method.
+func (m *MockService) CreateVoteCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.VoteCreateAndUpdateDTO) (application.Vote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVoteCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Vote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVoteCommand indicates an expected call of CreateVoteCommand.
+func (mr *MockServiceMockRecorder) CreateVoteCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVoteCommand", reflect.TypeOf((*MockService)(nil).CreateVoteCommand), arg0, arg1, arg2)
+}
+
+// DeleteOpinionCommand mocks base method.
+func (m *MockService) DeleteOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOpinionCommand indicates an expected call of DeleteOpinionCommand.
+func (mr *MockServiceMockRecorder) DeleteOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOpinionCommand", reflect.TypeOf((*MockService)(nil).DeleteOpinionCommand), arg0, arg1, arg2)
+}
+
+// DeleteVoteCommand mocks base method.
+func (m *MockService) DeleteVoteCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId) (application.Vote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVoteCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Vote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteVoteCommand indicates an expected call of DeleteVoteCommand.
+func (mr *MockServiceMockRecorder) DeleteVoteCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVoteCommand", reflect.TypeOf((*MockService)(nil).DeleteVoteCommand), arg0, arg1, arg2)
+}
+
+// EditOpinionCommand mocks base method.
+func (m *MockService) EditOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId, arg3 application.OpinionUpdateDTO) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditOpinionCommand", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EditOpinionCommand indicates an expected call of EditOpinionCommand.
+func (mr *MockServiceMockRecorder) EditOpinionCommand(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditOpinionCommand", reflect.TypeOf((*MockService)(nil).EditOpinionCommand), arg0, arg1, arg2, arg3)
+}
+
+// HandleUserDeletionEvent mocks base method.
+func (m *MockService) HandleUserDeletionEvent(arg0 context.Context, arg1 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleUserDeletionEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleUserDeletionEvent indicates an expected call of HandleUserDeletionEvent.
+func (mr *MockServiceMockRecorder) HandleUserDeletionEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleUserDeletionEvent", reflect.TypeOf((*MockService)(nil).HandleUserDeletionEvent), arg0, arg1)
+}
+
+// HideOpinionCommand mocks base method.
+func (m *MockService) HideOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HideOpinionCommand indicates an expected call of HideOpinionCommand.
+func (mr *MockServiceMockRecorder) HideOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideOpinionCommand", reflect.TypeOf((*MockService)(nil).HideOpinionCommand), arg0, arg1, arg2)
+}
+
+// ListDecisionsQuery mocks base method.
+func (m *MockService) ListDecisionsQuery(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.DecisionFilter) ([]application.Decision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDecisionsQuery", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]application.Decision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDecisionsQuery indicates an expected call of ListDecisionsQuery.
+func (mr *MockServiceMockRecorder) ListDecisionsQuery(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDecisionsQuery", reflect.TypeOf((*MockService)(nil).ListDecisionsQuery), arg0, arg1, arg2)
+}
+
+// ListOpinionsPageQuery mocks base method.
+func (m *MockService) ListOpinionsPageQuery(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionPage) (application.OpinionList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpinionsPageQuery", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.OpinionList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpinionsPageQuery indicates an expected call of ListOpinionsPageQuery.
+func (mr *MockServiceMockRecorder) ListOpinionsPageQuery(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpinionsPageQuery", reflect.TypeOf((*MockService)(nil).ListOpinionsPageQuery), arg0, arg1, arg2)
+}
+
+// ListOpinionsQuery mocks base method.
+func (m *MockService) ListOpinionsQuery(arg0 context.Context, arg1 application.AuthenticatedUser) ([]application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpinionsQuery", arg0, arg1)
+	ret0, _ := ret[0].([]application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpinionsQuery indicates an expected call of ListOpinionsQuery.
+func (mr *MockServiceMockRecorder) ListOpinionsQuery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpinionsQuery", reflect.TypeOf((*MockService)(nil).ListOpinionsQuery), arg0, arg1)
+}
+
+// ListReportsQuery mocks base method.
+func (m *MockService) ListReportsQuery(arg0 context.Context, arg1 application.AuthenticatedUser) ([]application.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReportsQuery", arg0, arg1)
+	ret0, _ := ret[0].([]application.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReportsQuery indicates an expected call of ListReportsQuery.
+func (mr *MockServiceMockRecorder) ListReportsQuery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReportsQuery", reflect.TypeOf((*MockService)(nil).ListReportsQuery), arg0, arg1)
+}
+
+// OpinionVisibilityQuery mocks base method.
+func (m *MockService) OpinionVisibilityQuery(arg0 context.Context, arg1 application.AuthenticatedUser) (application.OpinionVisibility, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpinionVisibilityQuery", arg0, arg1)
+	ret0, _ := ret[0].(application.OpinionVisibility)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpinionVisibilityQuery indicates an expected call of OpinionVisibilityQuery.
+func (mr *MockServiceMockRecorder) OpinionVisibilityQuery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpinionVisibilityQuery", reflect.TypeOf((*MockService)(nil).OpinionVisibilityQuery), arg0, arg1)
+}
+
+// RejectOpinionCommand mocks base method.
+func (m *MockService) RejectOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectOpinionCommand indicates an expected call of RejectOpinionCommand.
+func (mr *MockServiceMockRecorder) RejectOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectOpinionCommand", reflect.TypeOf((*MockService)(nil).RejectOpinionCommand), arg0, arg1, arg2)
+}
+
+// ReportOpinionCommand mocks base method.
+func (m *MockService) ReportOpinionCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.ReportCreateDTO) (application.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportOpinionCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportOpinionCommand indicates an expected call of ReportOpinionCommand.
+func (mr *MockServiceMockRecorder) ReportOpinionCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportOpinionCommand", reflect.TypeOf((*MockService)(nil).ReportOpinionCommand), arg0, arg1, arg2)
+}
+
+// ResolveReportsCommand mocks base method.
+func (m *MockService) ResolveReportsCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.OpinionId, arg3 application.ReportResolution) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveReportsCommand", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveReportsCommand indicates an expected call of ResolveReportsCommand.
+func (mr *MockServiceMockRecorder) ResolveReportsCommand(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveReportsCommand", reflect.TypeOf((*MockService)(nil).ResolveReportsCommand), arg0, arg1, arg2, arg3)
+}
+
+// UpdateVoteCommand mocks base method.
+func (m *MockService) UpdateVoteCommand(arg0 context.Context, arg1 application.AuthenticatedUser, arg2 application.VoteCreateAndUpdateDTO) (application.Vote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVoteCommand", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Vote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateVoteCommand indicates an expected call of UpdateVoteCommand.
+func (mr *MockServiceMockRecorder) UpdateVoteCommand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVoteCommand", reflect.TypeOf((*MockService)(nil).UpdateVoteCommand), arg0, arg1, arg2)
+}
+
 // MockRepository is a mock of Repository interface.
 type MockRepository struct {
 	ctrl     *gomock.Controller
@@ -36,6 +342,65 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CountOpenReports mocks base method.
+func (m *MockRepository) CountOpenReports(arg0 context.Context, arg1 application.OpinionId) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOpenReports", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOpenReports indicates an expected call of CountOpenReports.
+func (mr *MockRepositoryMockRecorder) CountOpenReports(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOpenReports", reflect.TypeOf((*MockRepository)(nil).CountOpenReports), arg0, arg1)
+}
+
+// CountOpinions mocks base method.
+func (m *MockRepository) CountOpinions(arg0 context.Context, arg1 application.OpinionVisibility) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOpinions", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOpinions indicates an expected call of CountOpinions.
+func (mr *MockRepositoryMockRecorder) CountOpinions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOpinions", reflect.TypeOf((*MockRepository)(nil).CountOpinions), arg0, arg1)
+}
+
+// CountVotes mocks base method.
+func (m *MockRepository) CountVotes(arg0 context.Context, arg1 application.OpinionVisibility, arg2 []application.OpinionId) (map[application.OpinionId]application.VoteCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountVotes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[application.OpinionId]application.VoteCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountVotes indicates an expected call of CountVotes.
+func (mr *MockRepositoryMockRecorder) CountVotes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountVotes", reflect.TypeOf((*MockRepository)(nil).CountVotes), arg0, arg1, arg2)
+}
+
+// CreateIdempotencyRecord mocks base method.
+func (m *MockRepository) CreateIdempotencyRecord(arg0 context.Context, arg1 application.IdempotencyRecord, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdempotencyRecord", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdempotencyRecord indicates an expected call of CreateIdempotencyRecord.
+func (mr *MockRepositoryMockRecorder) CreateIdempotencyRecord(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotencyRecord", reflect.TypeOf((*MockRepository)(nil).CreateIdempotencyRecord), arg0, arg1, arg2)
+}
+
 // CreateOpinion mocks base method.
 func (m *MockRepository) CreateOpinion(arg0 context.Context, arg1 application.Opinion) error {
 	m.ctrl.T.Helper()
@@ -50,6 +415,20 @@ func (mr *MockRepositoryMockRecorder) CreateOpinion(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOpinion", reflect.TypeOf((*MockRepository)(nil).CreateOpinion), arg0, arg1)
 }
 
+// CreateReport mocks base method.
+func (m *MockRepository) CreateReport(arg0 context.Context, arg1 application.Report) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReport", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateReport indicates an expected call of CreateReport.
+func (mr *MockRepositoryMockRecorder) CreateReport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReport", reflect.TypeOf((*MockRepository)(nil).CreateReport), arg0, arg1)
+}
+
 // CreateVote mocks base method.
 func (m *MockRepository) CreateVote(arg0 context.Context, arg1 application.Vote) error {
 	m.ctrl.T.Helper()
@@ -64,6 +443,35 @@ func (mr *MockRepositoryMockRecorder) CreateVote(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVote", reflect.TypeOf((*MockRepository)(nil).CreateVote), arg0, arg1)
 }
 
+// DeleteExpiredIdempotencyRecords mocks base method.
+func (m *MockRepository) DeleteExpiredIdempotencyRecords(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyRecords", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyRecords indicates an expected call of DeleteExpiredIdempotencyRecords.
+func (mr *MockRepositoryMockRecorder) DeleteExpiredIdempotencyRecords(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyRecords", reflect.TypeOf((*MockRepository)(nil).DeleteExpiredIdempotencyRecords), arg0, arg1)
+}
+
+// DeleteIdempotencyRecord mocks base method.
+func (m *MockRepository) DeleteIdempotencyRecord(arg0 context.Context, arg1 application.UserId, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyRecord", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyRecord indicates an expected call of DeleteIdempotencyRecord.
+func (mr *MockRepositoryMockRecorder) DeleteIdempotencyRecord(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyRecord", reflect.TypeOf((*MockRepository)(nil).DeleteIdempotencyRecord), arg0, arg1, arg2)
+}
+
 // DeleteOpinion mocks base method.
 func (m *MockRepository) DeleteOpinion(arg0 context.Context, arg1 application.OpinionId) error {
 	m.ctrl.T.Helper()
@@ -78,33 +486,123 @@ func (mr *MockRepositoryMockRecorder) DeleteOpinion(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOpinion", reflect.TypeOf((*MockRepository)(nil).DeleteOpinion), arg0, arg1)
 }
 
+// DeleteOpinions mocks base method.
+func (m *MockRepository) DeleteOpinions(arg0 context.Context, arg1 application.OpinionFilter) ([]application.OpinionId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOpinions", arg0, arg1)
+	ret0, _ := ret[0].([]application.OpinionId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOpinions indicates an expected call of DeleteOpinions.
+func (mr *MockRepositoryMockRecorder) DeleteOpinions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOpinions", reflect.TypeOf((*MockRepository)(nil).DeleteOpinions), arg0, arg1)
+}
+
 // DeleteVote mocks base method.
-func (m *MockRepository) DeleteVote(arg0 context.Context, arg1 application.OpinionId) error {
+func (m *MockRepository) DeleteVote(arg0 context.Context, arg1 application.OpinionId, arg2 application.UserId) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteVote", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteVote", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteVote indicates an expected call of DeleteVote.
-func (mr *MockRepositoryMockRecorder) DeleteVote(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) DeleteVote(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVote", reflect.TypeOf((*MockRepository)(nil).DeleteVote), arg0, arg1, arg2)
+}
+
+// GetIdempotencyRecord mocks base method.
+func (m *MockRepository) GetIdempotencyRecord(arg0 context.Context, arg1 application.UserId, arg2 string, arg3 time.Time) (application.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyRecord", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(application.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyRecord indicates an expected call of GetIdempotencyRecord.
+func (mr *MockRepositoryMockRecorder) GetIdempotencyRecord(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyRecord", reflect.TypeOf((*MockRepository)(nil).GetIdempotencyRecord), arg0, arg1, arg2, arg3)
+}
+
+// GetOpinion mocks base method.
+func (m *MockRepository) GetOpinion(arg0 context.Context, arg1 application.OpinionId) (application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpinion", arg0, arg1)
+	ret0, _ := ret[0].(application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpinion indicates an expected call of GetOpinion.
+func (mr *MockRepositoryMockRecorder) GetOpinion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpinion", reflect.TypeOf((*MockRepository)(nil).GetOpinion), arg0, arg1)
+}
+
+// GetVote mocks base method.
+func (m *MockRepository) GetVote(arg0 context.Context, arg1 application.OpinionId, arg2 application.UserId) (application.Vote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVote", arg0, arg1, arg2)
+	ret0, _ := ret[0].(application.Vote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVote indicates an expected call of GetVote.
+func (mr *MockRepositoryMockRecorder) GetVote(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVote", reflect.TypeOf((*MockRepository)(nil).DeleteVote), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVote", reflect.TypeOf((*MockRepository)(nil).GetVote), arg0, arg1, arg2)
+}
+
+// ListOpenReports mocks base method.
+func (m *MockRepository) ListOpenReports(arg0 context.Context) ([]application.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpenReports", arg0)
+	ret0, _ := ret[0].([]application.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpenReports indicates an expected call of ListOpenReports.
+func (mr *MockRepositoryMockRecorder) ListOpenReports(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpenReports", reflect.TypeOf((*MockRepository)(nil).ListOpenReports), arg0)
 }
 
 // ListOpinions mocks base method.
-func (m *MockRepository) ListOpinions(arg0 context.Context) ([]application.Opinion, error) {
+func (m *MockRepository) ListOpinions(arg0 context.Context, arg1 application.OpinionVisibility) ([]application.Opinion, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListOpinions", arg0)
+	ret := m.ctrl.Call(m, "ListOpinions", arg0, arg1)
 	ret0, _ := ret[0].([]application.Opinion)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListOpinions indicates an expected call of ListOpinions.
-func (mr *MockRepositoryMockRecorder) ListOpinions(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ListOpinions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpinions", reflect.TypeOf((*MockRepository)(nil).ListOpinions), arg0, arg1)
+}
+
+// ListOpinionsPage mocks base method.
+func (m *MockRepository) ListOpinionsPage(arg0 context.Context, arg1 application.OpinionVisibility, arg2 application.OpinionPage) ([]application.Opinion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpinionsPage", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]application.Opinion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpinionsPage indicates an expected call of ListOpinionsPage.
+func (mr *MockRepositoryMockRecorder) ListOpinionsPage(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpinions", reflect.TypeOf((*MockRepository)(nil).ListOpinions), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpinionsPage", reflect.TypeOf((*MockRepository)(nil).ListOpinionsPage), arg0, arg1, arg2)
 }
 
 // ListVotes mocks base method.
@@ -122,6 +620,34 @@ func (mr *MockRepositoryMockRecorder) ListVotes(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVotes", reflect.TypeOf((*MockRepository)(nil).ListVotes), arg0)
 }
 
+// ResolveReports mocks base method.
+func (m *MockRepository) ResolveReports(arg0 context.Context, arg1 application.OpinionId, arg2 application.ReportResolution, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveReports", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveReports indicates an expected call of ResolveReports.
+func (mr *MockRepositoryMockRecorder) ResolveReports(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveReports", reflect.TypeOf((*MockRepository)(nil).ResolveReports), arg0, arg1, arg2, arg3)
+}
+
+// UpdateOpinion mocks base method.
+func (m *MockRepository) UpdateOpinion(arg0 context.Context, arg1 application.Opinion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOpinion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOpinion indicates an expected call of UpdateOpinion.
+func (mr *MockRepositoryMockRecorder) UpdateOpinion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOpinion", reflect.TypeOf((*MockRepository)(nil).UpdateOpinion), arg0, arg1)
+}
+
 // UpdateVote mocks base method.
 func (m *MockRepository) UpdateVote(arg0 context.Context, arg1 application.Vote) error {
 	m.ctrl.T.Helper()
@@ -160,17 +686,55 @@ func (m *MockPolicyEnforcementPoint) EXPECT() *MockPolicyEnforcementPointMockRec
 }
 
 // RequestAccessForUser mocks base method.
-func (m *MockPolicyEnforcementPoint) RequestAccessForUser(arg0 context.Context, arg1, arg2 string) error {
+func (m *MockPolicyEnforcementPoint) RequestAccessForUser(arg0 context.Context, arg1 application.AccessRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RequestAccessForUser", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "RequestAccessForUser", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RequestAccessForUser indicates an expected call of RequestAccessForUser.
-func (mr *MockPolicyEnforcementPointMockRecorder) RequestAccessForUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockPolicyEnforcementPointMockRecorder) RequestAccessForUser(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestAccessForUser", reflect.TypeOf((*MockPolicyEnforcementPoint)(nil).RequestAccessForUser), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestAccessForUser", reflect.TypeOf((*MockPolicyEnforcementPoint)(nil).RequestAccessForUser), arg0, arg1)
+}
+
+// MockVisibilityPolicy is a mock of VisibilityPolicy interface.
+type MockVisibilityPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockVisibilityPolicyMockRecorder
+}
+
+// MockVisibilityPolicyMockRecorder is the mock recorder for MockVisibilityPolicy.
+type MockVisibilityPolicyMockRecorder struct {
+	mock *MockVisibilityPolicy
+}
+
+// NewMockVisibilityPolicy creates a new mock instance.
+func NewMockVisibilityPolicy(ctrl *gomock.Controller) *MockVisibilityPolicy {
+	mock := &MockVisibilityPolicy{ctrl: ctrl}
+	mock.recorder = &MockVisibilityPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVisibilityPolicy) EXPECT() *MockVisibilityPolicyMockRecorder {
+	return m.recorder
+}
+
+// OpinionVisibility mocks base method.
+func (m *MockVisibilityPolicy) OpinionVisibility(arg0 context.Context, arg1 application.AccessRequest) (application.OpinionVisibility, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpinionVisibility", arg0, arg1)
+	ret0, _ := ret[0].(application.OpinionVisibility)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpinionVisibility indicates an expected call of OpinionVisibility.
+func (mr *MockVisibilityPolicyMockRecorder) OpinionVisibility(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpinionVisibility", reflect.TypeOf((*MockVisibilityPolicy)(nil).OpinionVisibility), arg0, arg1)
 }
 
 // MockIdService is a mock of IdService interface.
@@ -246,3 +810,130 @@ func (mr *MockTimeServiceMockRecorder) CurrentTime() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentTime", reflect.TypeOf((*MockTimeService)(nil).CurrentTime))
 }
+
+// MockEventPublisher is a mock of EventPublisher interface.
+type MockEventPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventPublisherMockRecorder
+}
+
+// MockEventPublisherMockRecorder is the mock recorder for MockEventPublisher.
+type MockEventPublisherMockRecorder struct {
+	mock *MockEventPublisher
+}
+
+// NewMockEventPublisher creates a new mock instance.
+func NewMockEventPublisher(ctrl *gomock.Controller) *MockEventPublisher {
+	mock := &MockEventPublisher{ctrl: ctrl}
+	mock.recorder = &MockEventPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventPublisher) EXPECT() *MockEventPublisherMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockEventPublisher) Publish(arg0 context.Context, arg1 application.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockEventPublisherMockRecorder) Publish(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockEventPublisher)(nil).Publish), arg0, arg1)
+}
+
+// MockRateLimitStore is a mock of RateLimitStore interface.
+type MockRateLimitStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitStoreMockRecorder
+}
+
+// MockRateLimitStoreMockRecorder is the mock recorder for MockRateLimitStore.
+type MockRateLimitStoreMockRecorder struct {
+	mock *MockRateLimitStore
+}
+
+// NewMockRateLimitStore creates a new mock instance.
+func NewMockRateLimitStore(ctrl *gomock.Controller) *MockRateLimitStore {
+	mock := &MockRateLimitStore{ctrl: ctrl}
+	mock.recorder = &MockRateLimitStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitStore) EXPECT() *MockRateLimitStoreMockRecorder {
+	return m.recorder
+}
+
+// Take mocks base method.
+func (m *MockRateLimitStore) Take(arg0 context.Context, arg1 string, arg2 application.RateLimit, arg3 time.Time) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockRateLimitStoreMockRecorder) Take(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockRateLimitStore)(nil).Take), arg0, arg1, arg2, arg3)
+}
+
+// MockDecisionLog is a mock of DecisionLog interface.
+type MockDecisionLog struct {
+	ctrl     *gomock.Controller
+	recorder *MockDecisionLogMockRecorder
+}
+
+// MockDecisionLogMockRecorder is the mock recorder for MockDecisionLog.
+type MockDecisionLogMockRecorder struct {
+	mock *MockDecisionLog
+}
+
+// NewMockDecisionLog creates a new mock instance.
+func NewMockDecisionLog(ctrl *gomock.Controller) *MockDecisionLog {
+	mock := &MockDecisionLog{ctrl: ctrl}
+	mock.recorder = &MockDecisionLogMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDecisionLog) EXPECT() *MockDecisionLogMockRecorder {
+	return m.recorder
+}
+
+// ListDecisions mocks base method.
+func (m *MockDecisionLog) ListDecisions(arg0 context.Context, arg1 application.DecisionFilter) ([]application.Decision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDecisions", arg0, arg1)
+	ret0, _ := ret[0].([]application.Decision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDecisions indicates an expected call of ListDecisions.
+func (mr *MockDecisionLogMockRecorder) ListDecisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDecisions", reflect.TypeOf((*MockDecisionLog)(nil).ListDecisions), arg0, arg1)
+}
+
+// LogDecision mocks base method.
+func (m *MockDecisionLog) LogDecision(arg0 context.Context, arg1 application.Decision) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogDecision", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogDecision indicates an expected call of LogDecision.
+func (mr *MockDecisionLogMockRecorder) LogDecision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogDecision", reflect.TypeOf((*MockDecisionLog)(nil).LogDecision), arg0, arg1)
+}
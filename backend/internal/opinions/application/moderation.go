@@ -0,0 +1,76 @@
+package application
+
+import "context"
+
+// Verdict is the outcome of a ModerationCheck, a higher verdict is stricter
+type Verdict int
+
+const (
+	// VerdictPublish makes the opinion visible immediately
+	VerdictPublish Verdict = iota
+	// VerdictReview holds the opinion as OpinionPendingReview until an admin decides
+	VerdictReview
+	// VerdictReject declines the opinion, it is not stored
+	VerdictReject
+)
+
+// ModerationResult is the verdict of a check together with the reasons which are shown to the author
+type ModerationResult struct {
+	Verdict Verdict
+	Reasons []string
+}
+
+// ModerationCheck inspects a normalized statement before it is stored
+type ModerationCheck interface {
+	Check(ctx context.Context, statement string) (ModerationResult, error)
+}
+
+// NewModerationPipeline chains the checks in the given order
+func NewModerationPipeline(checks ...ModerationCheck) ModerationPipeline {
+	return ModerationPipeline{checks: checks}
+}
+
+// ModerationPipeline is a ModerationCheck which returns the strictest verdict of all checks.
+// It stops at the first rejection, the reasons of all stricter verdicts are collected.
+type ModerationPipeline struct {
+	checks []ModerationCheck
+}
+
+func (p ModerationPipeline) Check(ctx context.Context, statement string) (ModerationResult, error) {
+	var result ModerationResult
+	for _, check := range p.checks {
+		r, err := check.Check(ctx, statement)
+		if err != nil {
+			return ModerationResult{}, err
+		}
+
+		switch {
+		case r.Verdict > result.Verdict:
+			result = r
+		case r.Verdict == result.Verdict && r.Verdict != VerdictPublish:
+			result.Reasons = append(result.Reasons, r.Reasons...)
+		}
+
+		if result.Verdict == VerdictReject {
+			break
+		}
+	}
+	return result, nil
+}
+
+// statusOf maps the verdict of a stored opinion to its status
+func statusOf(verdict Verdict) OpinionStatus {
+	if verdict == VerdictReview {
+		return OpinionPendingReview
+	}
+	return OpinionPublished
+}
+
+// moderationError converts the reasons of a rejection into field errors
+func moderationError(result ModerationResult) error {
+	fields := make([]FieldError, 0, len(result.Reasons))
+	for _, reason := range result.Reasons {
+		fields = append(fields, FieldError{Field: "statement", Message: reason})
+	}
+	return &Error{Code: OpinionRejectedError.Code, Message: OpinionRejectedError.Message, Fields: fields}
+}
@@ -0,0 +1,74 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type checkFunc func(ctx context.Context, statement string) (application.ModerationResult, error)
+
+func (f checkFunc) Check(ctx context.Context, statement string) (application.ModerationResult, error) {
+	return f(ctx, statement)
+}
+
+func verdict(v application.Verdict, reason string) application.ModerationCheck {
+	return checkFunc(func(context.Context, string) (application.ModerationResult, error) {
+		if v == application.VerdictPublish {
+			return application.ModerationResult{}, nil
+		}
+		return application.ModerationResult{Verdict: v, Reasons: []string{reason}}, nil
+	})
+}
+
+func TestModerationPipeline_Check(t *testing.T) {
+	t.Parallel()
+	checkError := errors.New("check error")
+	unreachable := checkFunc(func(context.Context, string) (application.ModerationResult, error) {
+		t.Error("checks after a rejection must not be called")
+		return application.ModerationResult{}, nil
+	})
+
+	tests := []struct {
+		name    string
+		checks  []application.ModerationCheck
+		want    application.ModerationResult
+		wantErr error
+	}{
+		{
+			name:   "Should publish without checks",
+			checks: nil,
+			want:   application.ModerationResult{},
+		},
+		{
+			name:   "Should return the strictest verdict and its reasons",
+			checks: []application.ModerationCheck{verdict(application.VerdictPublish, ""), verdict(application.VerdictReview, "a"), verdict(application.VerdictReview, "b")},
+			want:   application.ModerationResult{Verdict: application.VerdictReview, Reasons: []string{"a", "b"}},
+		},
+		{
+			name:   "Should stop at the first rejection",
+			checks: []application.ModerationCheck{verdict(application.VerdictReview, "a"), verdict(application.VerdictReject, "b"), unreachable},
+			want:   application.ModerationResult{Verdict: application.VerdictReject, Reasons: []string{"b"}},
+		},
+		{
+			name: "Should return the error of a check",
+			checks: []application.ModerationCheck{checkFunc(func(context.Context, string) (application.ModerationResult, error) {
+				return application.ModerationResult{}, checkError
+			})},
+			wantErr: checkError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := application.NewModerationPipeline(tt.checks...).Check(context.Background(), "copy and pasta is fine")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
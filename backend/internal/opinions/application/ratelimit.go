@@ -0,0 +1,166 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket which holds up to Burst tokens and regains one token every Refill
+type RateLimit struct {
+	Burst  int
+	Refill time.Duration
+}
+
+// RateLimitStore keeps the token buckets, e.g. in memory or in a shared cache for multiple instances
+type RateLimitStore interface {
+	// Take removes one token from the bucket of the key. If the bucket is empty,
+	// no token is removed and the duration until the next token is available is returned.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (retryAfter time.Duration, err error)
+}
+
+// DefaultRateLimits throttle the commands which write user content
+func DefaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		ActionCreateOpinion: {Burst: 5, Refill: time.Minute},
+		ActionEditOpinion:   {Burst: 10, Refill: 30 * time.Second},
+		ActionReportOpinion: {Burst: 10, Refill: time.Minute},
+		ActionCreateVote:    {Burst: 30, Refill: 2 * time.Second},
+		ActionUpdateVote:    {Burst: 30, Refill: 2 * time.Second},
+		ActionDeleteVote:    {Burst: 30, Refill: 2 * time.Second},
+	}
+}
+
+// ParseRateLimits overrides the limits with a comma separated list of action=burst/refill, e.g. CreateOpinion=5/1m.
+// Only the actions of the limits can be overridden, the other commands are never throttled.
+func ParseRateLimits(list string, limits map[string]RateLimit) error {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		action, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("rate limit %q is not formatted as action=burst/refill", item)
+		}
+		action = strings.TrimSpace(action)
+		if _, limited := limits[action]; !limited {
+			return fmt.Errorf("action %q of the rate limit %q is not rate limited", action, item)
+		}
+
+		burst, refill, ok := strings.Cut(value, "/")
+		if !ok {
+			return fmt.Errorf("rate limit %q is not formatted as action=burst/refill", item)
+		}
+		var limit RateLimit
+		var err error
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil {
+			return fmt.Errorf("burst of the rate limit %q: %w", item, err)
+		}
+		if limit.Refill, err = time.ParseDuration(strings.TrimSpace(refill)); err != nil {
+			return fmt.Errorf("refill of the rate limit %q: %w", item, err)
+		}
+		if err := limit.Validate(); err != nil {
+			return fmt.Errorf("limit of %s: %w", action, err)
+		}
+		limits[action] = limit
+	}
+	return nil
+}
+
+// Validate rejects buckets which never regain a token or never hold one
+func (l RateLimit) Validate() error {
+	if l.Burst < 1 {
+		return fmt.Errorf("burst %d of the rate limit is less than 1", l.Burst)
+	}
+	if l.Refill <= 0 {
+		return fmt.Errorf("refill %s of the rate limit is not positive", l.Refill)
+	}
+	return nil
+}
+
+// NewRateLimitedService decorates the service with a token bucket per user and action.
+// Actions without a limit and calls without a user identity are passed through.
+func NewRateLimitedService(next Service, store RateLimitStore, limits map[string]RateLimit, timeService TimeService) (Service, error) {
+	for action, limit := range limits {
+		if err := limit.Validate(); err != nil {
+			return nil, fmt.Errorf("limit of %s: %w", action, err)
+		}
+	}
+	return &rateLimitedService{
+		Service:     next,
+		store:       store,
+		limits:      limits,
+		timeService: timeService,
+	}, nil
+}
+
+type rateLimitedService struct {
+	Service
+	store       RateLimitStore
+	limits      map[string]RateLimit
+	timeService TimeService
+}
+
+func (r *rateLimitedService) CreateOpinionCommand(ctx context.Context, user AuthenticatedUser, opinion OpinionCreateDTO) (Opinion, error) {
+	if err := r.take(ctx, user, ActionCreateOpinion); err != nil {
+		return Opinion{}, err
+	}
+	return r.Service.CreateOpinionCommand(ctx, user, opinion)
+}
+
+func (r *rateLimitedService) EditOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId, opinion OpinionUpdateDTO) (Opinion, error) {
+	if err := r.take(ctx, user, ActionEditOpinion); err != nil {
+		return Opinion{}, err
+	}
+	return r.Service.EditOpinionCommand(ctx, user, id, opinion)
+}
+
+func (r *rateLimitedService) ReportOpinionCommand(ctx context.Context, user AuthenticatedUser, report ReportCreateDTO) (Report, error) {
+	if err := r.take(ctx, user, ActionReportOpinion); err != nil {
+		return Report{}, err
+	}
+	return r.Service.ReportOpinionCommand(ctx, user, report)
+}
+
+func (r *rateLimitedService) CreateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error) {
+	if err := r.take(ctx, user, ActionCreateVote); err != nil {
+		return Vote{}, err
+	}
+	return r.Service.CreateVoteCommand(ctx, user, vote)
+}
+
+func (r *rateLimitedService) UpdateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error) {
+	if err := r.take(ctx, user, ActionUpdateVote); err != nil {
+		return Vote{}, err
+	}
+	return r.Service.UpdateVoteCommand(ctx, user, vote)
+}
+
+func (r *rateLimitedService) DeleteVoteCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Vote, error) {
+	if err := r.take(ctx, user, ActionDeleteVote); err != nil {
+		return Vote{}, err
+	}
+	return r.Service.DeleteVoteCommand(ctx, user, id)
+}
+
+// take consumes a token of the user for the action and returns a RateLimitedError if the bucket is empty
+func (r *rateLimitedService) take(ctx context.Context, user AuthenticatedUser, action string) error {
+	limit, limited := r.limits[action]
+	if !limited || user.Id == "" {
+		return nil
+	}
+
+	retryAfter, err := r.store.Take(ctx, string(user.Id)+"/"+action, limit, r.timeService.CurrentTime())
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if retryAfter > 0 {
+		return NewRateLimitedError(retryAfter)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	mock_application "github.com/fwiedmann/site/backend/internal/opinions/application/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedService(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	storeError := errors.New("store error")
+	testDate := time.Now()
+	limits := map[string]application.RateLimit{
+		application.ActionCreateOpinion: {Burst: 1, Refill: time.Minute},
+	}
+
+	tests := []struct {
+		name           string
+		user           application.AuthenticatedUser
+		retryAfter     time.Duration
+		storeError     error
+		wantStoreCall  bool
+		wantNextCall   bool
+		wantErr        error
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "Should pass the command if a token is available",
+			user:          application.AuthenticatedUser{Id: testUserId},
+			wantStoreCall: true,
+			wantNextCall:  true,
+		},
+		{
+			name:           "Should reject the command if the bucket is empty",
+			user:           application.AuthenticatedUser{Id: testUserId},
+			retryAfter:     20 * time.Second,
+			wantStoreCall:  true,
+			wantErr:        application.RateLimitedError,
+			wantRetryAfter: 20 * time.Second,
+		},
+		{
+			name:          "Should return store failures as internal error",
+			user:          application.AuthenticatedUser{Id: testUserId},
+			storeError:    storeError,
+			wantStoreCall: true,
+			wantErr:       storeError,
+		},
+		{
+			name:         "Should pass unauthenticated commands to the service",
+			user:         application.AuthenticatedUser{},
+			wantNextCall: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			next := mock_application.NewMockService(ctrl)
+			if tt.wantNextCall {
+				next.EXPECT().CreateOpinionCommand(gomock.Any(), tt.user, gomock.Any()).Return(application.Opinion{ID: "187"}, nil)
+			}
+
+			store := mock_application.NewMockRateLimitStore(ctrl)
+			if tt.wantStoreCall {
+				store.EXPECT().Take(gomock.Any(), "1/"+application.ActionCreateOpinion, limits[application.ActionCreateOpinion], testDate).Return(tt.retryAfter, tt.storeError)
+			}
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).AnyTimes()
+
+			s, err := application.NewRateLimitedService(next, store, limits, timeService)
+			assert.NoError(t, err)
+			got, err := s.CreateOpinionCommand(context.Background(), tt.user, application.OpinionCreateDTO{Statement: "copy and pasta is fine"})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				var appErr *application.Error
+				if assert.True(t, errors.As(err, &appErr)) {
+					assert.Equal(t, tt.wantRetryAfter, appErr.RetryAfter)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, application.OpinionId("187"), got.ID)
+		})
+	}
+}
+
+func TestRateLimitedService_unlimitedActions(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	user := application.AuthenticatedUser{Id: "1"}
+
+	next := mock_application.NewMockService(ctrl)
+	next.EXPECT().CreateVoteCommand(gomock.Any(), user, gomock.Any()).Return(application.Vote{}, nil)
+	next.EXPECT().ListOpinionsQuery(gomock.Any(), user).Return(nil, nil)
+
+	store := mock_application.NewMockRateLimitStore(ctrl)
+	timeService := mock_application.NewMockTimeService(ctrl)
+
+	s, err := application.NewRateLimitedService(next, store, map[string]application.RateLimit{}, timeService)
+	assert.NoError(t, err)
+
+	_, err = s.CreateVoteCommand(context.Background(), user, application.VoteCreateAndUpdateDTO{Opinion: "187"})
+	assert.NoError(t, err)
+	_, err = s.ListOpinionsQuery(context.Background(), user)
+	assert.NoError(t, err)
+}
+
+func TestNewRateLimitedService_invalidLimits(t *testing.T) {
+	t.Parallel()
+	for _, limit := range []application.RateLimit{{Burst: 1}, {Burst: 1, Refill: -time.Second}, {Burst: 0, Refill: time.Second}} {
+		_, err := application.NewRateLimitedService(nil, nil, map[string]application.RateLimit{application.ActionCreateOpinion: limit}, nil)
+		assert.Error(t, err, "limit %v", limit)
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		list    string
+		want    map[string]application.RateLimit
+		wantErr bool
+	}{
+		{
+			name: "empty list keeps the limits",
+			list: "",
+			want: application.DefaultRateLimits(),
+		},
+		{
+			name: "overrides the listed actions",
+			list: "CreateOpinion=2/1h, CreateVote = 100/1s",
+			want: func() map[string]application.RateLimit {
+				limits := application.DefaultRateLimits()
+				limits[application.ActionCreateOpinion] = application.RateLimit{Burst: 2, Refill: time.Hour}
+				limits[application.ActionCreateVote] = application.RateLimit{Burst: 100, Refill: time.Second}
+				return limits
+			}(),
+		},
+		{
+			name:    "action which is not rate limited",
+			list:    "ListOpinions=2/1h",
+			wantErr: true,
+		},
+		{
+			name:    "missing refill",
+			list:    "CreateOpinion=2",
+			wantErr: true,
+		},
+		{
+			name:    "missing limit",
+			list:    "CreateOpinion",
+			wantErr: true,
+		},
+		{
+			name:    "invalid burst",
+			list:    "CreateOpinion=many/1h",
+			wantErr: true,
+		},
+		{
+			name:    "invalid refill",
+			list:    "CreateOpinion=2/hourly",
+			wantErr: true,
+		},
+		{
+			name:    "burst less than 1",
+			list:    "CreateOpinion=0/1h",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			limits := application.DefaultRateLimits()
+			err := application.ParseRateLimits(tt.list, limits)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, limits)
+		})
+	}
+}
@@ -1,37 +1,89 @@
 package application
 
-//go:generate mockgen -destination mocks/mock.go . Repository,PolicyEnforcementPoint,IdService,TimeService
+//go:generate mockgen -destination mocks/mock.go . Service,Repository,PolicyEnforcementPoint,VisibilityPolicy,IdService,TimeService,EventPublisher,RateLimitStore,DecisionLog
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 )
 
 type Service interface {
 	CreateOpinionCommand(ctx context.Context, user AuthenticatedUser, opinion OpinionCreateDTO) (Opinion, error)
-	ListOpinionsCommand(ctx context.Context, user AuthenticatedUser) ([]Opinion, error)
+	EditOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId, opinion OpinionUpdateDTO) (Opinion, error)
+	ListOpinionsQuery(ctx context.Context, user AuthenticatedUser) ([]Opinion, error)
+	ListOpinionsPageQuery(ctx context.Context, user AuthenticatedUser, page OpinionPage) (OpinionList, error)
+	OpinionVisibilityQuery(ctx context.Context, user AuthenticatedUser) (OpinionVisibility, error)
 	DeleteOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) error
+	BulkDeleteOpinionsCommand(ctx context.Context, user AuthenticatedUser, filter OpinionFilter) ([]OpinionId, error)
+	ApproveOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error)
+	RejectOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error)
+	HideOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error)
+
+	ReportOpinionCommand(ctx context.Context, user AuthenticatedUser, report ReportCreateDTO) (Report, error)
+	ListReportsQuery(ctx context.Context, user AuthenticatedUser) ([]Report, error)
+	ResolveReportsCommand(ctx context.Context, user AuthenticatedUser, id OpinionId, resolution ReportResolution) (Opinion, error)
 	HandleUserDeletionEvent(ctx context.Context, event any) error
+	ListDecisionsQuery(ctx context.Context, user AuthenticatedUser, filter DecisionFilter) ([]Decision, error)
 
 	CreateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error)
 	UpdateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error)
 	DeleteVoteCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Vote, error)
+	CountVotesQuery(ctx context.Context, user AuthenticatedUser, opinions []OpinionId) (map[OpinionId]VoteCount, error)
 }
 
+// Repository persists opinions, votes and reports.
+// Implementations return OpinionNotFoundError, VoteNotFoundError, ReportNotFoundError, OpinionAlreadyExistsError,
+// VoteAlreadyExistsError and ReportAlreadyExistsError for the respective cases
+// and delete all votes and reports of an opinion together with the opinion.
+// Updates are only applied if the stored version equals the version of the given opinion or vote, the stored version is incremented.
+// Otherwise OpinionVersionConflictError or VoteVersionConflictError is returned.
+// Lists are ordered by creation time.
 type Repository interface {
 	CreateOpinion(ctx context.Context, opinion Opinion) error
+	GetOpinion(ctx context.Context, id OpinionId) (Opinion, error)
+	UpdateOpinion(ctx context.Context, opinion Opinion) error
 	DeleteOpinion(ctx context.Context, id OpinionId) error
-	ListOpinions(ctx context.Context) ([]Opinion, error)
+	// DeleteOpinions removes all opinions which match the filter and returns their ids
+	DeleteOpinions(ctx context.Context, filter OpinionFilter) ([]OpinionId, error)
+	// ListOpinions returns the opinions which match the visibility
+	ListOpinions(ctx context.Context, visibility OpinionVisibility) ([]Opinion, error)
+	// ListOpinionsPage returns at most page.Limit opinions which match the visibility and follow the cursor of the page
+	ListOpinionsPage(ctx context.Context, visibility OpinionVisibility, page OpinionPage) ([]Opinion, error)
+	// CountOpinions returns the count of the opinions which match the visibility
+	CountOpinions(ctx context.Context, visibility OpinionVisibility) (int, error)
 
 	CreateVote(ctx context.Context, vote Vote) error
 	UpdateVote(ctx context.Context, vote Vote) error
-	DeleteVote(ctx context.Context, id OpinionId) error
+	GetVote(ctx context.Context, opinion OpinionId, voter UserId) (Vote, error)
+	DeleteVote(ctx context.Context, opinion OpinionId, voter UserId) error
 	ListVotes(ctx context.Context) ([]Vote, error)
+	// CountVotes returns the tallies of the opinions which match the visibility in a single query,
+	// the other opinions and the opinions without votes are missing in the map
+	CountVotes(ctx context.Context, visibility OpinionVisibility, opinions []OpinionId) (map[OpinionId]VoteCount, error)
+
+	CreateReport(ctx context.Context, report Report) error
+	ListOpenReports(ctx context.Context) ([]Report, error)
+	CountOpenReports(ctx context.Context, opinion OpinionId) (int, error)
+	// ResolveReports sets the resolution on all open reports of the opinion
+	ResolveReports(ctx context.Context, opinion OpinionId, resolution ReportResolution, resolvedAt time.Time) error
+
+	// CreateIdempotencyRecord stores the record, an expired record of the same user and key is replaced
+	CreateIdempotencyRecord(ctx context.Context, record IdempotencyRecord, now time.Time) error
+	// GetIdempotencyRecord returns IdempotencyRecordNotFoundError for unknown and expired keys
+	GetIdempotencyRecord(ctx context.Context, user UserId, key string, now time.Time) (IdempotencyRecord, error)
+	DeleteIdempotencyRecord(ctx context.Context, user UserId, key string) error
+	// DeleteExpiredIdempotencyRecords removes all records which expired before now and returns their count
+	DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error)
 }
 
+// PolicyEnforcementPoint decides whether the user of the request may perform the action.
+// It returns AccessDeniedError if the action is not permitted.
 type PolicyEnforcementPoint interface {
-	RequestAccessForUser(ctx context.Context, userId string, action string) error
+	RequestAccessForUser(ctx context.Context, request AccessRequest) error
 }
 
 type IdService interface {
@@ -42,13 +94,88 @@ type TimeService interface {
 	CurrentTime() time.Time
 }
 
-func NewOpinionService(point PolicyEnforcementPoint, repository Repository, idService IdService, timeService TimeService) Service {
-	return &service{
+// Option changes the default configuration of the service
+type Option func(s *service)
+
+// WithStatementRules replaces the DefaultStatementRules which are applied on create and edit
+func WithStatementRules(rules StatementRules) Option {
+	return func(s *service) {
+		s.validator = NewStatementValidator(rules)
+	}
+}
+
+// WithModeration checks every created or edited statement, without it all opinions are published immediately
+func WithModeration(check ModerationCheck) Option {
+	return func(s *service) {
+		s.moderation = check
+	}
+}
+
+// DefaultIdempotencyTTL is the time a create command can be retried with the same idempotency key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength fits the varchar(255) column of the SQL repositories
+const maxIdempotencyKeyLength = 255
+
+// WithIdempotencyTTL changes how long the results of create commands are kept for their idempotency keys
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.idempotencyTTL = ttl
+	}
+}
+
+// DefaultReportThreshold is the count of open reports which hides an opinion
+const DefaultReportThreshold = 3
+
+// WithReportThreshold changes the count of open reports which hides an opinion, zero disables the auto-hide
+func WithReportThreshold(threshold int) Option {
+	return func(s *service) {
+		s.reportThreshold = threshold
+	}
+}
+
+// WithDecisionLog enables ListDecisionsQuery, without it the query returns DecisionLogUnavailableError
+func WithDecisionLog(log DecisionLog) Option {
+	return func(s *service) {
+		s.decisions = log
+	}
+}
+
+// WithPublishErrorHandler receives the events which could not be published, e.g. to log them.
+// The commands of these events are stored and succeed, the read models may miss the event.
+func WithPublishErrorHandler(handler func(event Event, err error)) Option {
+	return func(s *service) {
+		s.onPublishError = handler
+	}
+}
+
+// WithVisibilityPolicy lets the policy decide which opinions a user may list.
+// Without it the users see the published opinions and their own, users which may ViewUnpublishedOpinions see all opinions.
+func WithVisibilityPolicy(policy VisibilityPolicy) Option {
+	return func(s *service) {
+		s.visibility = policy
+	}
+}
+
+func NewOpinionService(point PolicyEnforcementPoint, repository Repository, publisher EventPublisher, idService IdService, timeService TimeService, options ...Option) Service {
+	s := &service{
 		pep:         point,
 		repo:        repository,
+		publisher:   publisher,
 		idService:   idService,
 		timeService: timeService,
+		validator:   NewStatementValidator(DefaultStatementRules()),
+		moderation:  NewModerationPipeline(),
+
+		onPublishError:  func(Event, error) {},
+		reportThreshold: DefaultReportThreshold,
+		idempotencyTTL:  DefaultIdempotencyTTL,
 	}
+
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
 const (
@@ -56,62 +183,464 @@ const (
 	ActionCreateOpinion = "CreateOpinion"
 	// ActionListOpinions will be used for the user policy enforcement
 	ActionListOpinions = "ListOpinions"
-	// ActionDeleteOpinion will be used for the user policy enforcement
+	// ActionEditOpinion will be used for the owner policy enforcement, the request carries the owner of the opinion
+	ActionEditOpinion = "EditOpinion"
+	// ActionDeleteOpinion will be used for the user policy enforcement, the request carries the owner of the opinion
 	ActionDeleteOpinion = "DeleteOpinion"
-)
-
-var (
-	// EmptyOpinionStatementError can be returned during OpinionCreateDTO validation
-	EmptyOpinionStatementError = errors.New("opinion statement is empty")
-	EmptyOpinionIdError        = errors.New("opinion id is empty")
+	// ActionBulkDeleteOpinions will be used for the admin policy enforcement
+	ActionBulkDeleteOpinions = "BulkDeleteOpinions"
+	// ActionHideOpinion will be used for the moderator policy enforcement, the request carries the owner of the opinion
+	ActionHideOpinion = "HideOpinion"
+	// ActionApproveOpinion will be used for the admin policy enforcement
+	ActionApproveOpinion = "ApproveOpinion"
+	// ActionRejectOpinion will be used for the admin policy enforcement
+	ActionRejectOpinion = "RejectOpinion"
+	// ActionViewUnpublishedOpinions permits to list opinions of other users which are not published
+	ActionViewUnpublishedOpinions = "ViewUnpublishedOpinions"
+	// ActionReportOpinion will be used for the user policy enforcement
+	ActionReportOpinion = "ReportOpinion"
+	// ActionListReports will be used for the admin policy enforcement
+	ActionListReports = "ListReports"
+	// ActionResolveReports will be used for the admin policy enforcement
+	ActionResolveReports = "ResolveReports"
+	// ActionListDecisions will be used for the admin policy enforcement
+	ActionListDecisions = "ListDecisions"
+	// ActionCreateVote will be used for the user policy enforcement
+	ActionCreateVote = "CreateVote"
+	// ActionUpdateVote will be used for the user policy enforcement
+	ActionUpdateVote = "UpdateVote"
+	// ActionDeleteVote will be used for the user policy enforcement
+	ActionDeleteVote = "DeleteVote"
 )
 
 type service struct {
 	pep         PolicyEnforcementPoint
 	repo        Repository
+	publisher   EventPublisher
 	idService   IdService
 	timeService TimeService
+	validator   StatementValidator
+	moderation  ModerationCheck
+	decisions   DecisionLog
+	visibility  VisibilityPolicy
+
+	onPublishError  func(event Event, err error)
+	reportThreshold int
+	idempotencyTTL  time.Duration
 }
 
-// CreateOpinionCommand handles the create command for the frontend
+// CreateOpinionCommand handles the create command for the frontend.
+// If the command carries an idempotency key, a retry with the same key and statement returns the opinion of the first command.
 func (s *service) CreateOpinionCommand(ctx context.Context, user AuthenticatedUser, opinion OpinionCreateDTO) (Opinion, error) {
-	if err := s.pep.RequestAccessForUser(ctx, string(user.Id), ActionCreateOpinion); err != nil {
+	if err := s.authorize(ctx, user, ActionCreateOpinion); err != nil {
 		return Opinion{}, err
 	}
 
-	if opinion.Statement == "" {
-		return Opinion{}, EmptyOpinionStatementError
+	if len(opinion.IdempotencyKey) > maxIdempotencyKeyLength {
+		return Opinion{}, InvalidIdempotencyKeyError
+	}
+
+	statement, err := s.validator.Validate(opinion.Statement)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	verdict, err := s.moderate(ctx, statement)
+	if err != nil {
+		return Opinion{}, err
 	}
 
 	o := Opinion{
 		ID:        OpinionId(s.idService.GenerateId()),
 		Owner:     user.Id,
 		CreatedAt: s.timeService.CurrentTime(),
-		Statement: opinion.Statement,
+		Statement: statement,
+		Status:    statusOf(verdict),
+		Version:   1,
+	}
+
+	if opinion.IdempotencyKey != "" {
+		stored, replayed, err := s.reserveIdempotencyKey(ctx, user, opinion.IdempotencyKey, o)
+		if err != nil {
+			return Opinion{}, err
+		}
+		if replayed {
+			return stored, nil
+		}
 	}
 
 	if err := s.repo.CreateOpinion(ctx, o); err != nil {
+		if opinion.IdempotencyKey != "" {
+			// the key is released, so the failed command can be retried
+			_ = s.repo.DeleteIdempotencyRecord(ctx, user.Id, opinion.IdempotencyKey)
+		}
 		return Opinion{}, err
 	}
+
+	s.publish(ctx, EventOpinionCreated, o.CreatedAt, o, Vote{})
 	return o, nil
 }
 
-func (s *service) ListOpinionsCommand(ctx context.Context, user AuthenticatedUser) ([]Opinion, error) {
-	if err := s.pep.RequestAccessForUser(ctx, string(user.Id), ActionDeleteOpinion); err != nil {
+// reserveIdempotencyKey stores the key together with the opinion which is about to be created.
+// If the key is already stored, the opinion of the first command is returned and replayed is true.
+// The request is identified by the normalized statement, so retries which only differ in e.g. whitespace are replayed.
+func (s *service) reserveIdempotencyKey(ctx context.Context, user AuthenticatedUser, key string, o Opinion) (_ Opinion, replayed bool, err error) {
+	hash := sha256.Sum256([]byte(o.Statement))
+	record := IdempotencyRecord{
+		User:        user.Id,
+		Key:         key,
+		RequestHash: hex.EncodeToString(hash[:]),
+		Opinion:     o,
+		ExpiresAt:   o.CreatedAt.Add(s.idempotencyTTL),
+	}
+
+	err = s.repo.CreateIdempotencyRecord(ctx, record, o.CreatedAt)
+	if !errors.Is(err, IdempotencyRecordAlreadyExistsError) {
+		return Opinion{}, false, err
+	}
+
+	stored, err := s.repo.GetIdempotencyRecord(ctx, user.Id, key, o.CreatedAt)
+	if err != nil {
+		return Opinion{}, false, err
+	}
+
+	if stored.RequestHash != record.RequestHash {
+		return Opinion{}, false, IdempotencyKeyReusedError
+	}
+	return stored.Opinion, true, nil
+}
+
+// EditOpinionCommand replaces the statement of an opinion, only the owner is allowed to edit it.
+// The statement is moderated again, an opinion which is not published can not become published by an edit.
+func (s *service) EditOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId, opinion OpinionUpdateDTO) (Opinion, error) {
+	o, err := s.authorizeOpinion(ctx, user, id, ActionEditOpinion)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	statement, err := s.validator.Validate(opinion.Statement)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	if opinion.Version != 0 && opinion.Version != o.Version {
+		return Opinion{}, OpinionVersionConflictError
+	}
+
+	verdict, err := s.moderate(ctx, statement)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	previous := o.Status
+	if o.Status == OpinionPublished {
+		o.Status = statusOf(verdict)
+	} else {
+		o.Status = OpinionPendingReview
+	}
+	o.Statement = statement
+	if o, err = s.updateOpinion(ctx, o); err != nil {
+		return Opinion{}, err
+	}
+
+	s.publishChange(ctx, EventOpinionEdited, s.timeService.CurrentTime(), o, previous)
+	return o, nil
+}
+
+// ListOpinionsQuery returns the opinions which are stored on the command side and visible to the user.
+// A user which may not see any opinion gets an empty list.
+func (s *service) ListOpinionsQuery(ctx context.Context, user AuthenticatedUser) ([]Opinion, error) {
+	visibility, err := s.OpinionVisibilityQuery(ctx, user)
+	if err != nil {
 		return nil, err
 	}
-	return s.repo.ListOpinions(ctx)
+
+	if len(visibility.Clauses) == 0 {
+		return []Opinion{}, nil
+	}
+	return s.repo.ListOpinions(ctx, visibility.Simplify())
+}
+
+// MaxOpinionPageLimit is the largest page of ListOpinionsPageQuery
+const MaxOpinionPageLimit = 100
+
+// ListOpinionsPageQuery returns a page of the opinions which are visible to the user in the order of ListOpinionsQuery.
+// The cursor of the last opinion of a page selects the next page.
+func (s *service) ListOpinionsPageQuery(ctx context.Context, user AuthenticatedUser, page OpinionPage) (OpinionList, error) {
+	if page.Limit < 0 || page.Limit > MaxOpinionPageLimit {
+		return OpinionList{}, NewValidationError(FieldError{Field: "limit", Message: fmt.Sprintf("must be between 0 and %d", MaxOpinionPageLimit)})
+	}
+
+	visibility, err := s.OpinionVisibilityQuery(ctx, user)
+	if err != nil {
+		return OpinionList{}, err
+	}
+
+	if len(visibility.Clauses) == 0 {
+		return OpinionList{Opinions: []Opinion{}}, nil
+	}
+	visibility = visibility.Simplify()
+
+	// one opinion more than the limit tells whether a next page follows
+	opinions, err := s.repo.ListOpinionsPage(ctx, visibility, OpinionPage{After: page.After, Limit: page.Limit + 1})
+	if err != nil {
+		return OpinionList{}, err
+	}
+
+	count, err := s.repo.CountOpinions(ctx, visibility)
+	if err != nil {
+		return OpinionList{}, err
+	}
+
+	list := OpinionList{Opinions: opinions, TotalCount: count}
+	if len(opinions) > page.Limit {
+		list.Opinions, list.HasNextPage = opinions[:page.Limit], true
+	}
+	return list, nil
 }
 
+// OpinionVisibilityQuery returns the opinions the user may list, the streams match the opinions of their events with it
+func (s *service) OpinionVisibilityQuery(ctx context.Context, user AuthenticatedUser) (OpinionVisibility, error) {
+	if err := s.authorize(ctx, user, ActionListOpinions); err != nil {
+		return OpinionVisibility{}, err
+	}
+
+	visibility, err := s.opinionVisibility(ctx, user)
+	if errors.Is(err, AccessDeniedError) {
+		return OpinionVisibility{}, nil
+	}
+	if err != nil {
+		return OpinionVisibility{}, err
+	}
+
+	if err := visibility.Validate(); err != nil {
+		return OpinionVisibility{}, err
+	}
+	return visibility, nil
+}
+
+// opinionVisibility asks the VisibilityPolicy which opinions the user may list, see WithVisibilityPolicy for the default
+func (s *service) opinionVisibility(ctx context.Context, user AuthenticatedUser) (OpinionVisibility, error) {
+	if s.visibility != nil {
+		visibility, err := s.visibility.OpinionVisibility(ctx, AccessRequest{User: user.Id, Roles: user.Roles, Action: ActionListOpinions})
+		return visibility, InternalError(err)
+	}
+
+	privileged, err := s.permitted(ctx, user, ActionViewUnpublishedOpinions)
+	if err != nil || privileged {
+		return AllOpinionsVisible, err
+	}
+
+	return OpinionVisibility{Clauses: [][]OpinionCondition{
+		{{Field: OpinionFieldStatus, Operator: OperatorEqual, Value: string(OpinionPublished)}},
+		{{Field: OpinionFieldOwner, Operator: OperatorEqual, Value: string(user.Id)}},
+	}}, nil
+}
+
+// DeleteOpinionCommand removes the opinion together with its votes and reports
 func (s *service) DeleteOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) error {
-	if err := s.pep.RequestAccessForUser(ctx, string(user.Id), ActionDeleteOpinion); err != nil {
+	o, err := s.authorizeOpinion(ctx, user, id, ActionDeleteOpinion)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteOpinion(ctx, id); err != nil {
 		return err
 	}
+	s.publish(ctx, EventOpinionDeleted, s.timeService.CurrentTime(), o, Vote{})
+	return nil
+}
+
+// BulkDeleteOpinionsCommand removes all opinions which match the filter together with their votes and reports.
+// The filter must not be empty, so a single command can not remove all opinions.
+func (s *service) BulkDeleteOpinionsCommand(ctx context.Context, user AuthenticatedUser, filter OpinionFilter) ([]OpinionId, error) {
+	if err := s.authorize(ctx, user, ActionBulkDeleteOpinions); err != nil {
+		return nil, err
+	}
+
+	if filter.Empty() {
+		return nil, EmptyOpinionFilterError
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedFrom.Before(filter.CreatedBefore) {
+		return nil, InvalidCreationTimeRangeError
+	}
+
+	ids, err := s.repo.DeleteOpinions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.timeService.CurrentTime()
+	for _, id := range ids {
+		s.publish(ctx, EventOpinionDeleted, now, Opinion{ID: id}, Vote{})
+	}
+	return ids, nil
+}
+
+// ApproveOpinionCommand publishes an opinion which is pending or was rejected
+func (s *service) ApproveOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error) {
+	return s.review(ctx, user, id, ActionApproveOpinion, OpinionPublished, EventOpinionApproved)
+}
+
+// RejectOpinionCommand hides an opinion from all users except the owner and admins
+func (s *service) RejectOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error) {
+	return s.review(ctx, user, id, ActionRejectOpinion, OpinionRejected, EventOpinionRejected)
+}
+
+// HideOpinionCommand hides an opinion like too many reports do, it is published again by an approval
+func (s *service) HideOpinionCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error) {
+	return s.review(ctx, user, id, ActionHideOpinion, OpinionHidden, EventOpinionHidden)
+}
+
+func (s *service) review(ctx context.Context, user AuthenticatedUser, id OpinionId, action string, status OpinionStatus, eventType EventType) (Opinion, error) {
+	o, err := s.authorizeOpinion(ctx, user, id, action)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	previous := o.Status
+	o.Status = status
+	if o, err = s.updateOpinion(ctx, o); err != nil {
+		return Opinion{}, err
+	}
+
+	s.publishChange(ctx, eventType, s.timeService.CurrentTime(), o, previous)
+	return o, nil
+}
+
+// ReportOpinionCommand flags the opinion, it is hidden if the count of open reports reaches the threshold
+func (s *service) ReportOpinionCommand(ctx context.Context, user AuthenticatedUser, report ReportCreateDTO) (Report, error) {
+	if err := s.authorize(ctx, user, ActionReportOpinion); err != nil {
+		return Report{}, err
+	}
+
+	if report.Opinion == "" {
+		return Report{}, EmptyOpinionIdError
+	}
+
+	if !report.Reason.Valid() {
+		return Report{}, InvalidReportReasonError
+	}
+
+	o, err := s.visibleOpinion(ctx, user, report.Opinion)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{
+		Opinion:   report.Opinion,
+		Reporter:  user.Id,
+		Reason:    report.Reason,
+		CreatedAt: s.timeService.CurrentTime(),
+	}
+
+	if err := s.repo.CreateReport(ctx, r); err != nil {
+		return Report{}, err
+	}
+
+	s.publishReport(ctx, EventOpinionReported, r.CreatedAt, o, r)
+
+	if s.reportThreshold <= 0 || o.Status != OpinionPublished {
+		return r, nil
+	}
+
+	count, err := s.repo.CountOpenReports(ctx, o.ID)
+	if err != nil {
+		return Report{}, err
+	}
+	if count < s.reportThreshold {
+		return r, nil
+	}
+
+	o.Status = OpinionHidden
+	if o, err = s.updateOpinion(ctx, o); err != nil {
+		return Report{}, err
+	}
+
+	s.publishChange(ctx, EventOpinionHidden, r.CreatedAt, o, OpinionPublished)
+	return r, nil
+}
+
+// ListReportsQuery returns the open reports of all opinions, oldest first
+func (s *service) ListReportsQuery(ctx context.Context, user AuthenticatedUser) ([]Report, error) {
+	if err := s.authorize(ctx, user, ActionListReports); err != nil {
+		return nil, err
+	}
+	return s.repo.ListOpenReports(ctx)
+}
+
+// ListDecisionsQuery returns the recorded decisions of the policy enforcement point, the newest first
+func (s *service) ListDecisionsQuery(ctx context.Context, user AuthenticatedUser, filter DecisionFilter) ([]Decision, error) {
+	if err := s.authorize(ctx, user, ActionListDecisions); err != nil {
+		return nil, err
+	}
+
+	if s.decisions == nil {
+		return nil, DecisionLogUnavailableError
+	}
+
+	if !filter.From.IsZero() && !filter.Before.IsZero() && !filter.From.Before(filter.Before) {
+		return nil, InvalidDecisionTimeRangeError
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultDecisionLimit
+	}
+	if filter.Limit > maxDecisionLimit {
+		filter.Limit = maxDecisionLimit
+	}
+	return s.decisions.ListDecisions(ctx, filter)
+}
+
+// ResolveReportsCommand closes all open reports of the opinion.
+// Dismissed reports publish a hidden opinion again, upheld reports reject the opinion.
+func (s *service) ResolveReportsCommand(ctx context.Context, user AuthenticatedUser, id OpinionId, resolution ReportResolution) (Opinion, error) {
+	if err := s.authorize(ctx, user, ActionResolveReports); err != nil {
+		return Opinion{}, err
+	}
 
 	if id == "" {
-		return EmptyOpinionIdError
+		return Opinion{}, EmptyOpinionIdError
+	}
+
+	if !resolution.Valid() {
+		return Opinion{}, InvalidReportResolutionError
+	}
+
+	o, err := s.repo.GetOpinion(ctx, id)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	now := s.timeService.CurrentTime()
+	if err := s.repo.ResolveReports(ctx, id, resolution, now); err != nil {
+		return Opinion{}, err
+	}
+
+	previous, status := o.Status, o.Status
+	switch {
+	case resolution == ReportUpheld:
+		status = OpinionRejected
+	case o.Status == OpinionHidden:
+		status = OpinionPublished
+	}
+
+	if status != o.Status {
+		o.Status = status
+		if o, err = s.updateOpinion(ctx, o); err != nil {
+			return Opinion{}, err
+		}
 	}
-	return s.repo.DeleteOpinion(ctx, id)
+
+	s.publishEvent(ctx, Event{
+		Type:           EventReportsResolved,
+		OccurredAt:     now,
+		Opinion:        o,
+		Report:         Report{Opinion: id, Resolution: resolution, ResolvedAt: now},
+		PreviousStatus: previous,
+	})
+	return o, nil
 }
 
 func (s *service) HandleUserDeletionEvent(ctx context.Context, event any) error {
@@ -119,17 +648,234 @@ func (s *service) HandleUserDeletionEvent(ctx context.Context, event any) error
 	panic("implement me")
 }
 
+// CreateVoteCommand submits the first vote of the user for the given opinion
 func (s *service) CreateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error) {
-	//TODO implement me
-	panic("implement me")
+	if err := s.authorize(ctx, user, ActionCreateVote); err != nil {
+		return Vote{}, err
+	}
+
+	if vote.Opinion == "" {
+		return Vote{}, EmptyOpinionIdError
+	}
+
+	if _, err := s.visibleOpinion(ctx, user, vote.Opinion); err != nil {
+		return Vote{}, err
+	}
+
+	now := s.timeService.CurrentTime()
+	v := Vote{
+		Agreement: vote.Agreement,
+		Opinion:   vote.Opinion,
+		Voter:     user.Id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	if err := s.repo.CreateVote(ctx, v); err != nil {
+		return Vote{}, err
+	}
+
+	s.publish(ctx, EventVoteSubmitted, v.CreatedAt, Opinion{}, v)
+	return v, nil
 }
 
+// UpdateVoteCommand changes the agreement of an existing vote of the user.
+// If the command carries a version, the vote must not have been updated since.
 func (s *service) UpdateVoteCommand(ctx context.Context, user AuthenticatedUser, vote VoteCreateAndUpdateDTO) (Vote, error) {
-	//TODO implement me
-	panic("implement me")
+	if err := s.authorize(ctx, user, ActionUpdateVote); err != nil {
+		return Vote{}, err
+	}
+
+	if vote.Opinion == "" {
+		return Vote{}, EmptyOpinionIdError
+	}
+
+	v, err := s.repo.GetVote(ctx, vote.Opinion, user.Id)
+	if err != nil {
+		return Vote{}, err
+	}
+
+	if vote.Version != 0 && vote.Version != v.Version {
+		return Vote{}, VoteVersionConflictError
+	}
+
+	v.Agreement = vote.Agreement
+	v.UpdatedAt = s.timeService.CurrentTime()
+
+	// the repository detects an update which happened between reading and writing the vote
+	if err := s.repo.UpdateVote(ctx, v); err != nil {
+		return Vote{}, err
+	}
+	v.Version++
+
+	s.publish(ctx, EventVoteUpdated, v.UpdatedAt, Opinion{}, v)
+	return v, nil
 }
 
+// DeleteVoteCommand withdraws the vote of the user from the given opinion and returns the removed vote
 func (s *service) DeleteVoteCommand(ctx context.Context, user AuthenticatedUser, id OpinionId) (Vote, error) {
-	//TODO implement me
-	panic("implement me")
+	if err := s.authorize(ctx, user, ActionDeleteVote); err != nil {
+		return Vote{}, err
+	}
+
+	if id == "" {
+		return Vote{}, EmptyOpinionIdError
+	}
+
+	v, err := s.repo.GetVote(ctx, id, user.Id)
+	if err != nil {
+		return Vote{}, err
+	}
+
+	if err := s.repo.DeleteVote(ctx, id, user.Id); err != nil {
+		return Vote{}, err
+	}
+
+	s.publish(ctx, EventVoteDeleted, s.timeService.CurrentTime(), Opinion{}, v)
+	return v, nil
+}
+
+// CountVotesQuery returns the tallies of the opinions, e.g. of a page of listed opinions.
+// Opinions without votes, unknown opinions and opinions the user may not list are missing in the map.
+func (s *service) CountVotesQuery(ctx context.Context, user AuthenticatedUser, opinions []OpinionId) (map[OpinionId]VoteCount, error) {
+	visibility, err := s.OpinionVisibilityQuery(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opinions) == 0 || len(visibility.Clauses) == 0 {
+		return map[OpinionId]VoteCount{}, nil
+	}
+	return s.repo.CountVotes(ctx, visibility.Simplify(), opinions)
+}
+
+// updateOpinion stores the changed opinion if it was not updated since it was read and returns it with the new version
+func (s *service) updateOpinion(ctx context.Context, o Opinion) (Opinion, error) {
+	if err := s.repo.UpdateOpinion(ctx, o); err != nil {
+		return Opinion{}, err
+	}
+	o.Version++
+	return o, nil
+}
+
+// authorize asks the policy enforcement point whether the user may perform the action.
+// Failures of the policy enforcement point which are not an Error are treated as internal errors.
+func (s *service) authorize(ctx context.Context, user AuthenticatedUser, action string) error {
+	return s.authorizeOn(ctx, user, action, Opinion{})
+}
+
+// authorizeOn asks the policy enforcement point whether the user may perform the action on the opinion
+func (s *service) authorizeOn(ctx context.Context, user AuthenticatedUser, action string, opinion Opinion) error {
+	if user.Id == "" {
+		return UnauthenticatedError
+	}
+	return InternalError(s.pep.RequestAccessForUser(ctx, AccessRequest{
+		User:    user.Id,
+		Roles:   user.Roles,
+		Action:  action,
+		Opinion: opinion.ID,
+		Owner:   opinion.Owner,
+	}))
+}
+
+// authorizeOpinion loads the opinion and asks the policy enforcement point whether the user may perform the action on it
+func (s *service) authorizeOpinion(ctx context.Context, user AuthenticatedUser, id OpinionId, action string) (Opinion, error) {
+	if user.Id == "" {
+		return Opinion{}, UnauthenticatedError
+	}
+
+	if id == "" {
+		return Opinion{}, EmptyOpinionIdError
+	}
+
+	o, err := s.repo.GetOpinion(ctx, id)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	if err := s.authorizeOn(ctx, user, action, o); err != nil {
+		return Opinion{}, err
+	}
+	return o, nil
+}
+
+// visibleOpinion loads the opinion if the user may list it, the opinions the user may not see are reported as not found
+func (s *service) visibleOpinion(ctx context.Context, user AuthenticatedUser, id OpinionId) (Opinion, error) {
+	o, err := s.repo.GetOpinion(ctx, id)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	visibility, err := s.OpinionVisibilityQuery(ctx, user)
+	if err != nil {
+		return Opinion{}, err
+	}
+
+	visible, err := visibility.Matches(o)
+	if err != nil {
+		return Opinion{}, err
+	}
+	if !visible {
+		return Opinion{}, OpinionNotFoundError
+	}
+	return o, nil
+}
+
+// permitted reports whether the user may perform the action, unlike authorize a denial is not an error
+func (s *service) permitted(ctx context.Context, user AuthenticatedUser, action string) (bool, error) {
+	err := s.authorize(ctx, user, action)
+	if CodeOf(err) == CodeForbidden {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// moderate returns the verdict for the statement, a rejection is returned as OpinionRejectedError
+func (s *service) moderate(ctx context.Context, statement string) (Verdict, error) {
+	result, err := s.moderation.Check(ctx, statement)
+	if err != nil {
+		return VerdictReject, InternalError(err)
+	}
+
+	if result.Verdict == VerdictReject {
+		return VerdictReject, moderationError(result)
+	}
+	return result.Verdict, nil
+}
+
+// publish announces the change after it was stored. A failed publish is passed to the publish error handler
+// instead of failing the command, otherwise the client would retry a command which already succeeded.
+func (s *service) publish(ctx context.Context, eventType EventType, occurredAt time.Time, opinion Opinion, vote Vote) {
+	s.publishEvent(ctx, Event{
+		Type:       eventType,
+		OccurredAt: occurredAt,
+		Opinion:    opinion,
+		Vote:       vote,
+	})
+}
+
+func (s *service) publishReport(ctx context.Context, eventType EventType, occurredAt time.Time, opinion Opinion, report Report) {
+	s.publishEvent(ctx, Event{
+		Type:       eventType,
+		OccurredAt: occurredAt,
+		Opinion:    opinion,
+		Report:     report,
+	})
+}
+
+// publishChange announces a change of the opinion, previous is its status before the change
+func (s *service) publishChange(ctx context.Context, eventType EventType, occurredAt time.Time, opinion Opinion, previous OpinionStatus) {
+	s.publishEvent(ctx, Event{
+		Type:           eventType,
+		OccurredAt:     occurredAt,
+		Opinion:        opinion,
+		PreviousStatus: previous,
+	})
+}
+
+func (s *service) publishEvent(ctx context.Context, event Event) {
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		s.onPublishError(event, err)
+	}
 }
@@ -0,0 +1,261 @@
+package application_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newIntegrationService wires the service with the memory adapters and an opinion list projection
+func newIntegrationService(t *testing.T, options ...application.Option) (application.Service, *projections.OpinionListProjection) {
+	t.Helper()
+	eventLog := infrastructure.NewEventLogMemory()
+	bus := infrastructure.NewEventBus(eventLog, func(_ application.Event, err error) {
+		t.Errorf("event could not be handled: %s", err)
+	})
+	list := projections.NewOpinionListProjection()
+	bus.Subscribe(projections.NewProjector(eventLog, list))
+
+	s := application.NewOpinionService(
+		infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+		infrastructure.NewOpinionsRepositoryMemory(),
+		bus,
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+		options...,
+	)
+	return s, list
+}
+
+func TestService_integration_opinion_and_vote_lifecycle(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	owner := application.AuthenticatedUser{Id: "owner"}
+	voter := application.AuthenticatedUser{Id: "voter"}
+
+	s, list := newIntegrationService(t)
+
+	opinion, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is fine"})
+	assert.NoError(t, err)
+
+	_, err = s.CreateVoteCommand(ctx, voter, application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: opinion.ID})
+	assert.NoError(t, err)
+
+	_, err = s.CreateVoteCommand(ctx, voter, application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: opinion.ID})
+	assert.ErrorIs(t, err, application.VoteAlreadyExistsError)
+
+	_, err = s.UpdateVoteCommand(ctx, voter, application.VoteCreateAndUpdateDTO{Agreement: false, Opinion: opinion.ID})
+	assert.NoError(t, err)
+
+	summary, ok := list.Get(opinion.ID)
+	assert.True(t, ok)
+	assert.Equal(t, 0, summary.Agreements)
+	assert.Equal(t, 1, summary.Disagreements)
+
+	assert.NoError(t, s.DeleteOpinionCommand(ctx, owner, opinion.ID))
+	assert.ErrorIs(t, s.DeleteOpinionCommand(ctx, owner, opinion.ID), application.OpinionNotFoundError)
+
+	_, err = s.DeleteVoteCommand(ctx, voter, opinion.ID)
+	assert.ErrorIs(t, err, application.VoteNotFoundError)
+
+	opinions, err := s.ListOpinionsQuery(ctx, owner)
+	assert.NoError(t, err)
+	assert.Empty(t, opinions)
+	assert.Empty(t, list.List())
+}
+
+func TestService_integration_moderation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	owner := application.AuthenticatedUser{Id: "owner"}
+	reader := application.AuthenticatedUser{Id: "reader"}
+	admin := application.AuthenticatedUser{Id: "admin", Roles: []application.Role{application.RoleAdmin}}
+
+	s, list := newIntegrationService(t, application.WithModeration(application.NewModerationPipeline(
+		infrastructure.NewWordListCheck([]string{"spaghetti"}, application.VerdictReject),
+		infrastructure.LinkSpamCheck{MaxLinks: 0, MaxLinkRatio: 1},
+	)))
+
+	_, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "Spaghetti code is fine"})
+	assert.ErrorIs(t, err, application.OpinionRejectedError)
+
+	published, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is fine"})
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionPublished, published.Status)
+
+	pending, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "read https://felixwiedmann.de"})
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionPendingReview, pending.Status)
+
+	visible := func(user application.AuthenticatedUser) int {
+		opinions, err := s.ListOpinionsQuery(ctx, user)
+		assert.NoError(t, err)
+		return len(opinions)
+	}
+	assert.Equal(t, 1, visible(reader))
+	assert.Equal(t, 2, visible(owner))
+	assert.Equal(t, 2, visible(admin))
+
+	_, err = s.ApproveOpinionCommand(ctx, owner, pending.ID)
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	approved, err := s.ApproveOpinionCommand(ctx, admin, pending.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionPublished, approved.Status)
+	assert.Equal(t, 2, visible(reader))
+
+	_, err = s.RejectOpinionCommand(ctx, admin, published.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, visible(reader))
+
+	edited, err := s.EditOpinionCommand(ctx, owner, published.ID, application.OpinionUpdateDTO{Statement: "copy and pasta is great"})
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionPendingReview, edited.Status)
+
+	summary, ok := list.Get(published.ID)
+	assert.True(t, ok)
+	assert.Equal(t, application.OpinionPendingReview, summary.Status)
+}
+
+func TestService_integration_reports(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	owner := application.AuthenticatedUser{Id: "owner"}
+	admin := application.AuthenticatedUser{Id: "admin", Roles: []application.Role{application.RoleAdmin}}
+
+	s, list := newIntegrationService(t, application.WithReportThreshold(2))
+
+	opinion, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is fine"})
+	assert.NoError(t, err)
+
+	report := application.ReportCreateDTO{Opinion: opinion.ID, Reason: application.ReportReasonAbuse}
+
+	_, err = s.ReportOpinionCommand(ctx, application.AuthenticatedUser{Id: "a"}, application.ReportCreateDTO{Opinion: opinion.ID, Reason: "boring"})
+	assert.ErrorIs(t, err, application.InvalidReportReasonError)
+
+	_, err = s.ReportOpinionCommand(ctx, application.AuthenticatedUser{Id: "a"}, report)
+	assert.NoError(t, err)
+	_, err = s.ReportOpinionCommand(ctx, application.AuthenticatedUser{Id: "a"}, report)
+	assert.ErrorIs(t, err, application.ReportAlreadyExistsError)
+
+	summary, _ := list.Get(opinion.ID)
+	assert.Equal(t, application.OpinionPublished, summary.Status)
+	assert.Equal(t, 1, summary.OpenReports)
+
+	_, err = s.ReportOpinionCommand(ctx, application.AuthenticatedUser{Id: "b"}, report)
+	assert.NoError(t, err)
+
+	summary, _ = list.Get(opinion.ID)
+	assert.Equal(t, application.OpinionHidden, summary.Status)
+
+	opinions, err := s.ListOpinionsQuery(ctx, application.AuthenticatedUser{Id: "reader"})
+	assert.NoError(t, err)
+	assert.Empty(t, opinions)
+
+	_, err = s.ListReportsQuery(ctx, owner)
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	reports, err := s.ListReportsQuery(ctx, admin)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 2)
+
+	resolved, err := s.ResolveReportsCommand(ctx, admin, opinion.ID, application.ReportDismissed)
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionPublished, resolved.Status)
+
+	_, err = s.ResolveReportsCommand(ctx, admin, opinion.ID, application.ReportDismissed)
+	assert.ErrorIs(t, err, application.ReportNotFoundError)
+
+	reports, err = s.ListReportsQuery(ctx, admin)
+	assert.NoError(t, err)
+	assert.Empty(t, reports)
+
+	summary, _ = list.Get(opinion.ID)
+	assert.Equal(t, application.OpinionPublished, summary.Status)
+	assert.Equal(t, 0, summary.OpenReports)
+
+	_, err = s.ReportOpinionCommand(ctx, application.AuthenticatedUser{Id: "c"}, report)
+	assert.NoError(t, err)
+
+	resolved, err = s.ResolveReportsCommand(ctx, admin, opinion.ID, application.ReportUpheld)
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionRejected, resolved.Status)
+}
+
+func TestService_integration_idempotency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	owner := application.AuthenticatedUser{Id: "owner"}
+
+	s, list := newIntegrationService(t)
+
+	create := application.OpinionCreateDTO{Statement: "copy and pasta is fine", IdempotencyKey: "retry"}
+
+	first, err := s.CreateOpinionCommand(ctx, owner, create)
+	assert.NoError(t, err)
+
+	retried, err := s.CreateOpinionCommand(ctx, owner, create)
+	assert.NoError(t, err)
+	assert.Equal(t, first, retried)
+
+	_, err = s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is great", IdempotencyKey: "retry"})
+	assert.ErrorIs(t, err, application.IdempotencyKeyReusedError)
+
+	// the same key of another user is independent
+	other, err := s.CreateOpinionCommand(ctx, application.AuthenticatedUser{Id: "other"}, create)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	opinions, err := s.ListOpinionsQuery(ctx, owner)
+	assert.NoError(t, err)
+	assert.Len(t, opinions, 2)
+	assert.Len(t, list.List(), 2)
+}
+
+func TestService_integration_roles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	owner := application.AuthenticatedUser{Id: "owner", Roles: []application.Role{application.RoleUser}}
+	other := application.AuthenticatedUser{Id: "other", Roles: []application.Role{application.RoleUser}}
+	moderator := application.AuthenticatedUser{Id: "moderator", Roles: []application.Role{application.RoleModerator}}
+	admin := application.AuthenticatedUser{Id: "admin", Roles: []application.Role{application.RoleAdmin}}
+
+	s, list := newIntegrationService(t)
+
+	first, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is fine"})
+	assert.NoError(t, err)
+	second, err := s.CreateOpinionCommand(ctx, owner, application.OpinionCreateDTO{Statement: "copy and pasta is great"})
+	assert.NoError(t, err)
+	_, err = s.CreateOpinionCommand(ctx, other, application.OpinionCreateDTO{Statement: "copy and pasta is bad"})
+	assert.NoError(t, err)
+
+	_, err = s.HideOpinionCommand(ctx, other, first.ID)
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	hidden, err := s.HideOpinionCommand(ctx, moderator, first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, application.OpinionHidden, hidden.Status)
+
+	assert.ErrorIs(t, s.DeleteOpinionCommand(ctx, moderator, first.ID), application.AccessDeniedError)
+	assert.NoError(t, s.DeleteOpinionCommand(ctx, admin, first.ID))
+
+	_, err = s.BulkDeleteOpinionsCommand(ctx, moderator, application.OpinionFilter{Owner: owner.Id})
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	_, err = s.BulkDeleteOpinionsCommand(ctx, admin, application.OpinionFilter{})
+	assert.ErrorIs(t, err, application.EmptyOpinionFilterError)
+
+	ids, err := s.BulkDeleteOpinionsCommand(ctx, admin, application.OpinionFilter{Owner: owner.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, []application.OpinionId{second.ID}, ids)
+
+	_, ok := list.Get(second.ID)
+	assert.False(t, ok)
+
+	opinions, err := s.ListOpinionsQuery(ctx, other)
+	assert.NoError(t, err)
+	assert.Len(t, opinions, 1)
+}
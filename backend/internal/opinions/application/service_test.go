@@ -3,10 +3,12 @@ package application_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/fwiedmann/site/backend/internal/opinions/application"
 	mock_application "github.com/fwiedmann/site/backend/internal/opinions/application/mocks"
 	"github.com/golang/mock/gomock"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -105,6 +107,8 @@ func Test_service_CreateOpinionCommand(t *testing.T) {
 				Owner:     testUserId,
 				CreatedAt: testDate,
 				Statement: testStatement,
+				Status:    application.OpinionPublished,
+				Version:   1,
 			},
 			wantErr: nil,
 		},
@@ -121,12 +125,15 @@ func Test_service_CreateOpinionCommand(t *testing.T) {
 			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
 
 			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
-			pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.fields.pepError)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any()).Return(tt.fields.pepError)
 
 			repo := mock_application.NewMockRepository(ctrl)
 			repo.EXPECT().CreateOpinion(gomock.Any(), gomock.Any()).Return(tt.fields.repoError).MaxTimes(1)
 
-			s := application.NewOpinionService(pep, repo, idService, timeService)
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
 			got, err := s.CreateOpinionCommand(tt.args.ctx, tt.args.user, tt.args.opinion)
 
 			if (err != nil) && tt.wantErr == nil {
@@ -146,6 +153,157 @@ func Test_service_CreateOpinionCommand(t *testing.T) {
 	}
 }
 
+func TestService_CreateOpinionCommand_idempotency(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testKey = "retry"
+
+	repoError := errors.New("repo error")
+	testDate := time.Now()
+
+	stored := application.IdempotencyRecord{
+		User:        testUserId,
+		Key:         testKey,
+		RequestHash: "85bce3ab2ee8bf81a93b30f6c8a4b7e9a15f5b86b73a1a4bc3caa4b6f2e9fb94",
+		Opinion:     application.Opinion{ID: "first"},
+	}
+	// the SHA-256 of the normalized statement "copy and pasta is fine"
+	const normalizedHash = "d4935a8672e39d6531f03fd2721c8bb8339beee42da0e18d45921f023b84c989"
+
+	tests := []struct {
+		name         string
+		statement    string
+		key          string
+		reserveError error
+		storedHash   string
+		createError  error
+		wantRelease  bool
+		want         application.OpinionId
+		wantErr      error
+	}{
+		{
+			name:    "Should throw error because the key is too long",
+			key:     strings.Repeat("k", 256),
+			wantErr: application.InvalidIdempotencyKeyError,
+		},
+		{
+			name:      "Should reserve the key and create the opinion",
+			statement: "copy and pasta is fine",
+			key:       testKey,
+			want:      "187",
+		},
+		{
+			name:         "Should throw error because the key is reused for another statement",
+			statement:    "copy and pasta is fine",
+			key:          testKey,
+			reserveError: application.IdempotencyRecordAlreadyExistsError,
+			wantErr:      application.IdempotencyKeyReusedError,
+		},
+		{
+			name:         "Should replay the first opinion because the statements only differ before the normalization",
+			statement:    "  copy and pasta is fine\n",
+			key:          testKey,
+			reserveError: application.IdempotencyRecordAlreadyExistsError,
+			storedHash:   normalizedHash,
+			want:         "first",
+		},
+		{
+			name:        "Should release the key if the opinion could not be stored",
+			statement:   "copy and pasta is fine",
+			key:         testKey,
+			createError: repoError,
+			wantRelease: true,
+			wantErr:     repoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+			idService.EXPECT().GenerateId().Return("187").MaxTimes(1)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any()).Return(nil)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any(), testDate).DoAndReturn(func(_ context.Context, record application.IdempotencyRecord, _ time.Time) error {
+				if record.ExpiresAt != testDate.Add(application.DefaultIdempotencyTTL) || record.Opinion.ID != "187" || record.RequestHash != normalizedHash {
+					t.Errorf("CreateIdempotencyRecord() got unexpected record %v", record)
+				}
+				return tt.reserveError
+			}).MaxTimes(1)
+			record := stored
+			if tt.storedHash != "" {
+				record.RequestHash = tt.storedHash
+			}
+			repo.EXPECT().GetIdempotencyRecord(gomock.Any(), testUserId, testKey, testDate).Return(record, nil).MaxTimes(1)
+			repo.EXPECT().CreateOpinion(gomock.Any(), gomock.Any()).Return(tt.createError).MaxTimes(1)
+			if tt.wantRelease {
+				repo.EXPECT().DeleteIdempotencyRecord(gomock.Any(), testUserId, testKey).Return(nil)
+			}
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.CreateOpinionCommand(context.Background(), application.AuthenticatedUser{Id: testUserId}, application.OpinionCreateDTO{Statement: tt.statement, IdempotencyKey: tt.key})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CreateOpinionCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil || got.ID != tt.want {
+				t.Errorf("CreateOpinionCommand() got = %v, %v, want id %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_CreateOpinionCommand_publishError(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	idService := mock_application.NewMockIdService(ctrl)
+	idService.EXPECT().GenerateId().Return("187")
+
+	timeService := mock_application.NewMockTimeService(ctrl)
+	timeService.EXPECT().CurrentTime().Return(time.Now())
+
+	pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+	pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any()).Return(nil)
+
+	repo := mock_application.NewMockRepository(ctrl)
+	repo.EXPECT().CreateOpinion(gomock.Any(), gomock.Any()).Return(nil)
+
+	publishError := errors.New("broker unavailable")
+	publisher := mock_application.NewMockEventPublisher(ctrl)
+	publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(publishError)
+
+	var failed []application.EventType
+	s := application.NewOpinionService(pep, repo, publisher, idService, timeService, application.WithPublishErrorHandler(func(event application.Event, err error) {
+		if !errors.Is(err, publishError) {
+			t.Errorf("publish error handler got error %v, want %v", err, publishError)
+		}
+		failed = append(failed, event.Type)
+	}))
+	got, err := s.CreateOpinionCommand(context.Background(), application.AuthenticatedUser{Id: "1"}, application.OpinionCreateDTO{Statement: "stored is stored"})
+
+	if err != nil || got.ID != "187" {
+		t.Errorf("CreateOpinionCommand() got = %v, %v, want the stored opinion without error", got, err)
+	}
+	if !reflect.DeepEqual(failed, []application.EventType{application.EventOpinionCreated}) {
+		t.Errorf("publish error handler got events %v, want %v", failed, application.EventOpinionCreated)
+	}
+}
+
 func TestService_DeleteOpinionCommand(t *testing.T) {
 	t.Parallel()
 	const testDefaultId = "187"
@@ -238,12 +396,16 @@ func TestService_DeleteOpinionCommand(t *testing.T) {
 			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
 
 			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
-			pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.fields.pepError)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionDeleteOpinion)).Return(tt.fields.pepError).MaxTimes(1)
 
 			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetOpinion(gomock.Any(), tt.args.id).Return(application.Opinion{ID: tt.args.id, Owner: testUserId, CreatedAt: testDate}, nil).MaxTimes(1)
 			repo.EXPECT().DeleteOpinion(gomock.Any(), gomock.Any()).Return(tt.fields.repoError).MaxTimes(1)
 
-			s := application.NewOpinionService(pep, repo, idService, timeService)
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
 			err := s.DeleteOpinionCommand(tt.args.ctx, tt.args.user, tt.args.id)
 
 			if (err != nil) && tt.wantErr == nil {
@@ -259,48 +421,152 @@ func TestService_DeleteOpinionCommand(t *testing.T) {
 	}
 }
 
-func TestService_ListOpinionsCommand(t *testing.T) {
+func TestService_BulkDeleteOpinionsCommand(t *testing.T) {
 	t.Parallel()
 	const testUserId application.UserId = "1"
-	const testDefaultId = "187"
 
 	repoError := errors.New("repo error")
-	pepErrpr := errors.New("pep error")
 	testDate := time.Now()
 
 	type fields struct {
-		repoResp  []application.Opinion
+		deleted   []application.OpinionId
 		repoError error
 		pepError  error
 	}
-	type args struct {
-		ctx  context.Context
-		user application.AuthenticatedUser
+	tests := []struct {
+		name       string
+		fields     fields
+		filter     application.OpinionFilter
+		want       []application.OpinionId
+		wantEvents int
+		wantErr    error
+	}{
+		{
+			name:    "Should throw error because user is not permitted",
+			fields:  fields{pepError: application.AccessDeniedError},
+			filter:  application.OpinionFilter{Owner: "2"},
+			wantErr: application.AccessDeniedError,
+		},
+		{
+			name:    "Should throw error because filter is empty",
+			wantErr: application.EmptyOpinionFilterError,
+		},
+		{
+			name:    "Should throw error because creation time range is invalid",
+			filter:  application.OpinionFilter{CreatedFrom: testDate, CreatedBefore: testDate},
+			wantErr: application.InvalidCreationTimeRangeError,
+		},
+		{
+			name:    "Should throw error because repo error",
+			fields:  fields{repoError: repoError},
+			filter:  application.OpinionFilter{Owner: "2"},
+			wantErr: repoError,
+		},
+		{
+			name:       "Should successfully delete the opinions",
+			fields:     fields{deleted: []application.OpinionId{"187", "188"}},
+			filter:     application.OpinionFilter{Owner: "2", CreatedBefore: testDate},
+			want:       []application.OpinionId{"187", "188"},
+			wantEvents: 2,
+		},
 	}
-	type want struct {
-		length int
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionBulkDeleteOpinions)).Return(tt.fields.pepError)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().DeleteOpinions(gomock.Any(), tt.filter).Return(tt.fields.deleted, tt.fields.repoError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).Times(tt.wantEvents)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.BulkDeleteOpinionsCommand(context.Background(), application.AuthenticatedUser{Id: testUserId}, tt.filter)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("BulkDeleteOpinionsCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("BulkDeleteOpinionsCommand() error = %v, but no error is expected", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BulkDeleteOpinionsCommand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_EditOpinionCommand(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	pepErrpr := errors.New("pep error")
+	testDate := time.Now()
+
+	storedOpinion := application.Opinion{
+		ID:        testOpinionId,
+		Owner:     testUserId,
+		CreatedAt: testDate,
+		Statement: "copy and pasta is fine",
+		Status:    application.OpinionPublished,
+		Version:   3,
+	}
+
+	type fields struct {
+		getError    error
+		updateError error
+		pepError    error
+	}
+	type args struct {
+		ctx     context.Context
+		user    application.AuthenticatedUser
+		id      application.OpinionId
+		opinion application.OpinionUpdateDTO
 	}
 	tests := []struct {
 		name    string
 		fields  fields
 		args    args
-		want    want
+		want    application.Opinion
 		wantErr error
 	}{
 		{
-			name: "Should throw error because repo error",
-			fields: fields{
-				repoResp:  []application.Opinion{},
-				repoError: repoError,
+			name:   "Should throw error because empty opinion id",
+			fields: fields{},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great"},
 			},
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name:   "Should throw error because empty statement",
+			fields: fields{},
 			args: args{
-				ctx: context.Background(),
-				user: application.AuthenticatedUser{
-					Id: testUserId,
-				},
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: " \t "},
 			},
-			want:    want{length: 0},
-			wantErr: repoError,
+			wantErr: application.EmptyOpinionStatementError,
 		},
 		{
 			name: "Should throw error because pep error",
@@ -308,32 +574,91 @@ func TestService_ListOpinionsCommand(t *testing.T) {
 				pepError: pepErrpr,
 			},
 			args: args{
-				ctx: context.Background(),
-				user: application.AuthenticatedUser{
-					Id: testUserId,
-				},
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great"},
 			},
-			want:    want{length: 0},
 			wantErr: pepErrpr,
 		},
 		{
-			name: "Should successfully create an opinion",
+			name: "Should throw error because opinion could not be loaded",
 			fields: fields{
-				repoResp: []application.Opinion{{
-					ID:        "1",
-					Owner:     "2",
-					CreatedAt: time.Now(),
-					Statement: "copy and pasta is fine"},
-				},
+				getError: application.OpinionNotFoundError,
 			},
 			args: args{
-				ctx: context.Background(),
-				user: application.AuthenticatedUser{
-					Id: testUserId,
-				},
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great"},
+			},
+			wantErr: application.OpinionNotFoundError,
+		},
+		{
+			name:   "Should throw error because user is not the owner",
+			fields: fields{},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: "2"},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great"},
+			},
+			wantErr: application.AccessDeniedError,
+		},
+		{
+			name: "Should throw error because opinion could not be updated",
+			fields: fields{
+				updateError: repoError,
+			},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great"},
+			},
+			wantErr: repoError,
+		},
+		{
+			name:   "Should throw error because the user has seen an outdated version",
+			fields: fields{},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great", Version: 2},
+			},
+			wantErr: application.OpinionVersionConflictError,
+		},
+		{
+			name: "Should throw error because the opinion was updated concurrently",
+			fields: fields{
+				updateError: application.OpinionVersionConflictError,
+			},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "copy and pasta is great", Version: 3},
+			},
+			wantErr: application.OpinionVersionConflictError,
+		},
+		{
+			name:   "Should successfully edit the normalized statement",
+			fields: fields{},
+			args: args{
+				ctx:     context.Background(),
+				user:    application.AuthenticatedUser{Id: testUserId},
+				id:      testOpinionId,
+				opinion: application.OpinionUpdateDTO{Statement: "  copy and pasta is great\r\n "},
+			},
+			want: application.Opinion{
+				ID:        testOpinionId,
+				Owner:     testUserId,
+				CreatedAt: testDate,
+				Statement: "copy and pasta is great",
+				Status:    application.OpinionPublished,
+				Version:   4,
 			},
-			want:    want{length: 1},
-			wantErr: nil,
 		},
 	}
 	for _, tt := range tests {
@@ -342,35 +667,1247 @@ func TestService_ListOpinionsCommand(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			idService := mock_application.NewMockIdService(ctrl)
-			idService.EXPECT().GenerateId().Return(testDefaultId).MaxTimes(1)
 
 			timeService := mock_application.NewMockTimeService(ctrl)
 			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
 
 			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
-			pep.EXPECT().RequestAccessForUser(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.fields.pepError)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(tt.args.user.Id, application.ActionEditOpinion)).DoAndReturn(func(_ context.Context, request application.AccessRequest) error {
+				if tt.fields.pepError != nil {
+					return tt.fields.pepError
+				}
+				// the policy permits the edit to the owner only, so the request has to carry it
+				if request.Owner != request.User {
+					return application.AccessDeniedError
+				}
+				return nil
+			}).MaxTimes(1)
 
 			repo := mock_application.NewMockRepository(ctrl)
-			repo.EXPECT().ListOpinions(gomock.Any()).Return(tt.fields.repoResp, tt.fields.pepError).MaxTimes(1)
+			repo.EXPECT().GetOpinion(gomock.Any(), testOpinionId).Return(storedOpinion, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().UpdateOpinion(gomock.Any(), gomock.Any()).Return(tt.fields.updateError).MaxTimes(1)
 
-			s := application.NewOpinionService(pep, repo, idService, timeService)
-			got, err := s.ListOpinionsCommand(tt.args.ctx, tt.args.user)
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.EditOpinionCommand(tt.args.ctx, tt.args.user, tt.args.id, tt.args.opinion)
 
 			if (err != nil) && tt.wantErr == nil {
-				t.Errorf("ListOpinionsCommand() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("EditOpinionCommand() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 			if (err != nil) && !errors.Is(err, tt.wantErr) {
-				t.Errorf("ListOpinionsCommand() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("EditOpinionCommand() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if len(got) != tt.want.length {
-				t.Errorf("ListOpinionsCommand() returned wrong count of opinions got = %d, want %d", len(got), tt.want.length)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EditOpinionCommand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ReviewOpinionCommands(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	testDate := time.Now()
+
+	storedOpinion := application.Opinion{
+		ID:        testOpinionId,
+		Owner:     "2",
+		CreatedAt: testDate,
+		Statement: "copy and pasta is fine",
+		Status:    application.OpinionPendingReview,
+	}
+
+	type fields struct {
+		getError    error
+		updateError error
+		pepError    error
+	}
+	tests := []struct {
+		name    string
+		approve bool
+		fields  fields
+		id      application.OpinionId
+		want    application.OpinionStatus
+		wantErr error
+	}{
+		{
+			name:    "Should throw error because empty opinion id",
+			approve: true,
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name:    "Should throw error because user is not permitted",
+			approve: true,
+			fields:  fields{pepError: application.AccessDeniedError},
+			id:      testOpinionId,
+			wantErr: application.AccessDeniedError,
+		},
+		{
+			name:    "Should throw error because opinion could not be loaded",
+			fields:  fields{getError: application.OpinionNotFoundError},
+			id:      testOpinionId,
+			wantErr: application.OpinionNotFoundError,
+		},
+		{
+			name:    "Should throw error because opinion could not be updated",
+			fields:  fields{updateError: repoError},
+			id:      testOpinionId,
+			wantErr: repoError,
+		},
+		{
+			name:    "Should successfully approve the opinion",
+			approve: true,
+			id:      testOpinionId,
+			want:    application.OpinionPublished,
+		},
+		{
+			name: "Should successfully reject the opinion",
+			id:   testOpinionId,
+			want: application.OpinionRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			action := application.ActionRejectOpinion
+			if tt.approve {
+				action = application.ActionApproveOpinion
+			}
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, action)).Return(tt.fields.pepError).MaxTimes(1)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetOpinion(gomock.Any(), testOpinionId).Return(storedOpinion, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().UpdateOpinion(gomock.Any(), gomock.Any()).Return(tt.fields.updateError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event application.Event) error {
+				if event.Opinion.Status != tt.want || event.PreviousStatus != storedOpinion.Status {
+					t.Errorf("Publish() got status %s after %s, want %s after %s", event.Opinion.Status, event.PreviousStatus, tt.want, storedOpinion.Status)
+				}
+				return nil
+			}).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			command := s.RejectOpinionCommand
+			if tt.approve {
+				command = s.ApproveOpinionCommand
+			}
+			got, err := command(context.Background(), application.AuthenticatedUser{Id: testUserId}, tt.id)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("review command error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("review command error = %v, but no error is expected", err)
 				return
 			}
 
+			if got.Status != tt.want || got.Statement != storedOpinion.Statement {
+				t.Errorf("review command got = %v, want status %v", got, tt.want)
+			}
 		})
 	}
 }
+
+func TestService_ReportOpinionCommand(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	testDate := time.Now()
+
+	type fields struct {
+		status application.OpinionStatus
+		// privileged users see the opinions which are not published
+		privileged  bool
+		getError    error
+		createError error
+		openReports int
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		report     application.ReportCreateDTO
+		wantHidden bool
+		wantErr    error
+	}{
+		{
+			name:    "Should throw error because empty opinion id",
+			report:  application.ReportCreateDTO{Reason: application.ReportReasonSpam},
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name:    "Should throw error because unknown reason",
+			report:  application.ReportCreateDTO{Opinion: testOpinionId, Reason: "boring"},
+			wantErr: application.InvalidReportReasonError,
+		},
+		{
+			name:    "Should throw error because opinion could not be loaded",
+			fields:  fields{getError: application.OpinionNotFoundError},
+			report:  application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+			wantErr: application.OpinionNotFoundError,
+		},
+		{
+			name:    "Should throw error because report could not be stored",
+			fields:  fields{status: application.OpinionPublished, createError: repoError},
+			report:  application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+			wantErr: repoError,
+		},
+		{
+			name:   "Should keep the opinion below the threshold",
+			fields: fields{status: application.OpinionPublished, openReports: 1},
+			report: application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+		},
+		{
+			name:       "Should hide the opinion at the threshold",
+			fields:     fields{status: application.OpinionPublished, openReports: 2},
+			report:     application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+			wantHidden: true,
+		},
+		{
+			name:   "Should not hide opinions which are not published",
+			fields: fields{status: application.OpinionRejected, privileged: true, openReports: 2},
+			report: application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+		},
+		{
+			name:    "Should throw error because the pending opinion of another user is not visible",
+			fields:  fields{status: application.OpinionPendingReview},
+			report:  application.ReportCreateDTO{Opinion: testOpinionId, Reason: application.ReportReasonSpam},
+			wantErr: application.OpinionNotFoundError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionReportOpinion)).Return(nil)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(nil).MaxTimes(1)
+			var privilegeError error = application.AccessDeniedError
+			if tt.fields.privileged {
+				privilegeError = nil
+			}
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionViewUnpublishedOpinions)).Return(privilegeError).MaxTimes(1)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetOpinion(gomock.Any(), testOpinionId).Return(application.Opinion{ID: testOpinionId, Owner: "2", Status: tt.fields.status}, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Return(tt.fields.createError).MaxTimes(1)
+			repo.EXPECT().CountOpenReports(gomock.Any(), testOpinionId).Return(tt.fields.openReports, nil).MaxTimes(1)
+			if tt.wantHidden {
+				repo.EXPECT().UpdateOpinion(gomock.Any(), application.Opinion{ID: testOpinionId, Owner: "2", Status: application.OpinionHidden}).Return(nil)
+			}
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(2)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService, application.WithReportThreshold(2))
+			got, err := s.ReportOpinionCommand(context.Background(), application.AuthenticatedUser{Id: testUserId}, tt.report)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ReportOpinionCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			want := application.Report{Opinion: testOpinionId, Reporter: testUserId, Reason: tt.report.Reason, CreatedAt: testDate}
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("ReportOpinionCommand() got = %v, %v, want %v", got, err, want)
+			}
+		})
+	}
+}
+
+func TestService_ListOpinionsQuery(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testDefaultId = "187"
+
+	repoError := errors.New("repo error")
+	pepErrpr := errors.New("pep error")
+	testDate := time.Now()
+
+	opinions := []application.Opinion{
+		{ID: "1", Owner: "2", Status: application.OpinionPublished},
+		{ID: "4", Owner: testUserId, Status: application.OpinionPendingReview},
+	}
+
+	ownAndPublished := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+		{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: string(testUserId)}},
+	}}
+
+	type fields struct {
+		repoResp   []application.Opinion
+		repoError  error
+		pepError   error
+		privileged bool
+		// policy replaces the default visibility if it is set
+		policy      *application.OpinionVisibility
+		policyError error
+	}
+	type args struct {
+		ctx  context.Context
+		user application.AuthenticatedUser
+	}
+	type want struct {
+		length int
+		// visibility is passed to the repository
+		visibility application.OpinionVisibility
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    want
+		wantErr error
+	}{
+		{
+			name: "Should throw error because repo error",
+			fields: fields{
+				repoResp:  []application.Opinion{},
+				repoError: repoError,
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 0, visibility: ownAndPublished},
+			wantErr: repoError,
+		},
+		{
+			name: "Should throw error because pep error",
+			fields: fields{
+				pepError: pepErrpr,
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 0},
+			wantErr: pepErrpr,
+		},
+		{
+			name: "Should list the published opinions of other users and the own opinions",
+			fields: fields{
+				repoResp: opinions,
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 2, visibility: ownAndPublished},
+			wantErr: nil,
+		},
+		{
+			name: "Should list all opinions to privileged users",
+			fields: fields{
+				repoResp:   opinions,
+				privileged: true,
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 2, visibility: application.AllOpinionsVisible},
+			wantErr: nil,
+		},
+		{
+			name: "Should list the opinions which are visible by the policy",
+			fields: fields{
+				repoResp: opinions[:1],
+				policy: &application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+					{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+				}},
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want: want{length: 1, visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+			}}},
+			wantErr: nil,
+		},
+		{
+			name: "Should return an empty list if the policy denies all opinions",
+			fields: fields{
+				policy:      &application.OpinionVisibility{},
+				policyError: application.AccessDeniedError,
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 0},
+			wantErr: nil,
+		},
+		{
+			name: "Should throw error because the policy returned an unknown field",
+			fields: fields{
+				policy: &application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+					{{Field: "statement", Operator: application.OperatorEqual, Value: "published"}},
+				}},
+			},
+			args: args{
+				ctx: context.Background(),
+				user: application.AuthenticatedUser{
+					Id: testUserId,
+				},
+			},
+			want:    want{length: 0},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+			idService.EXPECT().GenerateId().Return(testDefaultId).MaxTimes(1)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(tt.fields.pepError)
+
+			var privilegeError error = application.AccessDeniedError
+			if tt.fields.privileged {
+				privilegeError = nil
+			}
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest("", application.ActionViewUnpublishedOpinions)).Return(privilegeError).MaxTimes(1)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().ListOpinions(gomock.Any(), tt.want.visibility).Return(tt.fields.repoResp, tt.fields.repoError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			var options []application.Option
+			if tt.fields.policy != nil {
+				policy := mock_application.NewMockVisibilityPolicy(ctrl)
+				policy.EXPECT().OpinionVisibility(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(*tt.fields.policy, tt.fields.policyError)
+				options = append(options, application.WithVisibilityPolicy(policy))
+			}
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService, options...)
+			got, err := s.ListOpinionsQuery(tt.args.ctx, tt.args.user)
+
+			if (err != nil) && tt.wantErr == nil {
+				t.Errorf("ListOpinionsQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (err != nil) && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ListOpinionsQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && tt.wantErr != nil {
+				t.Errorf("ListOpinionsQuery() returned no error, wantErr %v", tt.wantErr)
+				return
+			}
+
+			if len(got) != tt.want.length {
+				t.Errorf("ListOpinionsQuery() returned wrong count of opinions got = %d, want %d", len(got), tt.want.length)
+				return
+			}
+
+		})
+	}
+}
+
+func TestService_ListOpinionsPageQuery(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+
+	repoError := errors.New("repo error")
+	after := application.OpinionCursor{CreatedAt: time.Now(), ID: "deleted"}
+	opinions := []application.Opinion{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	published := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+	}}
+
+	tests := []struct {
+		name        string
+		limit       int
+		policy      application.OpinionVisibility
+		repoResp    []application.Opinion
+		repoError   error
+		count       int
+		want        application.OpinionList
+		wantErr     error
+		wantRepoHit bool
+	}{
+		{
+			name:    "Should throw error because the limit is too large",
+			limit:   application.MaxOpinionPageLimit + 1,
+			policy:  published,
+			wantErr: application.NewValidationError(application.FieldError{Field: "limit", Message: "must be between 0 and 100"}),
+		},
+		{
+			name:        "Should throw error because repo error",
+			limit:       2,
+			policy:      published,
+			repoError:   repoError,
+			wantErr:     repoError,
+			wantRepoHit: true,
+		},
+		{
+			name:        "Should return the page and tell that a next page follows",
+			limit:       2,
+			policy:      published,
+			repoResp:    opinions,
+			count:       5,
+			want:        application.OpinionList{Opinions: opinions[:2], TotalCount: 5, HasNextPage: true},
+			wantRepoHit: true,
+		},
+		{
+			name:        "Should return the last page",
+			limit:       3,
+			policy:      published,
+			repoResp:    opinions,
+			count:       3,
+			want:        application.OpinionList{Opinions: opinions, TotalCount: 3},
+			wantRepoHit: true,
+		},
+		{
+			name:   "Should return an empty page if the user may not list any opinion",
+			limit:  2,
+			policy: application.OpinionVisibility{},
+			want:   application.OpinionList{Opinions: []application.Opinion{}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(nil).MaxTimes(1)
+
+			policy := mock_application.NewMockVisibilityPolicy(ctrl)
+			policy.EXPECT().OpinionVisibility(gomock.Any(), gomock.Any()).Return(tt.policy, nil).MaxTimes(1)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			if tt.wantRepoHit {
+				repo.EXPECT().ListOpinionsPage(gomock.Any(), tt.policy, application.OpinionPage{After: after, Limit: tt.limit + 1}).Return(tt.repoResp, tt.repoError)
+				repo.EXPECT().CountOpinions(gomock.Any(), tt.policy).Return(tt.count, nil).MaxTimes(1)
+			}
+
+			s := application.NewOpinionService(pep, repo, mock_application.NewMockEventPublisher(ctrl), mock_application.NewMockIdService(ctrl), mock_application.NewMockTimeService(ctrl), application.WithVisibilityPolicy(policy))
+			got, err := s.ListOpinionsPageQuery(context.Background(), application.AuthenticatedUser{Id: testUserId}, application.OpinionPage{After: after, Limit: tt.limit})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ListOpinionsPageQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListOpinionsPageQuery() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+func TestService_OpinionVisibilityQuery(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+
+	ownAndPublished := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+		{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: string(testUserId)}},
+	}}
+
+	tests := []struct {
+		name        string
+		pepError    error
+		privileged  bool
+		policyError error
+		want        application.OpinionVisibility
+		wantErr     error
+	}{
+		{
+			name: "Should return the published and the own opinions to users",
+			want: ownAndPublished,
+		},
+		{
+			name:       "Should return all opinions to moderators",
+			privileged: true,
+			want:       application.AllOpinionsVisible,
+		},
+		{
+			name:        "Should return no opinion if the policy denies the visibility",
+			policyError: application.AccessDeniedError,
+			want:        application.OpinionVisibility{},
+		},
+		{
+			name:     "Should throw error because the user may not list opinions",
+			pepError: application.AccessDeniedError,
+			wantErr:  application.AccessDeniedError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(tt.pepError)
+
+			var privilegeError error = application.AccessDeniedError
+			if tt.privileged {
+				privilegeError = nil
+			}
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionViewUnpublishedOpinions)).Return(privilegeError).MaxTimes(1)
+
+			var options []application.Option
+			if tt.policyError != nil {
+				policy := mock_application.NewMockVisibilityPolicy(ctrl)
+				policy.EXPECT().OpinionVisibility(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(application.OpinionVisibility{}, tt.policyError)
+				options = append(options, application.WithVisibilityPolicy(policy))
+			}
+
+			s := application.NewOpinionService(pep, mock_application.NewMockRepository(ctrl), mock_application.NewMockEventPublisher(ctrl),
+				mock_application.NewMockIdService(ctrl), mock_application.NewMockTimeService(ctrl), options...)
+			got, err := s.OpinionVisibilityQuery(context.Background(), application.AuthenticatedUser{Id: testUserId})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("OpinionVisibilityQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OpinionVisibilityQuery() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_CreateVoteCommand(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	pepErrpr := errors.New("pep error")
+	publisherError := errors.New("publisher error")
+	testDate := time.Now()
+
+	type fields struct {
+		status         application.OpinionStatus
+		getError       error
+		repoError      error
+		pepError       error
+		publisherError error
+	}
+	type args struct {
+		ctx  context.Context
+		user application.AuthenticatedUser
+		vote application.VoteCreateAndUpdateDTO
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    application.Vote
+		wantErr error
+	}{
+		{
+			name:   "Should throw error because empty opinion id",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true},
+			},
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name: "Should throw error because pep error",
+			fields: fields{
+				pepError: pepErrpr,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			wantErr: pepErrpr,
+		},
+		{
+			name: "Should throw error because opinion could not be loaded",
+			fields: fields{
+				getError: application.OpinionNotFoundError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			wantErr: application.OpinionNotFoundError,
+		},
+		{
+			name: "Should throw error because the pending opinion of another user is not visible",
+			fields: fields{
+				status: application.OpinionPendingReview,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			wantErr: application.OpinionNotFoundError,
+		},
+		{
+			name: "Should throw error because repo error",
+			fields: fields{
+				repoError: repoError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			wantErr: repoError,
+		},
+		{
+			name: "Should create the vote although the event could not be published",
+			fields: fields{
+				publisherError: publisherError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			want: application.Vote{
+				Agreement: true,
+				Opinion:   testOpinionId,
+				Voter:     testUserId,
+				CreatedAt: testDate,
+				UpdatedAt: testDate,
+				Version:   1,
+			},
+		},
+		{
+			name:   "Should successfully create a vote",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: true, Opinion: testOpinionId},
+			},
+			want: application.Vote{
+				Agreement: true,
+				Opinion:   testOpinionId,
+				Voter:     testUserId,
+				CreatedAt: testDate,
+				UpdatedAt: testDate,
+				Version:   1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionCreateVote)).Return(tt.fields.pepError)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(nil).MaxTimes(1)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionViewUnpublishedOpinions)).Return(application.AccessDeniedError).MaxTimes(1)
+
+			status := tt.fields.status
+			if status == "" {
+				status = application.OpinionPublished
+			}
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetOpinion(gomock.Any(), testOpinionId).Return(application.Opinion{ID: testOpinionId, Owner: "2", Status: status}, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().CreateVote(gomock.Any(), gomock.Any()).Return(tt.fields.repoError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event application.Event) error {
+				if event.Type != application.EventVoteSubmitted {
+					t.Errorf("Publish() got event type %s, want %s", event.Type, application.EventVoteSubmitted)
+				}
+				return tt.fields.publisherError
+			}).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.CreateVoteCommand(tt.args.ctx, tt.args.user, tt.args.vote)
+
+			if (err != nil) && tt.wantErr == nil {
+				t.Errorf("CreateVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (err != nil) && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateVoteCommand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_UpdateVoteCommand(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	pepErrpr := errors.New("pep error")
+	createdDate := time.Now().Add(-time.Hour)
+	testDate := time.Now()
+
+	storedVote := application.Vote{
+		Agreement: true,
+		Opinion:   testOpinionId,
+		Voter:     testUserId,
+		CreatedAt: createdDate,
+		UpdatedAt: createdDate,
+		Version:   2,
+	}
+
+	type fields struct {
+		getError    error
+		updateError error
+		pepError    error
+	}
+	type args struct {
+		ctx  context.Context
+		user application.AuthenticatedUser
+		vote application.VoteCreateAndUpdateDTO
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    application.Vote
+		wantErr error
+	}{
+		{
+			name:   "Should throw error because empty opinion id",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{},
+			},
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name: "Should throw error because pep error",
+			fields: fields{
+				pepError: pepErrpr,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Opinion: testOpinionId},
+			},
+			wantErr: pepErrpr,
+		},
+		{
+			name: "Should throw error because vote could not be loaded",
+			fields: fields{
+				getError: repoError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Opinion: testOpinionId},
+			},
+			wantErr: repoError,
+		},
+		{
+			name: "Should throw error because vote could not be updated",
+			fields: fields{
+				updateError: repoError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Opinion: testOpinionId},
+			},
+			wantErr: repoError,
+		},
+		{
+			name:   "Should throw error because the user has seen an outdated version",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Opinion: testOpinionId, Version: 1},
+			},
+			wantErr: application.VoteVersionConflictError,
+		},
+		{
+			name: "Should throw error because the vote was updated concurrently",
+			fields: fields{
+				updateError: application.VoteVersionConflictError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Opinion: testOpinionId, Version: 2},
+			},
+			wantErr: application.VoteVersionConflictError,
+		},
+		{
+			name:   "Should successfully update the agreement and keep the creation time",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				vote: application.VoteCreateAndUpdateDTO{Agreement: false, Opinion: testOpinionId},
+			},
+			want: application.Vote{
+				Agreement: false,
+				Opinion:   testOpinionId,
+				Voter:     testUserId,
+				CreatedAt: createdDate,
+				UpdatedAt: testDate,
+				Version:   3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionUpdateVote)).Return(tt.fields.pepError)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetVote(gomock.Any(), testOpinionId, testUserId).Return(storedVote, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().UpdateVote(gomock.Any(), gomock.Any()).Return(tt.fields.updateError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.UpdateVoteCommand(tt.args.ctx, tt.args.user, tt.args.vote)
+
+			if (err != nil) && tt.wantErr == nil {
+				t.Errorf("UpdateVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (err != nil) && !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateVoteCommand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_DeleteVoteCommand(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+	const testOpinionId application.OpinionId = "187"
+
+	repoError := errors.New("repo error")
+	pepErrpr := errors.New("pep error")
+	testDate := time.Now()
+
+	storedVote := application.Vote{
+		Agreement: true,
+		Opinion:   testOpinionId,
+		Voter:     testUserId,
+		CreatedAt: testDate,
+		UpdatedAt: testDate,
+	}
+
+	type fields struct {
+		getError    error
+		deleteError error
+		pepError    error
+	}
+	type args struct {
+		ctx  context.Context
+		user application.AuthenticatedUser
+		id   application.OpinionId
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    application.Vote
+		wantErr error
+	}{
+		{
+			name:   "Should throw error because empty opinion id",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+			},
+			wantErr: application.EmptyOpinionIdError,
+		},
+		{
+			name: "Should throw error because pep error",
+			fields: fields{
+				pepError: pepErrpr,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				id:   testOpinionId,
+			},
+			wantErr: pepErrpr,
+		},
+		{
+			name: "Should throw error because vote could not be loaded",
+			fields: fields{
+				getError: repoError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				id:   testOpinionId,
+			},
+			wantErr: repoError,
+		},
+		{
+			name: "Should throw error because vote could not be deleted",
+			fields: fields{
+				deleteError: repoError,
+			},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				id:   testOpinionId,
+			},
+			wantErr: repoError,
+		},
+		{
+			name:   "Should successfully delete the vote",
+			fields: fields{},
+			args: args{
+				ctx:  context.Background(),
+				user: application.AuthenticatedUser{Id: testUserId},
+				id:   testOpinionId,
+			},
+			want: storedVote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+
+			idService := mock_application.NewMockIdService(ctrl)
+
+			timeService := mock_application.NewMockTimeService(ctrl)
+			timeService.EXPECT().CurrentTime().Return(testDate).MaxTimes(1)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionDeleteVote)).Return(tt.fields.pepError)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			repo.EXPECT().GetVote(gomock.Any(), testOpinionId, testUserId).Return(storedVote, tt.fields.getError).MaxTimes(1)
+			repo.EXPECT().DeleteVote(gomock.Any(), testOpinionId, testUserId).Return(tt.fields.deleteError).MaxTimes(1)
+
+			publisher := mock_application.NewMockEventPublisher(ctrl)
+			publisher.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+
+			s := application.NewOpinionService(pep, repo, publisher, idService, timeService)
+			got, err := s.DeleteVoteCommand(tt.args.ctx, tt.args.user, tt.args.id)
+
+			if (err != nil) && tt.wantErr == nil {
+				t.Errorf("DeleteVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (err != nil) && !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeleteVoteCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeleteVoteCommand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_CountVotesQuery(t *testing.T) {
+	t.Parallel()
+	const testUserId application.UserId = "1"
+
+	repoError := errors.New("repo error")
+	pepError := errors.New("pep error")
+	counts := map[application.OpinionId]application.VoteCount{"187": {Agreements: 2, Disagreements: 1}}
+
+	ownAndPublished := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+		{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: string(testUserId)}},
+	}}
+
+	tests := []struct {
+		name       string
+		user       application.AuthenticatedUser
+		opinions   []application.OpinionId
+		pepError   error
+		privileged bool
+		repoError  error
+		// visibility is passed to the repository
+		visibility application.OpinionVisibility
+		want       map[application.OpinionId]application.VoteCount
+		wantErr    error
+	}{
+		{
+			name:     "Should throw error because the user is not authenticated",
+			opinions: []application.OpinionId{"187"},
+			wantErr:  application.UnauthenticatedError,
+		},
+		{
+			name:     "Should throw error because pep error",
+			user:     application.AuthenticatedUser{Id: testUserId},
+			opinions: []application.OpinionId{"187"},
+			pepError: pepError,
+			wantErr:  pepError,
+		},
+		{
+			name:       "Should throw error because votes could not be counted",
+			user:       application.AuthenticatedUser{Id: testUserId},
+			opinions:   []application.OpinionId{"187"},
+			repoError:  repoError,
+			visibility: ownAndPublished,
+			wantErr:    repoError,
+		},
+		{
+			name: "Should not query the repository without opinions",
+			user: application.AuthenticatedUser{Id: testUserId},
+			want: map[application.OpinionId]application.VoteCount{},
+		},
+		{
+			name:       "Should count the votes of the opinions the user may list",
+			user:       application.AuthenticatedUser{Id: testUserId},
+			opinions:   []application.OpinionId{"187", "188"},
+			visibility: ownAndPublished,
+			want:       counts,
+		},
+		{
+			name:       "Should count the votes of all opinions for privileged users",
+			user:       application.AuthenticatedUser{Id: testUserId},
+			opinions:   []application.OpinionId{"187", "188"},
+			privileged: true,
+			visibility: application.AllOpinionsVisible,
+			want:       counts,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			pep := mock_application.NewMockPolicyEnforcementPoint(ctrl)
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionListOpinions)).Return(tt.pepError).MaxTimes(1)
+			var privilegeError error = application.AccessDeniedError
+			if tt.privileged {
+				privilegeError = nil
+			}
+			pep.EXPECT().RequestAccessForUser(gomock.Any(), accessRequest(testUserId, application.ActionViewUnpublishedOpinions)).Return(privilegeError).MaxTimes(1)
+
+			repo := mock_application.NewMockRepository(ctrl)
+			if len(tt.opinions) > 0 && tt.user.Id != "" && tt.pepError == nil {
+				repo.EXPECT().CountVotes(gomock.Any(), tt.visibility, tt.opinions).Return(counts, tt.repoError)
+			}
+
+			s := application.NewOpinionService(pep, repo, mock_application.NewMockEventPublisher(ctrl), mock_application.NewMockIdService(ctrl), mock_application.NewMockTimeService(ctrl))
+			got, err := s.CountVotesQuery(context.Background(), tt.user, tt.opinions)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CountVotesQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CountVotesQuery() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// accessRequestMatcher matches an application.AccessRequest by user and action, empty fields match every value
+type accessRequestMatcher struct {
+	user   application.UserId
+	action string
+}
+
+func accessRequest(user application.UserId, action string) gomock.Matcher {
+	return accessRequestMatcher{user: user, action: action}
+}
+
+func (m accessRequestMatcher) Matches(x any) bool {
+	r, ok := x.(application.AccessRequest)
+	return ok && (m.user == "" || m.user == r.User) && (m.action == "" || m.action == r.Action)
+}
+
+func (m accessRequestMatcher) String() string {
+	return fmt.Sprintf("is access request of user %q for action %q", m.user, m.action)
+}
@@ -0,0 +1,113 @@
+package application
+
+import (
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StatementRules configure which opinion statements are accepted
+type StatementRules struct {
+	// MinLength is the minimal count of runes after trimming
+	MinLength int
+	// MaxLength is the maximal count of runes after trimming
+	MaxLength int
+	// MaxNewlines limits the count of line breaks
+	MaxNewlines int
+	// MaxURLs limits the count of links, a negative value allows any count
+	MaxURLs int
+}
+
+// DefaultStatementRules fit the varchar(255) statement column of the repositories
+func DefaultStatementRules() StatementRules {
+	return StatementRules{
+		MinLength:   3,
+		MaxLength:   255,
+		MaxNewlines: 5,
+		MaxURLs:     1,
+	}
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// FindURLs returns the links of the statement which count against the MaxURLs, e.g. for the moderation checks
+func FindURLs(statement string) []string {
+	return urlPattern.FindAllString(statement, -1)
+}
+
+// maxBytesPerRune bounds the input size before the normalization, so huge inputs are rejected cheaply.
+// It is generous because whitespace and control characters are removed afterwards.
+const maxBytesPerRune = 16
+
+// NewStatementValidator creates a validator for the given rules
+func NewStatementValidator(rules StatementRules) StatementValidator {
+	return StatementValidator{rules: rules}
+}
+
+// StatementValidator normalizes and validates opinion statements
+type StatementValidator struct {
+	rules StatementRules
+}
+
+// Normalize returns the statement in NFC without control characters and surrounding whitespace.
+// Line breaks are kept and unified to "\n", tabs are replaced by spaces.
+func (v StatementValidator) Normalize(statement string) string {
+	statement = strings.ToValidUTF8(statement, "")
+	statement = strings.ReplaceAll(statement, "\r\n", "\n")
+	statement = norm.NFC.String(statement)
+
+	statement = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, statement)
+
+	return strings.TrimSpace(statement)
+}
+
+// Validate normalizes the statement and checks it against the rules.
+// All violated rules are returned as field errors of a single validation Error.
+func (v StatementValidator) Validate(statement string) (string, error) {
+	if len(statement) > v.rules.MaxLength*maxBytesPerRune {
+		return "", NewValidationError(FieldError{Field: "statement", Message: fmt.Sprintf("must contain at most %d characters", v.rules.MaxLength)})
+	}
+
+	normalized := v.Normalize(statement)
+	if normalized == "" {
+		return "", EmptyOpinionStatementError
+	}
+
+	var fields []FieldError
+
+	length := utf8.RuneCountInString(normalized)
+	if length < v.rules.MinLength {
+		fields = append(fields, FieldError{Field: "statement", Message: fmt.Sprintf("must contain at least %d characters", v.rules.MinLength)})
+	}
+	if length > v.rules.MaxLength {
+		fields = append(fields, FieldError{Field: "statement", Message: fmt.Sprintf("must contain at most %d characters", v.rules.MaxLength)})
+	}
+
+	if newlines := strings.Count(normalized, "\n"); newlines > v.rules.MaxNewlines {
+		fields = append(fields, FieldError{Field: "statement", Message: fmt.Sprintf("must contain at most %d line breaks", v.rules.MaxNewlines)})
+	}
+
+	if v.rules.MaxURLs >= 0 {
+		if urls := len(FindURLs(normalized)); urls > v.rules.MaxURLs {
+			fields = append(fields, FieldError{Field: "statement", Message: fmt.Sprintf("must contain at most %d links", v.rules.MaxURLs)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return "", NewValidationError(fields...)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,121 @@
+package application_test
+
+import (
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestStatementValidator_Validate(t *testing.T) {
+	t.Parallel()
+	validator := application.NewStatementValidator(application.DefaultStatementRules())
+
+	tests := []struct {
+		name       string
+		statement  string
+		want       string
+		wantFields []application.FieldError
+		wantErr    error
+	}{
+		{
+			name:      "Should trim surrounding whitespace",
+			statement: "  copy and pasta is fine \n",
+			want:      "copy and pasta is fine",
+		},
+		{
+			name:      "Should unify line breaks and replace tabs",
+			statement: "copy\r\nand\tpasta",
+			want:      "copy\nand pasta",
+		},
+		{
+			name:      "Should compose to NFC",
+			statement: "café is fine",
+			want:      "café is fine",
+		},
+		{
+			name:      "Should strip control and format characters",
+			statement: "copy\u0000 and​ pasta‮",
+			want:      "copy and pasta",
+		},
+		{
+			name:      "Should drop invalid utf-8",
+			statement: "copy \xff and pasta",
+			want:      "copy  and pasta",
+		},
+		{
+			name:      "Should accept a single link",
+			statement: "read https://felixwiedmann.de",
+			want:      "read https://felixwiedmann.de",
+		},
+		{
+			name:      "Should reject statements which are empty after the normalization",
+			statement: " ​\t\r\n",
+			wantErr:   application.EmptyOpinionStatementError,
+		},
+		{
+			name:       "Should reject too short statements",
+			statement:  "ok",
+			wantFields: []application.FieldError{{Field: "statement", Message: "must contain at least 3 characters"}},
+		},
+		{
+			name:       "Should count runes instead of bytes",
+			statement:  strings.Repeat("ä", 256),
+			wantFields: []application.FieldError{{Field: "statement", Message: "must contain at most 255 characters"}},
+		},
+		{
+			name:       "Should reject huge inputs before the normalization",
+			statement:  strings.Repeat("a", 1<<20),
+			wantFields: []application.FieldError{{Field: "statement", Message: "must contain at most 255 characters"}},
+		},
+		{
+			name:      "Should report all violated rules",
+			statement: "a\n\n\n\n\n\nwww.a.de http://b.de",
+			wantFields: []application.FieldError{
+				{Field: "statement", Message: "must contain at most 5 line breaks"},
+				{Field: "statement", Message: "must contain at most 1 links"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validator.Validate(tt.statement)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			if tt.wantFields != nil {
+				var validationErr *application.Error
+				if assert.True(t, errors.As(err, &validationErr)) {
+					assert.Equal(t, application.CodeValidation, validationErr.Code)
+					assert.Equal(t, tt.wantFields, validationErr.Fields)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatementValidator_Validate_customRules(t *testing.T) {
+	t.Parallel()
+	validator := application.NewStatementValidator(application.StatementRules{MinLength: 1, MaxLength: 10, MaxNewlines: 0, MaxURLs: -1})
+
+	got, err := validator.Validate("www.a.de")
+	assert.NoError(t, err)
+	assert.Equal(t, "www.a.de", got)
+
+	_, err = validator.Validate("a\nb")
+	assert.Equal(t, application.CodeValidation, application.CodeOf(err))
+}
+
+func TestFindURLs(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"https://example.com/a", "WWW.example.org"}, application.FindURLs("see https://example.com/a and WWW.example.org"))
+	assert.Empty(t, application.FindURLs("no links, just example.com"))
+}
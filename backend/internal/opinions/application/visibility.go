@@ -0,0 +1,246 @@
+package application
+
+import (
+	"context"
+	"fmt"
+)
+
+// Fields of an opinion which can be compared by an OpinionCondition, they are named like the fields of the opinion documents of the policies
+const (
+	OpinionFieldID     = "id"
+	OpinionFieldOwner  = "ownerId"
+	OpinionFieldStatus = "status"
+)
+
+// Operators of an OpinionCondition, they are named like the Rego comparisons
+const (
+	OperatorEqual          = "eq"
+	OperatorEqualCompare   = "equal"
+	OperatorNotEqual       = "neq"
+	OperatorLess           = "lt"
+	OperatorLessOrEqual    = "lte"
+	OperatorGreater        = "gt"
+	OperatorGreaterOrEqual = "gte"
+	// OperatorIn is the Rego operator of "field in [values]", its value is a list
+	OperatorIn = "internal.member_2"
+)
+
+// OpinionCondition compares a field of an opinion with a value, it reads as "Field Operator Value".
+// The value is a string, a list of strings and booleans for OperatorIn, or null and booleans for the (in)equality.
+// The fields of an opinion are strings, so they never equal null or a boolean.
+type OpinionCondition struct {
+	Field    string
+	Operator string
+	Value    any
+}
+
+// OpinionVisibility selects the opinions a user may list.
+// The conditions of a clause are combined with AND, the clauses with OR.
+// A clause without conditions matches every opinion, a visibility without clauses matches no opinion.
+type OpinionVisibility struct {
+	Clauses [][]OpinionCondition
+}
+
+// AllOpinionsVisible matches every opinion
+var AllOpinionsVisible = OpinionVisibility{Clauses: [][]OpinionCondition{{}}}
+
+// Unrestricted reports whether the visibility matches every opinion
+func (v OpinionVisibility) Unrestricted() bool {
+	for _, clause := range v.Clauses {
+		if len(clause) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns InvalidOpinionConditionError if a condition uses an unknown field, an unknown operator or a value the operator does not accept
+func (v OpinionVisibility) Validate() error {
+	for _, clause := range v.Clauses {
+		for _, c := range clause {
+			if _, err := c.compare(""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Simplify replaces the conditions which match every or no opinion, e.g. comparisons with null, and removes the values
+// of lists which match no opinion. The result only compares fields with strings, so every repository can store it.
+// The visibility has to be valid.
+func (v OpinionVisibility) Simplify() OpinionVisibility {
+	simplified := OpinionVisibility{Clauses: make([][]OpinionCondition, 0, len(v.Clauses))}
+	for _, clause := range v.Clauses {
+		if conditions, possible := simplifyClause(clause); possible {
+			simplified.Clauses = append(simplified.Clauses, conditions)
+		}
+	}
+	return simplified
+}
+
+// simplifyClause returns the conditions which depend on the opinion, possible is false if the clause matches no opinion
+func simplifyClause(clause []OpinionCondition) (_ []OpinionCondition, possible bool) {
+	conditions := make([]OpinionCondition, 0, len(clause))
+	for _, c := range clause {
+		switch value := c.Value.(type) {
+		case string:
+			conditions = append(conditions, c)
+		case []any:
+			values := make([]any, 0, len(value))
+			for _, v := range value {
+				if _, ok := v.(string); ok {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 {
+				return nil, false
+			}
+			conditions = append(conditions, OpinionCondition{Field: c.Field, Operator: c.Operator, Value: values})
+		default:
+			// null and booleans match independent of the field
+			if matches, _ := c.compare(""); !matches {
+				return nil, false
+			}
+		}
+	}
+	return conditions, true
+}
+
+// Matches reports whether the opinion is selected by the visibility
+func (v OpinionVisibility) Matches(o Opinion) (bool, error) {
+	for _, clause := range v.Clauses {
+		matches, err := clauseMatches(clause, o)
+		if err != nil || matches {
+			return matches, err
+		}
+	}
+	return false, nil
+}
+
+// Visible is Matches for the live updates, an invalid visibility matches no opinion
+func (v OpinionVisibility) Visible(o Opinion) bool {
+	matches, err := v.Matches(o)
+	return err == nil && matches
+}
+
+// OpinionChange is the effect of an event on the opinions a subscriber with an OpinionVisibility may list
+type OpinionChange int
+
+const (
+	// OpinionUnseen events concern an opinion the subscriber may neither list before nor after the event
+	OpinionUnseen OpinionChange = iota
+	// OpinionShown events concern an opinion the subscriber may list after the event, e.g. an approved opinion
+	OpinionShown
+	// OpinionRemoved events concern an opinion the subscriber could list before but not after the event, e.g. a hidden opinion
+	OpinionRemoved
+)
+
+// ChangeOf returns how the event changes the opinions of the visibility, see Event.PreviousOpinion
+func (v OpinionVisibility) ChangeOf(event Event) OpinionChange {
+	if v.Visible(event.Opinion) {
+		return OpinionShown
+	}
+	if previous, ok := event.PreviousOpinion(); ok && v.Visible(previous) {
+		return OpinionRemoved
+	}
+	return OpinionUnseen
+}
+
+func clauseMatches(clause []OpinionCondition, o Opinion) (bool, error) {
+	for _, c := range clause {
+		matches, err := c.matches(o)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (c OpinionCondition) matches(o Opinion) (bool, error) {
+	switch c.Field {
+	case OpinionFieldID:
+		return c.compare(string(o.ID))
+	case OpinionFieldOwner:
+		return c.compare(string(o.Owner))
+	case OpinionFieldStatus:
+		return c.compare(string(o.Status))
+	default:
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("unknown field %q", c.Field))
+	}
+}
+
+// compare compares the field value with the value of the condition
+func (c OpinionCondition) compare(field string) (bool, error) {
+	switch c.Field {
+	case OpinionFieldID, OpinionFieldOwner, OpinionFieldStatus:
+	default:
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("unknown field %q", c.Field))
+	}
+
+	switch c.Operator {
+	case OperatorIn:
+		return c.member(field)
+	case OperatorEqual, OperatorEqualCompare:
+		return c.equal(field)
+	case OperatorNotEqual:
+		equal, err := c.equal(field)
+		return !equal, err
+	}
+
+	value, ok := c.Value.(string)
+	if !ok {
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("value %v of field %s is not a string", c.Value, c.Field))
+	}
+
+	switch c.Operator {
+	case OperatorLess:
+		return field < value, nil
+	case OperatorLessOrEqual:
+		return field <= value, nil
+	case OperatorGreater:
+		return field > value, nil
+	case OperatorGreaterOrEqual:
+		return field >= value, nil
+	default:
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("unknown operator %q", c.Operator))
+	}
+}
+
+// equal compares the field with a string, null or a boolean, the fields are never null or a boolean
+func (c OpinionCondition) equal(field string) (bool, error) {
+	switch value := c.Value.(type) {
+	case string:
+		return field == value, nil
+	case nil, bool:
+		return false, nil
+	default:
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("value %v of field %s is not a string, null or a boolean", c.Value, c.Field))
+	}
+}
+
+// member reports whether the field is one of the strings of the list, the list must not contain null like in SQL
+func (c OpinionCondition) member(field string) (bool, error) {
+	list, ok := c.Value.([]any)
+	if !ok {
+		return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("value %v of field %s is not a list", c.Value, c.Field))
+	}
+
+	member := false
+	for _, v := range list {
+		switch v := v.(type) {
+		case string:
+			member = member || v == field
+		case bool:
+		default:
+			return false, InvalidOpinionConditionError.WithCause(fmt.Errorf("list value %v of field %s is not a string or a boolean", v, c.Field))
+		}
+	}
+	return member, nil
+}
+
+// VisibilityPolicy decides which opinions the user of the request may list.
+// It returns AccessDeniedError if the user may not see any opinion.
+type VisibilityPolicy interface {
+	OpinionVisibility(ctx context.Context, request AccessRequest) (OpinionVisibility, error)
+}
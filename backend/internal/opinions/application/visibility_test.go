@@ -0,0 +1,250 @@
+package application_test
+
+import (
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOpinionVisibility_Matches(t *testing.T) {
+	t.Parallel()
+	opinion := application.Opinion{ID: "2", Owner: "123", Status: application.OpinionPendingReview}
+
+	tests := []struct {
+		name       string
+		visibility application.OpinionVisibility
+		want       bool
+		wantErr    error
+	}{
+		{
+			name:       "Should match every opinion without conditions",
+			visibility: application.AllOpinionsVisible,
+			want:       true,
+		},
+		{
+			name:       "Should match no opinion without clauses",
+			visibility: application.OpinionVisibility{},
+			want:       false,
+		},
+		{
+			name: "Should match if one clause matches",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+				{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: "123"}},
+			}},
+			want: true,
+		},
+		{
+			name: "Should only match if all conditions of a clause match",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{
+				{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: "123"},
+				{Field: application.OpinionFieldID, Operator: application.OperatorGreater, Value: "2"},
+			}}},
+			want: false,
+		},
+		{
+			name: "Should reject unknown fields",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: "statement", Operator: application.OperatorEqual, Value: "123"}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+		{
+			name: "Should reject unknown operators",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldOwner, Operator: "startswith", Value: "1"}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+		{
+			name: "Should reject values which are not strings",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: 123.0}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+		{
+			name: "Should match if the field is in the list",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published", true, "pending_review"}}},
+			}},
+			want: true,
+		},
+		{
+			name: "Should not match if the field is not in the list",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published"}}},
+			}},
+			want: false,
+		},
+		{
+			name: "Should reject lists with null",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published", nil}}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+		{
+			name: "Should reject membership tests without a list",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: "published"}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+		{
+			name: "Should never match null because the fields are set",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqualCompare, Value: nil}},
+			}},
+			want: false,
+		},
+		{
+			name: "Should match the comparison with a boolean as inequality",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{
+				{Field: application.OpinionFieldOwner, Operator: application.OperatorNotEqual, Value: nil},
+				{Field: application.OpinionFieldStatus, Operator: application.OperatorNotEqual, Value: true},
+			}}},
+			want: true,
+		},
+		{
+			name: "Should reject ordering comparisons with null",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldOwner, Operator: application.OperatorLess, Value: nil}},
+			}},
+			wantErr: application.InvalidOpinionConditionError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.visibility.Matches(opinion)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, tt.visibility.Validate(), tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NoError(t, tt.visibility.Validate())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOpinionVisibility_Simplify(t *testing.T) {
+	t.Parallel()
+	published := application.OpinionCondition{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}
+
+	tests := []struct {
+		name       string
+		visibility application.OpinionVisibility
+		want       application.OpinionVisibility
+	}{
+		{
+			name: "Should keep the comparisons with strings",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{published},
+			}},
+			want: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{published},
+			}},
+		},
+		{
+			name: "Should remove the conditions which match every opinion",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{
+				published,
+				{Field: application.OpinionFieldOwner, Operator: application.OperatorNotEqual, Value: nil},
+				{Field: application.OpinionFieldOwner, Operator: application.OperatorNotEqual, Value: false},
+			}}},
+			want: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{published},
+			}},
+		},
+		{
+			name: "Should remove the clauses which match no opinion",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{published, {Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: nil}},
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{true, false}}},
+			}},
+			want: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{}},
+		},
+		{
+			name: "Should remove the booleans of lists",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published", true}}},
+			}},
+			want: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published"}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.visibility.Simplify())
+		})
+	}
+}
+
+func TestOpinionVisibility_ChangeOf(t *testing.T) {
+	t.Parallel()
+	published := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: string(application.OpinionPublished)}},
+	}}
+	opinion := func(status application.OpinionStatus) application.Opinion {
+		return application.Opinion{ID: "2", Owner: "123", Status: status}
+	}
+
+	tests := []struct {
+		name       string
+		visibility application.OpinionVisibility
+		event      application.Event
+		want       application.OpinionChange
+	}{
+		{
+			name:       "Should show created opinions which match",
+			visibility: published,
+			event:      application.Event{Type: application.EventOpinionCreated, Opinion: opinion(application.OpinionPublished)},
+			want:       application.OpinionShown,
+		},
+		{
+			name:       "Should show approved opinions",
+			visibility: published,
+			event:      application.Event{Type: application.EventOpinionApproved, Opinion: opinion(application.OpinionPublished), PreviousStatus: application.OpinionPendingReview},
+			want:       application.OpinionShown,
+		},
+		{
+			name:       "Should remove hidden opinions which matched before",
+			visibility: published,
+			event:      application.Event{Type: application.EventOpinionHidden, Opinion: opinion(application.OpinionHidden), PreviousStatus: application.OpinionPublished},
+			want:       application.OpinionRemoved,
+		},
+		{
+			name:       "Should not remove opinions which did not match before",
+			visibility: published,
+			event:      application.Event{Type: application.EventOpinionRejected, Opinion: opinion(application.OpinionRejected), PreviousStatus: application.OpinionPendingReview},
+			want:       application.OpinionUnseen,
+		},
+		{
+			name:       "Should keep showing hidden opinions to unrestricted visibilities",
+			visibility: application.AllOpinionsVisible,
+			event:      application.Event{Type: application.EventOpinionHidden, Opinion: opinion(application.OpinionHidden), PreviousStatus: application.OpinionPublished},
+			want:       application.OpinionShown,
+		},
+		{
+			name:       "Should show nothing to invalid visibilities",
+			visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{{Field: "statement", Operator: application.OperatorEqual, Value: "x"}}}},
+			event:      application.Event{Type: application.EventOpinionCreated, Opinion: opinion(application.OpinionPublished)},
+			want:       application.OpinionUnseen,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.visibility.ChangeOf(tt.event))
+		})
+	}
+}
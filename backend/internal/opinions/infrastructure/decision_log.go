@@ -0,0 +1,247 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// decisionRecord is the serialized form of an application.Decision
+type decisionRecord struct {
+	Time           time.Time `json:"time"`
+	User           string    `json:"user"`
+	Roles          []string  `json:"roles"`
+	Action         string    `json:"action"`
+	Opinion        string    `json:"opinion,omitempty"`
+	Owner          string    `json:"owner,omitempty"`
+	InputHash      string    `json:"inputHash"`
+	Allowed        bool      `json:"allowed"`
+	Reason         string    `json:"reason,omitempty"`
+	PolicyRevision string    `json:"policyRevision,omitempty"`
+	LatencyNs      int64     `json:"latencyNs"`
+}
+
+// encodeDecision returns the decision as a single JSON line
+func encodeDecision(d application.Decision) ([]byte, error) {
+	roles := make([]string, 0, len(d.Roles))
+	for _, r := range d.Roles {
+		roles = append(roles, string(r))
+	}
+
+	line, err := json.Marshal(decisionRecord{
+		Time:           d.Time,
+		User:           string(d.User),
+		Roles:          roles,
+		Action:         d.Action,
+		Opinion:        string(d.Opinion),
+		Owner:          string(d.Owner),
+		InputHash:      d.InputHash,
+		Allowed:        d.Allowed,
+		Reason:         d.Reason,
+		PolicyRevision: d.PolicyRevision,
+		LatencyNs:      d.Latency.Nanoseconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// NewDecisionLoggerJSON writes every decision as a JSON line to w, e.g. to os.Stdout
+func NewDecisionLoggerJSON(w io.Writer) *DecisionLoggerJSON {
+	return &DecisionLoggerJSON{
+		w: w,
+	}
+}
+
+type DecisionLoggerJSON struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *DecisionLoggerJSON) LogDecision(_ context.Context, decision application.Decision) error {
+	line, err := encodeDecision(decision)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.w.Write(line)
+	return err
+}
+
+// NewDecisionLoggerFile appends every decision as a JSON line to the file at path.
+// The file is rotated once it would exceed maxBytes, the newest maxBackups rotated files are kept as path.1, path.2 and so on.
+func NewDecisionLoggerFile(path string, maxBytes int64, maxBackups int) (*DecisionLoggerFile, error) {
+	l := &DecisionLoggerFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+type DecisionLoggerFile struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func (l *DecisionLoggerFile) LogDecision(_ context.Context, decision application.Decision) error {
+	line, err := encodeDecision(decision)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the current file
+func (l *DecisionLoggerFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *DecisionLoggerFile) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups by one, the oldest backup is overwritten, and starts a new file.
+// If the rotation fails, the current file is opened again and the next decision retries the rotation.
+func (l *DecisionLoggerFile) rotate() error {
+	err := l.file.Close()
+	if err == nil {
+		err = l.shiftBackups()
+	}
+
+	if openErr := l.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// shiftBackups renames the current file to the first backup, without backups it is removed
+func (l *DecisionLoggerFile) shiftBackups() error {
+	if l.maxBackups <= 0 {
+		return os.Remove(l.path)
+	}
+
+	for i := l.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.path, l.path+".1")
+}
+
+// DefaultDecisionLogMemorySize is the count of decisions the DecisionLogMemory keeps by default
+const DefaultDecisionLogMemorySize = 10000
+
+// NewDecisionLogMemory keeps the newest size decisions until restart, it is used together with the memory repository.
+// A size below 1 keeps the newest decision.
+func NewDecisionLogMemory(size int) *DecisionLogMemory {
+	if size < 1 {
+		size = 1
+	}
+	return &DecisionLogMemory{size: size}
+}
+
+type DecisionLogMemory struct {
+	mu   sync.RWMutex
+	size int
+	// decisions is a ring buffer once it holds size decisions, next is the index of the oldest decision which is overwritten next
+	decisions []application.Decision
+	next      int
+}
+
+func (l *DecisionLogMemory) LogDecision(ctx context.Context, decision application.Decision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.decisions) < l.size {
+		l.decisions = append(l.decisions, decision)
+		return nil
+	}
+	l.decisions[l.next] = decision
+	l.next = (l.next + 1) % l.size
+	return nil
+}
+
+// ListDecisions returns the matching decisions in the reverse order they were logged
+func (l *DecisionLogMemory) ListDecisions(ctx context.Context, filter application.DecisionFilter) ([]application.Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	decisions := make([]application.Decision, 0)
+	for i := 0; i < len(l.decisions) && len(decisions) < filter.Limit; i++ {
+		// the newest decision is logged before next
+		d := l.decisions[(l.next-1-i+len(l.decisions))%len(l.decisions)]
+		if filter.Matches(d) {
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions, nil
+}
+
+// DecisionLoggers passes every decision to all loggers, e.g. to stdout and a queryable DecisionLog
+type DecisionLoggers []application.DecisionLogger
+
+// LogDecision returns the first error after every logger was called
+func (l DecisionLoggers) LogDecision(ctx context.Context, decision application.Decision) error {
+	var first error
+	for _, logger := range l {
+		if err := logger.LogDecision(ctx, decision); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,101 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	_ "github.com/mattn/go-sqlite3"
+	"math"
+	"time"
+)
+
+// NewDecisionLogSQLite opens the audit trail of the policy decisions
+func NewDecisionLogSQLite(dbLocation string) (*DecisionLogSQLite, error) {
+	db, err := sql.Open("sqlite3", dbLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	// time is stored as unix nanoseconds because it is compared in queries
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS decisions ( sequence INTEGER PRIMARY KEY AUTOINCREMENT, time bigint NOT NULL, userId varchar(255) NOT NULL, roles TEXT NOT NULL, action varchar(255) NOT NULL, opinionId varchar(255) NOT NULL, ownerId varchar(255) NOT NULL, inputHash varchar(64) NOT NULL, allowed boolean NOT NULL, reason TEXT NOT NULL, policyRevision varchar(255) NOT NULL, latency bigint NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS decisions_user on decisions (userId, time)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecisionLogSQLite{
+		db: db,
+	}, nil
+}
+
+type DecisionLogSQLite struct {
+	db *sql.DB
+}
+
+func (l *DecisionLogSQLite) LogDecision(ctx context.Context, decision application.Decision) error {
+	roles, err := json.Marshal(decision.Roles)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.db.ExecContext(ctx, "INSERT INTO decisions (time, userId, roles, action, opinionId, ownerId, inputHash, allowed, reason, policyRevision, latency) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		decision.Time.UnixNano(), decision.User, string(roles), decision.Action, decision.Opinion, decision.Owner, decision.InputHash, decision.Allowed, decision.Reason, decision.PolicyRevision, decision.Latency.Nanoseconds())
+	return err
+}
+
+// ListDecisions returns the matching decisions in the reverse order they were logged
+func (l *DecisionLogSQLite) ListDecisions(ctx context.Context, filter application.DecisionFilter) ([]application.Decision, error) {
+	from := int64(math.MinInt64)
+	if !filter.From.IsZero() {
+		from = filter.From.UnixNano()
+	}
+
+	before := int64(math.MaxInt64)
+	if !filter.Before.IsZero() {
+		before = filter.Before.UnixNano()
+	}
+
+	allowed := false
+	if filter.Allowed != nil {
+		allowed = *filter.Allowed
+	}
+
+	rows, err := l.db.QueryContext(ctx, "SELECT time, userId, roles, action, opinionId, ownerId, inputHash, allowed, reason, policyRevision, latency FROM decisions WHERE (? = '' OR userId = ?) AND (? = '' OR action = ?) AND (? OR allowed = ?) AND time >= ? AND time < ? ORDER BY sequence DESC LIMIT ?",
+		filter.User, filter.User, filter.Action, filter.Action, filter.Allowed == nil, allowed, from, before, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := make([]application.Decision, 0)
+
+	for rows.Next() {
+		var d application.Decision
+		var decided int64
+		var roles string
+		var latency int64
+
+		if err := rows.Scan(&decided, &d.User, &roles, &d.Action, &d.Opinion, &d.Owner, &d.InputHash, &d.Allowed, &d.Reason, &d.PolicyRevision, &latency); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(roles), &d.Roles); err != nil {
+			return nil, err
+		}
+
+		d.Time = time.Unix(0, decided).UTC()
+		d.Latency = time.Duration(latency)
+		decisions = append(decisions, d)
+	}
+
+	return decisions, rows.Err()
+}
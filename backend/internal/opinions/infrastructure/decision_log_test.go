@@ -0,0 +1,265 @@
+package infrastructure_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecisionLogs_ListDecisions(t *testing.T) {
+	t.Parallel()
+
+	logs := map[string]func(t *testing.T) application.DecisionLog{
+		"memory": func(t *testing.T) application.DecisionLog {
+			return infrastructure.NewDecisionLogMemory(infrastructure.DefaultDecisionLogMemorySize)
+		},
+		"sqlite": func(t *testing.T) application.DecisionLog {
+			log, err := infrastructure.NewDecisionLogSQLite(fmt.Sprintf("%s/%s", t.TempDir(), "decisions.db"))
+			if err != nil {
+				t.Fatalf("NewDecisionLogSQLite() returned error %s, but no error is expected", err)
+			}
+			return log
+		},
+	}
+
+	testTime := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	allowed := application.Decision{
+		Time:           testTime,
+		User:           "123",
+		Roles:          []application.Role{application.RoleUser},
+		Action:         application.ActionDeleteOpinion,
+		Opinion:        "1",
+		Owner:          "123",
+		InputHash:      "abc",
+		Allowed:        true,
+		PolicyRevision: "rev",
+		Latency:        1500 * time.Microsecond,
+	}
+	denied := application.Decision{
+		Time:      testTime.Add(time.Minute),
+		User:      "456",
+		Roles:     []application.Role{application.RoleUser},
+		Action:    application.ActionDeleteOpinion,
+		Opinion:   "1",
+		Owner:     "123",
+		InputHash: "def",
+		Reason:    "access denied",
+	}
+	listed := application.Decision{
+		Time:      testTime.Add(2 * time.Minute),
+		User:      "123",
+		Roles:     []application.Role{application.RoleUser},
+		Action:    application.ActionListOpinions,
+		InputHash: "ghi",
+		Allowed:   true,
+	}
+
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		filter application.DecisionFilter
+		want   []application.Decision
+	}{
+		{
+			name:   "Should list all decisions newest first",
+			filter: application.DecisionFilter{Limit: 10},
+			want:   []application.Decision{listed, denied, allowed},
+		},
+		{
+			name:   "Should limit the decisions",
+			filter: application.DecisionFilter{Limit: 1},
+			want:   []application.Decision{listed},
+		},
+		{
+			name:   "Should filter by user",
+			filter: application.DecisionFilter{User: "123", Limit: 10},
+			want:   []application.Decision{listed, allowed},
+		},
+		{
+			name:   "Should filter by action",
+			filter: application.DecisionFilter{Action: application.ActionDeleteOpinion, Limit: 10},
+			want:   []application.Decision{denied, allowed},
+		},
+		{
+			name:   "Should filter denied decisions",
+			filter: application.DecisionFilter{Allowed: &no, Limit: 10},
+			want:   []application.Decision{denied},
+		},
+		{
+			name:   "Should filter allowed decisions",
+			filter: application.DecisionFilter{Allowed: &yes, Limit: 10},
+			want:   []application.Decision{listed, allowed},
+		},
+		{
+			name:   "Should filter by time range",
+			filter: application.DecisionFilter{From: testTime.Add(time.Minute), Before: testTime.Add(2 * time.Minute), Limit: 10},
+			want:   []application.Decision{denied},
+		},
+	}
+
+	for name, newLog := range logs {
+		newLog := newLog
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			log := newLog(t)
+			for _, d := range []application.Decision{allowed, denied, listed} {
+				if err := log.LogDecision(context.Background(), d); err != nil {
+					t.Fatalf("LogDecision() returned error %s, but no error is expected", err)
+				}
+			}
+
+			for _, tt := range tests {
+				tt := tt
+				t.Run(tt.name, func(t *testing.T) {
+					got, err := log.ListDecisions(context.Background(), tt.filter)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.want, got)
+				})
+			}
+		})
+	}
+}
+
+func TestDecisionLoggerJSON_LogDecision(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	logger := infrastructure.NewDecisionLoggerJSON(&out)
+
+	decision := application.Decision{
+		Time:      time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+		User:      "123",
+		Roles:     []application.Role{application.RoleUser},
+		Action:    application.ActionDeleteOpinion,
+		Owner:     "456",
+		InputHash: "abc",
+		Reason:    "access denied",
+		Latency:   time.Millisecond,
+	}
+	assert.NoError(t, logger.LogDecision(context.Background(), decision))
+	assert.NoError(t, logger.LogDecision(context.Background(), decision))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, map[string]any{
+		"time":      "2022-06-01T12:00:00Z",
+		"user":      "123",
+		"roles":     []any{"user"},
+		"action":    "DeleteOpinion",
+		"owner":     "456",
+		"inputHash": "abc",
+		"allowed":   false,
+		"reason":    "access denied",
+		"latencyNs": float64(time.Millisecond),
+	}, got)
+}
+
+func TestDecisionLoggerFile_rotation(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	decision := application.Decision{User: "123", Action: application.ActionCreateOpinion, Allowed: true}
+
+	// every file holds two decisions
+	logger, err := infrastructure.NewDecisionLoggerFile(path, 300, 2)
+	if err != nil {
+		t.Fatalf("NewDecisionLoggerFile() returned error %s, but no error is expected", err)
+	}
+
+	for i := 0; i < 7; i++ {
+		decision.InputHash = fmt.Sprint(i)
+		assert.NoError(t, logger.LogDecision(context.Background(), decision))
+	}
+	assert.NoError(t, logger.Close())
+
+	hashes := func(name string) []string {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("%s could not be read: %s", name, err)
+		}
+
+		var got []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			var record struct {
+				InputHash string `json:"inputHash"`
+			}
+			assert.NoError(t, json.Unmarshal([]byte(line), &record))
+			got = append(got, record.InputHash)
+		}
+		return got
+	}
+
+	assert.Equal(t, []string{"6"}, hashes(path))
+	assert.Equal(t, []string{"4", "5"}, hashes(path+".1"))
+	assert.Equal(t, []string{"2", "3"}, hashes(path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestDecisionLoggerFile_failedRotation(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	decision := application.Decision{User: "123", Action: application.ActionCreateOpinion, Allowed: true}
+
+	logger, err := infrastructure.NewDecisionLoggerFile(path, 300, 1)
+	if err != nil {
+		t.Fatalf("NewDecisionLoggerFile() returned error %s, but no error is expected", err)
+	}
+
+	// the current file can not be renamed to a non-empty directory
+	assert.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o755))
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, logger.LogDecision(context.Background(), decision))
+	}
+	assert.Error(t, logger.LogDecision(context.Background(), decision))
+
+	assert.NoError(t, os.RemoveAll(path+".1"))
+	assert.NoError(t, logger.LogDecision(context.Background(), decision))
+	assert.NoError(t, logger.Close())
+
+	rotated, err := os.ReadFile(path + ".1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(rotated, []byte("\n")))
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(current, []byte("\n")))
+}
+
+func TestDecisionLogMemory_size(t *testing.T) {
+	t.Parallel()
+	log := infrastructure.NewDecisionLogMemory(3)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, log.LogDecision(context.Background(), application.Decision{InputHash: fmt.Sprint(i)}))
+	}
+
+	got, err := log.ListDecisions(context.Background(), application.DecisionFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []application.Decision{{InputHash: "4"}, {InputHash: "3"}, {InputHash: "2"}}, got)
+
+	got, err = log.ListDecisions(context.Background(), application.DecisionFilter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []application.Decision{{InputHash: "4"}, {InputHash: "3"}}, got)
+}
+
+func TestDecisionLoggers_LogDecision(t *testing.T) {
+	t.Parallel()
+	first := infrastructure.NewDecisionLogMemory(infrastructure.DefaultDecisionLogMemorySize)
+	second := infrastructure.NewDecisionLogMemory(infrastructure.DefaultDecisionLogMemorySize)
+	loggers := infrastructure.DecisionLoggers{first, second}
+	assert.NoError(t, loggers.LogDecision(context.Background(), application.Decision{User: "123"}))
+
+	for _, log := range []*infrastructure.DecisionLogMemory{first, second} {
+		got, err := log.ListDecisions(context.Background(), application.DecisionFilter{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []application.Decision{{User: "123"}}, got)
+	}
+}
@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	postgresUniqueViolation     = "23505"
+	postgresForeignKeyViolation = "23503"
+)
+
+// mapError wraps all failures which are not an application.Error yet as internal error.
+// It is deferred by the repositories with a pointer to the named error result.
+func mapError(err *error) {
+	*err = application.InternalError(*err)
+}
+
+// mapSQLiteError translates constraint violations of SQLite into application errors
+func mapSQLiteError(err *error) {
+	var sqliteErr sqlite3.Error
+	if errors.As(*err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintUnique:
+			*err = application.NewError(application.CodeConflict, "already exists", *err)
+			return
+		case sqlite3.ErrConstraintForeignKey:
+			*err = application.OpinionNotFoundError.WithCause(*err)
+			return
+		}
+	}
+	mapError(err)
+}
+
+// mapPostgresError translates constraint violations of PostgreSQL into application errors
+func mapPostgresError(err *error) {
+	var pgErr *pgconn.PgError
+	if errors.As(*err, &pgErr) {
+		switch pgErr.Code {
+		case postgresUniqueViolation:
+			*err = application.NewError(application.CodeConflict, "already exists", *err)
+			return
+		case postgresForeignKeyViolation:
+			*err = application.OpinionNotFoundError.WithCause(*err)
+			return
+		}
+	}
+	mapError(err)
+}
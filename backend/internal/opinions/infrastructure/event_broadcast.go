@@ -0,0 +1,58 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+)
+
+// NewEventBroadcast creates an application.EventHandler which passes every event to the subscribed channels, e.g. of streaming clients.
+// Subscribers never block the publisher: a subscriber whose buffer is full is removed and its channel is closed.
+func NewEventBroadcast() *EventBroadcast {
+	return &EventBroadcast{
+		subscribers: make(map[chan application.Event]struct{}),
+	}
+}
+
+type EventBroadcast struct {
+	mu          sync.Mutex
+	subscribers map[chan application.Event]struct{}
+}
+
+// Subscribe returns a channel with the buffer size which receives the events handled after the call.
+// The channel is closed by cancel or if the subscriber fell behind by more than the buffer.
+func (b *EventBroadcast) Subscribe(buffer int) (events <-chan application.Event, cancel func()) {
+	ch := make(chan application.Event, buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(ch)
+	}
+}
+
+func (b *EventBroadcast) HandleEvent(_ context.Context, event application.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.remove(ch)
+		}
+	}
+	return nil
+}
+
+// remove closes the channel once, b.mu must be held
+func (b *EventBroadcast) remove(ch chan application.Event) {
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
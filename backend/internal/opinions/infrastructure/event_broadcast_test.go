@@ -0,0 +1,35 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEventBroadcast_HandleEvent(t *testing.T) {
+	t.Parallel()
+	broadcast := infrastructure.NewEventBroadcast()
+
+	fast, cancelFast := broadcast.Subscribe(2)
+	defer cancelFast()
+	slow, cancelSlow := broadcast.Subscribe(1)
+	defer cancelSlow()
+
+	for sequence := uint64(1); sequence <= 2; sequence++ {
+		assert.NoError(t, broadcast.HandleEvent(context.Background(), application.Event{Sequence: sequence}))
+	}
+
+	assert.Equal(t, uint64(1), (<-fast).Sequence)
+	assert.Equal(t, uint64(2), (<-fast).Sequence)
+
+	assert.Equal(t, uint64(1), (<-slow).Sequence)
+	_, open := <-slow
+	assert.False(t, open, "the subscriber which fell behind is removed")
+
+	cancelFast()
+	_, open = <-fast
+	assert.False(t, open)
+	assert.NoError(t, broadcast.HandleEvent(context.Background(), application.Event{Sequence: 3}), "canceled subscribers are not sent to")
+}
@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+)
+
+// EventStore persists events before they are dispatched
+type EventStore interface {
+	Append(ctx context.Context, event application.Event) (application.Event, error)
+}
+
+// HandlerErrorFunc is called when a subscriber could not handle an event
+type HandlerErrorFunc func(event application.Event, err error)
+
+// NewEventBus creates an in process application.EventPublisher.
+// Every published event gets appended to the store first, so the sequence is known by all subscribers.
+// Failing subscribers are reported to onHandlerError, because the command side already succeeded at that point
+// and read models can be rebuilt from the store.
+func NewEventBus(store EventStore, onHandlerError HandlerErrorFunc) *EventBus {
+	if onHandlerError == nil {
+		onHandlerError = func(application.Event, error) {}
+	}
+	return &EventBus{
+		store:          store,
+		onHandlerError: onHandlerError,
+	}
+}
+
+type EventBus struct {
+	store          EventStore
+	onHandlerError HandlerErrorFunc
+	mu             sync.RWMutex
+	handlers       []application.EventHandler
+}
+
+// Subscribe registers a handler which receives all events published after the registration
+func (b *EventBus) Subscribe(handler application.EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish appends the event to the store and dispatches it synchronously to all subscribers.
+// Only a failing append is returned to the caller.
+func (b *EventBus) Publish(ctx context.Context, event application.Event) error {
+	recorded, err := b.store.Append(ctx, event)
+	if err != nil {
+		return application.InternalError(err)
+	}
+
+	b.mu.RLock()
+	handlers := make([]application.EventHandler, len(b.handlers))
+	copy(handlers, b.handlers)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler.HandleEvent(ctx, recorded); err != nil {
+			b.onHandlerError(recorded, fmt.Errorf("event %d of type %s could not be handled: %w", recorded.Sequence, recorded.Type, err))
+		}
+	}
+	return nil
+}
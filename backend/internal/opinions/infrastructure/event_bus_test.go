@@ -0,0 +1,73 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type sequenceStore struct {
+	sequence uint64
+	err      error
+}
+
+func (s *sequenceStore) Append(_ context.Context, event application.Event) (application.Event, error) {
+	if s.err != nil {
+		return application.Event{}, s.err
+	}
+	s.sequence++
+	event.Sequence = s.sequence
+	return event, nil
+}
+
+type handlerFunc func(ctx context.Context, event application.Event) error
+
+func (h handlerFunc) HandleEvent(ctx context.Context, event application.Event) error {
+	return h(ctx, event)
+}
+
+func TestEventBus_Publish(t *testing.T) {
+	t.Parallel()
+	handlerError := errors.New("handler error")
+
+	var reported []error
+	bus := infrastructure.NewEventBus(&sequenceStore{}, func(_ application.Event, err error) {
+		reported = append(reported, err)
+	})
+
+	var received []application.Event
+	bus.Subscribe(handlerFunc(func(_ context.Context, _ application.Event) error {
+		return handlerError
+	}))
+	bus.Subscribe(handlerFunc(func(_ context.Context, event application.Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	if err := bus.Publish(context.Background(), application.Event{Type: application.EventOpinionCreated}); err != nil {
+		t.Errorf("Publish() retunred error %s, but no error is expected", err)
+	}
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, uint64(1), received[0].Sequence)
+	assert.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0], handlerError)
+}
+
+func TestEventBus_Publish_store_error(t *testing.T) {
+	t.Parallel()
+	storeError := errors.New("store error")
+
+	bus := infrastructure.NewEventBus(&sequenceStore{err: storeError}, nil)
+	bus.Subscribe(handlerFunc(func(_ context.Context, _ application.Event) error {
+		t.Errorf("HandleEvent() should not be called if the event could not be stored")
+		return nil
+	}))
+
+	if err := bus.Publish(context.Background(), application.Event{Type: application.EventOpinionCreated}); !errors.Is(err, storeError) {
+		t.Errorf("Publish() error = %v, wantErr %v", err, storeError)
+	}
+}
@@ -0,0 +1,47 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+)
+
+// NewEventLogMemory creates an event log which is lost on restart, it is used together with the memory repository
+func NewEventLogMemory() *EventLogMemory {
+	return &EventLogMemory{}
+}
+
+type EventLogMemory struct {
+	mu     sync.RWMutex
+	events []application.Event
+}
+
+// Append stores the event and returns it with the assigned sequence number
+func (e *EventLogMemory) Append(ctx context.Context, event application.Event) (application.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return application.Event{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	event.Sequence = uint64(len(e.events) + 1)
+	e.events = append(e.events, event)
+	return event, nil
+}
+
+// Load returns all events with a sequence greater than afterSequence in the order they were appended
+func (e *EventLogMemory) Load(ctx context.Context, afterSequence uint64) ([]application.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	events := make([]application.Event, 0)
+	if afterSequence < uint64(len(e.events)) {
+		events = append(events, e.events[afterSequence:]...)
+	}
+	return events, nil
+}
@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// NewEventLogPostgres opens the append only event log in the database of the postgres repository
+func NewEventLogPostgres(ctx context.Context, connString string) (*EventLogPostgres, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	// the lock of the migrations also serializes the creation of the table by concurrently starting instances
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockId); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "CREATE TABLE IF NOT EXISTS events ( sequence bigserial PRIMARY KEY, type varchar(255) NOT NULL, occurredAt varchar(255) NOT NULL, payload text NOT NULL)")
+		return err
+	})
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &EventLogPostgres{
+		pool: pool,
+	}, nil
+}
+
+type EventLogPostgres struct {
+	pool *pgxpool.Pool
+}
+
+// Close releases all pooled connections
+func (e *EventLogPostgres) Close() {
+	e.pool.Close()
+}
+
+// Append stores the event and returns it with the assigned sequence number
+func (e *EventLogPostgres) Append(ctx context.Context, event application.Event) (application.Event, error) {
+	payload, err := json.Marshal(eventPayload{
+		Opinion:        event.Opinion,
+		Vote:           event.Vote,
+		Report:         event.Report,
+		PreviousStatus: event.PreviousStatus,
+	})
+	if err != nil {
+		return application.Event{}, err
+	}
+
+	var sequence int64
+	err = e.pool.QueryRow(ctx, "INSERT INTO events (type, occurredAt, payload) VALUES ($1, $2, $3) RETURNING sequence", event.Type, event.OccurredAt.Format(time.RFC3339Nano), string(payload)).Scan(&sequence)
+	if err != nil {
+		return application.Event{}, err
+	}
+
+	event.Sequence = uint64(sequence)
+	return event, nil
+}
+
+// Load returns all events with a sequence greater than afterSequence in the order they were appended
+func (e *EventLogPostgres) Load(ctx context.Context, afterSequence uint64) ([]application.Event, error) {
+	rows, err := e.pool.Query(ctx, "SELECT sequence, type, occurredAt, payload FROM events WHERE sequence > $1 ORDER BY sequence", int64(afterSequence))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]application.Event, 0)
+
+	for rows.Next() {
+		var sequence int64
+		var eventType application.EventType
+		var date string
+		var rawPayload string
+
+		if err := rows.Scan(&sequence, &eventType, &date, &rawPayload); err != nil {
+			return nil, err
+		}
+
+		occurredAt, err := time.Parse(time.RFC3339Nano, date)
+		if err != nil {
+			return nil, err
+		}
+
+		var payload eventPayload
+		if err := json.Unmarshal([]byte(rawPayload), &payload); err != nil {
+			return nil, err
+		}
+
+		events = append(events, application.Event{
+			Sequence:       uint64(sequence),
+			Type:           eventType,
+			OccurredAt:     occurredAt,
+			Opinion:        payload.Opinion,
+			Vote:           payload.Vote,
+			Report:         payload.Report,
+			PreviousStatus: payload.PreviousStatus,
+		})
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	_ "github.com/mattn/go-sqlite3"
+	"time"
+)
+
+// NewEventLogSQLite opens the append only event log which is the source for rebuilding projections
+func NewEventLogSQLite(dbLocation string) (*EventLogSQLite, error) {
+	db, err := sql.Open("sqlite3", dbLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS events ( sequence INTEGER PRIMARY KEY AUTOINCREMENT, type varchar(255) NOT NULL, occurredAt varchar(255) NOT NULL, payload TEXT NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLogSQLite{
+		db: db,
+	}, nil
+}
+
+type EventLogSQLite struct {
+	db *sql.DB
+}
+
+// eventPayload is the serialized part of an application.Event which is not stored in own columns
+type eventPayload struct {
+	Opinion application.Opinion `json:"opinion"`
+	Vote    application.Vote    `json:"vote"`
+	Report  application.Report  `json:"report"`
+	// PreviousStatus is missing in the events which were appended before it was added
+	PreviousStatus application.OpinionStatus `json:"previousStatus,omitempty"`
+}
+
+// Append stores the event and returns it with the assigned sequence number
+func (e *EventLogSQLite) Append(ctx context.Context, event application.Event) (application.Event, error) {
+	payload, err := json.Marshal(eventPayload{
+		Opinion:        event.Opinion,
+		Vote:           event.Vote,
+		Report:         event.Report,
+		PreviousStatus: event.PreviousStatus,
+	})
+	if err != nil {
+		return application.Event{}, err
+	}
+
+	result, err := e.db.ExecContext(ctx, "INSERT INTO events (type, occurredAt, payload) VALUES (?, ?, ?)", event.Type, event.OccurredAt.Format(time.RFC3339Nano), string(payload))
+	if err != nil {
+		return application.Event{}, err
+	}
+
+	sequence, err := result.LastInsertId()
+	if err != nil {
+		return application.Event{}, err
+	}
+
+	event.Sequence = uint64(sequence)
+	return event, nil
+}
+
+// Load returns all events with a sequence greater than afterSequence in the order they were appended
+func (e *EventLogSQLite) Load(ctx context.Context, afterSequence uint64) ([]application.Event, error) {
+	rows, err := e.db.QueryContext(ctx, "SELECT sequence, type, occurredAt, payload FROM events WHERE sequence > ? ORDER BY sequence", afterSequence)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]application.Event, 0)
+
+	for rows.Next() {
+		var sequence uint64
+		var eventType application.EventType
+		var date string
+		var rawPayload string
+
+		if err := rows.Scan(&sequence, &eventType, &date, &rawPayload); err != nil {
+			return nil, err
+		}
+
+		occurredAt, err := time.Parse(time.RFC3339Nano, date)
+		if err != nil {
+			return nil, err
+		}
+
+		var payload eventPayload
+		if err := json.Unmarshal([]byte(rawPayload), &payload); err != nil {
+			return nil, err
+		}
+
+		events = append(events, application.Event{
+			Sequence:       sequence,
+			Type:           eventType,
+			OccurredAt:     occurredAt,
+			Opinion:        payload.Opinion,
+			Vote:           payload.Vote,
+			Report:         payload.Report,
+			PreviousStatus: payload.PreviousStatus,
+		})
+	}
+
+	return events, rows.Err()
+}
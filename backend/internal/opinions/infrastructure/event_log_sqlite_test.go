@@ -0,0 +1,107 @@
+package infrastructure_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventLogSQLite_Append_and_Load(t *testing.T) {
+	t.Parallel()
+	const testDBInstance = "testInstance.db"
+	dbAbsolutePath := fmt.Sprintf("%s/%s", t.TempDir(), testDBInstance)
+
+	eventLog, err := infrastructure.NewEventLogSQLite(dbAbsolutePath)
+	if err != nil {
+		t.Fatalf("NewEventLogSQLite() retunred error %s, but no error is expected", err)
+	}
+	testEventLog(t, eventLog)
+}
+
+// eventLog is implemented by the SQL based event logs
+type eventLog interface {
+	infrastructure.EventStore
+	Load(ctx context.Context, afterSequence uint64) ([]application.Event, error)
+}
+
+// testEventLog appends events of every kind and expects to load them in order
+func testEventLog(t *testing.T, eventLog eventLog) {
+	testTime := time.Now().UTC()
+	created := application.Event{
+		Type:       application.EventOpinionCreated,
+		OccurredAt: testTime,
+		Opinion: application.Opinion{
+			ID:        "1",
+			Owner:     "123",
+			CreatedAt: testTime,
+			Statement: "copy and pasta is fine",
+		},
+	}
+	voted := application.Event{
+		Type:       application.EventVoteSubmitted,
+		OccurredAt: testTime,
+		Vote: application.Vote{
+			Agreement: true,
+			Opinion:   "1",
+			Voter:     "456",
+			CreatedAt: testTime,
+			UpdatedAt: testTime,
+		},
+	}
+
+	reported := application.Event{
+		Type:       application.EventOpinionReported,
+		OccurredAt: testTime,
+		Opinion:    created.Opinion,
+		Report: application.Report{
+			Opinion:   "1",
+			Reporter:  "456",
+			Reason:    application.ReportReasonSpam,
+			CreatedAt: testTime,
+		},
+	}
+
+	hiddenOpinion := created.Opinion
+	hiddenOpinion.Status = application.OpinionHidden
+	hidden := application.Event{
+		Type:           application.EventOpinionHidden,
+		OccurredAt:     testTime,
+		Opinion:        hiddenOpinion,
+		PreviousStatus: application.OpinionPublished,
+	}
+
+	recordedCreated, err := eventLog.Append(context.Background(), created)
+	if err != nil {
+		t.Errorf("Append() retunred error %s, but no error is expected", err)
+	}
+	recordedVoted, err := eventLog.Append(context.Background(), voted)
+	if err != nil {
+		t.Errorf("Append() retunred error %s, but no error is expected", err)
+	}
+	recordedReported, err := eventLog.Append(context.Background(), reported)
+	if err != nil {
+		t.Errorf("Append() retunred error %s, but no error is expected", err)
+	}
+	recordedHidden, err := eventLog.Append(context.Background(), hidden)
+	if err != nil {
+		t.Errorf("Append() retunred error %s, but no error is expected", err)
+	}
+	assert.Less(t, recordedCreated.Sequence, recordedVoted.Sequence)
+	assert.Less(t, recordedVoted.Sequence, recordedReported.Sequence)
+
+	all, err := eventLog.Load(context.Background(), 0)
+	if err != nil {
+		t.Errorf("Load() retunred error %s, but no error is expected", err)
+	}
+	assert.Equal(t, []application.Event{recordedCreated, recordedVoted, recordedReported, recordedHidden}, all)
+
+	afterFirst, err := eventLog.Load(context.Background(), recordedCreated.Sequence)
+	if err != nil {
+		t.Errorf("Load() retunred error %s, but no error is expected", err)
+	}
+	assert.Equal(t, []application.Event{recordedVoted, recordedReported, recordedHidden}, afterFirst)
+}
@@ -0,0 +1,173 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// The label values of the metrics are taken from fixed sets, so the count of series stays bounded
+const (
+	// codeOK labels the calls which did not fail, the failed calls are labelled with their application.ErrorCode
+	codeOK = "ok"
+	// actionOther labels the decisions of actions which are not known to the service
+	actionOther = "other"
+
+	decisionPermitted = "permitted"
+	decisionDenied    = "denied"
+	decisionError     = "error"
+)
+
+// knownActions are the actions the service passes to the policy enforcement point
+var knownActions = map[string]struct{}{
+	application.ActionCreateOpinion:           {},
+	application.ActionListOpinions:            {},
+	application.ActionEditOpinion:             {},
+	application.ActionDeleteOpinion:           {},
+	application.ActionBulkDeleteOpinions:      {},
+	application.ActionHideOpinion:             {},
+	application.ActionApproveOpinion:          {},
+	application.ActionRejectOpinion:           {},
+	application.ActionViewUnpublishedOpinions: {},
+	application.ActionReportOpinion:           {},
+	application.ActionListReports:             {},
+	application.ActionResolveReports:          {},
+	application.ActionListDecisions:           {},
+	application.ActionCreateVote:              {},
+	application.ActionUpdateVote:              {},
+	application.ActionDeleteVote:              {},
+}
+
+// NewMetrics creates the collectors of the opinions module and registers them with the registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "opinions",
+			Subsystem: "service",
+			Name:      "calls_total",
+			Help:      "Count of the commands and queries of the service by method and error code.",
+		}, []string{"method", "code"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "opinions",
+			Subsystem: "service",
+			Name:      "call_duration_seconds",
+			Help:      "Duration of the commands and queries of the service by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "opinions",
+			Subsystem: "repository",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of the repository operations by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "opinions",
+			Subsystem: "repository",
+			Name:      "errors_total",
+			Help:      "Count of the failed repository operations by operation and error code, not_found and conflict are expected results.",
+		}, []string{"operation", "code"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "opinions",
+			Subsystem: "authorization",
+			Name:      "decisions_total",
+			Help:      "Count of the decisions of the policy enforcement point by action and result: permitted, denied or error.",
+		}, []string{"action", "result"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.calls, m.callDuration, m.queryDuration, m.queryErrors, m.decisions} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Metrics are recorded by the instrumented service, repository and policy enforcement point
+type Metrics struct {
+	calls         *prometheus.CounterVec
+	callDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	decisions     *prometheus.CounterVec
+}
+
+// observeCall records a call of the service, err points to the named result of the method
+func (m *Metrics) observeCall(method string, start time.Time, err *error) {
+	m.callDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	m.calls.WithLabelValues(method, errorCode(*err)).Inc()
+}
+
+// observeQuery records a repository operation, err points to the named result of the method
+func (m *Metrics) observeQuery(operation string, start time.Time, err *error) {
+	m.queryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		m.queryErrors.WithLabelValues(operation, errorCode(*err)).Inc()
+	}
+}
+
+// errorCode returns the application.ErrorCode of the error, internal for all other errors and ok without an error
+func errorCode(err error) string {
+	if err == nil {
+		return codeOK
+	}
+	var appErr *application.Error
+	if errors.As(err, &appErr) {
+		return string(appErr.Code)
+	}
+	return string(application.CodeInternal)
+}
+
+// NewInstrumentedPolicyEnforcementPoint decorates the policy enforcement point and counts its decisions.
+// The decorator passes the policy revision through and is a VisibilityPolicy if the policy enforcement point is one.
+func NewInstrumentedPolicyEnforcementPoint(next application.PolicyEnforcementPoint, metrics *Metrics) application.PolicyEnforcementPoint {
+	p := &instrumentedPolicyEnforcementPoint{next: next, metrics: metrics}
+	if visibility, ok := next.(application.VisibilityPolicy); ok {
+		return &instrumentedVisibilityPolicy{instrumentedPolicyEnforcementPoint: p, visibility: visibility}
+	}
+	return p
+}
+
+type instrumentedPolicyEnforcementPoint struct {
+	next    application.PolicyEnforcementPoint
+	metrics *Metrics
+}
+
+func (p *instrumentedPolicyEnforcementPoint) RequestAccessForUser(ctx context.Context, request application.AccessRequest) error {
+	err := p.next.RequestAccessForUser(ctx, request)
+
+	action := request.Action
+	if _, ok := knownActions[action]; !ok {
+		action = actionOther
+	}
+
+	result := decisionPermitted
+	switch {
+	case errors.Is(err, application.AccessDeniedError):
+		result = decisionDenied
+	case err != nil:
+		result = decisionError
+	}
+	p.metrics.decisions.WithLabelValues(action, result).Inc()
+	return err
+}
+
+// PolicyRevision returns the revision of the decorated policy enforcement point
+func (p *instrumentedPolicyEnforcementPoint) PolicyRevision() string {
+	if r, ok := p.next.(application.PolicyRevisioner); ok {
+		return r.PolicyRevision()
+	}
+	return ""
+}
+
+// instrumentedVisibilityPolicy passes the visibility decisions through, they are not counted as decisions of an action
+type instrumentedVisibilityPolicy struct {
+	*instrumentedPolicyEnforcementPoint
+	visibility application.VisibilityPolicy
+}
+
+func (p *instrumentedVisibilityPolicy) OpinionVisibility(ctx context.Context, request application.AccessRequest) (application.OpinionVisibility, error) {
+	return p.visibility.OpinionVisibility(ctx, request)
+}
@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewPartialEvalCacheCollector exports the PartialEvalCacheStats of the cache, the counters are read on every scrape
+func NewPartialEvalCacheCollector(cache *PartialEvalCache) prometheus.Collector {
+	return &partialEvalCacheCollector{
+		cache:         cache,
+		hits:          partialEvalCacheDesc("hits_total", "Count of the partial evaluations answered by the cache."),
+		misses:        partialEvalCacheDesc("misses_total", "Count of the partial evaluations not found in the cache."),
+		evictions:     partialEvalCacheDesc("evictions_total", "Count of the entries evicted as least recently used."),
+		expirations:   partialEvalCacheDesc("expirations_total", "Count of the entries removed after their TTL."),
+		invalidations: partialEvalCacheDesc("invalidations_total", "Count of the invalidations of the whole cache by a new policy revision."),
+		entries:       partialEvalCacheDesc("entries", "Count of the entries in the cache."),
+	}
+}
+
+func partialEvalCacheDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName("opinions", "partial_eval_cache", name), help, nil, nil)
+}
+
+type partialEvalCacheCollector struct {
+	cache         *PartialEvalCache
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	evictions     *prometheus.Desc
+	expirations   *prometheus.Desc
+	invalidations *prometheus.Desc
+	entries       *prometheus.Desc
+}
+
+func (c *partialEvalCacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.hits
+	descs <- c.misses
+	descs <- c.evictions
+	descs <- c.expirations
+	descs <- c.invalidations
+	descs <- c.entries
+}
+
+func (c *partialEvalCacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	metrics <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	metrics <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	metrics <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	metrics <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+	metrics <- prometheus.MustNewConstMetric(c.invalidations, prometheus.CounterValue, float64(stats.Invalidations))
+	metrics <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries))
+}
@@ -0,0 +1,127 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"time"
+)
+
+// NewInstrumentedRepository decorates the repository and records the duration and the errors of every operation, e.g. of the SQLite queries
+func NewInstrumentedRepository(next application.Repository, metrics *Metrics) application.Repository {
+	return &instrumentedRepository{next: next, metrics: metrics}
+}
+
+type instrumentedRepository struct {
+	next    application.Repository
+	metrics *Metrics
+}
+
+func (r *instrumentedRepository) CreateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer r.metrics.observeQuery("CreateOpinion", time.Now(), &err)
+	return r.next.CreateOpinion(ctx, opinion)
+}
+
+func (r *instrumentedRepository) GetOpinion(ctx context.Context, id application.OpinionId) (_ application.Opinion, err error) {
+	defer r.metrics.observeQuery("GetOpinion", time.Now(), &err)
+	return r.next.GetOpinion(ctx, id)
+}
+
+func (r *instrumentedRepository) UpdateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer r.metrics.observeQuery("UpdateOpinion", time.Now(), &err)
+	return r.next.UpdateOpinion(ctx, opinion)
+}
+
+func (r *instrumentedRepository) DeleteOpinion(ctx context.Context, id application.OpinionId) (err error) {
+	defer r.metrics.observeQuery("DeleteOpinion", time.Now(), &err)
+	return r.next.DeleteOpinion(ctx, id)
+}
+
+func (r *instrumentedRepository) DeleteOpinions(ctx context.Context, filter application.OpinionFilter) (_ []application.OpinionId, err error) {
+	defer r.metrics.observeQuery("DeleteOpinions", time.Now(), &err)
+	return r.next.DeleteOpinions(ctx, filter)
+}
+
+func (r *instrumentedRepository) ListOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ []application.Opinion, err error) {
+	defer r.metrics.observeQuery("ListOpinions", time.Now(), &err)
+	return r.next.ListOpinions(ctx, visibility)
+}
+
+func (r *instrumentedRepository) ListOpinionsPage(ctx context.Context, visibility application.OpinionVisibility, page application.OpinionPage) (_ []application.Opinion, err error) {
+	defer r.metrics.observeQuery("ListOpinionsPage", time.Now(), &err)
+	return r.next.ListOpinionsPage(ctx, visibility, page)
+}
+
+func (r *instrumentedRepository) CountOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ int, err error) {
+	defer r.metrics.observeQuery("CountOpinions", time.Now(), &err)
+	return r.next.CountOpinions(ctx, visibility)
+}
+
+func (r *instrumentedRepository) CreateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer r.metrics.observeQuery("CreateVote", time.Now(), &err)
+	return r.next.CreateVote(ctx, vote)
+}
+
+func (r *instrumentedRepository) UpdateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer r.metrics.observeQuery("UpdateVote", time.Now(), &err)
+	return r.next.UpdateVote(ctx, vote)
+}
+
+func (r *instrumentedRepository) GetVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (_ application.Vote, err error) {
+	defer r.metrics.observeQuery("GetVote", time.Now(), &err)
+	return r.next.GetVote(ctx, opinion, voter)
+}
+
+func (r *instrumentedRepository) DeleteVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (err error) {
+	defer r.metrics.observeQuery("DeleteVote", time.Now(), &err)
+	return r.next.DeleteVote(ctx, opinion, voter)
+}
+
+func (r *instrumentedRepository) ListVotes(ctx context.Context) (_ []application.Vote, err error) {
+	defer r.metrics.observeQuery("ListVotes", time.Now(), &err)
+	return r.next.ListVotes(ctx)
+}
+
+func (r *instrumentedRepository) CountVotes(ctx context.Context, visibility application.OpinionVisibility, opinions []application.OpinionId) (_ map[application.OpinionId]application.VoteCount, err error) {
+	defer r.metrics.observeQuery("CountVotes", time.Now(), &err)
+	return r.next.CountVotes(ctx, visibility, opinions)
+}
+
+func (r *instrumentedRepository) CreateReport(ctx context.Context, report application.Report) (err error) {
+	defer r.metrics.observeQuery("CreateReport", time.Now(), &err)
+	return r.next.CreateReport(ctx, report)
+}
+
+func (r *instrumentedRepository) ListOpenReports(ctx context.Context) (_ []application.Report, err error) {
+	defer r.metrics.observeQuery("ListOpenReports", time.Now(), &err)
+	return r.next.ListOpenReports(ctx)
+}
+
+func (r *instrumentedRepository) CountOpenReports(ctx context.Context, opinion application.OpinionId) (_ int, err error) {
+	defer r.metrics.observeQuery("CountOpenReports", time.Now(), &err)
+	return r.next.CountOpenReports(ctx, opinion)
+}
+
+func (r *instrumentedRepository) ResolveReports(ctx context.Context, opinion application.OpinionId, resolution application.ReportResolution, resolvedAt time.Time) (err error) {
+	defer r.metrics.observeQuery("ResolveReports", time.Now(), &err)
+	return r.next.ResolveReports(ctx, opinion, resolution, resolvedAt)
+}
+
+func (r *instrumentedRepository) CreateIdempotencyRecord(ctx context.Context, record application.IdempotencyRecord, now time.Time) (err error) {
+	defer r.metrics.observeQuery("CreateIdempotencyRecord", time.Now(), &err)
+	return r.next.CreateIdempotencyRecord(ctx, record, now)
+}
+
+func (r *instrumentedRepository) GetIdempotencyRecord(ctx context.Context, user application.UserId, key string, now time.Time) (_ application.IdempotencyRecord, err error) {
+	defer r.metrics.observeQuery("GetIdempotencyRecord", time.Now(), &err)
+	return r.next.GetIdempotencyRecord(ctx, user, key, now)
+}
+
+func (r *instrumentedRepository) DeleteIdempotencyRecord(ctx context.Context, user application.UserId, key string) (err error) {
+	defer r.metrics.observeQuery("DeleteIdempotencyRecord", time.Now(), &err)
+	return r.next.DeleteIdempotencyRecord(ctx, user, key)
+}
+
+func (r *instrumentedRepository) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (_ int64, err error) {
+	defer r.metrics.observeQuery("DeleteExpiredIdempotencyRecords", time.Now(), &err)
+	return r.next.DeleteExpiredIdempotencyRecords(ctx, now)
+}
@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"time"
+)
+
+// NewInstrumentedService decorates the service and records the count, the error code and the duration of every call.
+// The method is the only other label, so the count of series is bounded by the methods of the service.
+func NewInstrumentedService(next application.Service, metrics *Metrics) application.Service {
+	return &instrumentedService{next: next, metrics: metrics}
+}
+
+type instrumentedService struct {
+	next    application.Service
+	metrics *Metrics
+}
+
+func (s *instrumentedService) CreateOpinionCommand(ctx context.Context, user application.AuthenticatedUser, opinion application.OpinionCreateDTO) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("CreateOpinionCommand", time.Now(), &err)
+	return s.next.CreateOpinionCommand(ctx, user, opinion)
+}
+
+func (s *instrumentedService) EditOpinionCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId, opinion application.OpinionUpdateDTO) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("EditOpinionCommand", time.Now(), &err)
+	return s.next.EditOpinionCommand(ctx, user, id, opinion)
+}
+
+func (s *instrumentedService) ListOpinionsQuery(ctx context.Context, user application.AuthenticatedUser) (_ []application.Opinion, err error) {
+	defer s.metrics.observeCall("ListOpinionsQuery", time.Now(), &err)
+	return s.next.ListOpinionsQuery(ctx, user)
+}
+
+func (s *instrumentedService) ListOpinionsPageQuery(ctx context.Context, user application.AuthenticatedUser, page application.OpinionPage) (_ application.OpinionList, err error) {
+	defer s.metrics.observeCall("ListOpinionsPageQuery", time.Now(), &err)
+	return s.next.ListOpinionsPageQuery(ctx, user, page)
+}
+
+func (s *instrumentedService) OpinionVisibilityQuery(ctx context.Context, user application.AuthenticatedUser) (_ application.OpinionVisibility, err error) {
+	defer s.metrics.observeCall("OpinionVisibilityQuery", time.Now(), &err)
+	return s.next.OpinionVisibilityQuery(ctx, user)
+}
+
+func (s *instrumentedService) DeleteOpinionCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (err error) {
+	defer s.metrics.observeCall("DeleteOpinionCommand", time.Now(), &err)
+	return s.next.DeleteOpinionCommand(ctx, user, id)
+}
+
+func (s *instrumentedService) BulkDeleteOpinionsCommand(ctx context.Context, user application.AuthenticatedUser, filter application.OpinionFilter) (_ []application.OpinionId, err error) {
+	defer s.metrics.observeCall("BulkDeleteOpinionsCommand", time.Now(), &err)
+	return s.next.BulkDeleteOpinionsCommand(ctx, user, filter)
+}
+
+func (s *instrumentedService) ApproveOpinionCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("ApproveOpinionCommand", time.Now(), &err)
+	return s.next.ApproveOpinionCommand(ctx, user, id)
+}
+
+func (s *instrumentedService) RejectOpinionCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("RejectOpinionCommand", time.Now(), &err)
+	return s.next.RejectOpinionCommand(ctx, user, id)
+}
+
+func (s *instrumentedService) HideOpinionCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("HideOpinionCommand", time.Now(), &err)
+	return s.next.HideOpinionCommand(ctx, user, id)
+}
+
+func (s *instrumentedService) ReportOpinionCommand(ctx context.Context, user application.AuthenticatedUser, report application.ReportCreateDTO) (_ application.Report, err error) {
+	defer s.metrics.observeCall("ReportOpinionCommand", time.Now(), &err)
+	return s.next.ReportOpinionCommand(ctx, user, report)
+}
+
+func (s *instrumentedService) ListReportsQuery(ctx context.Context, user application.AuthenticatedUser) (_ []application.Report, err error) {
+	defer s.metrics.observeCall("ListReportsQuery", time.Now(), &err)
+	return s.next.ListReportsQuery(ctx, user)
+}
+
+func (s *instrumentedService) ResolveReportsCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId, resolution application.ReportResolution) (_ application.Opinion, err error) {
+	defer s.metrics.observeCall("ResolveReportsCommand", time.Now(), &err)
+	return s.next.ResolveReportsCommand(ctx, user, id, resolution)
+}
+
+func (s *instrumentedService) HandleUserDeletionEvent(ctx context.Context, event any) (err error) {
+	defer s.metrics.observeCall("HandleUserDeletionEvent", time.Now(), &err)
+	return s.next.HandleUserDeletionEvent(ctx, event)
+}
+
+func (s *instrumentedService) ListDecisionsQuery(ctx context.Context, user application.AuthenticatedUser, filter application.DecisionFilter) (_ []application.Decision, err error) {
+	defer s.metrics.observeCall("ListDecisionsQuery", time.Now(), &err)
+	return s.next.ListDecisionsQuery(ctx, user, filter)
+}
+
+func (s *instrumentedService) CreateVoteCommand(ctx context.Context, user application.AuthenticatedUser, vote application.VoteCreateAndUpdateDTO) (_ application.Vote, err error) {
+	defer s.metrics.observeCall("CreateVoteCommand", time.Now(), &err)
+	return s.next.CreateVoteCommand(ctx, user, vote)
+}
+
+func (s *instrumentedService) UpdateVoteCommand(ctx context.Context, user application.AuthenticatedUser, vote application.VoteCreateAndUpdateDTO) (_ application.Vote, err error) {
+	defer s.metrics.observeCall("UpdateVoteCommand", time.Now(), &err)
+	return s.next.UpdateVoteCommand(ctx, user, vote)
+}
+
+func (s *instrumentedService) DeleteVoteCommand(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (_ application.Vote, err error) {
+	defer s.metrics.observeCall("DeleteVoteCommand", time.Now(), &err)
+	return s.next.DeleteVoteCommand(ctx, user, id)
+}
+
+func (s *instrumentedService) CountVotesQuery(ctx context.Context, user application.AuthenticatedUser, opinions []application.OpinionId) (_ map[application.OpinionId]application.VoteCount, err error) {
+	defer s.metrics.observeCall("CountVotesQuery", time.Now(), &err)
+	return s.next.CountVotesQuery(ctx, user, opinions)
+}
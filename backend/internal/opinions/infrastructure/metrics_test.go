@@ -0,0 +1,167 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMetrics(t *testing.T) (*infrastructure.Metrics, *prometheus.Registry) {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	metrics, err := infrastructure.NewMetrics(registry)
+	if err != nil {
+		t.Fatalf("NewMetrics() returned error %s, but no error is expected", err)
+	}
+	return metrics, registry
+}
+
+func TestInstrumentedService(t *testing.T) {
+	t.Parallel()
+	metrics, registry := newMetrics(t)
+	service := infrastructure.NewInstrumentedService(application.NewOpinionService(
+		infrastructure.NewInstrumentedPolicyEnforcementPoint(infrastructure.AuthenticatedUsersPolicyEnforcementPoint{}, metrics),
+		infrastructure.NewInstrumentedRepository(infrastructure.NewOpinionsRepositoryMemory(), metrics),
+		infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+	), metrics)
+
+	ctx := context.Background()
+	alice := application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}
+
+	opinion, err := service.CreateOpinionCommand(ctx, alice, application.OpinionCreateDTO{Statement: "metrics are nice"})
+	assert.NoError(t, err)
+	_, err = service.CreateOpinionCommand(ctx, alice, application.OpinionCreateDTO{})
+	assert.Error(t, err)
+	_, err = service.DeleteVoteCommand(ctx, alice, opinion.ID)
+	assert.Error(t, err)
+	_, err = service.ApproveOpinionCommand(ctx, alice, opinion.ID)
+	assert.Error(t, err)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP opinions_service_calls_total Count of the commands and queries of the service by method and error code.
+# TYPE opinions_service_calls_total counter
+opinions_service_calls_total{code="forbidden",method="ApproveOpinionCommand"} 1
+opinions_service_calls_total{code="not_found",method="DeleteVoteCommand"} 1
+opinions_service_calls_total{code="ok",method="CreateOpinionCommand"} 1
+opinions_service_calls_total{code="validation",method="CreateOpinionCommand"} 1
+# HELP opinions_repository_errors_total Count of the failed repository operations by operation and error code, not_found and conflict are expected results.
+# TYPE opinions_repository_errors_total counter
+opinions_repository_errors_total{code="not_found",operation="GetVote"} 1
+# HELP opinions_authorization_decisions_total Count of the decisions of the policy enforcement point by action and result: permitted, denied or error.
+# TYPE opinions_authorization_decisions_total counter
+opinions_authorization_decisions_total{action="ApproveOpinion",result="denied"} 1
+opinions_authorization_decisions_total{action="CreateOpinion",result="permitted"} 2
+opinions_authorization_decisions_total{action="DeleteVote",result="permitted"} 1
+`), "opinions_service_calls_total", "opinions_repository_errors_total", "opinions_authorization_decisions_total"))
+
+	count, err := testutil.GatherAndCount(registry, "opinions_service_call_duration_seconds", "opinions_repository_query_duration_seconds")
+	assert.NoError(t, err)
+	// a histogram per called method of the service and per operation of the repository: CreateOpinion, GetVote and GetOpinion
+	assert.Equal(t, 6, count)
+}
+
+type failingPolicyEnforcementPoint struct{}
+
+func (failingPolicyEnforcementPoint) RequestAccessForUser(context.Context, application.AccessRequest) error {
+	return errors.New("policy could not be evaluated")
+}
+
+func TestInstrumentedPolicyEnforcementPoint(t *testing.T) {
+	t.Parallel()
+	metrics, registry := newMetrics(t)
+	pep := infrastructure.NewInstrumentedPolicyEnforcementPoint(failingPolicyEnforcementPoint{}, metrics)
+
+	for _, action := range []string{application.ActionListOpinions, "unknown-1", "unknown-2"} {
+		assert.Error(t, pep.RequestAccessForUser(context.Background(), application.AccessRequest{User: "alice", Action: action}))
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP opinions_authorization_decisions_total Count of the decisions of the policy enforcement point by action and result: permitted, denied or error.
+# TYPE opinions_authorization_decisions_total counter
+opinions_authorization_decisions_total{action="ListOpinions",result="error"} 1
+opinions_authorization_decisions_total{action="other",result="error"} 2
+`), "opinions_authorization_decisions_total"), "unknown actions do not create new series")
+}
+
+func TestInstrumentedPolicyEnforcementPoint_decisionLog(t *testing.T) {
+	t.Parallel()
+	metrics, _ := newMetrics(t)
+	opa, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	instrumented := infrastructure.NewInstrumentedPolicyEnforcementPoint(opa, metrics)
+	_, ok := instrumented.(application.VisibilityPolicy)
+	assert.True(t, ok, "the visibility of the policies is passed through")
+
+	// the chain is wired like the server does it
+	decisions := infrastructure.NewDecisionLogMemory(infrastructure.DefaultDecisionLogMemorySize)
+	pep := application.NewDecisionLoggingPolicyEnforcementPoint(instrumented, decisions, infrastructure.SystemTimeService{})
+	visibility, ok := pep.(application.VisibilityPolicy)
+	if !ok {
+		t.Fatal("the decision logging policy enforcement point is not a VisibilityPolicy")
+	}
+
+	alice := application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}
+	service := application.NewOpinionService(pep, infrastructure.NewOpinionsRepositoryMemory(), infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+		infrastructure.RandomIdService{}, infrastructure.SystemTimeService{}, application.WithVisibilityPolicy(visibility))
+	_, err = service.CreateOpinionCommand(context.Background(), alice, application.OpinionCreateDTO{Statement: "revisions are logged"})
+	assert.NoError(t, err)
+	_, err = service.ListOpinionsQuery(context.Background(), alice)
+	assert.NoError(t, err)
+
+	logged, err := decisions.ListDecisions(context.Background(), application.DecisionFilter{Limit: 10})
+	assert.NoError(t, err)
+	// the newest decisions first: the visibility and the permission of the list, the permission of the create
+	if assert.Len(t, logged, 3) {
+		assert.Equal(t, application.VisibilityDecisionAction, logged[0].Action)
+		for _, decision := range logged {
+			assert.NotEmpty(t, decision.PolicyRevision)
+			assert.Equal(t, opa.PolicyRevision(), decision.PolicyRevision)
+		}
+	}
+}
+
+func TestNewMetrics(t *testing.T) {
+	t.Parallel()
+	_, registry := newMetrics(t)
+
+	_, err := infrastructure.NewMetrics(registry)
+	assert.Error(t, err, "the collectors are registered once")
+}
+
+func TestPartialEvalCacheCollector(t *testing.T) {
+	t.Parallel()
+	pep, cache, _ := cachedPolicyEnforcementPoint(t, 10, time.Minute)
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(infrastructure.NewPartialEvalCacheCollector(cache)))
+
+	for i := 0; i < 2; i++ {
+		_, err := pep.OpinionVisibility(context.Background(), listRequest("123", application.RoleUser))
+		assert.NoError(t, err)
+	}
+
+	expected := `
+# HELP opinions_partial_eval_cache_entries Count of the entries in the cache.
+# TYPE opinions_partial_eval_cache_entries gauge
+opinions_partial_eval_cache_entries 1
+# HELP opinions_partial_eval_cache_hits_total Count of the partial evaluations answered by the cache.
+# TYPE opinions_partial_eval_cache_hits_total counter
+opinions_partial_eval_cache_hits_total 1
+# HELP opinions_partial_eval_cache_misses_total Count of the partial evaluations not found in the cache.
+# TYPE opinions_partial_eval_cache_misses_total counter
+opinions_partial_eval_cache_misses_total 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"opinions_partial_eval_cache_entries", "opinions_partial_eval_cache_hits_total", "opinions_partial_eval_cache_misses_total"))
+}
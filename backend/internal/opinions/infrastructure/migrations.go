@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+)
+
+// migration is a versioned schema change. The statements are shared by all SQL based repositories,
+// therefore only types and syntax supported by SQLite and PostgreSQL are allowed.
+type migration struct {
+	version    int
+	statements []string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			"CREATE TABLE IF NOT EXISTS opinions ( id varchar(255) NOT NULL , userId varchar(255) NOT NULL, creationTime varchar(255) NOT NULL, statement varchar(255) NOT NULL, PRIMARY KEY (id))",
+			"CREATE INDEX IF NOT EXISTS opinion_id on opinions (id)",
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			"CREATE TABLE IF NOT EXISTS votes ( opinionId varchar(255) NOT NULL, voterId varchar(255) NOT NULL, agreement boolean NOT NULL, creationTime varchar(255) NOT NULL, updateTime varchar(255) NOT NULL, PRIMARY KEY (opinionId, voterId), FOREIGN KEY (opinionId) REFERENCES opinions (id) ON DELETE CASCADE)",
+		},
+	},
+	{
+		version: 3,
+		statements: []string{
+			// opinions which were stored before the moderation existed stay published
+			"ALTER TABLE opinions ADD COLUMN status varchar(32) NOT NULL DEFAULT 'published'",
+		},
+	},
+	{
+		version: 4,
+		statements: []string{
+			// open reports have an empty resolution and resolutionTime
+			"CREATE TABLE IF NOT EXISTS reports ( opinionId varchar(255) NOT NULL, reporterId varchar(255) NOT NULL, reason varchar(32) NOT NULL, creationTime varchar(255) NOT NULL, resolution varchar(32) NOT NULL DEFAULT '', resolutionTime varchar(255) NOT NULL DEFAULT '', PRIMARY KEY (opinionId, reporterId), FOREIGN KEY (opinionId) REFERENCES opinions (id) ON DELETE CASCADE)",
+			"CREATE INDEX IF NOT EXISTS reports_open on reports (resolution, creationTime)",
+		},
+	},
+	{
+		version: 5,
+		statements: []string{
+			// expiresAt is stored as unix seconds because it is compared in queries, the opinion is a snapshot without foreign key
+			"CREATE TABLE IF NOT EXISTS idempotency_keys ( userId varchar(255) NOT NULL, idempotencyKey varchar(255) NOT NULL, requestHash varchar(64) NOT NULL, opinionId varchar(255) NOT NULL, creationTime varchar(255) NOT NULL, statement varchar(255) NOT NULL, status varchar(32) NOT NULL, expiresAt bigint NOT NULL, PRIMARY KEY (userId, idempotencyKey))",
+			"CREATE INDEX IF NOT EXISTS idempotency_keys_expiry on idempotency_keys (expiresAt)",
+		},
+	},
+	{
+		version: 6,
+		statements: []string{
+			// rows which were stored before the versioning existed start with the initial version
+			"ALTER TABLE opinions ADD COLUMN version bigint NOT NULL DEFAULT 1",
+			"ALTER TABLE votes ADD COLUMN version bigint NOT NULL DEFAULT 1",
+			"ALTER TABLE idempotency_keys ADD COLUMN opinionVersion bigint NOT NULL DEFAULT 1",
+		},
+	},
+}
+
+// migrationTx abstracts the transaction of the different SQL drivers
+type migrationTx interface {
+	exec(ctx context.Context, statement string) error
+	queryInt(ctx context.Context, query string) (int, error)
+}
+
+// migrate applies all migrations which are newer than the version stored in the schema_migrations table.
+// The caller is responsible to commit the transaction.
+func migrate(ctx context.Context, tx migrationTx) error {
+	if err := tx.exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations ( version integer NOT NULL, PRIMARY KEY (version))"); err != nil {
+		return err
+	}
+
+	current, err := tx.queryInt(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		for _, statement := range m.statements {
+			if err := tx.exec(ctx, statement); err != nil {
+				return fmt.Errorf("migration %d failed: %w", m.version, err)
+			}
+		}
+
+		if err := tx.exec(ctx, fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", m.version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
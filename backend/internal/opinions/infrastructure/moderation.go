@@ -0,0 +1,110 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NewWordListCheck matches whole words case-insensitively, e.g. against a profanity list
+func NewWordListCheck(words []string, verdict application.Verdict) WordListCheck {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			set[w] = struct{}{}
+		}
+	}
+	return WordListCheck{words: set, verdict: verdict}
+}
+
+// WordListCheck returns its verdict if the statement contains one of the words
+type WordListCheck struct {
+	words   map[string]struct{}
+	verdict application.Verdict
+}
+
+func (c WordListCheck) Check(_ context.Context, statement string) (application.ModerationResult, error) {
+	words := strings.FieldsFunc(strings.ToLower(statement), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, w := range words {
+		if _, denied := c.words[w]; denied {
+			return application.ModerationResult{Verdict: c.verdict, Reasons: []string{"contains a word which is not allowed"}}, nil
+		}
+	}
+	return application.ModerationResult{}, nil
+}
+
+// NewDenyListCheck compiles the regular expressions, which are matched against the whole statement
+func NewDenyListCheck(patterns []string, verdict application.Verdict) (DenyListCheck, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return DenyListCheck{}, err
+		}
+		compiled = append(compiled, r)
+	}
+	return DenyListCheck{patterns: compiled, verdict: verdict}, nil
+}
+
+// DenyListCheck returns its verdict if one of the patterns matches
+type DenyListCheck struct {
+	patterns []*regexp.Regexp
+	verdict  application.Verdict
+}
+
+func (c DenyListCheck) Check(_ context.Context, statement string) (application.ModerationResult, error) {
+	for _, p := range c.patterns {
+		if p.MatchString(statement) {
+			return application.ModerationResult{Verdict: c.verdict, Reasons: []string{"contains a phrase which is not allowed"}}, nil
+		}
+	}
+	return application.ModerationResult{}, nil
+}
+
+// LinkSpamCheck holds statements for review which contain many links or mostly consist of links
+type LinkSpamCheck struct {
+	// MaxLinks is the highest count of links which is published without review
+	MaxLinks int
+	// MaxLinkRatio is the highest share of characters which may belong to links, e.g. 0.5
+	MaxLinkRatio float64
+}
+
+func (c LinkSpamCheck) Check(_ context.Context, statement string) (application.ModerationResult, error) {
+	links := application.FindURLs(statement)
+	if len(links) == 0 {
+		return application.ModerationResult{}, nil
+	}
+
+	var linkLength int
+	for _, l := range links {
+		linkLength += utf8.RuneCountInString(l)
+	}
+
+	ratio := float64(linkLength) / float64(utf8.RuneCountInString(statement))
+	if len(links) > c.MaxLinks || ratio > c.MaxLinkRatio {
+		return application.ModerationResult{Verdict: application.VerdictReview, Reasons: []string{"looks like link spam"}}, nil
+	}
+	return application.ModerationResult{}, nil
+}
+
+// ReadList returns the non-empty lines of a word or pattern list, lines starting with "#" are comments
+func ReadList(r io.Reader) ([]string, error) {
+	var list []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, scanner.Err()
+}
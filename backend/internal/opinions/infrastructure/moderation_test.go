@@ -0,0 +1,95 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestModerationChecks(t *testing.T) {
+	t.Parallel()
+	denyList, err := infrastructure.NewDenyListCheck([]string{`(?i)buy\s+now`}, application.VerdictReject)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		check     application.ModerationCheck
+		statement string
+		want      application.Verdict
+	}{
+		{
+			name:      "Should match whole words case-insensitively",
+			check:     infrastructure.NewWordListCheck([]string{" Pasta "}, application.VerdictReject),
+			statement: "copy and PASTA!",
+			want:      application.VerdictReject,
+		},
+		{
+			name:      "Should not match parts of words",
+			check:     infrastructure.NewWordListCheck([]string{"pasta"}, application.VerdictReject),
+			statement: "copy and pastafarian",
+			want:      application.VerdictPublish,
+		},
+		{
+			name:      "Should apply the configured verdict of the word list",
+			check:     infrastructure.NewWordListCheck([]string{"pasta"}, application.VerdictReview),
+			statement: "copy and pasta",
+			want:      application.VerdictReview,
+		},
+		{
+			name:      "Should reject statements matching the deny list",
+			check:     denyList,
+			statement: "Buy   now at my shop",
+			want:      application.VerdictReject,
+		},
+		{
+			name:      "Should publish statements not matching the deny list",
+			check:     denyList,
+			statement: "copy and pasta is fine",
+			want:      application.VerdictPublish,
+		},
+		{
+			name:      "Should publish statements with few links",
+			check:     infrastructure.LinkSpamCheck{MaxLinks: 1, MaxLinkRatio: 0.5},
+			statement: "my thoughts on copy and pasta https://felixwiedmann.de",
+			want:      application.VerdictPublish,
+		},
+		{
+			name:      "Should review statements with too many links",
+			check:     infrastructure.LinkSpamCheck{MaxLinks: 1, MaxLinkRatio: 1},
+			statement: "see https://a.de and www.b.de",
+			want:      application.VerdictReview,
+		},
+		{
+			name:      "Should review statements which are mostly links",
+			check:     infrastructure.LinkSpamCheck{MaxLinks: 1, MaxLinkRatio: 0.5},
+			statement: "see https://felixwiedmann.de/opinions",
+			want:      application.VerdictReview,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.check.Check(context.Background(), tt.statement)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Verdict)
+			if tt.want != application.VerdictPublish {
+				assert.NotEmpty(t, got.Reasons)
+			}
+		})
+	}
+}
+
+func TestNewDenyListCheck_invalidPattern(t *testing.T) {
+	t.Parallel()
+	_, err := infrastructure.NewDenyListCheck([]string{"("}, application.VerdictReject)
+	assert.Error(t, err)
+}
+
+func TestReadList(t *testing.T) {
+	t.Parallel()
+	list, err := infrastructure.ReadList(strings.NewReader("# comment\n\n pasta \nspaghetti\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pasta", "spaghetti"}, list)
+}
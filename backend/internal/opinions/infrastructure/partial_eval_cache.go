@@ -0,0 +1,169 @@
+package infrastructure
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of the PartialEvalCache
+const (
+	DefaultPartialEvalCacheSize = 1000
+	DefaultPartialEvalCacheTTL  = 5 * time.Minute
+)
+
+// volatileInputFields are set per request but do not change the result of a partial evaluation with the opinions as unknown
+var volatileInputFields = []string{"opinion", "owner"}
+
+// NewPartialEvalCache keeps up to size results of partial evaluations for the ttl, the least recently used result is evicted first
+func NewPartialEvalCache(size int, ttl time.Duration, timeService application.TimeService) *PartialEvalCache {
+	return &PartialEvalCache{
+		size:        size,
+		ttl:         ttl,
+		timeService: timeService,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+// PartialEvalCache is a thread safe LRU cache of the visibilities of an OPAPolicyEnforcementPoint
+type PartialEvalCache struct {
+	size        int
+	ttl         time.Duration
+	timeService application.TimeService
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the *partialEvalEntry values, the most recently used at the front
+	order *list.List
+	stats PartialEvalCacheStats
+}
+
+type partialEvalEntry struct {
+	key        string
+	visibility application.OpinionVisibility
+	// denied is set if no opinion is visible
+	denied    bool
+	expiresAt time.Time
+}
+
+// PartialEvalCacheStats are the counters of a PartialEvalCache since its creation
+type PartialEvalCacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Expirations   uint64 `json:"expirations"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+// partialEvalKey identifies a partial evaluation by the policy revision, the query and the input without the volatileInputFields
+func partialEvalKey(revision string, query string, input map[string]any) (string, error) {
+	stable := make(map[string]any, len(input))
+	for field, value := range input {
+		stable[field] = value
+	}
+	for _, field := range volatileInputFields {
+		delete(stable, field)
+	}
+
+	// the order of the roles does not change the result
+	if roles, ok := stable["roles"].([]string); ok {
+		sorted := append([]string(nil), roles...)
+		sort.Strings(sorted)
+		stable["roles"] = sorted
+	}
+
+	// maps are encoded with sorted keys
+	encoded, err := json.Marshal(stable)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(revision))
+	h.Write([]byte{0})
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// get returns the cached visibility, an expired entry is removed and counted as miss
+func (c *PartialEvalCache) get(key string) (visibility application.OpinionVisibility, denied bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		c.stats.Misses++
+		return application.OpinionVisibility{}, false, false
+	}
+
+	entry := element.Value.(*partialEvalEntry)
+	if !c.timeService.CurrentTime().Before(entry.expiresAt) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return application.OpinionVisibility{}, false, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return entry.visibility, entry.denied, true
+}
+
+// put stores the visibility and evicts the least recently used entries above the size
+func (c *PartialEvalCache) put(key string, visibility application.OpinionVisibility, denied bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &partialEvalEntry{
+		key:        key,
+		visibility: visibility,
+		denied:     denied,
+		expiresAt:  c.timeService.CurrentTime().Add(c.ttl),
+	}
+
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *PartialEvalCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*partialEvalEntry).key)
+}
+
+// Invalidate removes all entries, it is called when a new policy revision is activated
+func (c *PartialEvalCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.stats.Invalidations++
+}
+
+// Stats returns the current counters
+func (c *PartialEvalCache) Stats() PartialEvalCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
@@ -0,0 +1,122 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a application.TimeService which only advances if the test moves it
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) CurrentTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func listRequest(user application.UserId, roles ...application.Role) application.AccessRequest {
+	return application.AccessRequest{User: user, Roles: roles, Action: application.ActionListOpinions}
+}
+
+func cachedPolicyEnforcementPoint(t *testing.T, size int, ttl time.Duration) (*infrastructure.OPAPolicyEnforcementPoint, *infrastructure.PartialEvalCache, *manualClock) {
+	t.Helper()
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	clock := &manualClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := infrastructure.NewPartialEvalCache(size, ttl, clock)
+	return pep.CachePartialEvaluations(cache), cache, clock
+}
+
+func TestPartialEvalCache_hits(t *testing.T) {
+	t.Parallel()
+	pep, cache, _ := cachedPolicyEnforcementPoint(t, 10, time.Minute)
+	ctx := context.Background()
+
+	first, err := pep.OpinionVisibility(ctx, listRequest("123", application.RoleUser, application.RoleModerator))
+	assert.NoError(t, err)
+
+	second, err := pep.OpinionVisibility(ctx, listRequest("123", application.RoleModerator, application.RoleUser))
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// the opinion and the owner of the request do not change the visibility
+	withOpinion := listRequest("123", application.RoleUser, application.RoleModerator)
+	withOpinion.Opinion, withOpinion.Owner = "789", "456"
+	_, err = pep.OpinionVisibility(ctx, withOpinion)
+	assert.NoError(t, err)
+
+	other, err := pep.OpinionVisibility(ctx, listRequest("456", application.RoleUser))
+	assert.NoError(t, err)
+	assert.False(t, other.Unrestricted())
+
+	assert.Equal(t, infrastructure.PartialEvalCacheStats{Hits: 2, Misses: 2, Entries: 2}, cache.Stats())
+}
+
+func TestPartialEvalCache_expiration_and_eviction(t *testing.T) {
+	t.Parallel()
+	pep, cache, clock := cachedPolicyEnforcementPoint(t, 2, time.Minute)
+	ctx := context.Background()
+
+	for _, user := range []application.UserId{"1", "2", "3"} {
+		_, err := pep.OpinionVisibility(ctx, listRequest(user))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, infrastructure.PartialEvalCacheStats{Misses: 3, Evictions: 1, Entries: 2}, cache.Stats())
+
+	_, err := pep.OpinionVisibility(ctx, listRequest("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), cache.Stats().Misses, "the least recently used entry was evicted")
+
+	clock.advance(time.Minute)
+	_, err = pep.OpinionVisibility(ctx, listRequest("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, infrastructure.PartialEvalCacheStats{Misses: 5, Evictions: 2, Expirations: 1, Entries: 2}, cache.Stats())
+}
+
+func TestPartialEvalCache_invalidation(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeBundle(t, path, buildBundle(t, "v1", readTestPolicy(t), false))
+
+	pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleFile(path, nil))
+	if err != nil {
+		t.Fatalf("NewOPABundlePolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+	cache := infrastructure.NewPartialEvalCache(10, time.Hour, infrastructure.SystemTimeService{})
+	pep.CachePartialEvaluations(cache)
+
+	visibility, err := pep.OpinionVisibility(context.Background(), listRequest("123"))
+	assert.NoError(t, err)
+	assert.False(t, visibility.Unrestricted())
+
+	writeBundle(t, path, buildBundle(t, "v2", denyAllPolicy, false))
+	reloaded, err := pep.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, infrastructure.PartialEvalCacheStats{Misses: 1, Invalidations: 1}, cache.Stats())
+
+	_, err = pep.OpinionVisibility(context.Background(), listRequest("123"))
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	_, err = pep.OpinionVisibility(context.Background(), listRequest("123"))
+	assert.ErrorIs(t, err, application.AccessDeniedError, "denials are cached")
+	assert.Equal(t, infrastructure.PartialEvalCacheStats{Hits: 1, Misses: 2, Invalidations: 1, Entries: 1}, cache.Stats())
+}
@@ -0,0 +1,240 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// PolicyBundleSource provides the policy bundles of an OPAPolicyEnforcementPoint
+type PolicyBundleSource interface {
+	// Fetch returns the current bundle or nil if the bundle did not change since the last fetch
+	Fetch(ctx context.Context) (*bundle.Bundle, error)
+}
+
+const (
+	// PolicyBundleTimeout bounds a download of a bundle, the reloads wait for the running download
+	PolicyBundleTimeout = 30 * time.Second
+	// MaxPolicyBundleSize bounds the size of a downloaded bundle and of each of its files
+	MaxPolicyBundleSize int64 = 32 << 20
+)
+
+// NoPolicyBundleError is returned if the first fetch of a PolicyBundleSource did not return a bundle
+var NoPolicyBundleError = errors.New("policy bundle source returned no bundle")
+
+// NewPolicyBundleVerification verifies the signatures of bundles with the key.
+// The key is a PEM encoded public key or the secret of HMAC algorithms, e.g. RS256 or HS256.
+func NewPolicyBundleVerification(keyID string, algorithm string, key []byte) *bundle.VerificationConfig {
+	return bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{
+		keyID: {Key: string(key), Algorithm: algorithm},
+	}, keyID, "", nil)
+}
+
+// NewPolicyBundleSource returns a PolicyBundleHTTP for http and https URLs and a PolicyBundleFile for all other locations.
+// Bundles without a valid signature are rejected if the verification is set, otherwise signed bundles are rejected.
+func NewPolicyBundleSource(location string, verification *bundle.VerificationConfig) PolicyBundleSource {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewPolicyBundleHTTP(location, &http.Client{Timeout: PolicyBundleTimeout}, verification)
+	}
+	return NewPolicyBundleFile(location, verification)
+}
+
+// NewPolicyBundleFile reads the bundle from a tar.gz file or from a directory with the bundle layout
+func NewPolicyBundleFile(path string, verification *bundle.VerificationConfig) *PolicyBundleFile {
+	return &PolicyBundleFile{
+		path:         path,
+		verification: verification,
+	}
+}
+
+type PolicyBundleFile struct {
+	path         string
+	verification *bundle.VerificationConfig
+}
+
+// Fetch reads the bundle on every call, the OPAPolicyEnforcementPoint skips bundles of the active revision
+func (f *PolicyBundleFile) Fetch(_ context.Context) (*bundle.Bundle, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return readBundle(bundle.NewDirectoryLoader(f.path), f.verification, "")
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readBundle(bundle.NewTarballLoaderWithBaseURL(file, f.path), f.verification, "")
+}
+
+// NewPolicyBundleHTTP downloads the tar.gz bundle from the URL, unchanged bundles are detected by their ETag.
+// The client should have a timeout, bundles above MaxPolicyBundleSize are rejected.
+func NewPolicyBundleHTTP(url string, client *http.Client, verification *bundle.VerificationConfig) *PolicyBundleHTTP {
+	return &PolicyBundleHTTP{
+		url:          url,
+		client:       client,
+		verification: verification,
+	}
+}
+
+// PolicyBundleHTTP is not safe for concurrent use, the OPAPolicyEnforcementPoint serializes the fetches
+type PolicyBundleHTTP struct {
+	url          string
+	client       *http.Client
+	verification *bundle.VerificationConfig
+	etag         string
+}
+
+func (h *PolicyBundleHTTP) Fetch(ctx context.Context) (*bundle.Bundle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.etag != "" {
+		req.Header.Set("If-None-Match", h.etag)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("policy bundle %s could not be downloaded: %s", h.url, resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxPolicyBundleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > MaxPolicyBundleSize {
+		return nil, fmt.Errorf("policy bundle %s exceeds %d bytes", h.url, MaxPolicyBundleSize)
+	}
+
+	etag := resp.Header.Get("ETag")
+	b, err := readBundle(bundle.NewTarballLoaderWithBaseURL(bytes.NewReader(content), h.url), h.verification, etag)
+	if err != nil {
+		return nil, err
+	}
+
+	h.etag = etag
+	return b, nil
+}
+
+// readBundle reads and verifies the bundle, the Rego test files are removed
+func readBundle(loader bundle.DirectoryLoader, verification *bundle.VerificationConfig, etag string) (*bundle.Bundle, error) {
+	b, err := bundle.NewCustomReader(loader).
+		WithBundleVerificationConfig(verification).
+		WithBundleEtag(etag).
+		WithSizeLimitBytes(MaxPolicyBundleSize).
+		Read()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := b.Modules[:0]
+	for _, m := range b.Modules {
+		if !strings.HasSuffix(m.Path, "_test.rego") {
+			modules = append(modules, m)
+		}
+	}
+	b.Modules = modules
+	return &b, nil
+}
+
+// bundleRevision is the revision of the manifest or the hash of the modules and data if the manifest has no revision
+func bundleRevision(b *bundle.Bundle) (string, error) {
+	if b.Manifest.Revision != "" {
+		return b.Manifest.Revision, nil
+	}
+
+	modules := make(map[string][]byte, len(b.Modules))
+	for _, m := range b.Modules {
+		modules[m.Path] = m.Raw
+	}
+	return hashPolicies(modules, b.Data)
+}
+
+// NewOPABundlePolicyEnforcementPoint activates the bundle of the source, the first bundle has to be valid
+func NewOPABundlePolicyEnforcementPoint(ctx context.Context, source PolicyBundleSource) (*OPAPolicyEnforcementPoint, error) {
+	p := &OPAPolicyEnforcementPoint{
+		source: source,
+	}
+
+	if _, err := p.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if p.active.Load() == nil {
+		return nil, NoPolicyBundleError
+	}
+	return p, nil
+}
+
+// Reload fetches the bundle of the source and activates it if its revision differs from the active revision.
+// It reports whether a new revision was activated. If the bundle can not be fetched, verified or compiled,
+// the active revision keeps deciding the requests and the error is returned.
+func (p *OPAPolicyEnforcementPoint) Reload(ctx context.Context) (bool, error) {
+	if p.source == nil {
+		return false, nil
+	}
+
+	p.reload.Lock()
+	defer p.reload.Unlock()
+
+	b, err := p.source.Fetch(ctx)
+	if err != nil || b == nil {
+		return false, err
+	}
+
+	revision, err := bundleRevision(b)
+	if err != nil {
+		return false, err
+	}
+
+	if active, ok := p.active.Load().(*opaPolicy); ok && active.revision == revision {
+		return false, nil
+	}
+
+	policy, err := prepareOPAPolicy(ctx, revision, rego.ParsedBundle("policies", b))
+	if err != nil {
+		return false, fmt.Errorf("policy bundle revision %s could not be compiled: %w", revision, err)
+	}
+
+	p.activate(policy)
+	return true, nil
+}
+
+// Watch reloads the bundle every interval until the context is done, failed reloads are passed to onError
+func (p *OPAPolicyEnforcementPoint) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Reload(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package infrastructure_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const testBundleKeyID = "site"
+
+// denyAllPolicy replaces the opinion policies in the bundles of the reload tests
+const denyAllPolicy = "package site.opinions\n\ndefault allow = false\n"
+
+// testBundleKey is shared by the tests because generating RSA keys is slow
+var testBundleKey = struct {
+	once    sync.Once
+	private *rsa.PrivateKey
+}{}
+
+func bundleKeys(t *testing.T) (privatePEM string, publicPEM []byte) {
+	t.Helper()
+	testBundleKey.once.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		testBundleKey.private = key
+	})
+
+	public, err := x509.MarshalPKIXPublicKey(&testBundleKey.private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testBundleKey.private)}))
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+	return privatePEM, publicPEM
+}
+
+// buildBundle returns a tar.gz bundle with the policy, it is signed with the test key if sign is set
+func buildBundle(t *testing.T, revision string, policy string, sign bool) []byte {
+	t.Helper()
+	parsed, err := ast.ParseModule("opinions.rego", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: revision},
+		Data:     map[string]any{},
+		Modules:  []bundle.ModuleFile{{URL: "/opinions.rego", Path: "/opinions.rego", Raw: []byte(policy), Parsed: parsed}},
+	}
+
+	if sign {
+		private, _ := bundleKeys(t)
+		if err := b.GenerateSignature(bundle.NewSigningConfig(private, "RS256", ""), testBundleKeyID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readTestPolicy(t *testing.T) string {
+	t.Helper()
+	policy, err := os.ReadFile(filepath.Join(testPolicies, "opinions.rego"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(policy)
+}
+
+func writeBundle(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewOPABundlePolicyEnforcementPoint_verification(t *testing.T) {
+	t.Parallel()
+	_, public := bundleKeys(t)
+	policy := readTestPolicy(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, err := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		signed       bool
+		verification *bundle.VerificationConfig
+		wantErr      bool
+	}{
+		{
+			name:         "Should load a bundle with a valid signature",
+			signed:       true,
+			verification: infrastructure.NewPolicyBundleVerification(testBundleKeyID, "RS256", public),
+		},
+		{
+			name:   "Should load an unsigned bundle without verification",
+			signed: false,
+		},
+		{
+			name:         "Should reject an unsigned bundle if a key is configured",
+			signed:       false,
+			verification: infrastructure.NewPolicyBundleVerification(testBundleKeyID, "RS256", public),
+			wantErr:      true,
+		},
+		{
+			name:         "Should reject a bundle signed with another key",
+			signed:       true,
+			verification: infrastructure.NewPolicyBundleVerification(testBundleKeyID, "RS256", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherPublic})),
+			wantErr:      true,
+		},
+		{
+			name:    "Should reject a signed bundle without verification",
+			signed:  true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+			writeBundle(t, path, buildBundle(t, "v1", policy, tt.signed))
+
+			pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleSource(path, tt.verification))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "v1", pep.PolicyRevision())
+			assert.NoError(t, pep.RequestAccessForUser(context.Background(), application.AccessRequest{User: "123", Action: application.ActionCreateOpinion}))
+		})
+	}
+}
+
+func TestOPAPolicyEnforcementPoint_Reload(t *testing.T) {
+	t.Parallel()
+	_, public := bundleKeys(t)
+	verification := infrastructure.NewPolicyBundleVerification(testBundleKeyID, "RS256", public)
+	request := application.AccessRequest{User: "123", Action: application.ActionCreateOpinion}
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeBundle(t, path, buildBundle(t, "v1", readTestPolicy(t), true))
+
+	pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleFile(path, verification))
+	if err != nil {
+		t.Fatalf("NewOPABundlePolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	reloaded, err := pep.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "the active revision is not activated again")
+
+	writeBundle(t, path, buildBundle(t, "v2", denyAllPolicy, false))
+	reloaded, err = pep.Reload(context.Background())
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, "v1", pep.PolicyRevision())
+	assert.NoError(t, pep.RequestAccessForUser(context.Background(), request))
+
+	writeBundle(t, path, buildBundle(t, "v3", "package site.opinions\n\nallow {\n\tundefined_function(input.user)\n}\n", true))
+	_, err = pep.Reload(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "v1", pep.PolicyRevision())
+
+	writeBundle(t, path, buildBundle(t, "v4", denyAllPolicy, true))
+	reloaded, err = pep.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "v4", pep.PolicyRevision())
+	assert.ErrorIs(t, pep.RequestAccessForUser(context.Background(), request), application.AccessDeniedError)
+}
+
+func TestPolicyBundleHTTP_Fetch(t *testing.T) {
+	t.Parallel()
+	_, public := bundleKeys(t)
+	verification := infrastructure.NewPolicyBundleVerification(testBundleKeyID, "RS256", public)
+	request := application.AccessRequest{User: "123", Action: application.ActionCreateOpinion}
+
+	var mu sync.Mutex
+	content, etag := buildBundle(t, "v1", readTestPolicy(t), true), `"v1"`
+	var notModified int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleSource(server.URL, verification))
+	if err != nil {
+		t.Fatalf("NewOPABundlePolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+	assert.Equal(t, "v1", pep.PolicyRevision())
+
+	reloaded, err := pep.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+	mu.Lock()
+	assert.Equal(t, 1, notModified)
+	mu.Unlock()
+
+	mu.Lock()
+	content, etag = buildBundle(t, "v2", denyAllPolicy, true), `"v2"`
+	mu.Unlock()
+
+	reloaded, err = pep.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "v2", pep.PolicyRevision())
+	assert.ErrorIs(t, pep.RequestAccessForUser(context.Background(), request), application.AccessDeniedError)
+}
+
+func TestPolicyBundleHTTP_Fetch_error(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	_, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleSource(server.URL, nil))
+	assert.Error(t, err)
+}
+
+func TestPolicyBundleHTTP_Fetch_too_large(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, io.LimitReader(zeros{}, infrastructure.MaxPolicyBundleSize+1))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := infrastructure.NewPolicyBundleSource(server.URL, nil).Fetch(context.Background())
+	assert.ErrorContains(t, err, "exceeds")
+}
+
+// zeros is an endless reader of zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestPolicyBundleFile_directory(t *testing.T) {
+	t.Parallel()
+	pep, err := infrastructure.NewOPABundlePolicyEnforcementPoint(context.Background(), infrastructure.NewPolicyBundleSource(testPolicies, nil))
+	if err != nil {
+		t.Fatalf("NewOPABundlePolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	files, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	assert.Len(t, pep.PolicyRevision(), 64, "bundles without manifest revision are identified by their hash")
+	for _, tt := range accessCases {
+		assert.Equal(t, files.RequestAccessForUser(context.Background(), tt.request), pep.RequestAccessForUser(context.Background(), tt.request), tt.name)
+	}
+}
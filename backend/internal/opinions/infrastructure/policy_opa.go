@@ -0,0 +1,254 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OPAPolicyQuery is the rule of the policies which decides an application.AccessRequest
+const OPAPolicyQuery = "data.site.opinions.allow"
+
+// OPAVisibilityQuery is partially evaluated with the OPAVisibilityUnknown to get the opinions a user may list
+const OPAVisibilityQuery = "data.site.opinions.visible == true"
+
+// OPAVisibilityUnknown is the opinion document of the visibility rules, e.g. data.opinions.status
+const OPAVisibilityUnknown = "data.opinions"
+
+// NewOPAPolicyEnforcementPoint compiles the Rego policies and data documents of the given files or directories.
+// Rego test files are ignored.
+func NewOPAPolicyEnforcementPoint(ctx context.Context, paths ...string) (*OPAPolicyEnforcementPoint, error) {
+	result, err := loader.NewFileLoader().Filtered(paths, ignoreRegoTests)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler, err := result.Compiler()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := result.Store()
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := policyRevision(result)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := prepareOPAPolicy(ctx, revision, rego.Compiler(compiler), rego.Store(store))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &OPAPolicyEnforcementPoint{}
+	p.activate(policy)
+	return p, nil
+}
+
+// OPAPolicyEnforcementPoint evaluates the Rego policies in process.
+// Policies loaded from a PolicyBundleSource are replaced by Reload without interrupting running evaluations.
+type OPAPolicyEnforcementPoint struct {
+	// active holds the *opaPolicy which decides new requests
+	active atomic.Value
+
+	// reload serializes the reloads of the source
+	reload sync.Mutex
+	source PolicyBundleSource
+
+	// cache keeps the visibilities of the active revision, it is optional
+	cache *PartialEvalCache
+}
+
+// CachePartialEvaluations keeps the results of OpinionVisibility in the cache until a new revision is activated.
+// It has to be called before the first evaluation.
+func (p *OPAPolicyEnforcementPoint) CachePartialEvaluations(cache *PartialEvalCache) *OPAPolicyEnforcementPoint {
+	p.cache = cache
+	return p
+}
+
+// opaPolicy is a compiled revision of the policies
+type opaPolicy struct {
+	query      rego.PreparedEvalQuery
+	visibility rego.PreparedPartialQuery
+	revision   string
+}
+
+// prepareOPAPolicy prepares the queries of the policies which are loaded by the options
+func prepareOPAPolicy(ctx context.Context, revision string, options ...func(r *rego.Rego)) (*opaPolicy, error) {
+	query, err := rego.New(append(options, rego.Query(OPAPolicyQuery))...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility, err := rego.New(append(options, rego.Query(OPAVisibilityQuery), rego.Unknowns([]string{OPAVisibilityUnknown}))...).PrepareForPartial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &opaPolicy{query: query, visibility: visibility, revision: revision}, nil
+}
+
+func (p *OPAPolicyEnforcementPoint) policy() *opaPolicy {
+	return p.active.Load().(*opaPolicy)
+}
+
+func (p *OPAPolicyEnforcementPoint) activate(policy *opaPolicy) {
+	p.active.Store(policy)
+	if p.cache != nil {
+		p.cache.Invalidate()
+	}
+}
+
+// PolicyRevision is the revision of the bundle manifest or the SHA-256 of the active policies and data documents
+func (p *OPAPolicyEnforcementPoint) PolicyRevision() string {
+	return p.policy().revision
+}
+
+func (p *OPAPolicyEnforcementPoint) RequestAccessForUser(ctx context.Context, request application.AccessRequest) error {
+	if request.User == "" {
+		return application.UnauthenticatedError
+	}
+
+	results, err := p.policy().query.Eval(ctx, rego.EvalInput(policyInput(request)))
+	if err != nil {
+		return err
+	}
+
+	if !results.Allowed() {
+		return application.AccessDeniedError
+	}
+	return nil
+}
+
+// OpinionVisibility partially evaluates the visibility rules with the opinion document as unknown.
+// The remaining comparisons of the opinion document become the conditions of the application.OpinionVisibility,
+// the results are kept in the PartialEvalCache if one is set.
+func (p *OPAPolicyEnforcementPoint) OpinionVisibility(ctx context.Context, request application.AccessRequest) (application.OpinionVisibility, error) {
+	if request.User == "" {
+		return application.OpinionVisibility{}, application.UnauthenticatedError
+	}
+
+	policy, input := p.policy(), policyInput(request)
+	if p.cache == nil {
+		return policy.opinionVisibility(ctx, input)
+	}
+
+	// the key contains the revision, so results of a replaced revision are never returned
+	key, err := partialEvalKey(policy.revision, OPAVisibilityQuery, input)
+	if err != nil {
+		return application.OpinionVisibility{}, err
+	}
+
+	if visibility, denied, ok := p.cache.get(key); ok {
+		if denied {
+			return application.OpinionVisibility{}, application.AccessDeniedError
+		}
+		return visibility, nil
+	}
+
+	visibility, err := policy.opinionVisibility(ctx, input)
+	switch {
+	case err == nil:
+		p.cache.put(key, visibility, false)
+	case errors.Is(err, application.AccessDeniedError):
+		p.cache.put(key, visibility, true)
+	}
+	return visibility, err
+}
+
+func (o *opaPolicy) opinionVisibility(ctx context.Context, input map[string]any) (application.OpinionVisibility, error) {
+	queries, err := o.visibility.Partial(ctx, rego.EvalInput(input))
+	if err != nil {
+		return application.OpinionVisibility{}, err
+	}
+
+	// support modules are created for rules which can not be inlined, they can not be expressed as conditions
+	if len(queries.Support) > 0 {
+		return application.OpinionVisibility{}, application.InvalidOpinionConditionError.WithCause(fmt.Errorf("visibility rules of %s can not be inlined", OPAVisibilityQuery))
+	}
+
+	filters, err := authorization.ParsePartialQueries(*queries)
+	if errors.Is(err, authorization.AccessDeniedError) {
+		return application.OpinionVisibility{}, application.AccessDeniedError
+	}
+	if err != nil {
+		return application.OpinionVisibility{}, application.InvalidOpinionConditionError.WithCause(err)
+	}
+
+	visibility := application.OpinionVisibility{Clauses: make([][]application.OpinionCondition, 0, len(filters))}
+	for _, filter := range filters {
+		clause := make([]application.OpinionCondition, 0, len(filter))
+		for _, e := range filter {
+			clause = append(clause, application.OpinionCondition{Field: e.FieldName, Operator: e.Operator, Value: e.Value})
+		}
+		visibility.Clauses = append(visibility.Clauses, clause)
+	}
+	return visibility, nil
+}
+
+// policyInput is the input document of the policies, all fields are set so the policies do not have to handle missing fields
+func policyInput(request application.AccessRequest) map[string]any {
+	roles := make([]string, 0, len(request.Roles))
+	for _, r := range request.Roles {
+		roles = append(roles, string(r))
+	}
+
+	return map[string]any{
+		"user":    string(request.User),
+		"roles":   roles,
+		"action":  request.Action,
+		"opinion": string(request.Opinion),
+		"owner":   string(request.Owner),
+	}
+}
+
+// policyRevision hashes the loaded modules and data documents
+func policyRevision(result *loader.Result) (string, error) {
+	modules := make(map[string][]byte, len(result.Modules))
+	for name, module := range result.Modules {
+		modules[name] = module.Raw
+	}
+	return hashPolicies(modules, result.Documents)
+}
+
+// hashPolicies hashes the sources of the modules in the order of their names and the data documents
+func hashPolicies(modules map[string][]byte, documents map[string]any) (string, error) {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write(modules[name])
+	}
+
+	// maps are encoded with sorted keys
+	encoded, err := json.Marshal(documents)
+	if err != nil {
+		return "", err
+	}
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ignoreRegoTests is a loader.Filter which skips the Rego test files
+var ignoreRegoTests loader.Filter = func(_ string, info fs.FileInfo, _ int) bool {
+	return !info.IsDir() && strings.HasSuffix(info.Name(), "_test.rego")
+}
@@ -0,0 +1,319 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicies = "../../authorization/policies"
+
+// accessCases are decided equally by the Rego policies and the AuthenticatedUsersPolicyEnforcementPoint
+var accessCases = []struct {
+	name    string
+	request application.AccessRequest
+	wantErr error
+}{
+	{
+		name:    "Should deny unauthenticated users",
+		request: application.AccessRequest{Action: application.ActionCreateOpinion},
+		wantErr: application.UnauthenticatedError,
+	},
+	{
+		name:    "Should permit user actions to users",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionCreateOpinion},
+	},
+	{
+		name:    "Should permit the owner to delete the opinion",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionDeleteOpinion, Owner: "123"},
+	},
+	{
+		name:    "Should deny users to delete opinions without owner",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionDeleteOpinion},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit the owner to edit the opinion",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionEditOpinion, Owner: "123"},
+	},
+	{
+		name:    "Should deny admins to edit opinions of others",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleAdmin}, Action: application.ActionEditOpinion, Owner: "456"},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should deny users to edit opinions without owner",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionEditOpinion},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should deny users to delete opinions of others",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionDeleteOpinion, Owner: "456"},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should deny moderators to delete opinions of others",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleModerator}, Action: application.ActionDeleteOpinion, Owner: "456"},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit admins to delete any opinion",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleAdmin}, Action: application.ActionDeleteOpinion, Owner: "456"},
+	},
+	{
+		name:    "Should deny users to hide opinions of others",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionHideOpinion, Owner: "456"},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit moderators to hide any opinion",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleModerator}, Action: application.ActionHideOpinion, Owner: "456"},
+	},
+	{
+		name:    "Should deny users to approve opinions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionApproveOpinion},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit admins to approve opinions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleAdmin}, Action: application.ActionApproveOpinion},
+	},
+	{
+		name:    "Should deny moderators to bulk delete opinions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleModerator}, Action: application.ActionBulkDeleteOpinions},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit admins to bulk delete opinions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser, application.RoleAdmin}, Action: application.ActionBulkDeleteOpinions},
+	},
+	{
+		name:    "Should deny moderators to list decisions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleModerator}, Action: application.ActionListDecisions},
+		wantErr: application.AccessDeniedError,
+	},
+	{
+		name:    "Should permit admins to list decisions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleAdmin}, Action: application.ActionListDecisions},
+	},
+	{
+		name:    "Should deny unknown actions",
+		request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleAdmin}, Action: "Unknown"},
+		wantErr: application.AccessDeniedError,
+	},
+}
+
+func TestOPAPolicyEnforcementPoint_RequestAccessForUser(t *testing.T) {
+	t.Parallel()
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	for _, tt := range accessCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := pep.RequestAccessForUser(context.Background(), tt.request)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAuthenticatedUsersPolicyEnforcementPoint_RequestAccessForUser(t *testing.T) {
+	t.Parallel()
+	pep := infrastructure.AuthenticatedUsersPolicyEnforcementPoint{}
+
+	for _, tt := range accessCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := pep.RequestAccessForUser(context.Background(), tt.request)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewOPAPolicyEnforcementPoint_invalid_path(t *testing.T) {
+	t.Parallel()
+	_, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), t.TempDir()+"/does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestOPAPolicyEnforcementPoint_PolicyRevision(t *testing.T) {
+	t.Parallel()
+	first, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	second, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "allow.rego"), []byte("package site.opinions\n\nallow = true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	other, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	assert.Len(t, first.PolicyRevision(), 64)
+	assert.Equal(t, first.PolicyRevision(), second.PolicyRevision())
+	assert.NotEqual(t, first.PolicyRevision(), other.PolicyRevision())
+	assert.NoError(t, other.RequestAccessForUser(context.Background(), application.AccessRequest{User: "123", Action: "Unknown"}))
+}
+
+func TestOPAPolicyEnforcementPoint_OpinionVisibility(t *testing.T) {
+	t.Parallel()
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), testPolicies)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	tests := []struct {
+		name    string
+		request application.AccessRequest
+		want    application.OpinionVisibility
+		wantErr error
+	}{
+		{
+			name:    "Should show the published and the own opinions to users",
+			request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleUser}, Action: application.ActionListOpinions},
+			want: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+				{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: "123"}},
+				{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+			}},
+		},
+		{
+			name:    "Should show all opinions to moderators",
+			request: application.AccessRequest{User: "123", Roles: []application.Role{application.RoleModerator}, Action: application.ActionListOpinions},
+			want:    application.AllOpinionsVisible,
+		},
+		{
+			name:    "Should deny unauthenticated users",
+			request: application.AccessRequest{Action: application.ActionListOpinions},
+			wantErr: application.UnauthenticatedError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := pep.OpinionVisibility(context.Background(), tt.request)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want.Unrestricted(), got.Unrestricted())
+			if !tt.want.Unrestricted() {
+				assert.ElementsMatch(t, tt.want.Clauses, got.Clauses)
+			}
+		})
+	}
+}
+
+func TestOPAPolicyEnforcementPoint_OpinionVisibility_denied(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "opinions.rego"), []byte("package site.opinions\n\nallow = true\n\nvisible {\n\tinput.user == \"admin\"\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	_, err = pep.OpinionVisibility(context.Background(), application.AccessRequest{User: "123", Action: application.ActionListOpinions})
+	assert.ErrorIs(t, err, application.AccessDeniedError)
+
+	got, err := pep.OpinionVisibility(context.Background(), application.AccessRequest{User: "admin", Action: application.ActionListOpinions})
+	assert.NoError(t, err)
+	assert.True(t, got.Unrestricted())
+}
+
+func TestOPAPolicyEnforcementPoint_OpinionVisibility_list(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	policy := "package site.opinions\n\nimport future.keywords.in\n\nallow = true\n\n" +
+		"visible {\n\tdata.opinions.status in [\"published\", \"hidden\"]\n\tdata.opinions.ownerId != null\n}\n\n" +
+		"visible {\n\tdata.opinions.ownerId == input.user\n\tdata.opinions.status != true\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "opinions.rego"), []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pep, err := infrastructure.NewOPAPolicyEnforcementPoint(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("NewOPAPolicyEnforcementPoint() returned error %s, but no error is expected", err)
+	}
+
+	sqlite, err := infrastructure.NewOpinionsRepositorySQLite(filepath.Join(t.TempDir(), "opinions.db"))
+	if err != nil {
+		t.Fatalf("NewOpinionsRepositorySQLite() returned error %s, but no error is expected", err)
+	}
+	t.Cleanup(func() { _ = sqlite.Close() })
+
+	repositories := map[string]application.Repository{
+		"memory": infrastructure.NewOpinionsRepositoryMemory(),
+		"sqlite": sqlite,
+	}
+	for name, repository := range repositories {
+		repository := repository
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			alice := application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}
+			bob := application.AuthenticatedUser{Id: "bob", Roles: []application.Role{application.RoleUser}}
+			service := application.NewOpinionService(pep, repository, infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+				infrastructure.RandomIdService{}, infrastructure.SystemTimeService{}, application.WithVisibilityPolicy(pep))
+
+			published, err := service.CreateOpinionCommand(ctx, alice, application.OpinionCreateDTO{Statement: "lists are supported"})
+			assert.NoError(t, err)
+			rejected, err := service.CreateOpinionCommand(ctx, bob, application.OpinionCreateDTO{Statement: "this one gets rejected"})
+			assert.NoError(t, err)
+			_, err = service.RejectOpinionCommand(ctx, alice, rejected.ID)
+			assert.NoError(t, err)
+			hidden, err := service.CreateOpinionCommand(ctx, bob, application.OpinionCreateDTO{Statement: "this one gets hidden"})
+			assert.NoError(t, err)
+			_, err = service.HideOpinionCommand(ctx, alice, hidden.ID)
+			assert.NoError(t, err)
+
+			tests := []struct {
+				user application.AuthenticatedUser
+				want []application.OpinionId
+			}{
+				{user: alice, want: []application.OpinionId{published.ID, hidden.ID}},
+				{user: bob, want: []application.OpinionId{published.ID, rejected.ID, hidden.ID}},
+			}
+			for _, tt := range tests {
+				got, err := service.ListOpinionsQuery(ctx, tt.user)
+				if !assert.NoError(t, err) {
+					continue
+				}
+				ids := make([]application.OpinionId, 0, len(got))
+				for _, opinion := range got {
+					ids = append(ids, opinion.ID)
+				}
+				assert.ElementsMatch(t, tt.want, ids, "opinions visible to %s", tt.user.Id)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is the minimal time between two removals of idle buckets
+const rateLimitSweepInterval = time.Minute
+
+// NewRateLimitStoreMemory creates an empty store, the buckets are lost on restart and not shared between instances
+func NewRateLimitStoreMemory() *RateLimitStoreMemory {
+	return &RateLimitStoreMemory{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// RateLimitStoreMemory is a thread safe application.RateLimitStore
+type RateLimitStoreMemory struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full is the point in time at which the bucket is refilled completely
+	full time.Time
+}
+
+func (s *RateLimitStoreMemory) Take(ctx context.Context, key string, limit application.RateLimit, now time.Time) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	// without a refill an empty bucket is never reset, also not by the sweep
+	if err := limit.Validate(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(limit.Refill)
+		if b.tokens > float64(limit.Burst) {
+			b.tokens = float64(limit.Burst)
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(limit.Refill)), nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) * float64(limit.Refill)))
+	return 0, nil
+}
+
+// sweep removes the buckets which are refilled completely, they behave like new buckets
+func (s *RateLimitStoreMemory) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len returns the count of buckets which are currently tracked
+func (s *RateLimitStoreMemory) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
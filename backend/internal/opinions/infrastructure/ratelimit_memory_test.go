@@ -0,0 +1,71 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStoreMemory_Take(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	limit := application.RateLimit{Burst: 2, Refill: 10 * time.Second}
+	now := time.Now()
+
+	store := infrastructure.NewRateLimitStoreMemory()
+
+	take := func(key string, at time.Time) time.Duration {
+		retryAfter, err := store.Take(ctx, key, limit, at)
+		assert.NoError(t, err)
+		return retryAfter
+	}
+
+	assert.Zero(t, take("a", now))
+	assert.Zero(t, take("a", now))
+	assert.Equal(t, 10*time.Second, take("a", now))
+	assert.Equal(t, 4*time.Second, take("a", now.Add(6*time.Second)))
+
+	// other keys have their own bucket
+	assert.Zero(t, take("b", now))
+
+	assert.Zero(t, take("a", now.Add(10*time.Second)))
+	assert.Equal(t, 10*time.Second, take("a", now.Add(10*time.Second)))
+
+	// the bucket never holds more than the burst
+	assert.Zero(t, take("a", now.Add(time.Hour)))
+	assert.Zero(t, take("a", now.Add(time.Hour)))
+	assert.NotZero(t, take("a", now.Add(time.Hour)))
+}
+
+func TestRateLimitStoreMemory_sweep(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	limit := application.RateLimit{Burst: 1, Refill: time.Second}
+	now := time.Now()
+
+	store := infrastructure.NewRateLimitStoreMemory()
+	_, err := store.Take(ctx, "a", limit, now)
+	assert.NoError(t, err)
+	_, err = store.Take(ctx, "b", limit, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestRateLimitStoreMemory_invalidLimit(t *testing.T) {
+	t.Parallel()
+	_, err := infrastructure.NewRateLimitStoreMemory().Take(context.Background(), "a", application.RateLimit{Burst: 1}, time.Now())
+	assert.Error(t, err, "a bucket without refill is never reset")
+}
+
+func TestRateLimitStoreMemory_canceledContext(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := infrastructure.NewRateLimitStoreMemory().Take(ctx, "a", application.RateLimit{Burst: 1, Refill: time.Second}, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,527 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewOpinionsRepositoryMemory creates an empty repository which keeps all data in the process memory.
+// It is meant for tests and local development, all data is lost on restart.
+func NewOpinionsRepositoryMemory() *OpinionsRepositoryMemory {
+	return &OpinionsRepositoryMemory{
+		opinions: make(map[application.OpinionId]application.Opinion),
+		votes:    make(map[application.OpinionId]map[application.UserId]application.Vote),
+		reports:  make(map[application.OpinionId]map[application.UserId]application.Report),
+		keys:     make(map[idempotencyKey]application.IdempotencyRecord),
+	}
+}
+
+// OpinionsRepositoryMemory is a thread safe application.Repository with the same semantics as the SQL repositories
+type OpinionsRepositoryMemory struct {
+	mu       sync.RWMutex
+	opinions map[application.OpinionId]application.Opinion
+	votes    map[application.OpinionId]map[application.UserId]application.Vote
+	reports  map[application.OpinionId]map[application.UserId]application.Report
+	keys     map[idempotencyKey]application.IdempotencyRecord
+}
+
+type idempotencyKey struct {
+	user application.UserId
+	key  string
+}
+
+func (o *OpinionsRepositoryMemory) CreateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, exists := o.opinions[opinion.ID]; exists {
+		return application.OpinionAlreadyExistsError
+	}
+
+	o.opinions[opinion.ID] = opinion
+	o.votes[opinion.ID] = make(map[application.UserId]application.Vote)
+	o.reports[opinion.ID] = make(map[application.UserId]application.Report)
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) ListOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ []application.Opinion, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	opinions := make([]application.Opinion, 0, len(o.opinions))
+	for _, opinion := range o.opinions {
+		visible, err := visibility.Matches(opinion)
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			opinions = append(opinions, opinion)
+		}
+	}
+
+	sortOpinions(opinions)
+	return opinions, nil
+}
+
+// sortOpinions orders the opinions like the SQL repositories by creation time and id
+func (o *OpinionsRepositoryMemory) ListOpinionsPage(ctx context.Context, visibility application.OpinionVisibility, page application.OpinionPage) ([]application.Opinion, error) {
+	opinions, err := o.ListOpinions(ctx, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	start := sort.Search(len(opinions), func(i int) bool {
+		return page.After.Precedes(opinions[i])
+	})
+	opinions = opinions[start:]
+	if len(opinions) > page.Limit {
+		opinions = opinions[:page.Limit]
+	}
+	return opinions, nil
+}
+
+func (o *OpinionsRepositoryMemory) CountOpinions(ctx context.Context, visibility application.OpinionVisibility) (int, error) {
+	opinions, err := o.ListOpinions(ctx, visibility)
+	return len(opinions), err
+}
+
+func sortOpinions(opinions []application.Opinion) {
+	sort.Slice(opinions, func(i, j int) bool {
+		if opinions[i].CreatedAt.Equal(opinions[j].CreatedAt) {
+			return opinions[i].ID < opinions[j].ID
+		}
+		return opinions[i].CreatedAt.Before(opinions[j].CreatedAt)
+	})
+}
+
+func (o *OpinionsRepositoryMemory) GetOpinion(ctx context.Context, id application.OpinionId) (_ application.Opinion, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return application.Opinion{}, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	opinion, exists := o.opinions[id]
+	if !exists {
+		return application.Opinion{}, application.OpinionNotFoundError
+	}
+	return opinion, nil
+}
+
+// UpdateOpinion replaces the statement and the status if the stored version matches, owner and creation time are immutable
+func (o *OpinionsRepositoryMemory) UpdateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stored, exists := o.opinions[opinion.ID]
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+	if stored.Version != opinion.Version {
+		return application.OpinionVersionConflictError
+	}
+
+	stored.Statement = opinion.Statement
+	stored.Status = opinion.Status
+	stored.Version++
+	o.opinions[opinion.ID] = stored
+	return nil
+}
+
+// DeleteOpinion removes the opinion and all of its votes and reports
+func (o *OpinionsRepositoryMemory) DeleteOpinion(ctx context.Context, id application.OpinionId) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, exists := o.opinions[id]; !exists {
+		return application.OpinionNotFoundError
+	}
+
+	delete(o.opinions, id)
+	delete(o.votes, id)
+	delete(o.reports, id)
+	return nil
+}
+
+// DeleteOpinions removes the matching opinions and all of their votes and reports
+func (o *OpinionsRepositoryMemory) DeleteOpinions(ctx context.Context, filter application.OpinionFilter) (_ []application.OpinionId, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	matched := make([]application.Opinion, 0)
+	for _, opinion := range o.opinions {
+		if filter.Matches(opinion) {
+			matched = append(matched, opinion)
+		}
+	}
+	sortOpinions(matched)
+
+	ids := make([]application.OpinionId, 0, len(matched))
+	for _, opinion := range matched {
+		delete(o.opinions, opinion.ID)
+		delete(o.votes, opinion.ID)
+		delete(o.reports, opinion.ID)
+		ids = append(ids, opinion.ID)
+	}
+	return ids, nil
+}
+
+func (o *OpinionsRepositoryMemory) CreateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	votes, exists := o.votes[vote.Opinion]
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+
+	if _, voted := votes[vote.Voter]; voted {
+		return application.VoteAlreadyExistsError
+	}
+
+	votes[vote.Voter] = vote
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) CountVotes(ctx context.Context, visibility application.OpinionVisibility, opinions []application.OpinionId) (_ map[application.OpinionId]application.VoteCount, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	counts := make(map[application.OpinionId]application.VoteCount)
+	for _, id := range opinions {
+		if len(o.votes[id]) == 0 {
+			continue
+		}
+		visible, err := visibility.Matches(o.opinions[id])
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			continue
+		}
+		var count application.VoteCount
+		for _, vote := range o.votes[id] {
+			if vote.Agreement {
+				count.Agreements++
+			} else {
+				count.Disagreements++
+			}
+		}
+		counts[id] = count
+	}
+	return counts, nil
+}
+
+func (o *OpinionsRepositoryMemory) ListVotes(ctx context.Context) (_ []application.Vote, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	list := make([]application.Vote, 0)
+	for _, votes := range o.votes {
+		for _, vote := range votes {
+			list = append(list, vote)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		if list[i].Opinion != list[j].Opinion {
+			return list[i].Opinion < list[j].Opinion
+		}
+		return list[i].Voter < list[j].Voter
+	})
+	return list, nil
+}
+
+func (o *OpinionsRepositoryMemory) GetVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (_ application.Vote, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return application.Vote{}, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	vote, exists := o.votes[opinion][voter]
+	if !exists {
+		return application.Vote{}, application.VoteNotFoundError
+	}
+	return vote, nil
+}
+
+// UpdateVote replaces the agreement if the stored version matches
+func (o *OpinionsRepositoryMemory) UpdateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stored, exists := o.votes[vote.Opinion][vote.Voter]
+	if !exists {
+		return application.VoteNotFoundError
+	}
+	if stored.Version != vote.Version {
+		return application.VoteVersionConflictError
+	}
+
+	stored.Agreement = vote.Agreement
+	stored.UpdatedAt = vote.UpdatedAt
+	stored.Version++
+	o.votes[vote.Opinion][vote.Voter] = stored
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) DeleteVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, exists := o.votes[opinion][voter]; !exists {
+		return application.VoteNotFoundError
+	}
+
+	delete(o.votes[opinion], voter)
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) CreateReport(ctx context.Context, report application.Report) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	reports, exists := o.reports[report.Opinion]
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+
+	if _, reported := reports[report.Reporter]; reported {
+		return application.ReportAlreadyExistsError
+	}
+
+	reports[report.Reporter] = report
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) ListOpenReports(ctx context.Context) (_ []application.Report, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	list := make([]application.Report, 0)
+	for _, reports := range o.reports {
+		for _, report := range reports {
+			if report.Resolution == "" {
+				list = append(list, report)
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		if list[i].Opinion != list[j].Opinion {
+			return list[i].Opinion < list[j].Opinion
+		}
+		return list[i].Reporter < list[j].Reporter
+	})
+	return list, nil
+}
+
+func (o *OpinionsRepositoryMemory) CountOpenReports(ctx context.Context, opinion application.OpinionId) (_ int, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var count int
+	for _, report := range o.reports[opinion] {
+		if report.Resolution == "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (o *OpinionsRepositoryMemory) ResolveReports(ctx context.Context, opinion application.OpinionId, resolution application.ReportResolution, resolvedAt time.Time) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var resolved int
+	for reporter, report := range o.reports[opinion] {
+		if report.Resolution != "" {
+			continue
+		}
+		report.Resolution = resolution
+		report.ResolvedAt = resolvedAt
+		o.reports[opinion][reporter] = report
+		resolved++
+	}
+
+	if resolved == 0 {
+		return application.ReportNotFoundError
+	}
+	return nil
+}
+
+// CreateIdempotencyRecord inserts the record or replaces an expired record of the same user and key
+func (o *OpinionsRepositoryMemory) CreateIdempotencyRecord(ctx context.Context, record application.IdempotencyRecord, now time.Time) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	k := idempotencyKey{user: record.User, key: record.Key}
+	if stored, exists := o.keys[k]; exists && stored.ExpiresAt.After(now) {
+		return application.IdempotencyRecordAlreadyExistsError
+	}
+
+	o.keys[k] = record
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) GetIdempotencyRecord(ctx context.Context, user application.UserId, key string, now time.Time) (_ application.IdempotencyRecord, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return application.IdempotencyRecord{}, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	record, exists := o.keys[idempotencyKey{user: user, key: key}]
+	if !exists || !record.ExpiresAt.After(now) {
+		return application.IdempotencyRecord{}, application.IdempotencyRecordNotFoundError
+	}
+	return record, nil
+}
+
+func (o *OpinionsRepositoryMemory) DeleteIdempotencyRecord(ctx context.Context, user application.UserId, key string) (err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	k := idempotencyKey{user: user, key: key}
+	if _, exists := o.keys[k]; !exists {
+		return application.IdempotencyRecordNotFoundError
+	}
+
+	delete(o.keys, k)
+	return nil
+}
+
+func (o *OpinionsRepositoryMemory) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (_ int64, err error) {
+	defer mapError(&err)
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var deleted int64
+	for k, record := range o.keys {
+		if !record.ExpiresAt.After(now) {
+			delete(o.keys, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,15 @@
+package infrastructure_test
+
+import (
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/repositorytest"
+	"testing"
+)
+
+func TestOpinionsRepositoryMemory_contract(t *testing.T) {
+	t.Parallel()
+	repositorytest.Run(t, func(t *testing.T) application.Repository {
+		return infrastructure.NewOpinionsRepositoryMemory()
+	})
+}
@@ -0,0 +1,495 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/authorization"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// migrationLockId is the postgres advisory lock which serializes the migrations of concurrently starting instances
+const migrationLockId = 4711
+
+// NewOpinionsRepositoryPostgres connects to the database, pool settings can be passed as part of the connection string,
+// e.g. "postgres://user:pw@host:5432/site?pool_max_conns=10"
+func NewOpinionsRepositoryPostgres(ctx context.Context, connString string) (*OpinionsRepositoryPostgres, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockId); err != nil {
+			return err
+		}
+		return migrate(ctx, postgresMigrationTx{tx: tx})
+	})
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &OpinionsRepositoryPostgres{
+		pool: pool,
+	}, nil
+}
+
+type OpinionsRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+// Close releases all pooled connections
+func (o *OpinionsRepositoryPostgres) Close() {
+	o.pool.Close()
+}
+
+func (o *OpinionsRepositoryPostgres) CreateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "INSERT INTO opinions (id, userId, creationTime, statement, status, version) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO NOTHING", opinion.ID, opinion.Owner, opinion.CreatedAt.Format(time.RFC3339), opinion.Statement, opinion.Status, opinion.Version)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.OpinionAlreadyExistsError
+	}
+	return nil
+}
+
+// ListOpinions applies the visibility as WHERE clause
+func (o *OpinionsRepositoryPostgres) ListOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ []application.Opinion, err error) {
+	defer mapPostgresError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.PostgresDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := o.pool.Query(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE "+condition+" ORDER BY creationTime, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opinions := make([]application.Opinion, 0)
+
+	for rows.Next() {
+		opinion, err := scanOpinion(rows)
+		if err != nil {
+			return nil, err
+		}
+		opinions = append(opinions, opinion)
+	}
+
+	return opinions, rows.Err()
+}
+
+// ListOpinionsPage applies the visibility and the cursor as WHERE clause
+func (o *OpinionsRepositoryPostgres) ListOpinionsPage(ctx context.Context, visibility application.OpinionVisibility, page application.OpinionPage) (_ []application.Opinion, err error) {
+	defer mapPostgresError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.PostgresDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the arguments of the cursor and the limit follow the arguments of the condition
+	n := len(args)
+	args = append(args, page.After.CreatedAt.Format(time.RFC3339), page.After.ID, page.Limit)
+	query := fmt.Sprintf("SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE (%s) AND (creationTime > $%d OR (creationTime = $%d AND id > $%d)) ORDER BY creationTime, id LIMIT $%d", condition, n+1, n+1, n+2, n+3)
+	rows, err := o.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opinions := make([]application.Opinion, 0)
+
+	for rows.Next() {
+		opinion, err := scanOpinion(rows)
+		if err != nil {
+			return nil, err
+		}
+		opinions = append(opinions, opinion)
+	}
+
+	return opinions, rows.Err()
+}
+
+func (o *OpinionsRepositoryPostgres) CountOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ int, err error) {
+	defer mapPostgresError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.PostgresDialect{})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = o.pool.QueryRow(ctx, "SELECT COUNT(*) FROM opinions WHERE "+condition, args...).Scan(&count)
+	return count, err
+}
+
+func (o *OpinionsRepositoryPostgres) GetOpinion(ctx context.Context, id application.OpinionId) (_ application.Opinion, err error) {
+	defer mapPostgresError(&err)
+
+	row := o.pool.QueryRow(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE id = $1", id)
+
+	opinion, err := scanOpinion(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return application.Opinion{}, application.OpinionNotFoundError
+	}
+	return opinion, err
+}
+
+// UpdateOpinion replaces the statement and the status if the stored version matches, owner and creation time are immutable
+func (o *OpinionsRepositoryPostgres) UpdateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "UPDATE opinions SET statement = $1, status = $2, version = version + 1 WHERE id = $3 AND version = $4", opinion.Statement, opinion.Status, opinion.ID, opinion.Version)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := o.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM opinions WHERE id = $1)", opinion.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+	return application.OpinionVersionConflictError
+}
+
+// DeleteOpinion removes the opinion, the votes and reports are removed by the foreign key cascade
+func (o *OpinionsRepositoryPostgres) DeleteOpinion(ctx context.Context, id application.OpinionId) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "DELETE FROM opinions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.OpinionNotFoundError
+	}
+	return nil
+}
+
+// DeleteOpinions removes the matching opinions, the votes and reports are removed by the foreign key cascade
+func (o *OpinionsRepositoryPostgres) DeleteOpinions(ctx context.Context, filter application.OpinionFilter) (_ []application.OpinionId, err error) {
+	defer mapPostgresError(&err)
+
+	ids := make([]application.OpinionId, 0)
+	err = pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		// the creation time is compared after parsing, because the stored strings are not normalized to one time zone
+		rows, err := tx.Query(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE $1 = '' OR userId = $1 ORDER BY creationTime, id FOR UPDATE", string(filter.Owner))
+		if err != nil {
+			return err
+		}
+
+		var matched []string
+		for rows.Next() {
+			opinion, err := scanOpinion(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if filter.Matches(opinion) {
+				ids = append(ids, opinion.ID)
+				matched = append(matched, string(opinion.ID))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM opinions WHERE id = ANY($1)", matched)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (o *OpinionsRepositoryPostgres) CreateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapPostgresError(&err)
+
+	return pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		// the shared lock prevents a concurrent deletion of the opinion until the vote is stored
+		var id string
+		err := tx.QueryRow(ctx, "SELECT id FROM opinions WHERE id = $1 FOR SHARE", vote.Opinion).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return application.OpinionNotFoundError
+		}
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, "INSERT INTO votes (opinionId, voterId, agreement, creationTime, updateTime, version) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (opinionId, voterId) DO NOTHING", vote.Opinion, vote.Voter, vote.Agreement, vote.CreatedAt.Format(time.RFC3339), vote.UpdatedAt.Format(time.RFC3339), vote.Version)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return application.VoteAlreadyExistsError
+		}
+		return nil
+	})
+}
+
+// CountVotes applies the visibility to the opinions of the votes
+func (o *OpinionsRepositoryPostgres) CountVotes(ctx context.Context, visibility application.OpinionVisibility, opinions []application.OpinionId) (_ map[application.OpinionId]application.VoteCount, err error) {
+	defer mapPostgresError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.PostgresDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(opinions))
+	for _, id := range opinions {
+		ids = append(ids, string(id))
+	}
+	args = append(args, ids)
+
+	query := fmt.Sprintf("SELECT opinionId, COUNT(*) FILTER (WHERE agreement), COUNT(*) FILTER (WHERE NOT agreement) FROM votes WHERE opinionId IN (SELECT id FROM opinions WHERE %s) AND opinionId = ANY($%d) GROUP BY opinionId", condition, len(args))
+	rows, err := o.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[application.OpinionId]application.VoteCount)
+	for rows.Next() {
+		var id string
+		var count application.VoteCount
+		if err := rows.Scan(&id, &count.Agreements, &count.Disagreements); err != nil {
+			return nil, err
+		}
+		counts[application.OpinionId(id)] = count
+	}
+	return counts, rows.Err()
+}
+
+func (o *OpinionsRepositoryPostgres) ListVotes(ctx context.Context) (_ []application.Vote, err error) {
+	defer mapPostgresError(&err)
+
+	rows, err := o.pool.Query(ctx, "SELECT opinionId, voterId, agreement, creationTime, updateTime, version FROM votes ORDER BY creationTime, opinionId, voterId")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]application.Vote, 0)
+
+	for rows.Next() {
+		vote, err := scanVote(rows)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
+
+func (o *OpinionsRepositoryPostgres) GetVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (_ application.Vote, err error) {
+	defer mapPostgresError(&err)
+
+	row := o.pool.QueryRow(ctx, "SELECT opinionId, voterId, agreement, creationTime, updateTime, version FROM votes WHERE opinionId = $1 AND voterId = $2", opinion, voter)
+
+	vote, err := scanVote(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return application.Vote{}, application.VoteNotFoundError
+	}
+	return vote, err
+}
+
+// UpdateVote replaces the agreement if the stored version matches
+func (o *OpinionsRepositoryPostgres) UpdateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "UPDATE votes SET agreement = $1, updateTime = $2, version = version + 1 WHERE opinionId = $3 AND voterId = $4 AND version = $5", vote.Agreement, vote.UpdatedAt.Format(time.RFC3339), vote.Opinion, vote.Voter, vote.Version)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := o.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM votes WHERE opinionId = $1 AND voterId = $2)", vote.Opinion, vote.Voter).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return application.VoteNotFoundError
+	}
+	return application.VoteVersionConflictError
+}
+
+func (o *OpinionsRepositoryPostgres) DeleteVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "DELETE FROM votes WHERE opinionId = $1 AND voterId = $2", opinion, voter)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.VoteNotFoundError
+	}
+	return nil
+}
+
+func (o *OpinionsRepositoryPostgres) CreateReport(ctx context.Context, report application.Report) (err error) {
+	defer mapPostgresError(&err)
+
+	return pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		// the shared lock prevents a concurrent deletion of the opinion until the report is stored
+		var id string
+		err := tx.QueryRow(ctx, "SELECT id FROM opinions WHERE id = $1 FOR SHARE", report.Opinion).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return application.OpinionNotFoundError
+		}
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, "INSERT INTO reports (opinionId, reporterId, reason, creationTime) VALUES ($1, $2, $3, $4) ON CONFLICT (opinionId, reporterId) DO NOTHING", report.Opinion, report.Reporter, report.Reason, report.CreatedAt.Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return application.ReportAlreadyExistsError
+		}
+		return nil
+	})
+}
+
+func (o *OpinionsRepositoryPostgres) ListOpenReports(ctx context.Context) (_ []application.Report, err error) {
+	defer mapPostgresError(&err)
+
+	rows, err := o.pool.Query(ctx, "SELECT opinionId, reporterId, reason, creationTime, resolution, resolutionTime FROM reports WHERE resolution = '' ORDER BY creationTime, opinionId, reporterId")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]application.Report, 0)
+
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+func (o *OpinionsRepositoryPostgres) CountOpenReports(ctx context.Context, opinion application.OpinionId) (_ int, err error) {
+	defer mapPostgresError(&err)
+
+	var count int
+	err = o.pool.QueryRow(ctx, "SELECT COUNT(*) FROM reports WHERE opinionId = $1 AND resolution = ''", opinion).Scan(&count)
+	return count, err
+}
+
+func (o *OpinionsRepositoryPostgres) ResolveReports(ctx context.Context, opinion application.OpinionId, resolution application.ReportResolution, resolvedAt time.Time) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "UPDATE reports SET resolution = $1, resolutionTime = $2 WHERE opinionId = $3 AND resolution = ''", resolution, resolvedAt.Format(time.RFC3339), opinion)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.ReportNotFoundError
+	}
+	return nil
+}
+
+// CreateIdempotencyRecord inserts the record or replaces an expired record of the same user and key
+func (o *OpinionsRepositoryPostgres) CreateIdempotencyRecord(ctx context.Context, record application.IdempotencyRecord, now time.Time) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "INSERT INTO idempotency_keys (userId, idempotencyKey, requestHash, opinionId, creationTime, statement, status, opinionVersion, expiresAt) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (userId, idempotencyKey) DO UPDATE SET requestHash = excluded.requestHash, opinionId = excluded.opinionId, creationTime = excluded.creationTime, statement = excluded.statement, status = excluded.status, opinionVersion = excluded.opinionVersion, expiresAt = excluded.expiresAt WHERE idempotency_keys.expiresAt <= $10", record.User, record.Key, record.RequestHash, record.Opinion.ID, record.Opinion.CreatedAt.Format(time.RFC3339), record.Opinion.Statement, record.Opinion.Status, record.Opinion.Version, record.ExpiresAt.Unix(), now.Unix())
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.IdempotencyRecordAlreadyExistsError
+	}
+	return nil
+}
+
+func (o *OpinionsRepositoryPostgres) GetIdempotencyRecord(ctx context.Context, user application.UserId, key string, now time.Time) (_ application.IdempotencyRecord, err error) {
+	defer mapPostgresError(&err)
+
+	row := o.pool.QueryRow(ctx, "SELECT userId, idempotencyKey, requestHash, opinionId, creationTime, statement, status, opinionVersion, expiresAt FROM idempotency_keys WHERE userId = $1 AND idempotencyKey = $2 AND expiresAt > $3", user, key, now.Unix())
+
+	record, err := scanIdempotencyRecord(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return application.IdempotencyRecord{}, application.IdempotencyRecordNotFoundError
+	}
+	return record, err
+}
+
+func (o *OpinionsRepositoryPostgres) DeleteIdempotencyRecord(ctx context.Context, user application.UserId, key string) (err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE userId = $1 AND idempotencyKey = $2", user, key)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.IdempotencyRecordNotFoundError
+	}
+	return nil
+}
+
+func (o *OpinionsRepositoryPostgres) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (_ int64, err error) {
+	defer mapPostgresError(&err)
+
+	tag, err := o.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expiresAt <= $1", now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type postgresMigrationTx struct {
+	tx pgx.Tx
+}
+
+func (p postgresMigrationTx) exec(ctx context.Context, statement string) error {
+	_, err := p.tx.Exec(ctx, statement)
+	return err
+}
+
+func (p postgresMigrationTx) queryInt(ctx context.Context, query string) (int, error) {
+	var i int
+	err := p.tx.QueryRow(ctx, query).Scan(&i)
+	return i, err
+}
@@ -0,0 +1,108 @@
+package infrastructure_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/repositorytest"
+	"github.com/jackc/pgx/v5"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpinionsRepositoryPostgres_contract(t *testing.T) {
+	t.Parallel()
+	connString := startPostgres(t)
+
+	databases := 0
+	repositorytest.Run(t, func(t *testing.T) application.Repository {
+		databases++
+		dbConnString := createPostgresDatabase(t, connString, fmt.Sprintf("contract_%d", databases))
+
+		repo, err := infrastructure.NewOpinionsRepositoryPostgres(context.Background(), dbConnString)
+		if err != nil {
+			t.Fatalf("NewOpinionsRepositoryPostgres() retunred error %s, but no error is expected", err)
+		}
+		t.Cleanup(repo.Close)
+		return repo
+	})
+}
+
+func TestEventLogPostgres_Append_and_Load(t *testing.T) {
+	t.Parallel()
+	dbConnString := createPostgresDatabase(t, startPostgres(t), "event_log")
+
+	eventLog, err := infrastructure.NewEventLogPostgres(context.Background(), dbConnString)
+	if err != nil {
+		t.Fatalf("NewEventLogPostgres() retunred error %s, but no error is expected", err)
+	}
+	t.Cleanup(eventLog.Close)
+	testEventLog(t, eventLog)
+}
+
+// startPostgres returns the connection string of a server for the tests.
+// POSTGRES_TEST_DSN can point to an existing server, otherwise a temporary server is started
+// with the postgres binaries in the PATH. The test is skipped if neither is available.
+func startPostgres(t *testing.T) string {
+	if dsn := os.Getenv("POSTGRES_TEST_DSN"); dsn != "" {
+		return dsn
+	}
+
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		t.Skip("postgres binaries not found in PATH and POSTGRES_TEST_DSN is not set")
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		t.Skip("postgres binaries not found in PATH and POSTGRES_TEST_DSN is not set")
+	}
+
+	dataDir := filepath.Join(t.TempDir(), "data")
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		t.Fatalf("initdb failed: %s: %s", err, out)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not find a free port: %s", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1", port, dataDir)
+	if out, err := exec.Command(pgCtl, "-D", dataDir, "-o", options, "-w", "start").CombinedOutput(); err != nil {
+		t.Fatalf("pg_ctl start failed: %s: %s", err, out)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+	})
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+}
+
+// createPostgresDatabase creates a fresh database, so every contract test starts with an empty schema
+func createPostgresDatabase(t *testing.T, connString string, name string) string {
+	conn, err := pgx.Connect(context.Background(), connString)
+	if err != nil {
+		t.Fatalf("could not connect to postgres: %s", err)
+	}
+	defer conn.Close(context.Background())
+
+	name = fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+	if _, err := conn.Exec(context.Background(), "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("could not create database: %s", err)
+	}
+
+	u, err := url.Parse(connString)
+	if err != nil {
+		t.Fatalf("could not parse connection string: %s", err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
@@ -3,8 +3,11 @@ package infrastructure
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/authorization"
 	"github.com/fwiedmann/site/backend/internal/opinions/application"
 	_ "github.com/mattn/go-sqlite3"
+	"strings"
 	"time"
 )
 
@@ -18,18 +21,16 @@ func NewOpinionsRepositorySQLite(dbLocation string) (*OpinionsRepositorySQLite,
 		return &OpinionsRepositorySQLite{}, err
 	}
 
-	tx, err := db.BeginTx(context.Background(), nil)
-	if err != nil {
-		return nil, err
-	}
+	// SQLite allows only one writer at a time, sharing one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
 
-	_, err = tx.Exec("CREATE TABLE IF NOT EXISTS opinions ( id varchar(255) NOT NULL , userId varchar(255) NOT NULL, creationTime varchar(255) NOT NULL, statement varchar(255) NOT NULL, PRIMARY KEY (id))")
+	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	_, err = tx.Exec("CREATE INDEX opinion_id on opinions (id);")
-	if err != nil {
+	if err := migrate(context.Background(), sqliteMigrationTx{tx: tx}); err != nil {
 		return nil, err
 	}
 
@@ -46,82 +47,700 @@ type OpinionsRepositorySQLite struct {
 	db *sql.DB
 }
 
-func (o *OpinionsRepositorySQLite) CreateOpinion(ctx context.Context, opinion application.Opinion) error {
+// Close releases the underlying database
+func (o *OpinionsRepositorySQLite) Close() error {
+	return o.db.Close()
+}
+
+func (o *OpinionsRepositorySQLite) CreateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapSQLiteError(&err)
+
 	tx, err := o.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	_, err = tx.Exec("INSERT INTO  opinions (id, userId, creationTime, statement) VALUES (?, ?, ?, ?)", opinion.ID, opinion.Owner, opinion.CreatedAt.Format(time.RFC3339), opinion.Statement)
+	result, err := tx.ExecContext(ctx, "INSERT INTO  opinions (id, userId, creationTime, statement, status, version) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING", opinion.ID, opinion.Owner, opinion.CreatedAt.Format(time.RFC3339), opinion.Statement, opinion.Status, opinion.Version)
 	if err != nil {
 		return err
 	}
 
+	if err := expectAffected(result, application.OpinionAlreadyExistsError); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
-func (o *OpinionsRepositorySQLite) ListOpinions(ctx context.Context) ([]application.Opinion, error) {
-	rows, err := o.db.QueryContext(ctx, "SELECT * FROM opinions")
+
+// ListOpinions applies the visibility as WHERE clause
+func (o *OpinionsRepositorySQLite) ListOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ []application.Opinion, err error) {
+	defer mapSQLiteError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.SQLiteDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := o.db.QueryContext(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE "+condition+" ORDER BY creationTime, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opinions := make([]application.Opinion, 0)
+
+	for rows.Next() {
+		opinion, err := scanOpinion(rows)
+		if err != nil {
+			return nil, err
+		}
+		opinions = append(opinions, opinion)
+	}
+
+	return opinions, rows.Err()
+}
+
+// ListOpinionsPage applies the visibility and the cursor as WHERE clause
+func (o *OpinionsRepositorySQLite) ListOpinionsPage(ctx context.Context, visibility application.OpinionVisibility, page application.OpinionPage) (_ []application.Opinion, err error) {
+	defer mapSQLiteError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.SQLiteDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	after := page.After.CreatedAt.Format(time.RFC3339)
+	args = append(args, after, after, page.After.ID, page.Limit)
+	rows, err := o.db.QueryContext(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE ("+condition+") AND (creationTime > ? OR (creationTime = ? AND id > ?)) ORDER BY creationTime, id LIMIT ?", args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	opinions := make([]application.Opinion, 0)
 
 	for rows.Next() {
+		opinion, err := scanOpinion(rows)
+		if err != nil {
+			return nil, err
+		}
+		opinions = append(opinions, opinion)
+	}
+
+	return opinions, rows.Err()
+}
+
+func (o *OpinionsRepositorySQLite) CountOpinions(ctx context.Context, visibility application.OpinionVisibility) (_ int, err error) {
+	defer mapSQLiteError(&err)
+
+	condition, args, err := visibilityCondition(visibility, authorization.SQLiteDialect{})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM opinions WHERE "+condition, args...).Scan(&count)
+	return count, err
+}
+
+func (o *OpinionsRepositorySQLite) GetOpinion(ctx context.Context, id application.OpinionId) (_ application.Opinion, err error) {
+	defer mapSQLiteError(&err)
+
+	row := o.db.QueryRowContext(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE id = ?", id)
+
+	opinion, err := scanOpinion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return application.Opinion{}, application.OpinionNotFoundError
+	}
+	return opinion, err
+}
+
+// UpdateOpinion replaces the statement and the status if the stored version matches, owner and creation time are immutable
+func (o *OpinionsRepositorySQLite) UpdateOpinion(ctx context.Context, opinion application.Opinion) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE opinions SET statement = ?, status = ?, version = version + 1 WHERE id = ? AND version = ?", opinion.Statement, opinion.Status, opinion.ID, opinion.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := expectVersion(ctx, tx, result, application.OpinionVersionConflictError, application.OpinionNotFoundError, "SELECT EXISTS (SELECT 1 FROM opinions WHERE id = ?)", opinion.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteOpinion removes the opinion and all of its votes and reports
+func (o *OpinionsRepositorySQLite) DeleteOpinion(ctx context.Context, id application.OpinionId) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := deleteOpinion(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.OpinionNotFoundError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteOpinions removes the matching opinions and all of their votes and reports
+func (o *OpinionsRepositorySQLite) DeleteOpinions(ctx context.Context, filter application.OpinionFilter) (_ []application.OpinionId, err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the creation time is compared after parsing, because the stored strings are not normalized to one time zone
+	rows, err := tx.QueryContext(ctx, "SELECT id, userId, creationTime, statement, status, version FROM opinions WHERE ? = '' OR userId = ? ORDER BY creationTime, id", filter.Owner, filter.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]application.OpinionId, 0)
+	for rows.Next() {
+		opinion, err := scanOpinion(rows)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Matches(opinion) {
+			ids = append(ids, opinion.ID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err := deleteOpinion(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, tx.Commit()
+}
+
+// deleteOpinion removes the opinion together with its votes and reports
+func deleteOpinion(ctx context.Context, tx *sql.Tx, id application.OpinionId) (sql.Result, error) {
+	// foreign keys are not enforced by SQLite without the foreign_keys pragma, therefore the cascade is done by hand
+	if _, err := tx.ExecContext(ctx, "DELETE FROM votes WHERE opinionId = ?", id); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM reports WHERE opinionId = ?", id); err != nil {
+		return nil, err
+	}
+
+	return tx.ExecContext(ctx, "DELETE FROM opinions WHERE id = ?", id)
+}
+
+func (o *OpinionsRepositorySQLite) CreateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM opinions WHERE id = ?)", vote.Opinion).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO votes (opinionId, voterId, agreement, creationTime, updateTime, version) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (opinionId, voterId) DO NOTHING", vote.Opinion, vote.Voter, vote.Agreement, vote.CreatedAt.Format(time.RFC3339), vote.UpdatedAt.Format(time.RFC3339), vote.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.VoteAlreadyExistsError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountVotes applies the visibility to the opinions of the votes
+func (o *OpinionsRepositorySQLite) CountVotes(ctx context.Context, visibility application.OpinionVisibility, opinions []application.OpinionId) (_ map[application.OpinionId]application.VoteCount, err error) {
+	defer mapSQLiteError(&err)
+
+	counts := make(map[application.OpinionId]application.VoteCount)
+	if len(opinions) == 0 {
+		return counts, nil
+	}
+
+	condition, args, err := visibilityCondition(visibility, authorization.SQLiteDialect{})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range opinions {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(opinions)), ", ")
 
+	rows, err := o.db.QueryContext(ctx, "SELECT opinionId, SUM(agreement), SUM(NOT agreement) FROM votes WHERE opinionId IN (SELECT id FROM opinions WHERE "+condition+") AND opinionId IN ("+placeholders+") GROUP BY opinionId", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
 		var id application.OpinionId
-		var userId application.UserId
-		var date string
-		var statement string
+		var count application.VoteCount
+		if err := rows.Scan(&id, &count.Agreements, &count.Disagreements); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+func (o *OpinionsRepositorySQLite) ListVotes(ctx context.Context) (_ []application.Vote, err error) {
+	defer mapSQLiteError(&err)
 
-		err = rows.Scan(&id, &userId, &date, &statement)
+	rows, err := o.db.QueryContext(ctx, "SELECT opinionId, voterId, agreement, creationTime, updateTime, version FROM votes ORDER BY creationTime, opinionId, voterId")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]application.Vote, 0)
+
+	for rows.Next() {
+		vote, err := scanVote(rows)
 		if err != nil {
 			return nil, err
 		}
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
+
+func (o *OpinionsRepositorySQLite) GetVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (_ application.Vote, err error) {
+	defer mapSQLiteError(&err)
+
+	row := o.db.QueryRowContext(ctx, "SELECT opinionId, voterId, agreement, creationTime, updateTime, version FROM votes WHERE opinionId = ? AND voterId = ?", opinion, voter)
+
+	vote, err := scanVote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return application.Vote{}, application.VoteNotFoundError
+	}
+	return vote, err
+}
+
+// UpdateVote replaces the agreement if the stored version matches
+func (o *OpinionsRepositorySQLite) UpdateVote(ctx context.Context, vote application.Vote) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE votes SET agreement = ?, updateTime = ?, version = version + 1 WHERE opinionId = ? AND voterId = ? AND version = ?", vote.Agreement, vote.UpdatedAt.Format(time.RFC3339), vote.Opinion, vote.Voter, vote.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := expectVersion(ctx, tx, result, application.VoteVersionConflictError, application.VoteNotFoundError, "SELECT EXISTS (SELECT 1 FROM votes WHERE opinionId = ? AND voterId = ?)", vote.Opinion, vote.Voter); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (o *OpinionsRepositorySQLite) DeleteVote(ctx context.Context, opinion application.OpinionId, voter application.UserId) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM votes WHERE opinionId = ? AND voterId = ?", opinion, voter)
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.VoteNotFoundError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (o *OpinionsRepositorySQLite) CreateReport(ctx context.Context, report application.Report) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM opinions WHERE id = ?)", report.Opinion).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return application.OpinionNotFoundError
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO reports (opinionId, reporterId, reason, creationTime) VALUES (?, ?, ?, ?) ON CONFLICT (opinionId, reporterId) DO NOTHING", report.Opinion, report.Reporter, report.Reason, report.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.ReportAlreadyExistsError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (o *OpinionsRepositorySQLite) ListOpenReports(ctx context.Context) (_ []application.Report, err error) {
+	defer mapSQLiteError(&err)
+
+	rows, err := o.db.QueryContext(ctx, "SELECT opinionId, reporterId, reason, creationTime, resolution, resolutionTime FROM reports WHERE resolution = '' ORDER BY creationTime, opinionId, reporterId")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]application.Report, 0)
 
-		parsedTime, err := time.Parse(time.RFC3339, date)
+	for rows.Next() {
+		report, err := scanReport(rows)
 		if err != nil {
 			return nil, err
 		}
+		reports = append(reports, report)
+	}
 
-		opinions = append(opinions, application.Opinion{
-			ID:        id,
-			Owner:     userId,
-			CreatedAt: parsedTime,
-			Statement: statement,
-		})
+	return reports, rows.Err()
+}
+
+func (o *OpinionsRepositorySQLite) CountOpenReports(ctx context.Context, opinion application.OpinionId) (_ int, err error) {
+	defer mapSQLiteError(&err)
+
+	var count int
+	err = o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reports WHERE opinionId = ? AND resolution = ''", opinion).Scan(&count)
+	return count, err
+}
+
+func (o *OpinionsRepositorySQLite) ResolveReports(ctx context.Context, opinion application.OpinionId, resolution application.ReportResolution, resolvedAt time.Time) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	return opinions, rows.Err()
+	result, err := tx.ExecContext(ctx, "UPDATE reports SET resolution = ?, resolutionTime = ? WHERE opinionId = ? AND resolution = ''", resolution, resolvedAt.Format(time.RFC3339), opinion)
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.ReportNotFoundError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateIdempotencyRecord inserts the record or replaces an expired record of the same user and key
+func (o *OpinionsRepositorySQLite) CreateIdempotencyRecord(ctx context.Context, record application.IdempotencyRecord, now time.Time) (err error) {
+	defer mapSQLiteError(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO idempotency_keys (userId, idempotencyKey, requestHash, opinionId, creationTime, statement, status, opinionVersion, expiresAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (userId, idempotencyKey) DO UPDATE SET requestHash = excluded.requestHash, opinionId = excluded.opinionId, creationTime = excluded.creationTime, statement = excluded.statement, status = excluded.status, opinionVersion = excluded.opinionVersion, expiresAt = excluded.expiresAt WHERE idempotency_keys.expiresAt <= ?", record.User, record.Key, record.RequestHash, record.Opinion.ID, record.Opinion.CreatedAt.Format(time.RFC3339), record.Opinion.Statement, record.Opinion.Status, record.Opinion.Version, record.ExpiresAt.Unix(), now.Unix())
+	if err != nil {
+		return err
+	}
+
+	if err := expectAffected(result, application.IdempotencyRecordAlreadyExistsError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (o *OpinionsRepositorySQLite) DeleteOpinion(ctx context.Context, id application.OpinionId) error {
+func (o *OpinionsRepositorySQLite) GetIdempotencyRecord(ctx context.Context, user application.UserId, key string, now time.Time) (_ application.IdempotencyRecord, err error) {
+	defer mapSQLiteError(&err)
+
+	row := o.db.QueryRowContext(ctx, "SELECT userId, idempotencyKey, requestHash, opinionId, creationTime, statement, status, opinionVersion, expiresAt FROM idempotency_keys WHERE userId = ? AND idempotencyKey = ? AND expiresAt > ?", user, key, now.Unix())
+
+	record, err := scanIdempotencyRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return application.IdempotencyRecord{}, application.IdempotencyRecordNotFoundError
+	}
+	return record, err
+}
+
+func (o *OpinionsRepositorySQLite) DeleteIdempotencyRecord(ctx context.Context, user application.UserId, key string) (err error) {
+	defer mapSQLiteError(&err)
+
 	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM opinions WHERE id = ?", id)
+	result, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE userId = ? AND idempotencyKey = ?", user, key)
 	if err != nil {
 		return err
 	}
 
+	if err := expectAffected(result, application.IdempotencyRecordNotFoundError); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (o *OpinionsRepositorySQLite) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (_ int64, err error) {
+	defer mapSQLiteError(&err)
+
+	result, err := o.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expiresAt <= ?", now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOpinion(row rowScanner) (application.Opinion, error) {
+	var id application.OpinionId
+	var userId application.UserId
+	var date string
+	var statement string
+	var status application.OpinionStatus
+	var version int64
+
+	if err := row.Scan(&id, &userId, &date, &statement, &status, &version); err != nil {
+		return application.Opinion{}, err
+	}
+
+	parsedTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return application.Opinion{}, err
+	}
+
+	return application.Opinion{
+		ID:        id,
+		Owner:     userId,
+		CreatedAt: parsedTime,
+		Statement: statement,
+		Status:    status,
+		Version:   version,
+	}, nil
+}
+
+func scanVote(row rowScanner) (application.Vote, error) {
+	var opinion application.OpinionId
+	var voter application.UserId
+	var agreement bool
+	var created string
+	var updated string
+	var version int64
+
+	if err := row.Scan(&opinion, &voter, &agreement, &created, &updated, &version); err != nil {
+		return application.Vote{}, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return application.Vote{}, err
+	}
+
+	updatedAt, err := time.Parse(time.RFC3339, updated)
+	if err != nil {
+		return application.Vote{}, err
+	}
+
+	return application.Vote{
+		Agreement: agreement,
+		Opinion:   opinion,
+		Voter:     voter,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Version:   version,
+	}, nil
+}
+
+func scanReport(row rowScanner) (application.Report, error) {
+	var opinion application.OpinionId
+	var reporter application.UserId
+	var reason application.ReportReason
+	var created string
+	var resolution application.ReportResolution
+	var resolved string
+
+	if err := row.Scan(&opinion, &reporter, &reason, &created, &resolution, &resolved); err != nil {
+		return application.Report{}, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return application.Report{}, err
+	}
+
+	var resolvedAt time.Time
+	if resolved != "" {
+		if resolvedAt, err = time.Parse(time.RFC3339, resolved); err != nil {
+			return application.Report{}, err
+		}
+	}
+
+	return application.Report{
+		Opinion:    opinion,
+		Reporter:   reporter,
+		Reason:     reason,
+		CreatedAt:  createdAt,
+		Resolution: resolution,
+		ResolvedAt: resolvedAt,
+	}, nil
+}
+
+func scanIdempotencyRecord(row rowScanner) (application.IdempotencyRecord, error) {
+	var user application.UserId
+	var key string
+	var hash string
+	var opinion application.OpinionId
+	var created string
+	var statement string
+	var status application.OpinionStatus
+	var version int64
+	var expiresAt int64
+
+	if err := row.Scan(&user, &key, &hash, &opinion, &created, &statement, &status, &version, &expiresAt); err != nil {
+		return application.IdempotencyRecord{}, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return application.IdempotencyRecord{}, err
+	}
+
+	return application.IdempotencyRecord{
+		User:        user,
+		Key:         key,
+		RequestHash: hash,
+		Opinion: application.Opinion{
+			ID:        opinion,
+			Owner:     user,
+			CreatedAt: createdAt,
+			Statement: statement,
+			Status:    status,
+			Version:   version,
+		},
+		ExpiresAt: time.Unix(expiresAt, 0).UTC(),
+	}, nil
+}
+
+// opinionColumns are the columns of the opinion fields which can be compared by an application.OpinionCondition.
+// They are lower case, because postgres folds the unquoted identifiers of the migrations to lower case.
+var opinionColumns = map[string]string{
+	application.OpinionFieldID:     "id",
+	application.OpinionFieldOwner:  "userid",
+	application.OpinionFieldStatus: "status",
+}
+
+// visibilityCondition renders the visibility as condition of a WHERE clause for the dialect
+func visibilityCondition(visibility application.OpinionVisibility, dialect authorization.Dialect) (string, []any, error) {
+	filter := make(authorization.Or, 0, len(visibility.Clauses))
+	for _, clause := range visibility.Clauses {
+		and := make(authorization.And, 0, len(clause))
+		for _, c := range clause {
+			and = append(and, authorization.ParsedExpression{FieldName: c.Field, Operator: c.Operator, Value: c.Value})
+		}
+		filter = append(filter, and)
+	}
+
+	condition, args, err := authorization.NewSQLCompiler(dialect, opinionColumns).Compile(filter)
+	if err != nil {
+		return "", nil, application.InvalidOpinionConditionError.WithCause(err)
+	}
+	return condition, args, nil
+}
+
+// expectAffected returns the given error if the statement did not change any row
+func expectAffected(result sql.Result, notAffected error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notAffected
+	}
 	return nil
 }
 
-func (o *OpinionsRepositorySQLite) CreateVote(ctx context.Context, vote application.Vote) error {
-	//TODO implement me
-	panic("implement me")
+// expectVersion distinguishes a conditional update which did not change any row because of another version
+// from an update of a missing row, the exists query must select whether the row is stored
+func expectVersion(ctx context.Context, tx *sql.Tx, result sql.Result, conflict error, notFound error, exists string, args ...any) error {
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var stored bool
+	if err := tx.QueryRowContext(ctx, exists, args...).Scan(&stored); err != nil {
+		return err
+	}
+	if !stored {
+		return notFound
+	}
+	return conflict
 }
 
-func (o *OpinionsRepositorySQLite) ListVotes(ctx context.Context) ([]application.Vote, error) {
-	//TODO implement me
-	panic("implement me")
+type sqliteMigrationTx struct {
+	tx *sql.Tx
 }
 
-func (o *OpinionsRepositorySQLite) UpdateVote(ctx context.Context, vote application.Vote) error {
-	//TODO implement me
-	panic("implement me")
+func (s sqliteMigrationTx) exec(ctx context.Context, statement string) error {
+	_, err := s.tx.ExecContext(ctx, statement)
+	return err
 }
 
-func (o *OpinionsRepositorySQLite) DeleteVote(ctx context.Context, id application.OpinionId) error {
-	//TODO implement me
-	panic("implement me")
+func (s sqliteMigrationTx) queryInt(ctx context.Context, query string) (int, error) {
+	var i int
+	err := s.tx.QueryRowContext(ctx, query).Scan(&i)
+	return i, err
 }
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"github.com/fwiedmann/site/backend/internal/opinions/application"
 	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/repositorytest"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -49,6 +51,25 @@ func TestNewOpinionsRepositorySQLite_create_client_with_exising_db_instance(t *t
 	}
 }
 
+func TestNewOpinionsRepositorySQLite_reopen_migrated_db_instance(t *testing.T) {
+	t.Parallel()
+	const testDBInstance = "testInstance.db"
+	dbAbsolutePath := fmt.Sprintf("%s/%s", t.TempDir(), testDBInstance)
+
+	repo, err := infrastructure.NewOpinionsRepositorySQLite(dbAbsolutePath)
+	if err != nil {
+		t.Errorf("NewOpinionsRepositorySQLite() retunred error %s, but no error is expected", err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Errorf("Close() retunred error %s, but no error is expected", err)
+	}
+
+	_, err = infrastructure.NewOpinionsRepositorySQLite(dbAbsolutePath)
+	if err != nil {
+		t.Errorf("NewOpinionsRepositorySQLite() retunred error %s, but no error is expected", err)
+	}
+}
+
 func TestNewOpinionsRepositorySQLite_error_invalid_path(t *testing.T) {
 	t.Parallel()
 	const testDBInstance = "testInstance.db"
@@ -88,7 +109,7 @@ func TestOpinionsRepositorySQLite_CreateOpinion_successfully(t *testing.T) {
 
 	db, err := sql.Open("sqlite3", dbAbsolutePath)
 
-	row := db.QueryRow("SELECT * FROM opinions WHERE id = ?", testId)
+	row := db.QueryRow("SELECT id, userId, creationTime, statement FROM opinions WHERE id = ?", testId)
 	if row.Err() != nil {
 		t.Errorf("could not exec query satement: %q", err)
 	}
@@ -140,7 +161,7 @@ func TestOpinionsRepositorySQLite_ListOpinionsOpinion_successfully(t *testing.T)
 
 	}
 
-	list, err := repo.ListOpinions(context.Background())
+	list, err := repo.ListOpinions(context.Background(), application.AllOpinionsVisible)
 	if err != nil {
 		t.Errorf("ListOpinions() returned error: %q", err)
 	}
@@ -187,9 +208,21 @@ func TestOpinionsRepositorySQLite_DeleteOpinion(t *testing.T) {
 		t.Errorf("could not delete opinion: %q", err)
 	}
 
-	row := db.QueryRow("SELECT * FROM opinions WHERE id = ?", testId)
+	row := db.QueryRow("SELECT id, userId, creationTime, statement FROM opinions WHERE id = ?", testId)
 
 	if row.Scan() == nil {
 		t.Errorf("Scan() should return an error because opinion could not be found, but no error received")
 	}
 }
+
+func TestOpinionsRepositorySQLite_contract(t *testing.T) {
+	t.Parallel()
+	repositorytest.Run(t, func(t *testing.T) application.Repository {
+		repo, err := infrastructure.NewOpinionsRepositorySQLite(filepath.Join(t.TempDir(), "testInstance.db"))
+		if err != nil {
+			t.Fatalf("NewOpinionsRepositorySQLite() retunred error %s, but no error is expected", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
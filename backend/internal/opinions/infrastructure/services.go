@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"time"
+)
+
+// RandomIdService generates 128 bit random ids
+type RandomIdService struct{}
+
+func (RandomIdService) GenerateId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// SystemTimeService returns the current UTC time of the host
+type SystemTimeService struct{}
+
+func (SystemTimeService) CurrentTime() time.Time {
+	return time.Now().UTC()
+}
+
+// userActions can be performed by every authenticated user
+var userActions = map[string]struct{}{
+	application.ActionCreateOpinion: {},
+	application.ActionListOpinions:  {},
+	application.ActionReportOpinion: {},
+	application.ActionCreateVote:    {},
+	application.ActionUpdateVote:    {},
+	application.ActionDeleteVote:    {},
+}
+
+// roleActions can only be performed by users with the role or a role above it
+var roleActions = map[string]application.Role{
+	application.ActionApproveOpinion:          application.RoleModerator,
+	application.ActionRejectOpinion:           application.RoleModerator,
+	application.ActionViewUnpublishedOpinions: application.RoleModerator,
+	application.ActionListReports:             application.RoleModerator,
+	application.ActionResolveReports:          application.RoleModerator,
+	application.ActionBulkDeleteOpinions:      application.RoleAdmin,
+	application.ActionListDecisions:           application.RoleAdmin,
+}
+
+// ownerActions can be performed by the owner of the opinion and by users with the role or a role above it
+var ownerActions = map[string]application.Role{
+	application.ActionDeleteOpinion: application.RoleAdmin,
+	application.ActionHideOpinion:   application.RoleModerator,
+}
+
+// ownerOnlyActions can only be performed by the owner of the opinion
+var ownerOnlyActions = map[string]struct{}{
+	application.ActionEditOpinion: {},
+}
+
+// roleRanks orders the roles, a role grants all capabilities of the roles below it
+var roleRanks = map[application.Role]int{
+	application.RoleUser:      0,
+	application.RoleModerator: 1,
+	application.RoleAdmin:     2,
+}
+
+// AuthenticatedUsersPolicyEnforcementPoint permits every user action to every authenticated user
+// and the moderation actions to the users with the required roles, it mirrors the policies of the site.opinions package.
+// It is meant for local development and tests which do not evaluate the policies.
+type AuthenticatedUsersPolicyEnforcementPoint struct{}
+
+// BuiltinPolicyRevision is the policy revision of the AuthenticatedUsersPolicyEnforcementPoint
+const BuiltinPolicyRevision = "builtin"
+
+func (p AuthenticatedUsersPolicyEnforcementPoint) PolicyRevision() string {
+	return BuiltinPolicyRevision
+}
+
+func (p AuthenticatedUsersPolicyEnforcementPoint) RequestAccessForUser(_ context.Context, request application.AccessRequest) error {
+	if request.User == "" {
+		return application.UnauthenticatedError
+	}
+
+	// a request without an owner is denied, the service always passes the owner of the addressed opinion
+	if role, ok := ownerActions[request.Action]; ok {
+		if owns(request) || grants(request.Roles, role) {
+			return nil
+		}
+		return application.AccessDeniedError
+	}
+
+	if _, ok := ownerOnlyActions[request.Action]; ok {
+		if owns(request) {
+			return nil
+		}
+		return application.AccessDeniedError
+	}
+
+	if _, ok := userActions[request.Action]; ok {
+		return nil
+	}
+
+	if role, ok := roleActions[request.Action]; ok && grants(request.Roles, role) {
+		return nil
+	}
+	return application.AccessDeniedError
+}
+
+// owns reports whether the user of the request owns the addressed opinion
+func owns(request application.AccessRequest) bool {
+	return request.Owner != "" && request.Owner == request.User
+}
+
+// grants reports whether one of the roles is the required role or ranks above it
+func grants(roles []application.Role, required application.Role) bool {
+	for _, r := range roles {
+		if rank, known := roleRanks[r]; known && rank >= roleRanks[required] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"io"
+)
+
+// NewUserDirectoryMemory creates a directory of the display names of the users
+func NewUserDirectoryMemory(names map[application.UserId]string) UserDirectoryMemory {
+	copied := make(map[application.UserId]string, len(names))
+	for id, name := range names {
+		copied[id] = name
+	}
+	return UserDirectoryMemory{names: copied}
+}
+
+// ReadUserDirectory reads a JSON object which maps the user ids to their display names
+func ReadUserDirectory(r io.Reader) (UserDirectoryMemory, error) {
+	var names map[application.UserId]string
+	if err := json.NewDecoder(r).Decode(&names); err != nil {
+		return UserDirectoryMemory{}, err
+	}
+	return NewUserDirectoryMemory(names), nil
+}
+
+// UserDirectoryMemory resolves the display names of a fixed set of users, unknown users are omitted
+type UserDirectoryMemory struct {
+	names map[application.UserId]string
+}
+
+func (d UserDirectoryMemory) DisplayNames(_ context.Context, ids []application.UserId) (map[application.UserId]string, error) {
+	names := make(map[application.UserId]string, len(ids))
+	for _, id := range ids {
+		if name, ok := d.names[id]; ok {
+			names[id] = name
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,56 @@
+package infrastructure_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReadUserDirectory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		ids     []application.UserId
+		want    map[application.UserId]string
+		wantErr bool
+	}{
+		{
+			name:  "Should resolve the known users",
+			input: `{"alice":"Alice Liddell","bob":"Bob"}`,
+			ids:   []application.UserId{"alice", "carol"},
+			want:  map[application.UserId]string{"alice": "Alice Liddell"},
+		},
+		{
+			name:  "Should resolve no users of an empty directory",
+			input: `{}`,
+			ids:   []application.UserId{"alice"},
+			want:  map[application.UserId]string{},
+		},
+		{
+			name:    "Should fail for a list",
+			input:   `["alice"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			directory, err := infrastructure.ReadUserDirectory(strings.NewReader(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			names, err := directory.DisplayNames(context.Background(), tt.ids)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
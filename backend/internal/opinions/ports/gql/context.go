@@ -0,0 +1,44 @@
+package gql
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+)
+
+type (
+	userKey    struct{}
+	loadersKey struct{}
+)
+
+// loaders batch the lookups of a single response
+type loaders struct {
+	voteCounts   *loader[application.OpinionId, application.VoteCount]
+	displayNames *loader[application.UserId, string]
+}
+
+func newLoaders(service application.Service, directory UserDirectory, user application.AuthenticatedUser) *loaders {
+	return &loaders{
+		voteCounts: newLoader(func(ctx context.Context, ids []application.OpinionId) (map[application.OpinionId]application.VoteCount, error) {
+			return service.CountVotesQuery(ctx, user, ids)
+		}),
+		displayNames: newLoader(directory.DisplayNames),
+	}
+}
+
+func withUser(ctx context.Context, user application.AuthenticatedUser) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// userFrom returns the user of the request, the handler rejects the requests without a user
+func userFrom(ctx context.Context) application.AuthenticatedUser {
+	user, _ := ctx.Value(userKey{}).(application.AuthenticatedUser)
+	return user
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,177 @@
+// Package gql exposes the opinions application service as GraphQL API.
+// The code in generated.go and model/models_gen.go is generated by gqlgen from api/graphql/opinions.graphqls.
+package gql
+
+//go:generate gqlgen generate --config gqlgen.yml
+
+import (
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventSubscriber passes the events published after Subscribe to the channel until cancel is called, see infrastructure.EventBroadcast
+type EventSubscriber interface {
+	Subscribe(buffer int) (events <-chan application.Event, cancel func())
+}
+
+// TallyReader returns the current vote tally of an opinion, see projections.OpinionListProjection
+type TallyReader interface {
+	Get(id application.OpinionId) (projections.OpinionSummary, bool)
+}
+
+// UserDirectory resolves the display names of the users, unknown users are omitted, see infrastructure.UserDirectoryMemory
+type UserDirectory interface {
+	DisplayNames(ctx context.Context, ids []application.UserId) (map[application.UserId]string, error)
+}
+
+// Authenticator resolves the user of the request, see rest.HeaderAuthenticator
+type Authenticator interface {
+	Authenticate(r *http.Request) (application.AuthenticatedUser, error)
+}
+
+const (
+	// complexityLimit rejects queries which would resolve too many fields, e.g. large pages of deeply nested fields
+	complexityLimit = 1000
+	// keepAlive is the interval of the keep alive messages of the subscription connections
+	keepAlive = 15 * time.Second
+	// subscriptionBuffer is the count of events a subscription may fall behind before it is completed
+	subscriptionBuffer = 64
+)
+
+// NewHandler creates a handler which serves queries and mutations via GET and POST and the subscriptions via WebSocket.
+// The events must be published after the tallies are projected, so that a tally already contains the vote of an event.
+func NewHandler(service application.Service, events EventSubscriber, tallies TallyReader, directory UserDirectory, authenticator Authenticator) *Handler {
+	server := handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{
+		service: service,
+		events:  events,
+		tallies: tallies,
+	}}))
+	server.AddTransport(transport.Websocket{
+		Upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		KeepAlivePingInterval: keepAlive,
+	})
+	server.AddTransport(transport.Options{})
+	server.AddTransport(transport.GET{})
+	server.AddTransport(transport.POST{})
+	server.Use(extension.Introspection{})
+	server.Use(extension.FixedComplexityLimit(complexityLimit))
+	server.SetErrorPresenter(presentError)
+	// every response resolves its fields with new loaders, so the subscription events do not return cached tallies
+	server.AroundResponses(func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		return next(withLoaders(ctx, newLoaders(service, directory, userFrom(ctx))))
+	})
+
+	return &Handler{
+		server:        server,
+		authenticator: authenticator,
+		done:          make(chan struct{}),
+	}
+}
+
+type Handler struct {
+	server        *handler.Server
+	authenticator Authenticator
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	ctx := withUser(r.Context(), user)
+
+	// the subscription connections are upgraded, so they are not tracked by the http.Server
+	if websocket.IsWebSocketUpgrade(r) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		if !h.add(ctx, cancel) {
+			httperror.WriteStatus(w, http.StatusServiceUnavailable, application.NewError(application.CodeInternal, "server is shutting down", nil))
+			return
+		}
+		defer h.wg.Done()
+	}
+
+	h.server.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Close ends the subscription connections and waits until they are closed, new connections are rejected.
+// The http.Server does not track upgraded connections, so Close has to be registered with RegisterOnShutdown.
+func (h *Handler) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+}
+
+// add registers a subscription connection, Close cancels its context
+func (h *Handler) add(ctx context.Context, cancel context.CancelFunc) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.wg.Add(1)
+	go func() {
+		select {
+		case <-h.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return true
+}
+
+// presentError renders the application errors with their code and fields as extensions,
+// the cause of an error is never exposed to the client
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	var appErr *application.Error
+	var gqlErr *gqlerror.Error
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &gqlErr):
+		// the errors of gqlgen, e.g. of invalid arguments, are meant for the client
+		return graphql.DefaultErrorPresenter(ctx, err)
+	default:
+		appErr = application.NewError(application.CodeInternal, "internal error", err)
+	}
+
+	presented := &gqlerror.Error{
+		Message:    appErr.Message,
+		Path:       graphql.GetPath(ctx),
+		Extensions: map[string]any{"code": appErr.Code},
+	}
+	if body := httperror.NewBody(appErr); len(body.Fields) > 0 {
+		presented.Extensions["fields"] = body.Fields
+	}
+	if retryAfter := httperror.RetryAfter(appErr); retryAfter > 0 {
+		presented.Extensions["retryAfter"] = retryAfter
+	}
+	return presented
+}
@@ -0,0 +1,424 @@
+package gql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/gql"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rest"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	alice     = application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}
+	bob       = application.AuthenticatedUser{Id: "bob", Roles: []application.Role{application.RoleUser}}
+	moderator = application.AuthenticatedUser{Id: "moderator", Roles: []application.Role{application.RoleModerator}}
+)
+
+// countingService counts the vote count queries to verify the batching of the dataloader
+type countingService struct {
+	application.Service
+	countVotes int32
+}
+
+func (s *countingService) CountVotesQuery(ctx context.Context, user application.AuthenticatedUser, opinions []application.OpinionId) (map[application.OpinionId]application.VoteCount, error) {
+	atomic.AddInt32(&s.countVotes, 1)
+	return s.Service.CountVotesQuery(ctx, user, opinions)
+}
+
+type testServer struct {
+	url     string
+	handler *gql.Handler
+	service *countingService
+}
+
+func newTestServer(t *testing.T) testServer {
+	t.Helper()
+	opinionList := projections.NewOpinionListProjection()
+	broadcast := infrastructure.NewEventBroadcast()
+	bus := infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil)
+	bus.Subscribe(opinionList)
+	bus.Subscribe(broadcast)
+
+	service := &countingService{Service: application.NewOpinionService(
+		infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+		infrastructure.NewOpinionsRepositoryMemory(),
+		bus,
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+		// statements with a link are held for review
+		application.WithModeration(infrastructure.LinkSpamCheck{MaxLinkRatio: 1}),
+	)}
+	directory := infrastructure.NewUserDirectoryMemory(map[application.UserId]string{"alice": "Alice Liddell"})
+
+	handler := gql.NewHandler(service, broadcast, opinionList, directory, rest.HeaderAuthenticator{Header: "X-User-Id", Roles: map[application.UserId][]application.Role{"moderator": {application.RoleModerator}}})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Cleanup(handler.Close)
+
+	return testServer{url: server.URL, handler: handler, service: service}
+}
+
+type gqlError struct {
+	Message    string   `json:"message"`
+	Path       []string `json:"path"`
+	Extensions struct {
+		Code   application.ErrorCode `json:"code"`
+		Fields []struct {
+			Field string `json:"field"`
+		} `json:"fields"`
+	} `json:"extensions"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+// do posts the query as the user and returns the status code with the decoded response
+func do(t *testing.T, url string, user string, query string, variables map[string]any) (int, response) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("query could not be encoded: %s", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request could not be created: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User-Id", user)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("response could not be decoded: %s", err)
+	}
+	return resp.StatusCode, r
+}
+
+func createOpinions(t *testing.T, service application.Service, user application.AuthenticatedUser, statements ...string) []application.Opinion {
+	t.Helper()
+	var opinions []application.Opinion
+	for _, statement := range statements {
+		o, err := service.CreateOpinionCommand(context.Background(), user, application.OpinionCreateDTO{Statement: statement})
+		if err != nil {
+			t.Fatalf("CreateOpinionCommand() returned error %s, but no error is expected", err)
+		}
+		opinions = append(opinions, o)
+	}
+	return opinions
+}
+
+const opinionsQuery = `query($after: String) {
+  opinions(first: 2, after: $after) {
+    totalCount
+    pageInfo { hasNextPage endCursor }
+    edges { node { statement status author { id displayName } votes { agreements disagreements } } }
+  }
+}`
+
+type opinionsPage struct {
+	Opinions struct {
+		TotalCount int `json:"totalCount"`
+		PageInfo   struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+		Edges []struct {
+			Node struct {
+				Statement string `json:"statement"`
+				Status    string `json:"status"`
+				Author    struct {
+					ID          string `json:"id"`
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+				Votes struct {
+					Agreements    int `json:"agreements"`
+					Disagreements int `json:"disagreements"`
+				} `json:"votes"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"opinions"`
+}
+
+func TestHandler_opinions(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+	opinions := createOpinions(t, server.service, alice, "first", "second")
+	createOpinions(t, server.service, bob, "third")
+	_, err := server.service.CreateVoteCommand(context.Background(), bob, application.VoteCreateAndUpdateDTO{Opinion: opinions[0].ID, Agreement: true})
+	assert.NoError(t, err)
+
+	status, resp := do(t, server.url, "bob", opinionsQuery, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+
+	var page opinionsPage
+	assert.NoError(t, json.Unmarshal(resp.Data, &page))
+	assert.Equal(t, 3, page.Opinions.TotalCount)
+	assert.True(t, page.Opinions.PageInfo.HasNextPage)
+	if assert.Len(t, page.Opinions.Edges, 2) {
+		first := page.Opinions.Edges[0].Node
+		assert.Equal(t, "first", first.Statement)
+		assert.Equal(t, "PUBLISHED", first.Status)
+		assert.Equal(t, "alice", first.Author.ID)
+		assert.Equal(t, "Alice Liddell", first.Author.DisplayName)
+		assert.Equal(t, 1, first.Votes.Agreements)
+		assert.Equal(t, 0, page.Opinions.Edges[1].Node.Votes.Agreements)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.service.countVotes), "the vote counts of a page are loaded with a single query")
+
+	// the end cursor stays valid after its opinion was deleted
+	assert.NoError(t, server.service.DeleteOpinionCommand(context.Background(), alice, opinions[1].ID))
+
+	_, resp = do(t, server.url, "bob", opinionsQuery, map[string]any{"after": page.Opinions.PageInfo.EndCursor})
+	assert.Empty(t, resp.Errors)
+	page = opinionsPage{}
+	assert.NoError(t, json.Unmarshal(resp.Data, &page))
+	assert.False(t, page.Opinions.PageInfo.HasNextPage)
+	if assert.Len(t, page.Opinions.Edges, 1) {
+		assert.Equal(t, "third", page.Opinions.Edges[0].Node.Statement)
+		assert.Equal(t, "bob", page.Opinions.Edges[0].Node.Author.DisplayName, "unknown users are named by their id")
+	}
+}
+
+func TestHandler_mutations(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	_, resp := do(t, server.url, "alice", `mutation { createOpinion(input: {statement: "graphs are nice"}) { id version author { displayName } } }`, nil)
+	assert.Empty(t, resp.Errors)
+	var created struct {
+		CreateOpinion struct {
+			ID      string `json:"id"`
+			Version int    `json:"version"`
+			Author  struct {
+				DisplayName string `json:"displayName"`
+			} `json:"author"`
+		} `json:"createOpinion"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Data, &created))
+	assert.Equal(t, 1, created.CreateOpinion.Version)
+	assert.Equal(t, "Alice Liddell", created.CreateOpinion.Author.DisplayName)
+	id := created.CreateOpinion.ID
+
+	_, resp = do(t, server.url, "bob", `mutation($id: ID!) {
+  createVote(input: {opinionId: $id, agreement: true}) { version }
+  updateVote(input: {opinionId: $id, agreement: false, version: 1}) { agreement voter { id } }
+  reportOpinion(opinionId: $id, reason: SPAM) { reason reporter { id } }
+}`, map[string]any{"id": id})
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{
+  "createVote": {"version": 1},
+  "updateVote": {"agreement": false, "voter": {"id": "bob"}},
+  "reportOpinion": {"reason": "SPAM", "reporter": {"id": "bob"}}
+}`, string(resp.Data))
+
+	_, resp = do(t, server.url, "alice", `mutation($id: ID!) {
+  editOpinion(input: {id: $id, statement: "graphs are great", version: 1}) { statement version votes { disagreements } }
+  deleteOpinion(id: $id)
+}`, map[string]any{"id": id})
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"editOpinion": {"statement": "graphs are great", "version": 2, "votes": {"disagreements": 1}}, "deleteOpinion": "`+id+`"}`, string(resp.Data))
+}
+
+func TestHandler_errors(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	status, resp := do(t, server.url, "", `{ me { id } }`, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Empty(t, resp.Data)
+
+	tests := []struct {
+		name    string
+		query   string
+		code    application.ErrorCode
+		field   string
+		message string
+	}{
+		{
+			name:  "page too large",
+			query: `{ opinions(first: 101) { totalCount } }`,
+			code:  application.CodeValidation,
+			field: "first",
+		},
+		{
+			name:  "unknown cursor",
+			query: `{ opinions(after: "unknown") { totalCount } }`,
+			code:  application.CodeValidation,
+			field: "after",
+		},
+		{
+			name:  "unknown opinion",
+			query: `mutation { editOpinion(input: {id: "unknown", statement: "graphs are nice"}) { id } }`,
+			code:  application.CodeNotFound,
+		},
+		{
+			name:  "invalid statement",
+			query: `mutation { createOpinion(input: {statement: ""}) { id } }`,
+			code:  application.CodeValidation,
+			field: "statement",
+		},
+		{
+			name:    "unknown field",
+			query:   `{ me { name } }`,
+			code:    "GRAPHQL_VALIDATION_FAILED",
+			message: `Cannot query field "name" on type "User".`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, resp := do(t, server.url, "bob", tt.query, nil)
+			if !assert.Len(t, resp.Errors, 1) {
+				return
+			}
+			gqlErr := resp.Errors[0]
+			assert.Equal(t, tt.code, gqlErr.Extensions.Code)
+			if tt.field != "" && assert.Len(t, gqlErr.Extensions.Fields, 1) {
+				assert.Equal(t, tt.field, gqlErr.Extensions.Fields[0].Field)
+			}
+			if tt.message != "" {
+				assert.Equal(t, tt.message, gqlErr.Message)
+			}
+		})
+	}
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscribe opens a graphql-transport-ws connection as the user and starts the subscription
+func subscribe(t *testing.T, url string, user string, query string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http"), http.Header{"X-User-Id": {user}})
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	assert.NoError(t, conn.WriteJSON(wsMessage{Type: "connection_init"}))
+	var ack wsMessage
+	assert.NoError(t, conn.ReadJSON(&ack))
+	assert.Equal(t, "connection_ack", ack.Type)
+
+	payload, _ := json.Marshal(map[string]string{"query": query})
+	assert.NoError(t, conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload}))
+	return conn
+}
+
+// next reads the next result of the subscription, the keep alive messages are skipped
+func next(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("message could not be read: %s", err)
+		}
+		if msg.Type == "next" {
+			return string(msg.Payload)
+		}
+		if msg.Type != "ping" && msg.Type != "pong" {
+			t.Fatalf("unexpected message %s", msg.Type)
+		}
+	}
+}
+
+func TestHandler_subscriptions(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	created := subscribe(t, server.url, "bob", `subscription { opinionCreated { statement author { displayName } } }`)
+	tallies := subscribe(t, server.url, "bob", `subscription { voteTallyChanged { agreements disagreements } }`)
+	// the subscriptions are started asynchronously after the subscribe messages
+	time.Sleep(100 * time.Millisecond)
+
+	opinions := createOpinions(t, server.service, alice, "subscriptions are nice")
+	assert.JSONEq(t, `{"data": {"opinionCreated": {"statement": "subscriptions are nice", "author": {"displayName": "Alice Liddell"}}}}`, next(t, created))
+
+	_, err := server.service.CreateVoteCommand(context.Background(), alice, application.VoteCreateAndUpdateDTO{Opinion: opinions[0].ID, Agreement: false})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"voteTallyChanged": {"agreements": 0, "disagreements": 1}}}`, next(t, tallies))
+}
+
+func TestHandler_subscriptions_visibility(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	moderator := subscribe(t, server.url, "moderator", `subscription { opinionCreated { statement status } }`)
+	created := subscribe(t, server.url, "bob", `subscription { opinionCreated { statement status } }`)
+	time.Sleep(100 * time.Millisecond)
+
+	createOpinions(t, server.service, alice, "read https://example.com", "subscriptions are nice")
+	assert.JSONEq(t, `{"data": {"opinionCreated": {"statement": "read https://example.com", "status": "PENDING_REVIEW"}}}`, next(t, moderator),
+		"the moderators see the opinions which are held for review")
+	assert.JSONEq(t, `{"data": {"opinionCreated": {"statement": "subscriptions are nice", "status": "PUBLISHED"}}}`, next(t, created),
+		"the opinions of other users which are held for review are not sent")
+}
+
+func TestHandler_subscriptions_review(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	updated := subscribe(t, server.url, "bob", `subscription { opinionUpdated { statement status } }`)
+	removed := subscribe(t, server.url, "bob", `subscription { opinionRemoved }`)
+	time.Sleep(100 * time.Millisecond)
+
+	opinions := createOpinions(t, server.service, alice, "read https://example.com")
+	_, err := server.service.ApproveOpinionCommand(context.Background(), moderator, opinions[0].ID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"opinionUpdated": {"statement": "read https://example.com", "status": "PUBLISHED"}}}`, next(t, updated),
+		"the approved opinion is sent to the other users")
+
+	_, err = server.service.HideOpinionCommand(context.Background(), moderator, opinions[0].ID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"opinionRemoved": "`+string(opinions[0].ID)+`"}}`, next(t, removed),
+		"the hidden opinion is removed for the other users")
+}
+
+func TestHandler_Close(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+	conn := subscribe(t, server.url, "bob", `subscription { opinionDeleted }`)
+
+	server.handler.Close()
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error %v", err)
+			break
+		}
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	_, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.url, "http"), http.Header{"X-User-Id": {"bob"}})
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package gql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// loaderWait is the time a loader collects keys before it fetches them with a single call
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch is the count of keys which are fetched at once, a full batch is fetched immediately
+	loaderMaxBatch = 100
+)
+
+// loader batches the lookups of the resolvers which run concurrently for the fields of a list,
+// so a page of opinions costs one call per kind of lookup instead of one per opinion.
+// The results are cached for the lifetime of the loader, which is a single response.
+type loader[K comparable, V any] struct {
+	fetch    func(ctx context.Context, keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	batch   *batch[K, V]
+	batches map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:    fetch,
+		wait:     loaderWait,
+		maxBatch: loaderMaxBatch,
+		batches:  make(map[K]*batch[K, V]),
+	}
+}
+
+// Load returns the value of the key, the zero value if the fetch did not return the key
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = l.add(ctx, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.values[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// add appends the key to the open batch, l.mu must be held
+func (l *loader[K, V]) add(ctx context.Context, key K) *batch[K, V] {
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(l.wait, func() {
+			l.dispatch(ctx, b)
+		})
+	}
+
+	b.keys = append(b.keys, key)
+	l.batches[key] = b
+	if len(b.keys) >= l.maxBatch {
+		l.batch = nil
+		go l.dispatch(ctx, b)
+	}
+	return b
+}
+
+// dispatch fetches the keys of the batch once, either after the wait or when the batch is full
+func (l *loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		b.values, b.err = l.fetch(ctx, b.keys)
+		close(b.done)
+	})
+}
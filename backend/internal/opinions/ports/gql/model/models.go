@@ -0,0 +1,34 @@
+// Package model holds the types of the GraphQL schema, models_gen.go is generated by gqlgen.
+// The fields which reference users and tallies are resolved by the dataloaders of the gql package.
+package model
+
+import "time"
+
+type User struct {
+	ID string
+}
+
+type Opinion struct {
+	ID        string
+	OwnerID   string
+	CreatedAt time.Time
+	Statement string
+	Status    OpinionStatus
+	Version   int64
+}
+
+type Vote struct {
+	OpinionID string
+	VoterID   string
+	Agreement bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int64
+}
+
+type Report struct {
+	OpinionID  string
+	ReporterID string
+	Reason     ReportReason
+	CreatedAt  time.Time
+}
@@ -0,0 +1,187 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type CreateOpinionInput struct {
+	Statement string `json:"statement"`
+	// Makes retries safe, the same key returns the opinion of the first request
+	IdempotencyKey *string `json:"idempotencyKey"`
+}
+
+type EditOpinionInput struct {
+	ID        string `json:"id"`
+	Statement string `json:"statement"`
+	// The last known version, omitting it skips the version check
+	Version *int `json:"version"`
+}
+
+type OpinionConnection struct {
+	Edges      []*OpinionEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type OpinionEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Opinion `json:"node"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+type VoteCount struct {
+	Agreements    int `json:"agreements"`
+	Disagreements int `json:"disagreements"`
+}
+
+type VoteInput struct {
+	OpinionID string `json:"opinionId"`
+	Agreement bool   `json:"agreement"`
+	// The last known version of the vote for updates, omitting it skips the version check
+	Version *int `json:"version"`
+}
+
+type VoteTally struct {
+	OpinionID     string `json:"opinionId"`
+	Agreements    int    `json:"agreements"`
+	Disagreements int    `json:"disagreements"`
+}
+
+type OpinionStatus string
+
+const (
+	OpinionStatusPublished     OpinionStatus = "PUBLISHED"
+	OpinionStatusPendingReview OpinionStatus = "PENDING_REVIEW"
+	OpinionStatusRejected      OpinionStatus = "REJECTED"
+	OpinionStatusHidden        OpinionStatus = "HIDDEN"
+)
+
+var AllOpinionStatus = []OpinionStatus{
+	OpinionStatusPublished,
+	OpinionStatusPendingReview,
+	OpinionStatusRejected,
+	OpinionStatusHidden,
+}
+
+func (e OpinionStatus) IsValid() bool {
+	switch e {
+	case OpinionStatusPublished, OpinionStatusPendingReview, OpinionStatusRejected, OpinionStatusHidden:
+		return true
+	}
+	return false
+}
+
+func (e OpinionStatus) String() string {
+	return string(e)
+}
+
+func (e *OpinionStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OpinionStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OpinionStatus", str)
+	}
+	return nil
+}
+
+func (e OpinionStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "SPAM"
+	ReportReasonAbuse          ReportReason = "ABUSE"
+	ReportReasonMisinformation ReportReason = "MISINFORMATION"
+	ReportReasonOther          ReportReason = "OTHER"
+)
+
+var AllReportReason = []ReportReason{
+	ReportReasonSpam,
+	ReportReasonAbuse,
+	ReportReasonMisinformation,
+	ReportReasonOther,
+}
+
+func (e ReportReason) IsValid() bool {
+	switch e {
+	case ReportReasonSpam, ReportReasonAbuse, ReportReasonMisinformation, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+func (e ReportReason) String() string {
+	return string(e)
+}
+
+func (e *ReportReason) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportReason", str)
+	}
+	return nil
+}
+
+func (e ReportReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReportResolution string
+
+const (
+	ReportResolutionDismissed ReportResolution = "DISMISSED"
+	ReportResolutionUpheld    ReportResolution = "UPHELD"
+)
+
+var AllReportResolution = []ReportResolution{
+	ReportResolutionDismissed,
+	ReportResolutionUpheld,
+}
+
+func (e ReportResolution) IsValid() bool {
+	switch e {
+	case ReportResolutionDismissed, ReportResolutionUpheld:
+		return true
+	}
+	return false
+}
+
+func (e ReportResolution) String() string {
+	return string(e)
+}
+
+func (e *ReportResolution) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportResolution(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportResolution", str)
+	}
+	return nil
+}
+
+func (e ReportResolution) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,246 @@
+package gql
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/gql/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver is the root of the resolvers, every field passes the authenticated user to the service
+type Resolver struct {
+	service application.Service
+	events  EventSubscriber
+	tallies TallyReader
+}
+
+func (r *Resolver) Mutation() MutationResolver         { return &mutationResolver{r} }
+func (r *Resolver) Opinion() OpinionResolver           { return &opinionResolver{r} }
+func (r *Resolver) Query() QueryResolver               { return &queryResolver{r} }
+func (r *Resolver) Report() ReportResolver             { return &reportResolver{r} }
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+func (r *Resolver) User() UserResolver                 { return &userResolver{r} }
+func (r *Resolver) Vote() VoteResolver                 { return &voteResolver{r} }
+
+type queryResolver struct{ *Resolver }
+
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	return &model.User{ID: string(userFrom(ctx).Id)}, nil
+}
+
+// Opinions returns the page after the cursor, the cursor of an edge is the encoded position of its opinion
+func (r *queryResolver) Opinions(ctx context.Context, first *int, after *string) (*model.OpinionConnection, error) {
+	page := application.OpinionPage{Limit: 20}
+	if first != nil {
+		page.Limit = *first
+	}
+	if page.Limit < 0 || page.Limit > application.MaxOpinionPageLimit {
+		return nil, application.NewValidationError(application.FieldError{Field: "first", Message: "must be between 0 and " + strconv.Itoa(application.MaxOpinionPageLimit)})
+	}
+
+	if after != nil {
+		cursor, ok := decodeCursor(*after)
+		if !ok {
+			return nil, application.NewValidationError(application.FieldError{Field: "after", Message: "must be the cursor of a listed opinion"})
+		}
+		page.After = cursor
+	}
+
+	list, err := r.service.ListOpinionsPageQuery(ctx, userFrom(ctx), page)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.OpinionConnection{
+		Edges:      make([]*model.OpinionEdge, 0, len(list.Opinions)),
+		PageInfo:   &model.PageInfo{HasNextPage: list.HasNextPage},
+		TotalCount: list.TotalCount,
+	}
+	for _, o := range list.Opinions {
+		connection.Edges = append(connection.Edges, &model.OpinionEdge{Cursor: encodeCursor(o.Cursor()), Node: toOpinion(o)})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	return connection, nil
+}
+
+type mutationResolver struct{ *Resolver }
+
+func (r *mutationResolver) CreateOpinion(ctx context.Context, input model.CreateOpinionInput) (*model.Opinion, error) {
+	dto := application.OpinionCreateDTO{Statement: input.Statement}
+	if input.IdempotencyKey != nil {
+		dto.IdempotencyKey = *input.IdempotencyKey
+	}
+	return toOpinionOrError(r.service.CreateOpinionCommand(ctx, userFrom(ctx), dto))
+}
+
+func (r *mutationResolver) EditOpinion(ctx context.Context, input model.EditOpinionInput) (*model.Opinion, error) {
+	return toOpinionOrError(r.service.EditOpinionCommand(ctx, userFrom(ctx), application.OpinionId(input.ID), application.OpinionUpdateDTO{
+		Statement: input.Statement,
+		Version:   version(input.Version),
+	}))
+}
+
+func (r *mutationResolver) DeleteOpinion(ctx context.Context, id string) (string, error) {
+	if err := r.service.DeleteOpinionCommand(ctx, userFrom(ctx), application.OpinionId(id)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *mutationResolver) ApproveOpinion(ctx context.Context, id string) (*model.Opinion, error) {
+	return toOpinionOrError(r.service.ApproveOpinionCommand(ctx, userFrom(ctx), application.OpinionId(id)))
+}
+
+func (r *mutationResolver) RejectOpinion(ctx context.Context, id string) (*model.Opinion, error) {
+	return toOpinionOrError(r.service.RejectOpinionCommand(ctx, userFrom(ctx), application.OpinionId(id)))
+}
+
+func (r *mutationResolver) HideOpinion(ctx context.Context, id string) (*model.Opinion, error) {
+	return toOpinionOrError(r.service.HideOpinionCommand(ctx, userFrom(ctx), application.OpinionId(id)))
+}
+
+func (r *mutationResolver) ReportOpinion(ctx context.Context, opinionID string, reason model.ReportReason) (*model.Report, error) {
+	report, err := r.service.ReportOpinionCommand(ctx, userFrom(ctx), application.ReportCreateDTO{
+		Opinion: application.OpinionId(opinionID),
+		Reason:  application.ReportReason(strings.ToLower(string(reason))),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.Report{
+		OpinionID:  string(report.Opinion),
+		ReporterID: string(report.Reporter),
+		Reason:     model.ReportReason(strings.ToUpper(string(report.Reason))),
+		CreatedAt:  report.CreatedAt,
+	}, nil
+}
+
+func (r *mutationResolver) ResolveReports(ctx context.Context, opinionID string, resolution model.ReportResolution) (*model.Opinion, error) {
+	return toOpinionOrError(r.service.ResolveReportsCommand(ctx, userFrom(ctx), application.OpinionId(opinionID), application.ReportResolution(strings.ToLower(string(resolution)))))
+}
+
+func (r *mutationResolver) CreateVote(ctx context.Context, input model.VoteInput) (*model.Vote, error) {
+	return toVoteOrError(r.service.CreateVoteCommand(ctx, userFrom(ctx), toVoteDTO(input)))
+}
+
+func (r *mutationResolver) UpdateVote(ctx context.Context, input model.VoteInput) (*model.Vote, error) {
+	return toVoteOrError(r.service.UpdateVoteCommand(ctx, userFrom(ctx), toVoteDTO(input)))
+}
+
+func (r *mutationResolver) DeleteVote(ctx context.Context, opinionID string) (*model.Vote, error) {
+	return toVoteOrError(r.service.DeleteVoteCommand(ctx, userFrom(ctx), application.OpinionId(opinionID)))
+}
+
+type opinionResolver struct{ *Resolver }
+
+func (r *opinionResolver) Author(_ context.Context, obj *model.Opinion) (*model.User, error) {
+	return &model.User{ID: obj.OwnerID}, nil
+}
+
+func (r *opinionResolver) Votes(ctx context.Context, obj *model.Opinion) (*model.VoteCount, error) {
+	count, err := loadersFrom(ctx).voteCounts.Load(ctx, application.OpinionId(obj.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &model.VoteCount{Agreements: count.Agreements, Disagreements: count.Disagreements}, nil
+}
+
+type userResolver struct{ *Resolver }
+
+func (r *userResolver) DisplayName(ctx context.Context, obj *model.User) (string, error) {
+	name, err := loadersFrom(ctx).displayNames.Load(ctx, application.UserId(obj.ID))
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return obj.ID, nil
+	}
+	return name, nil
+}
+
+type voteResolver struct{ *Resolver }
+
+func (r *voteResolver) Voter(_ context.Context, obj *model.Vote) (*model.User, error) {
+	return &model.User{ID: obj.VoterID}, nil
+}
+
+type reportResolver struct{ *Resolver }
+
+func (r *reportResolver) Reporter(_ context.Context, obj *model.Report) (*model.User, error) {
+	return &model.User{ID: obj.ReporterID}, nil
+}
+
+// encodeCursor joins the creation time and the id of the position, the time has no spaces
+func encodeCursor(c application.OpinionCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.Format(time.RFC3339Nano) + " " + string(c.ID)))
+}
+
+func decodeCursor(cursor string) (application.OpinionCursor, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return application.OpinionCursor{}, false
+	}
+
+	createdAt, id, found := strings.Cut(string(decoded), " ")
+	if !found {
+		return application.OpinionCursor{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return application.OpinionCursor{}, false
+	}
+	return application.OpinionCursor{CreatedAt: t, ID: application.OpinionId(id)}, true
+}
+
+// version converts the optional version of an input, omitting it skips the version check
+func version(v *int) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
+
+func toVoteDTO(input model.VoteInput) application.VoteCreateAndUpdateDTO {
+	return application.VoteCreateAndUpdateDTO{
+		Agreement: input.Agreement,
+		Opinion:   application.OpinionId(input.OpinionID),
+		Version:   version(input.Version),
+	}
+}
+
+func toOpinion(o application.Opinion) *model.Opinion {
+	return &model.Opinion{
+		ID:        string(o.ID),
+		OwnerID:   string(o.Owner),
+		CreatedAt: o.CreatedAt,
+		Statement: o.Statement,
+		Status:    model.OpinionStatus(strings.ToUpper(string(o.Status))),
+		Version:   o.Version,
+	}
+}
+
+func toOpinionOrError(o application.Opinion, err error) (*model.Opinion, error) {
+	if err != nil {
+		return nil, err
+	}
+	return toOpinion(o), nil
+}
+
+func toVoteOrError(v application.Vote, err error) (*model.Vote, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &model.Vote{
+		OpinionID: string(v.Opinion),
+		VoterID:   string(v.Voter),
+		Agreement: v.Agreement,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Version:   v.Version,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package gql
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/gql/model"
+)
+
+type subscriptionResolver struct{ *Resolver }
+
+func (r *subscriptionResolver) OpinionCreated(ctx context.Context) (<-chan *model.Opinion, error) {
+	visibility, err := r.service.OpinionVisibilityQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(ctx, r.events, func(event application.Event) (*model.Opinion, bool) {
+		if event.Type != application.EventOpinionCreated || !visibility.Visible(event.Opinion) {
+			return nil, false
+		}
+		return toOpinion(event.Opinion), true
+	}), nil
+}
+
+func (r *subscriptionResolver) OpinionUpdated(ctx context.Context) (<-chan *model.Opinion, error) {
+	visibility, err := r.service.OpinionVisibilityQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(ctx, r.events, func(event application.Event) (*model.Opinion, bool) {
+		if !updated(event) || visibility.ChangeOf(event) != application.OpinionShown {
+			return nil, false
+		}
+		return toOpinion(event.Opinion), true
+	}), nil
+}
+
+func (r *subscriptionResolver) OpinionRemoved(ctx context.Context) (<-chan string, error) {
+	visibility, err := r.service.OpinionVisibilityQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(ctx, r.events, func(event application.Event) (string, bool) {
+		return string(event.Opinion.ID), updated(event) && visibility.ChangeOf(event) == application.OpinionRemoved
+	}), nil
+}
+
+func (r *subscriptionResolver) OpinionDeleted(ctx context.Context) (<-chan string, error) {
+	return subscribe(ctx, r.events, func(event application.Event) (string, bool) {
+		return string(event.Opinion.ID), event.Type == application.EventOpinionDeleted
+	}), nil
+}
+
+func (r *subscriptionResolver) VoteTallyChanged(ctx context.Context) (<-chan *model.VoteTally, error) {
+	visibility, err := r.service.OpinionVisibilityQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(ctx, r.events, func(event application.Event) (*model.VoteTally, bool) {
+		switch event.Type {
+		case application.EventVoteSubmitted, application.EventVoteUpdated, application.EventVoteDeleted:
+		default:
+			return nil, false
+		}
+		summary, found := r.tallies.Get(event.Vote.Opinion)
+		if !found || !visibility.Visible(application.Opinion{ID: summary.ID, Owner: summary.Owner, Status: summary.Status}) {
+			return nil, false
+		}
+		return &model.VoteTally{OpinionID: string(summary.ID), Agreements: summary.Agreements, Disagreements: summary.Disagreements}, true
+	}), nil
+}
+
+// subscribe passes the converted events until the subscription ends, a subscription which fell behind is completed
+func subscribe[T any](ctx context.Context, events EventSubscriber, convert func(application.Event) (T, bool)) <-chan T {
+	in, cancel := events.Subscribe(subscriptionBuffer)
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-in:
+				if !ok {
+					return
+				}
+				v, ok := convert(event)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// updated reports whether the event changes an existing opinion, the subscribers receive the opinion or its removal
+func updated(event application.Event) bool {
+	switch event.Type {
+	case application.EventOpinionEdited, application.EventOpinionApproved, application.EventOpinionRejected, application.EventOpinionHidden, application.EventReportsResolved:
+		return true
+	}
+	return false
+}
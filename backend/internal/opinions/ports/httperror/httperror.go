@@ -0,0 +1,90 @@
+// Package httperror renders the application errors for the HTTP ports with the status codes and the error schema of the REST API.
+package httperror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusCodes maps the application error codes to HTTP status codes
+var statusCodes = map[application.ErrorCode]int{
+	application.CodeValidation:      http.StatusBadRequest,
+	application.CodeNotFound:        http.StatusNotFound,
+	application.CodeConflict:        http.StatusConflict,
+	application.CodeForbidden:       http.StatusForbidden,
+	application.CodeUnauthenticated: http.StatusUnauthorized,
+	application.CodeRateLimited:     http.StatusTooManyRequests,
+	application.CodeInternal:        http.StatusInternalServerError,
+}
+
+// Status maps the error to its HTTP status code, errors which are no application errors are internal
+func Status(err error) int {
+	var appErr *application.Error
+	if !errors.As(err, &appErr) {
+		return http.StatusInternalServerError
+	}
+
+	status, ok := statusCodes[appErr.Code]
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// Body is the Error schema of the REST API
+type Body struct {
+	Code    application.ErrorCode `json:"code"`
+	Message string                `json:"message"`
+	Fields  []FieldError          `json:"fields,omitempty"`
+}
+
+// FieldError is the FieldError schema of the REST API
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewBody renders the error, the cause of an error is never exposed to the client
+func NewBody(err error) Body {
+	appErr := applicationError(err)
+
+	body := Body{Code: appErr.Code, Message: appErr.Message}
+	for _, f := range appErr.Fields {
+		body.Fields = append(body.Fields, FieldError{Field: f.Field, Message: f.Message})
+	}
+	return body
+}
+
+// RetryAfter returns the whole seconds after which a rate limited request may be sent again, zero for the other errors.
+// It is rounded up, which avoids a retry which is rejected again.
+func RetryAfter(err error) int {
+	appErr := applicationError(err)
+	return int((appErr.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// Write renders the error with the status code of Status
+func Write(w http.ResponseWriter, err error) {
+	WriteStatus(w, Status(err), err)
+}
+
+// WriteStatus renders the error with the status code, e.g. for the errors of a port which are not caused by the service
+func WriteStatus(w http.ResponseWriter, status int, err error) {
+	if seconds := RetryAfter(err); seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(NewBody(err))
+}
+
+func applicationError(err error) *application.Error {
+	var appErr *application.Error
+	if !errors.As(err, &appErr) {
+		return application.NewError(application.CodeInternal, "internal error", err)
+	}
+	return appErr
+}
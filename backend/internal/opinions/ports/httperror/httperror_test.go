@@ -0,0 +1,63 @@
+package httperror_test
+
+import (
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+		body       string
+	}{
+		{
+			name:   "validation error with fields",
+			err:    application.NewValidationError(application.FieldError{Field: "statement", Message: "must not be empty"}),
+			status: http.StatusBadRequest,
+			body:   `{"code":"validation","message":"validation failed","fields":[{"field":"statement","message":"must not be empty"}]}`,
+		},
+		{
+			name:   "not found",
+			err:    application.OpinionNotFoundError,
+			status: http.StatusNotFound,
+			body:   `{"code":"not_found","message":"opinion not found"}`,
+		},
+		{
+			name:       "rate limited error rounds the retry up",
+			err:        application.NewRateLimitedError(1500 * time.Millisecond),
+			status:     http.StatusTooManyRequests,
+			retryAfter: "2",
+			body:       `{"code":"rate_limited","message":"rate limit exceeded"}`,
+		},
+		{
+			name:   "the cause of an unknown error is not exposed",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+			body:   `{"code":"internal","message":"internal error"}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			httperror.Write(rec, tt.err)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.retryAfter, rec.Header().Get("Retry-After"))
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+}
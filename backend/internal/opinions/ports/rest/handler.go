@@ -0,0 +1,497 @@
+// Package rest exposes the opinions application service as JSON over HTTP.
+// The routes, parameters and models are generated from the OpenAPI spec in api/openapi.yaml.
+package rest
+
+//go:generate oapi-codegen --config oapi-codegen.yaml ../../../../api/openapi.yaml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Authenticator resolves the user of a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (application.AuthenticatedUser, error)
+}
+
+// HeaderAuthenticator trusts the user id in the given header and the comma separated roles in the RolesHeader.
+// It must only be used behind a proxy which authenticates the user and sets the headers.
+// Every user has the application.RoleUser, the Roles grant additional roles to the listed users.
+type HeaderAuthenticator struct {
+	Header      string
+	RolesHeader string
+	Roles       map[application.UserId][]application.Role
+}
+
+func (h HeaderAuthenticator) Authenticate(r *http.Request) (application.AuthenticatedUser, error) {
+	id := r.Header.Get(h.Header)
+	if id == "" {
+		return application.AuthenticatedUser{}, application.UnauthenticatedError
+	}
+
+	user := application.AuthenticatedUser{
+		Id:    application.UserId(id),
+		Roles: []application.Role{application.RoleUser},
+	}
+	user.Roles = append(user.Roles, h.Roles[user.Id]...)
+
+	if h.RolesHeader == "" {
+		return user, nil
+	}
+	for _, role := range strings.Split(r.Header.Get(h.RolesHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			user.Roles = append(user.Roles, application.Role(role))
+		}
+	}
+	return user, nil
+}
+
+// invalidIfMatchError is returned if the If-Match header does not contain a single ETag of this API
+var invalidIfMatchError = &application.Error{Code: application.CodeValidation, Message: "If-Match header is invalid", Fields: []application.FieldError{{Field: "If-Match", Message: "must be * or a single ETag"}}}
+
+// NewHandler creates the routes of the OpenAPI spec, requests which do not match the spec are rejected before they reach the service
+func NewHandler(service application.Service, authenticator Authenticator) (http.Handler, error) {
+	spec, err := GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+	routes := HandlerWithOptions(h, GorillaServerOptions{ErrorHandlerFunc: writeParameterError})
+	return newRequestValidator(spec, authenticator, routes)
+}
+
+// handler implements the generated ServerInterface
+type handler struct {
+	service       application.Service
+	authenticator Authenticator
+}
+
+var _ ServerInterface = (*handler)(nil)
+
+func (h *handler) GetHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Health{Status: HealthStatusOk})
+}
+
+func (h *handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, string(role))
+	}
+	writeJSON(w, http.StatusOK, User{Id: string(user.Id), Roles: roles})
+}
+
+func (h *handler) ListOpinions(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	opinions, err := h.service.ListOpinionsQuery(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	resp := make([]Opinion, 0, len(opinions))
+	for _, o := range opinions {
+		resp = append(resp, toOpinionResponse(o))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) CreateOpinion(w http.ResponseWriter, r *http.Request, params CreateOpinionParams) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	var req OpinionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.Write(w, application.NewError(application.CodeValidation, "invalid request body", err))
+		return
+	}
+
+	opinion, err := h.service.CreateOpinionCommand(r.Context(), user, application.OpinionCreateDTO{
+		Statement:      req.Statement,
+		IdempotencyKey: value(params.IdempotencyKey),
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeVersioned(w, http.StatusCreated, opinion.Version, toOpinionResponse(opinion))
+}
+
+func (h *handler) EditOpinion(w http.ResponseWriter, r *http.Request, id OpinionId, params EditOpinionParams) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	version, err := ifMatch(params.IfMatch)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	var req OpinionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.Write(w, application.NewError(application.CodeValidation, "invalid request body", err))
+		return
+	}
+
+	opinion, err := h.service.EditOpinionCommand(r.Context(), user, application.OpinionId(id), application.OpinionUpdateDTO{
+		Statement: req.Statement,
+		Version:   version,
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeVersioned(w, http.StatusOK, opinion.Version, toOpinionResponse(opinion))
+}
+
+func (h *handler) DeleteOpinion(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	if err := h.service.DeleteOpinionCommand(r.Context(), user, application.OpinionId(id)); err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkDeleteOpinions removes the opinions selected by the owner, createdFrom and createdBefore query parameters
+func (h *handler) BulkDeleteOpinions(w http.ResponseWriter, r *http.Request, params BulkDeleteOpinionsParams) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	ids, err := h.service.BulkDeleteOpinionsCommand(r.Context(), user, application.OpinionFilter{
+		Owner:         application.UserId(value(params.Owner)),
+		CreatedFrom:   value(params.CreatedFrom),
+		CreatedBefore: value(params.CreatedBefore),
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	resp := BulkDeleteResult{Deleted: make([]string, 0, len(ids))}
+	for _, id := range ids {
+		resp.Deleted = append(resp.Deleted, string(id))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) ApproveOpinion(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	h.reviewOpinion(w, r, id, h.service.ApproveOpinionCommand)
+}
+
+func (h *handler) RejectOpinion(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	h.reviewOpinion(w, r, id, h.service.RejectOpinionCommand)
+}
+
+func (h *handler) HideOpinion(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	h.reviewOpinion(w, r, id, h.service.HideOpinionCommand)
+}
+
+type reviewCommand func(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (application.Opinion, error)
+
+func (h *handler) reviewOpinion(w http.ResponseWriter, r *http.Request, id OpinionId, command reviewCommand) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	opinion, err := command(r.Context(), user, application.OpinionId(id))
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeVersioned(w, http.StatusOK, opinion.Version, toOpinionResponse(opinion))
+}
+
+func (h *handler) ReportOpinion(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.Write(w, application.NewError(application.CodeValidation, "invalid request body", err))
+		return
+	}
+
+	report, err := h.service.ReportOpinionCommand(r.Context(), user, application.ReportCreateDTO{
+		Opinion: application.OpinionId(id),
+		Reason:  application.ReportReason(req.Reason),
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toReportResponse(report))
+}
+
+func (h *handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	reports, err := h.service.ListReportsQuery(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	resp := make([]Report, 0, len(reports))
+	for _, report := range reports {
+		resp = append(resp, toReportResponse(report))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListDecisions returns the policy decisions selected by the user, action, allowed, from, before and limit query parameters
+func (h *handler) ListDecisions(w http.ResponseWriter, r *http.Request, params ListDecisionsParams) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	decisions, err := h.service.ListDecisionsQuery(r.Context(), user, application.DecisionFilter{
+		User:    application.UserId(value(params.User)),
+		Action:  value(params.Action),
+		Allowed: params.Allowed,
+		From:    value(params.From),
+		Before:  value(params.Before),
+		Limit:   value(params.Limit),
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	resp := make([]Decision, 0, len(decisions))
+	for _, decision := range decisions {
+		resp = append(resp, toDecisionResponse(decision))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) ResolveReports(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	var req ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.Write(w, application.NewError(application.CodeValidation, "invalid request body", err))
+		return
+	}
+
+	opinion, err := h.service.ResolveReportsCommand(r.Context(), user, application.OpinionId(id), application.ReportResolution(req.Resolution))
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeVersioned(w, http.StatusOK, opinion.Version, toOpinionResponse(opinion))
+}
+
+func (h *handler) CreateVote(w http.ResponseWriter, r *http.Request, id OpinionId, params CreateVoteParams) {
+	h.saveVote(w, r, id, params.IfMatch, h.service.CreateVoteCommand, http.StatusCreated)
+}
+
+func (h *handler) UpdateVote(w http.ResponseWriter, r *http.Request, id OpinionId, params UpdateVoteParams) {
+	h.saveVote(w, r, id, params.IfMatch, h.service.UpdateVoteCommand, http.StatusOK)
+}
+
+type voteCommand func(ctx context.Context, user application.AuthenticatedUser, vote application.VoteCreateAndUpdateDTO) (application.Vote, error)
+
+func (h *handler) saveVote(w http.ResponseWriter, r *http.Request, id OpinionId, etag *IfMatch, command voteCommand, status int) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	version, err := ifMatch(etag)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	var req VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.Write(w, application.NewError(application.CodeValidation, "invalid request body", err))
+		return
+	}
+
+	vote, err := command(r.Context(), user, application.VoteCreateAndUpdateDTO{
+		Agreement: req.Agreement,
+		Opinion:   application.OpinionId(id),
+		Version:   version,
+	})
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeVersioned(w, status, vote.Version, toVoteResponse(vote))
+}
+
+func (h *handler) DeleteVote(w http.ResponseWriter, r *http.Request, id OpinionId) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	vote, err := h.service.DeleteVoteCommand(r.Context(), user, application.OpinionId(id))
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toVoteResponse(vote))
+}
+
+func toOpinionResponse(o application.Opinion) Opinion {
+	return Opinion{
+		Id:        string(o.ID),
+		Owner:     string(o.Owner),
+		CreatedAt: o.CreatedAt,
+		Statement: o.Statement,
+		Status:    OpinionStatus(o.Status),
+		Version:   o.Version,
+	}
+}
+
+func toVoteResponse(v application.Vote) Vote {
+	return Vote{
+		OpinionId: string(v.Opinion),
+		Voter:     string(v.Voter),
+		Agreement: v.Agreement,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Version:   v.Version,
+	}
+}
+
+func toReportResponse(r application.Report) Report {
+	return Report{
+		OpinionId: string(r.Opinion),
+		Reporter:  string(r.Reporter),
+		Reason:    ReportReason(r.Reason),
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func toDecisionResponse(d application.Decision) Decision {
+	roles := make([]string, 0, len(d.Roles))
+	for _, r := range d.Roles {
+		roles = append(roles, string(r))
+	}
+
+	return Decision{
+		Time:           d.Time,
+		User:           string(d.User),
+		Roles:          roles,
+		Action:         d.Action,
+		OpinionId:      optional(string(d.Opinion)),
+		Owner:          optional(string(d.Owner)),
+		InputHash:      d.InputHash,
+		Allowed:        d.Allowed,
+		Reason:         optional(d.Reason),
+		PolicyRevision: optional(d.PolicyRevision),
+		LatencyNs:      d.Latency.Nanoseconds(),
+	}
+}
+
+// writeParameterError renders parameters which could not be bound to the generated parameter types
+func writeParameterError(w http.ResponseWriter, _ *http.Request, err error) {
+	var paramErr *InvalidParamFormatError
+	if errors.As(err, &paramErr) {
+		httperror.Write(w, application.NewValidationError(application.FieldError{Field: paramErr.ParamName, Message: "has an invalid format"}).WithCause(err))
+		return
+	}
+	httperror.Write(w, application.NewError(application.CodeValidation, "invalid request parameters", err))
+}
+
+// ifMatch returns the version of the If-Match header, a missing header or * skips the version check
+func ifMatch(etag *IfMatch) (int64, error) {
+	if etag == nil || *etag == "" || *etag == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(*etag)
+	if err != nil {
+		return 0, invalidIfMatchError.WithCause(err)
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, invalidIfMatchError.WithCause(err)
+	}
+	return version, nil
+}
+
+// writeVersioned renders the body of a single opinion or vote together with its version as ETag
+func writeVersioned(w http.ResponseWriter, status int, version int64, body any) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// value returns the value of an optional parameter or the zero value if it is missing
+func value[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
+
+// optional returns nil for empty strings which are omitted in the response
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,543 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rest"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testUserHeader  = "X-User-Id"
+	testRolesHeader = "X-User-Roles"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	decisions := infrastructure.NewDecisionLogMemory(infrastructure.DefaultDecisionLogMemorySize)
+	s := application.NewOpinionService(
+		application.NewDecisionLoggingPolicyEnforcementPoint(infrastructure.AuthenticatedUsersPolicyEnforcementPoint{}, decisions, infrastructure.SystemTimeService{}),
+		infrastructure.NewOpinionsRepositoryMemory(),
+		infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+		application.WithDecisionLog(decisions),
+	)
+	return newServer(t, s, rest.HeaderAuthenticator{
+		Header:      testUserHeader,
+		RolesHeader: testRolesHeader,
+		Roles:       map[application.UserId][]application.Role{"admin": {application.RoleAdmin}},
+	})
+}
+
+func newServer(t *testing.T, s application.Service, authenticator rest.Authenticator) *httptest.Server {
+	t.Helper()
+	handler, err := rest.NewHandler(s, authenticator)
+	if err != nil {
+		t.Fatalf("NewHandler() returned error %s, but no error is expected", err)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// specTransport fails the test if a response of an operation of the OpenAPI spec does not match the spec
+type specTransport struct {
+	t      *testing.T
+	router routers.Router
+}
+
+func newSpecClient(t *testing.T) *http.Client {
+	t.Helper()
+	spec, err := rest.GetSwagger()
+	if err != nil {
+		t.Fatalf("GetSwagger() returned error %s, but no error is expected", err)
+	}
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		t.Fatalf("spec routes could not be created: %s", err)
+	}
+	return &http.Client{Transport: specTransport{t: t, router: router}}
+}
+
+func (s specTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	route, pathParams, err := s.router.FindRoute(req)
+	if err != nil {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: route},
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateResponse(req.Context(), input.SetBodyBytes(body)); err != nil {
+		s.t.Errorf("response %d of %s %s does not match the spec: %s", resp.StatusCode, req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func doRequest(t *testing.T, method string, url string, user string, body string, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not create request: %s", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user != "" {
+		req.Header.Set(testUserHeader, user)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := newSpecClient(t).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHandler_opinions(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/opinions", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": ""}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var validation map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&validation))
+	assert.Equal(t, "validation", validation["code"])
+	assert.Equal(t, []any{map[string]any{"field": "statement", "message": "must not be empty"}}, validation["fields"])
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "123", created["owner"])
+	id := created["id"].(string)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/opinions", "123", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var list []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list, 1)
+
+	resp = doRequest(t, http.MethodPut, server.URL+"/opinions/"+id, "456", `{"statement": "copy and pasta is great"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPut, server.URL+"/opinions/"+id, "123", `{"statement": "ok"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPut, server.URL+"/opinions/"+id, "123", `{"statement": "  copy and pasta is great\t"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var edited map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&edited))
+	assert.Equal(t, "copy and pasta is great", edited["statement"])
+	assert.Equal(t, "published", edited["status"])
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reject", "123", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reject", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/opinions", "456", "")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/approve", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reports", "456", `{"reason": "boring"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reports", "456", `{"reason": "spam"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reports", "456", `{"reason": "spam"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/reports", "456", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/reports", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var reports []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&reports))
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "spam", reports[0]["reason"])
+	}
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+id+"/reports/resolve", "admin", `{"resolution": "dismissed"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions/"+id, "123", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions/"+id, "123", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandler_votes(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	var created map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	voteURL := server.URL + "/opinions/" + created["id"].(string) + "/vote"
+
+	resp = doRequest(t, http.MethodPut, voteURL, "456", `{"agreement": true}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, voteURL, "456", `{"agreement": true}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, voteURL, "456", `{"agreement": true}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPut, voteURL, "456", `{"agreement": false}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var vote map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&vote))
+	assert.Equal(t, false, vote["agreement"])
+
+	resp = doRequest(t, http.MethodDelete, voteURL, "456", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/unknown/vote", "456", `{"agreement": true}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandler_idempotencyKey(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	create := func(statement string) (*http.Response, map[string]any) {
+		resp := doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "`+statement+`"}`, "Idempotency-Key", "retry")
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp, body
+	}
+
+	resp, first := create("copy and pasta is fine")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, retried := create("copy and pasta is fine")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, first["id"], retried["id"])
+
+	resp, _ = create("copy and pasta is great")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestHandler_ifMatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	var created map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	opinionURL := server.URL + "/opinions/" + created["id"].(string)
+
+	resp = doRequest(t, http.MethodPut, opinionURL, "123", `{"statement": "copy and pasta is great"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	resp = doRequest(t, http.MethodPut, opinionURL, "123", `{"statement": "copy and pasta is bad"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPut, opinionURL, "123", `{"statement": "copy and pasta is bad"}`, "If-Match", "1")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, opinionURL+"/vote", "456", `{"agreement": true}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	// the second tab still has the first version of the vote
+	resp = doRequest(t, http.MethodPut, opinionURL+"/vote", "456", `{"agreement": false}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	resp = doRequest(t, http.MethodPut, opinionURL+"/vote", "456", `{"agreement": true}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPut, opinionURL+"/vote", "456", `{"agreement": true}`, "If-Match", "*")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+}
+
+func TestHandler_roles(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	var ids []string
+	for _, owner := range []string{"123", "123", "456"} {
+		resp := doRequest(t, http.MethodPost, server.URL+"/opinions", owner, `{"statement": "copy and pasta is fine"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var created map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		ids = append(ids, created["id"].(string))
+	}
+
+	resp := doRequest(t, http.MethodDelete, server.URL+"/opinions/"+ids[0], "456", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+ids[0]+"/hide", "456", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions/"+ids[0]+"/hide", "456", "", testRolesHeader, "moderator")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var hidden map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hidden))
+	assert.Equal(t, "hidden", hidden["status"])
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions?owner=123", "456", "", testRolesHeader, "moderator")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions", "admin", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions?createdBefore=yesterday", "admin", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions?owner=123", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var deleted map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deleted))
+	assert.ElementsMatch(t, []any{ids[0], ids[1]}, deleted["deleted"])
+
+	resp = doRequest(t, http.MethodDelete, server.URL+"/opinions/"+ids[2], "admin", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestHandler_decisions(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/reports", "123", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/decisions", "123", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/decisions?allowed=maybe", "admin", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/decisions?limit=0", "admin", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/decisions?user=123&allowed=false", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var denied []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&denied))
+	assert.Len(t, denied, 2)
+	assert.Equal(t, application.ActionListDecisions, denied[0]["action"])
+	assert.Equal(t, application.ActionListReports, denied[1]["action"])
+	assert.Equal(t, "forbidden: access denied", denied[1]["reason"])
+	assert.Equal(t, infrastructure.BuiltinPolicyRevision, denied[1]["policyRevision"])
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/decisions?user=123&action=CreateOpinion", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Len(t, created, 1)
+	assert.Equal(t, true, created[0]["allowed"])
+}
+
+func TestHandler_rateLimit(t *testing.T) {
+	t.Parallel()
+	s, err := application.NewRateLimitedService(
+		application.NewOpinionService(
+			infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+			infrastructure.NewOpinionsRepositoryMemory(),
+			infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+			infrastructure.RandomIdService{},
+			infrastructure.SystemTimeService{},
+		),
+		infrastructure.NewRateLimitStoreMemory(),
+		map[string]application.RateLimit{application.ActionCreateOpinion: {Burst: 1, Refill: time.Minute}},
+		infrastructure.SystemTimeService{},
+	)
+	assert.NoError(t, err)
+	server := newServer(t, s, rest.HeaderAuthenticator{Header: testUserHeader})
+
+	resp := doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions", "123", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	var body map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "rate_limited", body["code"])
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/opinions", "456", `{"statement": "copy and pasta is fine"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestGetSwagger(t *testing.T) {
+	t.Parallel()
+	spec, err := rest.GetSwagger()
+	if err != nil {
+		t.Fatalf("GetSwagger() returned error %s, but no error is expected", err)
+	}
+	assert.NoError(t, spec.Validate(context.Background()))
+}
+
+func TestHandler_requestValidation(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/health", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/users/me", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/users/me", "admin", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var user map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&user))
+	assert.Equal(t, map[string]any{"id": "admin", "roles": []any{"user", "admin"}}, user)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		body       string
+		wantStatus int
+		wantFields []any
+	}{
+		{
+			name:       "Should reject unauthenticated requests before the body is validated",
+			method:     http.MethodPost,
+			path:       "/opinions",
+			body:       `{"statement": 1}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Should reject bodies with wrong types",
+			method:     http.MethodPost,
+			path:       "/opinions",
+			user:       "123",
+			body:       `{"statement": 1}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []any{map[string]any{"field": "statement", "message": "field must be set to string or not be present"}},
+		},
+		{
+			name:       "Should reject bodies without required properties",
+			method:     http.MethodPost,
+			path:       "/opinions/123/vote",
+			user:       "123",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []any{map[string]any{"field": "agreement", "message": `property "agreement" is missing`}},
+		},
+		{
+			name:       "Should reject values which are not in the enum",
+			method:     http.MethodPost,
+			path:       "/opinions/123/reports/resolve",
+			user:       "admin",
+			body:       `{"resolution": "ignored"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []any{map[string]any{"field": "resolution", "message": `value "ignored" is not one of the allowed values`}},
+		},
+		{
+			name:       "Should reject invalid query parameters",
+			method:     http.MethodGet,
+			path:       "/decisions?limit=0",
+			user:       "admin",
+			wantStatus: http.StatusBadRequest,
+			wantFields: []any{map[string]any{"field": "limit", "message": "number must be at least 1"}},
+		},
+		{
+			name:       "Should reject bodies which are too large",
+			method:     http.MethodPost,
+			path:       "/opinions",
+			user:       "123",
+			body:       `{"statement": "` + strings.Repeat("a", 8<<10) + `"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []any{map[string]any{"field": "body", "message": "must not be larger than 8192 bytes"}},
+		},
+		{
+			name:       "Should pass unknown routes to the router",
+			method:     http.MethodGet,
+			path:       "/unknown",
+			user:       "123",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := doRequest(t, tt.method, server.URL+tt.path, tt.user, tt.body)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantFields == nil {
+				return
+			}
+
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "validation", body["code"])
+			assert.Equal(t, tt.wantFields, body["fields"])
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/opinions", strings.NewReader(`{"statement": "copy and pasta is fine"}`))
+	assert.NoError(t, err)
+	req.Header.Set(testUserHeader, "123")
+	resp, err = http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "JSON bodies require the content type")
+	}
+}
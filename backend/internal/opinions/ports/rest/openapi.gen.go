@@ -0,0 +1,1153 @@
+// Package rest provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.12.4 DO NOT EDIT.
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+)
+
+const (
+	UserIdScopes = "userId.Scopes"
+)
+
+// Defines values for ErrorCode.
+const (
+	ErrorCodeConflict        ErrorCode = "conflict"
+	ErrorCodeForbidden       ErrorCode = "forbidden"
+	ErrorCodeInternal        ErrorCode = "internal"
+	ErrorCodeNotFound        ErrorCode = "not_found"
+	ErrorCodeRateLimited     ErrorCode = "rate_limited"
+	ErrorCodeUnauthenticated ErrorCode = "unauthenticated"
+	ErrorCodeValidation      ErrorCode = "validation"
+)
+
+// Defines values for HealthStatus.
+const (
+	HealthStatusOk HealthStatus = "ok"
+)
+
+// Defines values for OpinionStatus.
+const (
+	OpinionStatusHidden        OpinionStatus = "hidden"
+	OpinionStatusPendingReview OpinionStatus = "pending_review"
+	OpinionStatusPublished     OpinionStatus = "published"
+	OpinionStatusRejected      OpinionStatus = "rejected"
+)
+
+// Defines values for ReportReason.
+const (
+	ReportReasonAbuse          ReportReason = "abuse"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonOther          ReportReason = "other"
+	ReportReasonSpam           ReportReason = "spam"
+)
+
+// Defines values for ResolveRequestResolution.
+const (
+	ResolveRequestResolutionDismissed ResolveRequestResolution = "dismissed"
+	ResolveRequestResolutionUpheld    ResolveRequestResolution = "upheld"
+)
+
+// BulkDeleteResult defines model for BulkDeleteResult.
+type BulkDeleteResult struct {
+	Deleted []string `json:"deleted"`
+}
+
+// Decision defines model for Decision.
+type Decision struct {
+	Action         string    `json:"action"`
+	Allowed        bool      `json:"allowed"`
+	InputHash      string    `json:"inputHash"`
+	LatencyNs      int64     `json:"latencyNs"`
+	OpinionId      *string   `json:"opinionId,omitempty"`
+	Owner          *string   `json:"owner,omitempty"`
+	PolicyRevision *string   `json:"policyRevision,omitempty"`
+	Reason         *string   `json:"reason,omitempty"`
+	Roles          []string  `json:"roles"`
+	Time           time.Time `json:"time"`
+	User           string    `json:"user"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	Code    ErrorCode     `json:"code"`
+	Fields  *[]FieldError `json:"fields,omitempty"`
+	Message string        `json:"message"`
+}
+
+// ErrorCode defines model for Error.Code.
+type ErrorCode string
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Health defines model for Health.
+type Health struct {
+	Status HealthStatus `json:"status"`
+}
+
+// HealthStatus defines model for Health.Status.
+type HealthStatus string
+
+// Opinion defines model for Opinion.
+type Opinion struct {
+	CreatedAt time.Time     `json:"createdAt"`
+	Id        string        `json:"id"`
+	Owner     string        `json:"owner"`
+	Statement string        `json:"statement"`
+	Status    OpinionStatus `json:"status"`
+	Version   int64         `json:"version"`
+}
+
+// OpinionRequest defines model for OpinionRequest.
+type OpinionRequest struct {
+	Statement string `json:"statement"`
+}
+
+// OpinionStatus defines model for OpinionStatus.
+type OpinionStatus string
+
+// Report defines model for Report.
+type Report struct {
+	CreatedAt time.Time    `json:"createdAt"`
+	OpinionId string       `json:"opinionId"`
+	Reason    ReportReason `json:"reason"`
+	Reporter  string       `json:"reporter"`
+}
+
+// ReportReason defines model for ReportReason.
+type ReportReason string
+
+// ReportRequest defines model for ReportRequest.
+type ReportRequest struct {
+	Reason ReportReason `json:"reason"`
+}
+
+// ResolveRequest defines model for ResolveRequest.
+type ResolveRequest struct {
+	Resolution ResolveRequestResolution `json:"resolution"`
+}
+
+// ResolveRequestResolution defines model for ResolveRequest.Resolution.
+type ResolveRequestResolution string
+
+// User defines model for User.
+type User struct {
+	Id    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+// Vote defines model for Vote.
+type Vote struct {
+	Agreement bool      `json:"agreement"`
+	CreatedAt time.Time `json:"createdAt"`
+	OpinionId string    `json:"opinionId"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int64     `json:"version"`
+	Voter     string    `json:"voter"`
+}
+
+// VoteRequest defines model for VoteRequest.
+type VoteRequest struct {
+	Agreement bool `json:"agreement"`
+}
+
+// IdempotencyKey defines model for IdempotencyKey.
+type IdempotencyKey = string
+
+// IfMatch defines model for IfMatch.
+type IfMatch = string
+
+// OpinionId defines model for OpinionId.
+type OpinionId = string
+
+// ErrorResponse defines model for ErrorResponse.
+type ErrorResponse = Error
+
+// OpinionResponse defines model for OpinionResponse.
+type OpinionResponse = Opinion
+
+// VoteResponse defines model for VoteResponse.
+type VoteResponse = Vote
+
+// ListDecisionsParams defines parameters for ListDecisions.
+type ListDecisionsParams struct {
+	User    *string    `form:"user,omitempty" json:"user,omitempty"`
+	Action  *string    `form:"action,omitempty" json:"action,omitempty"`
+	Allowed *bool      `form:"allowed,omitempty" json:"allowed,omitempty"`
+	From    *time.Time `form:"from,omitempty" json:"from,omitempty"`
+	Before  *time.Time `form:"before,omitempty" json:"before,omitempty"`
+	Limit   *int       `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// BulkDeleteOpinionsParams defines parameters for BulkDeleteOpinions.
+type BulkDeleteOpinionsParams struct {
+	Owner         *string    `form:"owner,omitempty" json:"owner,omitempty"`
+	CreatedFrom   *time.Time `form:"createdFrom,omitempty" json:"createdFrom,omitempty"`
+	CreatedBefore *time.Time `form:"createdBefore,omitempty" json:"createdBefore,omitempty"`
+}
+
+// CreateOpinionParams defines parameters for CreateOpinion.
+type CreateOpinionParams struct {
+	// IdempotencyKey Retries with the same key and statement return the opinion of the first request
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
+}
+
+// EditOpinionParams defines parameters for EditOpinion.
+type EditOpinionParams struct {
+	// IfMatch ETag of the last known version, a missing header or * skips the version check
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// CreateVoteParams defines parameters for CreateVote.
+type CreateVoteParams struct {
+	// IfMatch ETag of the last known version, a missing header or * skips the version check
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// UpdateVoteParams defines parameters for UpdateVote.
+type UpdateVoteParams struct {
+	// IfMatch ETag of the last known version, a missing header or * skips the version check
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// CreateOpinionJSONRequestBody defines body for CreateOpinion for application/json ContentType.
+type CreateOpinionJSONRequestBody = OpinionRequest
+
+// EditOpinionJSONRequestBody defines body for EditOpinion for application/json ContentType.
+type EditOpinionJSONRequestBody = OpinionRequest
+
+// ReportOpinionJSONRequestBody defines body for ReportOpinion for application/json ContentType.
+type ReportOpinionJSONRequestBody = ReportRequest
+
+// ResolveReportsJSONRequestBody defines body for ResolveReports for application/json ContentType.
+type ResolveReportsJSONRequestBody = ResolveRequest
+
+// CreateVoteJSONRequestBody defines body for CreateVote for application/json ContentType.
+type CreateVoteJSONRequestBody = VoteRequest
+
+// UpdateVoteJSONRequestBody defines body for UpdateVote for application/json ContentType.
+type UpdateVoteJSONRequestBody = VoteRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// Lists the logged policy decisions, the newest first
+	// (GET /decisions)
+	ListDecisions(w http.ResponseWriter, r *http.Request, params ListDecisionsParams)
+	// Reports that the backend is running
+	// (GET /health)
+	GetHealth(w http.ResponseWriter, r *http.Request)
+	// Deletes the opinions of an owner or a creation time range, at least one filter is required
+	// (DELETE /opinions)
+	BulkDeleteOpinions(w http.ResponseWriter, r *http.Request, params BulkDeleteOpinionsParams)
+	// Lists the opinions the user may see
+	// (GET /opinions)
+	ListOpinions(w http.ResponseWriter, r *http.Request)
+	// Creates an opinion of the user
+	// (POST /opinions)
+	CreateOpinion(w http.ResponseWriter, r *http.Request, params CreateOpinionParams)
+	// Deletes an opinion, owners delete their own opinions and admins every opinion
+	// (DELETE /opinions/{id})
+	DeleteOpinion(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Edits the statement of an own opinion
+	// (PUT /opinions/{id})
+	EditOpinion(w http.ResponseWriter, r *http.Request, id OpinionId, params EditOpinionParams)
+	// Publishes a held or rejected opinion
+	// (POST /opinions/{id}/approve)
+	ApproveOpinion(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Hides a published opinion
+	// (POST /opinions/{id}/hide)
+	HideOpinion(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Rejects an opinion
+	// (POST /opinions/{id}/reject)
+	RejectOpinion(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Reports an opinion, every user reports an opinion once
+	// (POST /opinions/{id}/reports)
+	ReportOpinion(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Resolves all open reports of an opinion
+	// (POST /opinions/{id}/reports/resolve)
+	ResolveReports(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Removes the vote of the user
+	// (DELETE /opinions/{id}/vote)
+	DeleteVote(w http.ResponseWriter, r *http.Request, id OpinionId)
+	// Votes for an opinion
+	// (POST /opinions/{id}/vote)
+	CreateVote(w http.ResponseWriter, r *http.Request, id OpinionId, params CreateVoteParams)
+	// Changes the vote of the user
+	// (PUT /opinions/{id}/vote)
+	UpdateVote(w http.ResponseWriter, r *http.Request, id OpinionId, params UpdateVoteParams)
+	// Lists the open reports
+	// (GET /reports)
+	ListReports(w http.ResponseWriter, r *http.Request)
+	// Returns the authenticated user and the user's roles
+	// (GET /users/me)
+	GetCurrentUser(w http.ResponseWriter, r *http.Request)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
+
+// ListDecisions operation middleware
+func (siw *ServerInterfaceWrapper) ListDecisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListDecisionsParams
+
+	// ------------- Optional query parameter "user" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "user", r.URL.Query(), &params.User)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "action" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "action", r.URL.Query(), &params.Action)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "action", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "allowed" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "allowed", r.URL.Query(), &params.Allowed)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "allowed", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "before", r.URL.Query(), &params.Before)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListDecisions(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetHealth operation middleware
+func (siw *ServerInterfaceWrapper) GetHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetHealth(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// BulkDeleteOpinions operation middleware
+func (siw *ServerInterfaceWrapper) BulkDeleteOpinions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params BulkDeleteOpinionsParams
+
+	// ------------- Optional query parameter "owner" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "owner", r.URL.Query(), &params.Owner)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "createdFrom" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdFrom", r.URL.Query(), &params.CreatedFrom)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "createdFrom", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "createdBefore" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdBefore", r.URL.Query(), &params.CreatedBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "createdBefore", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.BulkDeleteOpinions(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListOpinions operation middleware
+func (siw *ServerInterfaceWrapper) ListOpinions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListOpinions(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateOpinion operation middleware
+func (siw *ServerInterfaceWrapper) CreateOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateOpinionParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey IdempotencyKey
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Idempotency-Key", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Idempotency-Key", runtime.ParamLocationHeader, valueList[0], &IdempotencyKey)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Idempotency-Key", Err: err})
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateOpinion(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteOpinion operation middleware
+func (siw *ServerInterfaceWrapper) DeleteOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteOpinion(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// EditOpinion operation middleware
+func (siw *ServerInterfaceWrapper) EditOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params EditOpinionParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.EditOpinion(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ApproveOpinion operation middleware
+func (siw *ServerInterfaceWrapper) ApproveOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ApproveOpinion(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// HideOpinion operation middleware
+func (siw *ServerInterfaceWrapper) HideOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.HideOpinion(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// RejectOpinion operation middleware
+func (siw *ServerInterfaceWrapper) RejectOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RejectOpinion(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ReportOpinion operation middleware
+func (siw *ServerInterfaceWrapper) ReportOpinion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ReportOpinion(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ResolveReports operation middleware
+func (siw *ServerInterfaceWrapper) ResolveReports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ResolveReports(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteVote operation middleware
+func (siw *ServerInterfaceWrapper) DeleteVote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteVote(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateVote operation middleware
+func (siw *ServerInterfaceWrapper) CreateVote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateVoteParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateVote(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// UpdateVote operation middleware
+func (siw *ServerInterfaceWrapper) UpdateVote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id OpinionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", mux.Vars(r)["id"], &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateVoteParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateVote(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListReports operation middleware
+func (siw *ServerInterfaceWrapper) ListReports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListReports(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetCurrentUser operation middleware
+func (siw *ServerInterfaceWrapper) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, UserIdScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetCurrentUser(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+type UnescapedCookieParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnescapedCookieParamError) Error() string {
+	return fmt.Sprintf("error unescaping cookie parameter '%s'", e.ParamName)
+}
+
+func (e *UnescapedCookieParamError) Unwrap() error {
+	return e.Err
+}
+
+type UnmarshallingParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnmarshallingParamError) Error() string {
+	return fmt.Sprintf("Error unmarshalling parameter %s as JSON: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *UnmarshallingParamError) Unwrap() error {
+	return e.Err
+}
+
+type RequiredParamError struct {
+	ParamName string
+}
+
+func (e *RequiredParamError) Error() string {
+	return fmt.Sprintf("Query argument %s is required, but not found", e.ParamName)
+}
+
+type RequiredHeaderError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *RequiredHeaderError) Error() string {
+	return fmt.Sprintf("Header parameter %s is required, but not found", e.ParamName)
+}
+
+func (e *RequiredHeaderError) Unwrap() error {
+	return e.Err
+}
+
+type InvalidParamFormatError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *InvalidParamFormatError) Error() string {
+	return fmt.Sprintf("Invalid format for parameter %s: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *InvalidParamFormatError) Unwrap() error {
+	return e.Err
+}
+
+type TooManyValuesForParamError struct {
+	ParamName string
+	Count     int
+}
+
+func (e *TooManyValuesForParamError) Error() string {
+	return fmt.Sprintf("Expected one value for %s, got %d", e.ParamName, e.Count)
+}
+
+// Handler creates http.Handler with routing matching OpenAPI spec.
+func Handler(si ServerInterface) http.Handler {
+	return HandlerWithOptions(si, GorillaServerOptions{})
+}
+
+type GorillaServerOptions struct {
+	BaseURL          string
+	BaseRouter       *mux.Router
+	Middlewares      []MiddlewareFunc
+	ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// HandlerFromMux creates http.Handler with routing matching OpenAPI spec based on the provided mux.
+func HandlerFromMux(si ServerInterface, r *mux.Router) http.Handler {
+	return HandlerWithOptions(si, GorillaServerOptions{
+		BaseRouter: r,
+	})
+}
+
+func HandlerFromMuxWithBaseURL(si ServerInterface, r *mux.Router, baseURL string) http.Handler {
+	return HandlerWithOptions(si, GorillaServerOptions{
+		BaseURL:    baseURL,
+		BaseRouter: r,
+	})
+}
+
+// HandlerWithOptions creates http.Handler with additional options
+func HandlerWithOptions(si ServerInterface, options GorillaServerOptions) http.Handler {
+	r := options.BaseRouter
+
+	if r == nil {
+		r = mux.NewRouter()
+	}
+	if options.ErrorHandlerFunc == nil {
+		options.ErrorHandlerFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+	wrapper := ServerInterfaceWrapper{
+		Handler:            si,
+		HandlerMiddlewares: options.Middlewares,
+		ErrorHandlerFunc:   options.ErrorHandlerFunc,
+	}
+
+	r.HandleFunc(options.BaseURL+"/decisions", wrapper.ListDecisions).Methods("GET")
+
+	r.HandleFunc(options.BaseURL+"/health", wrapper.GetHealth).Methods("GET")
+
+	r.HandleFunc(options.BaseURL+"/opinions", wrapper.BulkDeleteOpinions).Methods("DELETE")
+
+	r.HandleFunc(options.BaseURL+"/opinions", wrapper.ListOpinions).Methods("GET")
+
+	r.HandleFunc(options.BaseURL+"/opinions", wrapper.CreateOpinion).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}", wrapper.DeleteOpinion).Methods("DELETE")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}", wrapper.EditOpinion).Methods("PUT")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/approve", wrapper.ApproveOpinion).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/hide", wrapper.HideOpinion).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/reject", wrapper.RejectOpinion).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/reports", wrapper.ReportOpinion).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/reports/resolve", wrapper.ResolveReports).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/vote", wrapper.DeleteVote).Methods("DELETE")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/vote", wrapper.CreateVote).Methods("POST")
+
+	r.HandleFunc(options.BaseURL+"/opinions/{id}/vote", wrapper.UpdateVote).Methods("PUT")
+
+	r.HandleFunc(options.BaseURL+"/reports", wrapper.ListReports).Methods("GET")
+
+	r.HandleFunc(options.BaseURL+"/users/me", wrapper.GetCurrentUser).Methods("GET")
+
+	return r
+}
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/+xbbY/bNhL+KwTvgAMO8tpp9g7ofkuatlncSwunCQ5IFwEtjS12JVIlKe8ZC//3w5CU",
+	"RFkvsV37sNn4U2KRnBnOPDOcGXIfaSzzQgoQRtObR5oCS0DZ/37/C1vhvwnoWPHCcCnoDf0ASnMpiFwS",
+	"kwKRBRf2pyJraYBGVMcp5AwXmk0B9IZqo7hY0e12G9GCKZaD8RxuE8gLaUDEm3/ApstrDkZx0OSBm9Ry",
+	"0ywHcg8bwkRCtGEGchCGKDClEm15nHhLrjSO/16CNjSiHMm6PdKICpajgIEYE5RjbA8RvV3+i5k47UqL",
+	"+qr4Zkwbci/kgyBrp7CIMJJzrblYEScA6uyvRN/zQts1fiKJU4jvB2VdThz7cSF/cmq4TXDYEiqYSRsy",
+	"PKERRbVwBQm9MaqEz1hOgS6k0OCgoZRUc/8FP8RSGBAG/8uKIuMxQ6VMf9OomceA8p8VLOkN/dO0gd3U",
+	"jeqppeq4tTX7SwqVDcmS8QxQ+gCpiJPN5NXSgOqa5R3EUiSalMLwjDCimAGS8ZwbSGqqXBOWZfIBEsJW",
+	"jIs+9XJhYAVWwEbDJ1eCpzukBo/v9v4rT+2j66dN7RxL9IM0cHKxkeiQzD4wHCnwtjKFXfm6zO7fQAZ2",
+	"C2VmpS6ULEAZ7rCZ2FEHfAO57sFzVH1gSrEN3W5DX/hYU7ir58nFbxAbXPgGYq65FF2+LDb+e4ebh1Yw",
+	"tpAyAyZwkIuiNG+ZTnuXZszGpX9bFkupcmYcFP9+TaMOMiMqQ8fvUJMPAlTvSCEzHm/msK5315migOmh",
+	"IZmBPkTjETU8h9aeEmZgYr9G3dWl7pV7x3B+tZ1cCRVVhgk13Zgk1HCfvV1M6hg7lomVHkSZI+c1y3jC",
+	"PB8hzaelLAVSj6VYZjw2NMKdLniSAE4pBStNCsKgt1kxFDPwyYclK6sBJVgWCNVoY8khS9r6HvPNH3C6",
+	"D65dQ+SgNVvB57Vr99zM79NWwKmjMit0LzT2lsCRGBfhLbDMpF322jBT6tBm8r5HuTsc/ao+RlWg7mJD",
+	"Adr0ldkf3vxQd61zn8HRUu950rxzk7cR9QlIb6TJueA56u1FN+rs6MzmFk70KFBGKHQtYsN0RMVzn771",
+	"2nRICz2GdFNHGL3rYKQoFxnXqXXJAkTCxeqTgjWHB5s/4Xo7ljrH7nPWORRSmZPAZDy4N/F5zOpOnLmb",
+	"a1fh732ia8M9WFWzDU3dp+IW30DDumA5jShblNpGF665cNpwwVSaFNSIYgfBcYw6dnbsSfRvR8tsDSPc",
+	"tcxKw9ubTbjGGsBCpixSjGafDUEBpT5B3vuTsc1+IKAcfE73ubYj0ieLTQK7idFKwa6XBvnPyf2gLJJD",
+	"CR4b+SKKue2BvuOWRIFe2mGykX88Oro8fgB/o0rfEa6Z22WDpwnEpeJm8w5dxlHHDMtpv53t3yZVAYwz",
+	"SCvHIYuNHSmU/O9mqLz9zwTxPLF6qpBYcCzKbSGAoaHL1UdvHdlKQ9vmQC4TUDaGVBJpbuCKIHlNmIKu",
+	"cMyJRh5SHqdEg3FleS3SrwIJ+59zdAJfyOvITsQt6IqbH7GcMAfkq1I1OphMUD8TN8nKO5lYt6o+LTO2",
+	"0le/itel5gK0JqpEfhm/B9dfALEyKTJjQR/EMsPuQcNJg1rzGCwPH7QTwjRpElYCSkmFzFDn3GSARTM3",
+	"da2pyaufbwMs3tAXV7OrmXNEEKzg9Ia+vJpdvaSRbTNYiEwTXynZXyuwQESEWqYIHvpPrs2bela7OfTR",
+	"ty1+L0FtGnz41H6k9dG/rq4BDl9ZFwqdpYE/9a9dKpm3Fu4TjYaILWApFZyMnC01WtRGU7y7nQ7QN7PZ",
+	"Qb2DvQqVurjunkO9jYUcO2HYUGuwto3o9Ww2xKjewrTdwbKrXhy16uUxq7759ohVfztiXxi+yzxnauP9",
+	"zcW0TK5WkBBX9Dfa83EMHmyjjSvbMjVshf5Im4hK75DuNK0rrV7v/hGMr8X+IHTGEOM5DOBjweJ7EAl2",
+	"91QpRHUwVwcavfl4F+rH5YKoIWaI6V/f6MNv3+miCpVNA6qrkKZ1VR1Y+8W8qpQ6OHT5pOKHU0YhT/P1",
+	"kcHo7oxY6LQGB1DBk/qU9p2++qS7hI+R8OF0q8NrFqtIJoiFKN5nMGIBgmkFIoAoJlYQEWZIBnglIgXe",
+	"yWQGlHWqKgkdiDLRcN4QuND5z6W6J7/fsYQ91EUGO6j6io6XGh11JZCzDdEAgaGrOdbMhdQ9dv7Oxpqf",
+	"6huPnVjZJ2kzZbpzuehCj62WXstkc+r7mqoQ2263uxdr2w5E94DC7vXSlxGYZt9+OXB16NI2fLUvjX2R",
+	"0YPU8KifPvJkO3bet876bpy67haywQUfeWC6Op7+/2a8/vJOpcaMkTuOKvWhSbnCb01cwmqYJTkXmsAa",
+	"1Ca4Vu2PTwdFnubyHYNOUfaEtu8Tbo4ObP4BwlOKaLNnG9Gun3scRCi6k7ppJNVZ3bhjdMLhlBWFkmvX",
+	"BP4jPtObD7xyxAcj6rEgvMDphHD62d+YacIIXnBgVVBdlPWgqdNVaOMp5cmZwPSWJxckPWkkoYUQRfUd",
+	"7OHwccA7D4DmlvYFQk8ZQs5GYXJ4CHZsN/Bc4EHiIXhOn8e178f3L0xPyHz4MacbvaSAT8RNXOM7rKFc",
+	"XWSbN6ozTKSI4WBXmir3aOJcLuVfZFheZ/Op1rOPS2303B3Dmts+DieyAFH7gq+QDj1U1v6FzHjPyL6j",
+	"OeNNydhjbQW5XEPiHm1fGk9j2EBF+T/dkAYGuoc4dIIm0kiD3KPlaTWRwsdJp+qJt/5w4RIin4YbfLBP",
+	"rpZS9cfDAP59fdD39q3b80Dw7ILgL/MiKMVL6r0DOR7qQW04eEMdJqLnvqCe1/XUPvfTYSLzFd5NB5sf",
+	"ydvQ+nqaw6CNfwTzXakUCPNe+4fwZ0rXLP0BW7ZfsVrIHmnRneTGlMpf4HdZ2PuzykX+okn1l1aVMvGz",
+	"dZX2Y6vm3fDHu+1dPf2xelvkX1Rto8fwuacOPwRPKupvgemCr85dt3fb/w0AgggjhuI8AAA=",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %s", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	var res = make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	var resolvePath = PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		var pathToFile = url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
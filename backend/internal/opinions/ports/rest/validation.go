@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"net/http"
+	"strings"
+)
+
+// maxBodySize bounds the request bodies before they are read, an opinion with the longest statement is far below it
+const maxBodySize = 8 << 10
+
+// newRequestValidator checks the requests against the spec before they are passed to next.
+// The authenticator is asked first, so unauthenticated requests are rejected before their parameters and bodies are validated.
+// Requests without an operation in the spec are passed to next, which answers them with 404 or 405.
+func newRequestValidator(spec *openapi3.T, authenticator Authenticator, next http.Handler) (http.Handler, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: func(_ context.Context, input *openapi3filter.AuthenticationInput) error {
+			_, err := authenticator.Authenticate(input.RequestValidationInput.Request)
+			return err
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		})
+		if err != nil {
+			httperror.Write(w, requestValidationError(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// requestValidationError converts the errors of openapi3filter to application errors, a failed security requirement is unauthenticated
+func requestValidationError(err error) error {
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &securityErr) {
+		for _, e := range securityErr.Errors {
+			var appErr *application.Error
+			if errors.As(e, &appErr) {
+				return appErr
+			}
+		}
+		return application.UnauthenticatedError.WithCause(err)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return application.NewValidationError(application.FieldError{Field: "body", Message: fmt.Sprintf("must not be larger than %d bytes", maxBytesErr.Limit)}).WithCause(err)
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return application.NewError(application.CodeValidation, "invalid request", err)
+	}
+
+	field := application.FieldError{Field: "body", Message: requestErr.Reason}
+	if requestErr.Parameter != nil {
+		field.Field = requestErr.Parameter.Name
+	}
+
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(requestErr.Err, &schemaErr):
+		if pointer := schemaErr.JSONPointer(); requestErr.Parameter == nil && len(pointer) > 0 {
+			field.Field = strings.Join(pointer, ".")
+		}
+		field.Message = schemaErr.Reason
+	case requestErr.Err != nil:
+		field.Message = requestErr.Err.Error()
+	}
+	return application.NewValidationError(field).WithCause(err)
+}
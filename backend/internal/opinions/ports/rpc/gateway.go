@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rpc/opinionsv1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// NewGateway serves the OpinionsService of the connection as JSON on the /v1 routes of api/opinions/v1/opinions_gateway.yaml.
+// The headers are forwarded as lowercase metadata keys, so that the MetadataAuthenticator of the server can read them.
+func NewGateway(ctx context.Context, conn *grpc.ClientConn, headers ...string) (http.Handler, error) {
+	forward := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		if h != "" {
+			forward[textproto.CanonicalMIMEHeaderKey(h)] = true
+		}
+	}
+
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+		if forward[textproto.CanonicalMIMEHeaderKey(key)] {
+			return strings.ToLower(key), true
+		}
+		return runtime.DefaultHeaderMatcher(key)
+	}))
+	if err := opinionsv1.RegisterOpinionsServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
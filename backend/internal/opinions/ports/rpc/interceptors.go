@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"strings"
+)
+
+// Authenticator resolves the user of a call
+type Authenticator interface {
+	Authenticate(ctx context.Context) (application.AuthenticatedUser, error)
+}
+
+// MetadataAuthenticator trusts the user id in the metadata Key and the comma separated roles in the RolesKey, like rest.HeaderAuthenticator.
+// It must only be used behind a proxy which authenticates the user and sets the metadata.
+// Every user has the application.RoleUser, the Roles grant additional roles to the listed users.
+type MetadataAuthenticator struct {
+	Key      string
+	RolesKey string
+	Roles    map[application.UserId][]application.Role
+}
+
+func (m MetadataAuthenticator) Authenticate(ctx context.Context) (application.AuthenticatedUser, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md.Get(m.Key))
+	if id == "" {
+		return application.AuthenticatedUser{}, application.UnauthenticatedError
+	}
+
+	user := application.AuthenticatedUser{
+		Id:    application.UserId(id),
+		Roles: []application.Role{application.RoleUser},
+	}
+	user.Roles = append(user.Roles, m.Roles[user.Id]...)
+
+	if m.RolesKey == "" {
+		return user, nil
+	}
+	for _, role := range strings.Split(first(md.Get(m.RolesKey)), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			user.Roles = append(user.Roles, application.Role(role))
+		}
+	}
+	return user, nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+type userKey struct{}
+
+// userFrom returns the user which was authenticated by the interceptors
+func userFrom(ctx context.Context) application.AuthenticatedUser {
+	user, _ := ctx.Value(userKey{}).(application.AuthenticatedUser)
+	return user
+}
+
+// AuthUnaryInterceptor rejects unauthenticated calls and passes the user to the handlers
+func AuthUnaryInterceptor(authenticator Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		user, err := authenticator.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, userKey{}, user), req)
+	}
+}
+
+// AuthStreamInterceptor rejects unauthenticated streams and passes the user to the handlers
+func AuthStreamInterceptor(authenticator Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		user, err := authenticator.Authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, authenticatedStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), userKey{}, user)})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// ErrorUnaryInterceptor converts the errors of the handlers and inner interceptors to gRPC status errors
+func ErrorUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return resp, nil
+}
+
+// ErrorStreamInterceptor converts the errors of the stream handlers and inner interceptors to gRPC status errors
+func ErrorStreamInterceptor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, stream); err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+// statusCodes maps the application error codes to gRPC codes, the grpc-gateway maps them to the status codes of the REST API
+var statusCodes = map[application.ErrorCode]codes.Code{
+	application.CodeValidation:      codes.InvalidArgument,
+	application.CodeNotFound:        codes.NotFound,
+	application.CodeConflict:        codes.Aborted,
+	application.CodeForbidden:       codes.PermissionDenied,
+	application.CodeUnauthenticated: codes.Unauthenticated,
+	application.CodeRateLimited:     codes.ResourceExhausted,
+	application.CodeInternal:        codes.Internal,
+}
+
+// statusError renders the application error with its field errors as BadRequest and its retry delay as RetryInfo detail.
+// The cause of an error is never exposed to the client, errors which already are a status are passed through.
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	var appErr *application.Error
+	if !errors.As(err, &appErr) {
+		appErr = application.NewError(application.CodeInternal, "internal error", err)
+	}
+
+	code, ok := statusCodes[appErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, appErr.Message)
+
+	if len(appErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, f := range appErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		if withDetails, err := st.WithDetails(badRequest); err == nil {
+			st = withDetails
+		}
+	}
+	if appErr.RetryAfter > 0 {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(appErr.RetryAfter)}); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
@@ -0,0 +1,675 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: opinions/v1/opinions.proto
+
+package opinionsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// OpinionsServiceClient is the client API for OpinionsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OpinionsServiceClient interface {
+	CreateOpinion(ctx context.Context, in *CreateOpinionRequest, opts ...grpc.CallOption) (*Opinion, error)
+	EditOpinion(ctx context.Context, in *EditOpinionRequest, opts ...grpc.CallOption) (*Opinion, error)
+	ListOpinions(ctx context.Context, in *ListOpinionsRequest, opts ...grpc.CallOption) (*ListOpinionsResponse, error)
+	DeleteOpinion(ctx context.Context, in *DeleteOpinionRequest, opts ...grpc.CallOption) (*DeleteOpinionResponse, error)
+	BulkDeleteOpinions(ctx context.Context, in *BulkDeleteOpinionsRequest, opts ...grpc.CallOption) (*BulkDeleteOpinionsResponse, error)
+	ApproveOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error)
+	RejectOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error)
+	HideOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error)
+	ReportOpinion(ctx context.Context, in *ReportOpinionRequest, opts ...grpc.CallOption) (*Report, error)
+	ListReports(ctx context.Context, in *ListReportsRequest, opts ...grpc.CallOption) (*ListReportsResponse, error)
+	ResolveReports(ctx context.Context, in *ResolveReportsRequest, opts ...grpc.CallOption) (*Opinion, error)
+	ListDecisions(ctx context.Context, in *ListDecisionsRequest, opts ...grpc.CallOption) (*ListDecisionsResponse, error)
+	CreateVote(ctx context.Context, in *SaveVoteRequest, opts ...grpc.CallOption) (*Vote, error)
+	UpdateVote(ctx context.Context, in *SaveVoteRequest, opts ...grpc.CallOption) (*Vote, error)
+	DeleteVote(ctx context.Context, in *DeleteVoteRequest, opts ...grpc.CallOption) (*Vote, error)
+	// WatchOpinions streams the changes after the call of the opinions which the user may list
+	WatchOpinions(ctx context.Context, in *WatchOpinionsRequest, opts ...grpc.CallOption) (OpinionsService_WatchOpinionsClient, error)
+}
+
+type opinionsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOpinionsServiceClient(cc grpc.ClientConnInterface) OpinionsServiceClient {
+	return &opinionsServiceClient{cc}
+}
+
+func (c *opinionsServiceClient) CreateOpinion(ctx context.Context, in *CreateOpinionRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/CreateOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) EditOpinion(ctx context.Context, in *EditOpinionRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/EditOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ListOpinions(ctx context.Context, in *ListOpinionsRequest, opts ...grpc.CallOption) (*ListOpinionsResponse, error) {
+	out := new(ListOpinionsResponse)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ListOpinions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) DeleteOpinion(ctx context.Context, in *DeleteOpinionRequest, opts ...grpc.CallOption) (*DeleteOpinionResponse, error) {
+	out := new(DeleteOpinionResponse)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/DeleteOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) BulkDeleteOpinions(ctx context.Context, in *BulkDeleteOpinionsRequest, opts ...grpc.CallOption) (*BulkDeleteOpinionsResponse, error) {
+	out := new(BulkDeleteOpinionsResponse)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/BulkDeleteOpinions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ApproveOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ApproveOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) RejectOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/RejectOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) HideOpinion(ctx context.Context, in *ReviewOpinionRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/HideOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ReportOpinion(ctx context.Context, in *ReportOpinionRequest, opts ...grpc.CallOption) (*Report, error) {
+	out := new(Report)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ReportOpinion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ListReports(ctx context.Context, in *ListReportsRequest, opts ...grpc.CallOption) (*ListReportsResponse, error) {
+	out := new(ListReportsResponse)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ListReports", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ResolveReports(ctx context.Context, in *ResolveReportsRequest, opts ...grpc.CallOption) (*Opinion, error) {
+	out := new(Opinion)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ResolveReports", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) ListDecisions(ctx context.Context, in *ListDecisionsRequest, opts ...grpc.CallOption) (*ListDecisionsResponse, error) {
+	out := new(ListDecisionsResponse)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/ListDecisions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) CreateVote(ctx context.Context, in *SaveVoteRequest, opts ...grpc.CallOption) (*Vote, error) {
+	out := new(Vote)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/CreateVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) UpdateVote(ctx context.Context, in *SaveVoteRequest, opts ...grpc.CallOption) (*Vote, error) {
+	out := new(Vote)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/UpdateVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) DeleteVote(ctx context.Context, in *DeleteVoteRequest, opts ...grpc.CallOption) (*Vote, error) {
+	out := new(Vote)
+	err := c.cc.Invoke(ctx, "/opinions.v1.OpinionsService/DeleteVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *opinionsServiceClient) WatchOpinions(ctx context.Context, in *WatchOpinionsRequest, opts ...grpc.CallOption) (OpinionsService_WatchOpinionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OpinionsService_ServiceDesc.Streams[0], "/opinions.v1.OpinionsService/WatchOpinions", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &opinionsServiceWatchOpinionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OpinionsService_WatchOpinionsClient interface {
+	Recv() (*WatchOpinionsResponse, error)
+	grpc.ClientStream
+}
+
+type opinionsServiceWatchOpinionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *opinionsServiceWatchOpinionsClient) Recv() (*WatchOpinionsResponse, error) {
+	m := new(WatchOpinionsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpinionsServiceServer is the server API for OpinionsService service.
+// All implementations must embed UnimplementedOpinionsServiceServer
+// for forward compatibility
+type OpinionsServiceServer interface {
+	CreateOpinion(context.Context, *CreateOpinionRequest) (*Opinion, error)
+	EditOpinion(context.Context, *EditOpinionRequest) (*Opinion, error)
+	ListOpinions(context.Context, *ListOpinionsRequest) (*ListOpinionsResponse, error)
+	DeleteOpinion(context.Context, *DeleteOpinionRequest) (*DeleteOpinionResponse, error)
+	BulkDeleteOpinions(context.Context, *BulkDeleteOpinionsRequest) (*BulkDeleteOpinionsResponse, error)
+	ApproveOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error)
+	RejectOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error)
+	HideOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error)
+	ReportOpinion(context.Context, *ReportOpinionRequest) (*Report, error)
+	ListReports(context.Context, *ListReportsRequest) (*ListReportsResponse, error)
+	ResolveReports(context.Context, *ResolveReportsRequest) (*Opinion, error)
+	ListDecisions(context.Context, *ListDecisionsRequest) (*ListDecisionsResponse, error)
+	CreateVote(context.Context, *SaveVoteRequest) (*Vote, error)
+	UpdateVote(context.Context, *SaveVoteRequest) (*Vote, error)
+	DeleteVote(context.Context, *DeleteVoteRequest) (*Vote, error)
+	// WatchOpinions streams the changes after the call of the opinions which the user may list
+	WatchOpinions(*WatchOpinionsRequest, OpinionsService_WatchOpinionsServer) error
+	mustEmbedUnimplementedOpinionsServiceServer()
+}
+
+// UnimplementedOpinionsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOpinionsServiceServer struct {
+}
+
+func (UnimplementedOpinionsServiceServer) CreateOpinion(context.Context, *CreateOpinionRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) EditOpinion(context.Context, *EditOpinionRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EditOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ListOpinions(context.Context, *ListOpinionsRequest) (*ListOpinionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOpinions not implemented")
+}
+func (UnimplementedOpinionsServiceServer) DeleteOpinion(context.Context, *DeleteOpinionRequest) (*DeleteOpinionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) BulkDeleteOpinions(context.Context, *BulkDeleteOpinionsRequest) (*BulkDeleteOpinionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkDeleteOpinions not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ApproveOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) RejectOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) HideOpinion(context.Context, *ReviewOpinionRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HideOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ReportOpinion(context.Context, *ReportOpinionRequest) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportOpinion not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ListReports(context.Context, *ListReportsRequest) (*ListReportsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReports not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ResolveReports(context.Context, *ResolveReportsRequest) (*Opinion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveReports not implemented")
+}
+func (UnimplementedOpinionsServiceServer) ListDecisions(context.Context, *ListDecisionsRequest) (*ListDecisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDecisions not implemented")
+}
+func (UnimplementedOpinionsServiceServer) CreateVote(context.Context, *SaveVoteRequest) (*Vote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVote not implemented")
+}
+func (UnimplementedOpinionsServiceServer) UpdateVote(context.Context, *SaveVoteRequest) (*Vote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateVote not implemented")
+}
+func (UnimplementedOpinionsServiceServer) DeleteVote(context.Context, *DeleteVoteRequest) (*Vote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVote not implemented")
+}
+func (UnimplementedOpinionsServiceServer) WatchOpinions(*WatchOpinionsRequest, OpinionsService_WatchOpinionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchOpinions not implemented")
+}
+func (UnimplementedOpinionsServiceServer) mustEmbedUnimplementedOpinionsServiceServer() {}
+
+// UnsafeOpinionsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OpinionsServiceServer will
+// result in compilation errors.
+type UnsafeOpinionsServiceServer interface {
+	mustEmbedUnimplementedOpinionsServiceServer()
+}
+
+func RegisterOpinionsServiceServer(s grpc.ServiceRegistrar, srv OpinionsServiceServer) {
+	s.RegisterService(&OpinionsService_ServiceDesc, srv)
+}
+
+func _OpinionsService_CreateOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).CreateOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/CreateOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).CreateOpinion(ctx, req.(*CreateOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_EditOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EditOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).EditOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/EditOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).EditOpinion(ctx, req.(*EditOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ListOpinions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOpinionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ListOpinions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ListOpinions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ListOpinions(ctx, req.(*ListOpinionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_DeleteOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).DeleteOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/DeleteOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).DeleteOpinion(ctx, req.(*DeleteOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_BulkDeleteOpinions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkDeleteOpinionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).BulkDeleteOpinions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/BulkDeleteOpinions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).BulkDeleteOpinions(ctx, req.(*BulkDeleteOpinionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ApproveOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ApproveOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ApproveOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ApproveOpinion(ctx, req.(*ReviewOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_RejectOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).RejectOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/RejectOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).RejectOpinion(ctx, req.(*ReviewOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_HideOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).HideOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/HideOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).HideOpinion(ctx, req.(*ReviewOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ReportOpinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportOpinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ReportOpinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ReportOpinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ReportOpinion(ctx, req.(*ReportOpinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ListReports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReportsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ListReports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ListReports",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ListReports(ctx, req.(*ListReportsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ResolveReports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveReportsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ResolveReports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ResolveReports",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ResolveReports(ctx, req.(*ResolveReportsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_ListDecisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDecisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).ListDecisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/ListDecisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).ListDecisions(ctx, req.(*ListDecisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_CreateVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveVoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).CreateVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/CreateVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).CreateVote(ctx, req.(*SaveVoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_UpdateVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveVoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).UpdateVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/UpdateVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).UpdateVote(ctx, req.(*SaveVoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_DeleteVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteVoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OpinionsServiceServer).DeleteVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opinions.v1.OpinionsService/DeleteVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OpinionsServiceServer).DeleteVote(ctx, req.(*DeleteVoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OpinionsService_WatchOpinions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchOpinionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OpinionsServiceServer).WatchOpinions(m, &opinionsServiceWatchOpinionsServer{stream})
+}
+
+type OpinionsService_WatchOpinionsServer interface {
+	Send(*WatchOpinionsResponse) error
+	grpc.ServerStream
+}
+
+type opinionsServiceWatchOpinionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *opinionsServiceWatchOpinionsServer) Send(m *WatchOpinionsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// OpinionsService_ServiceDesc is the grpc.ServiceDesc for OpinionsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OpinionsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opinions.v1.OpinionsService",
+	HandlerType: (*OpinionsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateOpinion",
+			Handler:    _OpinionsService_CreateOpinion_Handler,
+		},
+		{
+			MethodName: "EditOpinion",
+			Handler:    _OpinionsService_EditOpinion_Handler,
+		},
+		{
+			MethodName: "ListOpinions",
+			Handler:    _OpinionsService_ListOpinions_Handler,
+		},
+		{
+			MethodName: "DeleteOpinion",
+			Handler:    _OpinionsService_DeleteOpinion_Handler,
+		},
+		{
+			MethodName: "BulkDeleteOpinions",
+			Handler:    _OpinionsService_BulkDeleteOpinions_Handler,
+		},
+		{
+			MethodName: "ApproveOpinion",
+			Handler:    _OpinionsService_ApproveOpinion_Handler,
+		},
+		{
+			MethodName: "RejectOpinion",
+			Handler:    _OpinionsService_RejectOpinion_Handler,
+		},
+		{
+			MethodName: "HideOpinion",
+			Handler:    _OpinionsService_HideOpinion_Handler,
+		},
+		{
+			MethodName: "ReportOpinion",
+			Handler:    _OpinionsService_ReportOpinion_Handler,
+		},
+		{
+			MethodName: "ListReports",
+			Handler:    _OpinionsService_ListReports_Handler,
+		},
+		{
+			MethodName: "ResolveReports",
+			Handler:    _OpinionsService_ResolveReports_Handler,
+		},
+		{
+			MethodName: "ListDecisions",
+			Handler:    _OpinionsService_ListDecisions_Handler,
+		},
+		{
+			MethodName: "CreateVote",
+			Handler:    _OpinionsService_CreateVote_Handler,
+		},
+		{
+			MethodName: "UpdateVote",
+			Handler:    _OpinionsService_UpdateVote_Handler,
+		},
+		{
+			MethodName: "DeleteVote",
+			Handler:    _OpinionsService_DeleteVote_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchOpinions",
+			Handler:       _OpinionsService_WatchOpinions_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "opinions/v1/opinions.proto",
+}
@@ -0,0 +1,326 @@
+// Package rpc exposes the opinions application service as gRPC service opinions.v1 and as JSON over the grpc-gateway.
+// The code in opinionsv1 is generated from api/opinions/v1/opinions.proto.
+package rpc
+
+//go:generate buf generate ../../../../api --template ../../../../api/buf.gen.yaml
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rpc/opinionsv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// EventSubscriber passes the events published after Subscribe to the channel until cancel is called, see infrastructure.EventBroadcast
+type EventSubscriber interface {
+	Subscribe(buffer int) (events <-chan application.Event, cancel func())
+}
+
+// watchBuffer is the count of events a WatchOpinions stream may fall behind before it is closed
+const watchBuffer = 64
+
+// NewServer creates a gRPC server with the OpinionsService. Every call is authenticated, the errors are converted to status errors.
+func NewServer(service application.Service, events EventSubscriber, authenticator Authenticator, options ...grpc.ServerOption) *grpc.Server {
+	options = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(ErrorUnaryInterceptor, AuthUnaryInterceptor(authenticator)),
+		grpc.ChainStreamInterceptor(ErrorStreamInterceptor, AuthStreamInterceptor(authenticator)),
+	}, options...)
+
+	s := grpc.NewServer(options...)
+	opinionsv1.RegisterOpinionsServiceServer(s, &server{service: service, events: events})
+	return s
+}
+
+type server struct {
+	opinionsv1.UnimplementedOpinionsServiceServer
+	service application.Service
+	events  EventSubscriber
+}
+
+func (s *server) CreateOpinion(ctx context.Context, req *opinionsv1.CreateOpinionRequest) (*opinionsv1.Opinion, error) {
+	opinion, err := s.service.CreateOpinionCommand(ctx, userFrom(ctx), application.OpinionCreateDTO{
+		Statement:      req.GetStatement(),
+		IdempotencyKey: req.GetIdempotencyKey(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOpinion(opinion), nil
+}
+
+func (s *server) EditOpinion(ctx context.Context, req *opinionsv1.EditOpinionRequest) (*opinionsv1.Opinion, error) {
+	opinion, err := s.service.EditOpinionCommand(ctx, userFrom(ctx), application.OpinionId(req.GetId()), application.OpinionUpdateDTO{
+		Statement: req.GetStatement(),
+		Version:   req.GetVersion(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOpinion(opinion), nil
+}
+
+func (s *server) ListOpinions(ctx context.Context, _ *opinionsv1.ListOpinionsRequest) (*opinionsv1.ListOpinionsResponse, error) {
+	opinions, err := s.service.ListOpinionsQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &opinionsv1.ListOpinionsResponse{Opinions: make([]*opinionsv1.Opinion, 0, len(opinions))}
+	for _, o := range opinions {
+		resp.Opinions = append(resp.Opinions, toOpinion(o))
+	}
+	return resp, nil
+}
+
+func (s *server) DeleteOpinion(ctx context.Context, req *opinionsv1.DeleteOpinionRequest) (*opinionsv1.DeleteOpinionResponse, error) {
+	if err := s.service.DeleteOpinionCommand(ctx, userFrom(ctx), application.OpinionId(req.GetId())); err != nil {
+		return nil, err
+	}
+	return &opinionsv1.DeleteOpinionResponse{}, nil
+}
+
+func (s *server) BulkDeleteOpinions(ctx context.Context, req *opinionsv1.BulkDeleteOpinionsRequest) (*opinionsv1.BulkDeleteOpinionsResponse, error) {
+	ids, err := s.service.BulkDeleteOpinionsCommand(ctx, userFrom(ctx), application.OpinionFilter{
+		Owner:         application.UserId(req.GetOwner()),
+		CreatedFrom:   fromTimestamp(req.GetCreatedFrom()),
+		CreatedBefore: fromTimestamp(req.GetCreatedBefore()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &opinionsv1.BulkDeleteOpinionsResponse{Deleted: make([]string, 0, len(ids))}
+	for _, id := range ids {
+		resp.Deleted = append(resp.Deleted, string(id))
+	}
+	return resp, nil
+}
+
+func (s *server) ApproveOpinion(ctx context.Context, req *opinionsv1.ReviewOpinionRequest) (*opinionsv1.Opinion, error) {
+	return reviewOpinion(ctx, req, s.service.ApproveOpinionCommand)
+}
+
+func (s *server) RejectOpinion(ctx context.Context, req *opinionsv1.ReviewOpinionRequest) (*opinionsv1.Opinion, error) {
+	return reviewOpinion(ctx, req, s.service.RejectOpinionCommand)
+}
+
+func (s *server) HideOpinion(ctx context.Context, req *opinionsv1.ReviewOpinionRequest) (*opinionsv1.Opinion, error) {
+	return reviewOpinion(ctx, req, s.service.HideOpinionCommand)
+}
+
+type reviewCommand func(ctx context.Context, user application.AuthenticatedUser, id application.OpinionId) (application.Opinion, error)
+
+func reviewOpinion(ctx context.Context, req *opinionsv1.ReviewOpinionRequest, command reviewCommand) (*opinionsv1.Opinion, error) {
+	opinion, err := command(ctx, userFrom(ctx), application.OpinionId(req.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return toOpinion(opinion), nil
+}
+
+func (s *server) ReportOpinion(ctx context.Context, req *opinionsv1.ReportOpinionRequest) (*opinionsv1.Report, error) {
+	report, err := s.service.ReportOpinionCommand(ctx, userFrom(ctx), application.ReportCreateDTO{
+		Opinion: application.OpinionId(req.GetOpinionId()),
+		Reason:  application.ReportReason(req.GetReason()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toReport(report), nil
+}
+
+func (s *server) ListReports(ctx context.Context, _ *opinionsv1.ListReportsRequest) (*opinionsv1.ListReportsResponse, error) {
+	reports, err := s.service.ListReportsQuery(ctx, userFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &opinionsv1.ListReportsResponse{Reports: make([]*opinionsv1.Report, 0, len(reports))}
+	for _, r := range reports {
+		resp.Reports = append(resp.Reports, toReport(r))
+	}
+	return resp, nil
+}
+
+func (s *server) ResolveReports(ctx context.Context, req *opinionsv1.ResolveReportsRequest) (*opinionsv1.Opinion, error) {
+	opinion, err := s.service.ResolveReportsCommand(ctx, userFrom(ctx), application.OpinionId(req.GetOpinionId()), application.ReportResolution(req.GetResolution()))
+	if err != nil {
+		return nil, err
+	}
+	return toOpinion(opinion), nil
+}
+
+func (s *server) ListDecisions(ctx context.Context, req *opinionsv1.ListDecisionsRequest) (*opinionsv1.ListDecisionsResponse, error) {
+	decisions, err := s.service.ListDecisionsQuery(ctx, userFrom(ctx), application.DecisionFilter{
+		User:    application.UserId(req.GetUser()),
+		Action:  req.GetAction(),
+		Allowed: req.Allowed,
+		From:    fromTimestamp(req.GetFrom()),
+		Before:  fromTimestamp(req.GetBefore()),
+		Limit:   int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &opinionsv1.ListDecisionsResponse{Decisions: make([]*opinionsv1.Decision, 0, len(decisions))}
+	for _, d := range decisions {
+		resp.Decisions = append(resp.Decisions, toDecision(d))
+	}
+	return resp, nil
+}
+
+func (s *server) CreateVote(ctx context.Context, req *opinionsv1.SaveVoteRequest) (*opinionsv1.Vote, error) {
+	return saveVote(ctx, req, s.service.CreateVoteCommand)
+}
+
+func (s *server) UpdateVote(ctx context.Context, req *opinionsv1.SaveVoteRequest) (*opinionsv1.Vote, error) {
+	return saveVote(ctx, req, s.service.UpdateVoteCommand)
+}
+
+type voteCommand func(ctx context.Context, user application.AuthenticatedUser, vote application.VoteCreateAndUpdateDTO) (application.Vote, error)
+
+func saveVote(ctx context.Context, req *opinionsv1.SaveVoteRequest, command voteCommand) (*opinionsv1.Vote, error) {
+	vote, err := command(ctx, userFrom(ctx), application.VoteCreateAndUpdateDTO{
+		Agreement: req.GetAgreement(),
+		Opinion:   application.OpinionId(req.GetOpinionId()),
+		Version:   req.GetVersion(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toVote(vote), nil
+}
+
+func (s *server) DeleteVote(ctx context.Context, req *opinionsv1.DeleteVoteRequest) (*opinionsv1.Vote, error) {
+	vote, err := s.service.DeleteVoteCommand(ctx, userFrom(ctx), application.OpinionId(req.GetOpinionId()))
+	if err != nil {
+		return nil, err
+	}
+	return toVote(vote), nil
+}
+
+// WatchOpinions sends the changes of the opinions which match the opinion visibility of the user until the client cancels the stream.
+// The headers are sent once the stream is subscribed, a client which falls behind by more than the watchBuffer is disconnected with ResourceExhausted.
+func (s *server) WatchOpinions(_ *opinionsv1.WatchOpinionsRequest, stream opinionsv1.OpinionsService_WatchOpinionsServer) error {
+	visibility, err := s.service.OpinionVisibilityQuery(stream.Context(), userFrom(stream.Context()))
+	if err != nil {
+		return err
+	}
+
+	events, cancel := s.events.Subscribe(watchBuffer)
+	defer cancel()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the stream fell behind, watch again and list the opinions to catch up")
+			}
+			change, ok := toChange(visibility, event)
+			if !ok {
+				continue
+			}
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toChange converts the event for a watcher with the visibility, ok is false if the watcher does not receive it
+func toChange(visibility application.OpinionVisibility, event application.Event) (_ *opinionsv1.WatchOpinionsResponse, ok bool) {
+	switch event.Type {
+	case application.EventOpinionCreated:
+		if visibility.Visible(event.Opinion) {
+			return &opinionsv1.WatchOpinionsResponse{Change: &opinionsv1.WatchOpinionsResponse_Created{Created: toOpinion(event.Opinion)}}, true
+		}
+	case application.EventOpinionEdited, application.EventOpinionApproved, application.EventOpinionRejected, application.EventOpinionHidden, application.EventReportsResolved:
+		switch visibility.ChangeOf(event) {
+		case application.OpinionShown:
+			return &opinionsv1.WatchOpinionsResponse{Change: &opinionsv1.WatchOpinionsResponse_Updated{Updated: toOpinion(event.Opinion)}}, true
+		case application.OpinionRemoved:
+			return &opinionsv1.WatchOpinionsResponse{Change: &opinionsv1.WatchOpinionsResponse_RemovedId{RemovedId: string(event.Opinion.ID)}}, true
+		}
+	}
+	return nil, false
+}
+
+func toOpinion(o application.Opinion) *opinionsv1.Opinion {
+	return &opinionsv1.Opinion{
+		Id:        string(o.ID),
+		Owner:     string(o.Owner),
+		CreatedAt: toTimestamp(o.CreatedAt),
+		Statement: o.Statement,
+		Status:    string(o.Status),
+		Version:   o.Version,
+	}
+}
+
+func toReport(r application.Report) *opinionsv1.Report {
+	return &opinionsv1.Report{
+		OpinionId: string(r.Opinion),
+		Reporter:  string(r.Reporter),
+		Reason:    string(r.Reason),
+		CreatedAt: toTimestamp(r.CreatedAt),
+	}
+}
+
+func toDecision(d application.Decision) *opinionsv1.Decision {
+	roles := make([]string, 0, len(d.Roles))
+	for _, r := range d.Roles {
+		roles = append(roles, string(r))
+	}
+
+	return &opinionsv1.Decision{
+		Time:           toTimestamp(d.Time),
+		User:           string(d.User),
+		Roles:          roles,
+		Action:         d.Action,
+		OpinionId:      string(d.Opinion),
+		Owner:          string(d.Owner),
+		InputHash:      d.InputHash,
+		Allowed:        d.Allowed,
+		Reason:         d.Reason,
+		PolicyRevision: d.PolicyRevision,
+		LatencyNs:      d.Latency.Nanoseconds(),
+	}
+}
+
+func toVote(v application.Vote) *opinionsv1.Vote {
+	return &opinionsv1.Vote{
+		OpinionId: string(v.Opinion),
+		Voter:     string(v.Voter),
+		Agreement: v.Agreement,
+		CreatedAt: toTimestamp(v.CreatedAt),
+		UpdatedAt: toTimestamp(v.UpdatedAt),
+		Version:   v.Version,
+	}
+}
+
+// toTimestamp returns nil for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp returns the zero time for a missing timestamp
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
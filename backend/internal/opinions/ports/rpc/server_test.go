@@ -0,0 +1,312 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rpc"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rpc/opinionsv1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testUserKey  = "x-user-id"
+	testRolesKey = "x-user-roles"
+)
+
+// newTestClient serves the OpinionsService on an in-memory listener and returns a connection to it
+func newTestClient(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	broadcast := infrastructure.NewEventBroadcast()
+	bus := infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil)
+	bus.Subscribe(broadcast)
+
+	s := application.NewOpinionService(
+		infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+		infrastructure.NewOpinionsRepositoryMemory(),
+		bus,
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+		// statements with a link are held for review
+		application.WithModeration(infrastructure.LinkSpamCheck{MaxLinkRatio: 1}),
+	)
+	server := rpc.NewServer(s, broadcast, rpc.MetadataAuthenticator{
+		Key:      testUserKey,
+		RolesKey: testRolesKey,
+		Roles:    map[application.UserId][]application.Role{"admin": {application.RoleAdmin}, "moderator": {application.RoleModerator}},
+	})
+
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func asUser(user string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), testUserKey, user)
+}
+
+func TestServer_opinions(t *testing.T) {
+	t.Parallel()
+	client := opinionsv1.NewOpinionsServiceClient(newTestClient(t))
+
+	created, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "gRPC is nice"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", created.GetOwner())
+	assert.Equal(t, string(application.OpinionPublished), created.GetStatus())
+	assert.NotNil(t, created.GetCreatedAt())
+
+	edited, err := client.EditOpinion(asUser("alice"), &opinionsv1.EditOpinionRequest{Id: created.GetId(), Statement: "gRPC is great", Version: created.GetVersion()})
+	assert.NoError(t, err)
+	assert.Equal(t, "gRPC is great", edited.GetStatement())
+
+	vote, err := client.CreateVote(asUser("bob"), &opinionsv1.SaveVoteRequest{OpinionId: created.GetId(), Agreement: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", vote.GetVoter())
+	assert.True(t, vote.GetAgreement())
+
+	list, err := client.ListOpinions(asUser("bob"), &opinionsv1.ListOpinionsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, list.GetOpinions(), 1)
+
+	_, err = client.DeleteOpinion(asUser("alice"), &opinionsv1.DeleteOpinionRequest{Id: created.GetId()})
+	assert.NoError(t, err)
+
+	list, err = client.ListOpinions(asUser("bob"), &opinionsv1.ListOpinionsRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.GetOpinions())
+}
+
+func TestServer_errors(t *testing.T) {
+	t.Parallel()
+	client := opinionsv1.NewOpinionsServiceClient(newTestClient(t))
+
+	created, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "gRPC is nice"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "unauthenticated",
+			call: func() error {
+				_, err := client.ListOpinions(context.Background(), &opinionsv1.ListOpinionsRequest{})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "not found",
+			call: func() error {
+				_, err := client.DeleteOpinion(asUser("alice"), &opinionsv1.DeleteOpinionRequest{Id: "unknown"})
+				return err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "forbidden",
+			call: func() error {
+				_, err := client.DeleteOpinion(asUser("bob"), &opinionsv1.DeleteOpinionRequest{Id: created.GetId()})
+				return err
+			},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "stale version",
+			call: func() error {
+				_, err := client.EditOpinion(asUser("alice"), &opinionsv1.EditOpinionRequest{Id: created.GetId(), Statement: "gRPC is great", Version: created.GetVersion() + 1})
+				return err
+			},
+			code: codes.Aborted,
+		},
+		{
+			name: "only admins list the reports",
+			call: func() error {
+				_, err := client.ListReports(asUser("alice"), &opinionsv1.ListReportsRequest{})
+				return err
+			},
+			code: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.code, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestServer_validationDetails(t *testing.T) {
+	t.Parallel()
+	client := opinionsv1.NewOpinionsServiceClient(newTestClient(t))
+
+	_, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "ab"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, badRequest.GetFieldViolations()...)
+		}
+	}
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "statement", violations[0].GetField())
+		assert.Equal(t, "must contain at least 3 characters", violations[0].GetDescription())
+	}
+}
+
+func TestServer_WatchOpinions(t *testing.T) {
+	t.Parallel()
+	client := opinionsv1.NewOpinionsServiceClient(newTestClient(t))
+
+	ctx, cancel := context.WithTimeout(asUser("bob"), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchOpinions(ctx, &opinionsv1.WatchOpinionsRequest{})
+	assert.NoError(t, err)
+
+	// the stream is subscribed once the headers are sent, the opinions created before are not watched
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	created, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "streams are nice"})
+	assert.NoError(t, err)
+
+	received, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, created.GetId(), received.GetCreated().GetId())
+	assert.Equal(t, "streams are nice", received.GetCreated().GetStatement())
+}
+
+func TestServer_WatchOpinions_visibility(t *testing.T) {
+	t.Parallel()
+	client := opinionsv1.NewOpinionsServiceClient(newTestClient(t))
+
+	watch := func(user string) opinionsv1.OpinionsService_WatchOpinionsClient {
+		ctx, cancel := context.WithTimeout(asUser(user), 5*time.Second)
+		t.Cleanup(cancel)
+		stream, err := client.WatchOpinions(ctx, &opinionsv1.WatchOpinionsRequest{})
+		assert.NoError(t, err)
+		_, err = stream.Header()
+		assert.NoError(t, err)
+		return stream
+	}
+	moderator := watch("moderator")
+	bob := watch("bob")
+
+	pending, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "read https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, string(application.OpinionPendingReview), pending.GetStatus())
+	published, err := client.CreateOpinion(asUser("alice"), &opinionsv1.CreateOpinionRequest{Statement: "streams are nice"})
+	assert.NoError(t, err)
+
+	received, err := moderator.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, pending.GetId(), received.GetCreated().GetId(), "the moderators watch the opinions which are held for review")
+
+	received, err = bob.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, published.GetId(), received.GetCreated().GetId(), "the opinions of other users which are held for review are not watched")
+
+	_, err = client.ApproveOpinion(asUser("moderator"), &opinionsv1.ReviewOpinionRequest{Id: pending.GetId()})
+	assert.NoError(t, err)
+	received, err = bob.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, pending.GetId(), received.GetUpdated().GetId(), "the approved opinion is watched by the other users")
+
+	_, err = client.HideOpinion(asUser("moderator"), &opinionsv1.ReviewOpinionRequest{Id: pending.GetId()})
+	assert.NoError(t, err)
+	received, err = bob.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, pending.GetId(), received.GetRemovedId(), "the hidden opinion is removed for the other users")
+}
+
+func TestGateway(t *testing.T) {
+	t.Parallel()
+	gateway, err := rpc.NewGateway(context.Background(), newTestClient(t), "X-User-Id", "X-User-Roles")
+	assert.NoError(t, err)
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	do := func(method string, path string, user string, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request could not be created: %s", err)
+		}
+		if user != "" {
+			req.Header.Set("X-User-Id", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = resp.Body.Close()
+		})
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/v1/opinions", "alice", `{"statement":"gateways are nice"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var created struct {
+		Id        string `json:"id"`
+		Owner     string `json:"owner"`
+		Statement string `json:"statement"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "alice", created.Owner)
+	assert.Equal(t, "gateways are nice", created.Statement)
+
+	resp = do(http.MethodGet, "/v1/opinions", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = do(http.MethodDelete, "/v1/opinions/"+created.Id, "bob", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(http.MethodPost, "/v1/opinions/"+created.Id+":hide", "bob", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/v1/opinions", "bob", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Opinions []struct {
+			Id string `json:"id"`
+		} `json:"opinions"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	if assert.Len(t, list.Opinions, 1) {
+		assert.Equal(t, created.Id, list.Opinions[0].Id)
+	}
+}
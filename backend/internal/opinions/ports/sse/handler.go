@@ -0,0 +1,130 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultHeartbeat is the interval of the comments which keep idle connections open through proxies
+const DefaultHeartbeat = 15 * time.Second
+
+const (
+	// subscriberBuffer is the count of messages a connection may fall behind before it is closed
+	subscriberBuffer = 64
+	// retry is the delay after which the browsers reconnect with the Last-Event-ID
+	retry = 3 * time.Second
+)
+
+// Authenticator resolves the user of a request, see rest.HeaderAuthenticator
+type Authenticator interface {
+	Authenticate(r *http.Request) (application.AuthenticatedUser, error)
+}
+
+// VisibilityQuery returns the opinions a user may list, see application.Service
+type VisibilityQuery interface {
+	OpinionVisibilityQuery(ctx context.Context, user application.AuthenticatedUser) (application.OpinionVisibility, error)
+}
+
+// NewHandler streams the messages of the hub which match the opinion visibility of the user of the request.
+// A client which falls behind is disconnected and catches up with the replay buffer of the hub when it reconnects.
+func NewHandler(visibilities VisibilityQuery, hub *Hub, authenticator Authenticator, heartbeat time.Duration) http.Handler {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	return handler{visibilities: visibilities, hub: hub, authenticator: authenticator, heartbeat: heartbeat}
+}
+
+type handler struct {
+	visibilities  VisibilityQuery
+	hub           *Hub
+	authenticator Authenticator
+	heartbeat     time.Duration
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		httperror.WriteStatus(w, http.StatusMethodNotAllowed, application.NewError(application.CodeValidation, "only GET is allowed", nil))
+		return
+	}
+
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	visibility, err := h.visibilities.OpinionVisibilityQuery(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httperror.Write(w, application.NewError(application.CodeInternal, "streaming is not supported", nil))
+		return
+	}
+
+	var sub Subscription
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastEventID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			httperror.Write(w, application.NewValidationError(application.FieldError{Field: "Last-Event-ID", Message: "must be the id of a received event"}))
+			return
+		}
+		sub = h.hub.Resume(visibility, lastEventID, subscriberBuffer)
+	} else {
+		sub = h.hub.Subscribe(visibility, subscriberBuffer)
+	}
+	defer sub.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return
+	}
+	for _, m := range sub.Replay {
+		if err := writeMessage(w, m); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-sub.Messages:
+			if !ok {
+				// the client fell behind, it resumes with the Last-Event-ID after the retry delay
+				return
+			}
+			if err := writeMessage(w, m); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeMessage writes the message in the event stream format, the data is JSON without line breaks
+func writeMessage(w http.ResponseWriter, m Message) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", m.ID, m.Event, m.Data)
+	return err
+}
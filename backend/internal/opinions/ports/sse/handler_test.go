@@ -0,0 +1,191 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rest"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStreamServer(t *testing.T, heartbeat time.Duration) (*httptest.Server, *projections.OpinionListProjection, *sse.Hub) {
+	t.Helper()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 10)
+	service := application.NewOpinionService(
+		infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+		infrastructure.NewOpinionsRepositoryMemory(),
+		infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil),
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+	)
+	authenticator := rest.HeaderAuthenticator{Header: "X-User-Id", Roles: map[application.UserId][]application.Role{"moderator": {application.RoleModerator}}}
+	server := httptest.NewServer(sse.NewHandler(service, hub, authenticator, heartbeat))
+	t.Cleanup(server.Close)
+	return server, projection, hub
+}
+
+// connect opens the stream and reads the retry block, which is sent once the stream is subscribed
+func connect(t *testing.T, url string, headers ...string) (*bufio.Reader, *http.Response) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("request could not be created: %s", err)
+	}
+	req.Header.Set("X-User-Id", "bob")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+
+	reader := bufio.NewReader(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		assert.Equal(t, "retry: 3000\n", readBlock(t, reader))
+	}
+	return reader, resp
+}
+
+// readBlock reads the lines until the blank line which ends an event
+func readBlock(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var block strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream could not be read: %s", err)
+		}
+		if line == "\n" {
+			return block.String()
+		}
+		block.WriteString(line)
+	}
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	t.Parallel()
+	server, projection, hub := newStreamServer(t, time.Hour)
+
+	reader, resp := connect(t, server.URL)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	publish(t, projection, hub,
+		opinionCreated(1, "pending", "alice", application.OpinionPendingReview),
+		opinionCreated(2, "published", "alice", application.OpinionPublished),
+		voteSubmitted(3, "published", "bob", false),
+		application.Event{Sequence: 4, Type: application.EventOpinionDeleted, Opinion: application.Opinion{ID: "published"}},
+	)
+
+	assert.Equal(t, "id: 2\nevent: opinion-created\n"+
+		`data: {"id":"published","owner":"alice","createdAt":"0001-01-01T00:00:00Z","statement":"streams are nice","status":"published","version":0}`+"\n",
+		readBlock(t, reader))
+	assert.Equal(t, "id: 3\nevent: vote-tally-changed\n"+`data: {"opinionId":"published","agreements":0,"disagreements":1}`+"\n", readBlock(t, reader))
+	assert.Equal(t, "id: 4\nevent: opinion-deleted\n"+`data: {"id":"published"}`+"\n", readBlock(t, reader))
+}
+
+func TestHandler_ServeHTTP_moderator(t *testing.T) {
+	t.Parallel()
+	server, projection, hub := newStreamServer(t, time.Hour)
+
+	reader, _ := connect(t, server.URL, "X-User-Id", "moderator")
+	publish(t, projection, hub, opinionCreated(1, "pending", "alice", application.OpinionPendingReview))
+	assert.True(t, strings.HasPrefix(readBlock(t, reader), "id: 1\nevent: opinion-created\n"), "the moderators see the opinions which are held for review")
+}
+
+func TestHandler_ServeHTTP_heartbeat(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newStreamServer(t, 10*time.Millisecond)
+
+	reader, _ := connect(t, server.URL)
+	assert.Equal(t, ": heartbeat\n", readBlock(t, reader))
+	assert.Equal(t, ": heartbeat\n", readBlock(t, reader))
+}
+
+func TestHandler_ServeHTTP_lastEventID(t *testing.T) {
+	t.Parallel()
+	server, projection, hub := newStreamServer(t, time.Hour)
+	publish(t, projection, hub,
+		opinionCreated(1, "a", "alice", application.OpinionPublished),
+		opinionCreated(2, "b", "alice", application.OpinionPublished),
+	)
+
+	reader, _ := connect(t, server.URL, "Last-Event-ID", "1")
+	assert.True(t, strings.HasPrefix(readBlock(t, reader), "id: 2\nevent: opinion-created\n"))
+
+	publish(t, projection, hub, opinionCreated(3, "c", "alice", application.OpinionPublished))
+	assert.True(t, strings.HasPrefix(readBlock(t, reader), "id: 3\nevent: opinion-created\n"), "the live messages follow the replay")
+}
+
+func TestHandler_ServeHTTP_errors(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newStreamServer(t, time.Hour)
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+		body   string
+	}{
+		{
+			name:   "unauthenticated",
+			method: http.MethodGet,
+			header: http.Header{},
+			status: http.StatusUnauthorized,
+			body:   `{"code":"unauthenticated","message":"unauthenticated"}`,
+		},
+		{
+			name:   "invalid Last-Event-ID",
+			method: http.MethodGet,
+			header: http.Header{"X-User-Id": {"bob"}, "Last-Event-Id": {"latest"}},
+			status: http.StatusBadRequest,
+			body:   `{"code":"validation","message":"validation failed","fields":[{"field":"Last-Event-ID","message":"must be the id of a received event"}]}`,
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodPost,
+			header: http.Header{"X-User-Id": {"bob"}},
+			status: http.StatusMethodNotAllowed,
+			body:   `{"code":"validation","message":"only GET is allowed"}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatalf("request could not be created: %s", err)
+			}
+			req.Header = tt.header
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %s", err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			body, _ := bufio.NewReader(resp.Body).ReadString('\n')
+			assert.JSONEq(t, tt.body, body)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package sse streams live opinion and vote updates to the browsers as Server-Sent Events.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"sync"
+	"time"
+)
+
+// The event names of the stream
+const (
+	EventOpinionCreated = "opinion-created"
+	// EventOpinionUpdated carries an opinion which was edited, reviewed or whose reports were resolved, it may be new to the client, e.g. after an approval
+	EventOpinionUpdated = "opinion-updated"
+	// EventOpinionRemoved tells the client that it may no longer list the opinion, e.g. after it was hidden
+	EventOpinionRemoved   = "opinion-removed"
+	EventOpinionDeleted   = "opinion-deleted"
+	EventVoteTallyChanged = "vote-tally-changed"
+	// EventReset tells a resumed client that messages are missing, it has to reload the opinions
+	EventReset = "reset"
+)
+
+// DefaultReplayBufferSize is the count of messages which are kept for resuming clients
+const DefaultReplayBufferSize = 1024
+
+// TallyReader returns the current vote tally of an opinion, see projections.OpinionListProjection
+type TallyReader interface {
+	Get(id application.OpinionId) (projections.OpinionSummary, bool)
+}
+
+// Message is a single event of the stream, the ID is the sequence of the domain event
+type Message struct {
+	ID    uint64
+	Event string
+	Data  []byte
+	// public messages are sent to every subscriber, the others depend on how the subject changes the opinions of the subscriber
+	public  bool
+	subject application.Event
+	// removal is the data of the EventOpinionRemoved which is sent instead to the subscribers which can no longer list the opinion
+	removal []byte
+}
+
+// to returns the message for a subscriber with the visibility, ok is false if the subscriber does not receive it
+func (m Message) to(visibility application.OpinionVisibility) (_ Message, ok bool) {
+	if m.public {
+		return m, true
+	}
+
+	switch visibility.ChangeOf(m.subject) {
+	case application.OpinionShown:
+		return m, true
+	case application.OpinionRemoved:
+		if m.removal != nil {
+			return Message{ID: m.ID, Event: EventOpinionRemoved, Data: m.removal, public: true}, true
+		}
+	}
+	return Message{}, false
+}
+
+// opinionData is the data of EventOpinionCreated and EventOpinionUpdated
+type opinionData struct {
+	ID        application.OpinionId     `json:"id"`
+	Owner     application.UserId        `json:"owner"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	Statement string                    `json:"statement"`
+	Status    application.OpinionStatus `json:"status"`
+	Version   int64                     `json:"version"`
+}
+
+func newOpinionData(o application.Opinion) opinionData {
+	return opinionData{ID: o.ID, Owner: o.Owner, CreatedAt: o.CreatedAt, Statement: o.Statement, Status: o.Status, Version: o.Version}
+}
+
+// opinionDeleted is the data of EventOpinionDeleted and EventOpinionRemoved
+type opinionDeleted struct {
+	ID application.OpinionId `json:"id"`
+}
+
+type voteTallyChanged struct {
+	OpinionID     application.OpinionId `json:"opinionId"`
+	Agreements    int                   `json:"agreements"`
+	Disagreements int                   `json:"disagreements"`
+}
+
+// NewHub creates an application.EventHandler which converts the domain events to stream messages.
+// It keeps the last replay messages for clients which resume with a Last-Event-ID.
+// The hub must be subscribed after the TallyReader, so that the tallies already contain the vote of an event.
+func NewHub(tallies TallyReader, replay int) *Hub {
+	if replay <= 0 {
+		replay = DefaultReplayBufferSize
+	}
+	return &Hub{
+		tallies:     tallies,
+		replay:      make([]Message, 0, replay),
+		subscribers: make(map[chan Message]application.OpinionVisibility),
+	}
+}
+
+type Hub struct {
+	tallies TallyReader
+
+	mu     sync.Mutex
+	replay []Message
+	// horizon is the newest sequence whose message may be missing in the replay buffer
+	horizon     uint64
+	started     bool
+	subscribers map[chan Message]application.OpinionVisibility
+}
+
+// Subscription passes the messages which match the visibility of the subscriber
+type Subscription struct {
+	// Replay holds the buffered messages after the Last-Event-ID.
+	// It starts with an EventReset if messages after the Last-Event-ID are no longer buffered.
+	Replay []Message
+	// Messages receives the messages handled after the subscription.
+	// It is closed by Cancel or if the client fell behind by more than the buffer.
+	Messages <-chan Message
+	Cancel   func()
+}
+
+// Subscribe returns a subscription of the messages handled after the call, see application.Service OpinionVisibilityQuery for the visibility
+func (h *Hub) Subscribe(visibility application.OpinionVisibility, buffer int) Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.subscribe(visibility, buffer)
+}
+
+// Resume returns a subscription which replays the buffered messages after the lastEventID.
+// The ID of the EventReset is the horizon of the buffer, so that the client does not reset again when it resumes.
+func (h *Hub) Resume(visibility application.OpinionVisibility, lastEventID uint64, buffer int) Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := h.subscribe(visibility, buffer)
+	if h.started && lastEventID < h.horizon {
+		sub.Replay = append(sub.Replay, Message{ID: h.horizon, Event: EventReset, Data: []byte("{}"), public: true})
+	}
+	for _, m := range h.replay {
+		if m.ID <= lastEventID {
+			continue
+		}
+		if m, ok := m.to(visibility); ok {
+			sub.Replay = append(sub.Replay, m)
+		}
+	}
+	return sub
+}
+
+// subscribe registers the channel, h.mu must be held
+func (h *Hub) subscribe(visibility application.OpinionVisibility, buffer int) Subscription {
+	ch := make(chan Message, buffer)
+	h.subscribers[ch] = visibility
+
+	return Subscription{
+		Messages: ch,
+		Cancel: func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.remove(ch)
+		},
+	}
+}
+
+func (h *Hub) HandleEvent(_ context.Context, event application.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.started {
+		// the events before the first one were not seen by the hub and can not be replayed
+		h.started = true
+		h.horizon = event.Sequence - 1
+	}
+
+	m, ok, err := h.message(event)
+	if err != nil || !ok {
+		return err
+	}
+
+	if len(h.replay) == cap(h.replay) {
+		h.horizon = h.replay[0].ID
+		copy(h.replay, h.replay[1:])
+		h.replay = h.replay[:len(h.replay)-1]
+	}
+	h.replay = append(h.replay, m)
+
+	for ch, visibility := range h.subscribers {
+		m, ok := m.to(visibility)
+		if !ok {
+			continue
+		}
+		select {
+		case ch <- m:
+		default:
+			h.remove(ch)
+		}
+	}
+	return nil
+}
+
+// message converts the event, ok is false for events which are not streamed
+func (h *Hub) message(event application.Event) (m Message, ok bool, err error) {
+	m.ID = event.Sequence
+
+	var data any
+	switch event.Type {
+	case application.EventOpinionCreated:
+		o := event.Opinion
+		m.Event = EventOpinionCreated
+		m.subject = event
+		data = newOpinionData(o)
+	case application.EventOpinionEdited, application.EventOpinionApproved, application.EventOpinionRejected, application.EventOpinionHidden, application.EventReportsResolved:
+		m.Event = EventOpinionUpdated
+		m.subject = event
+		data = newOpinionData(event.Opinion)
+		if m.removal, err = json.Marshal(opinionDeleted{ID: event.Opinion.ID}); err != nil {
+			return Message{}, false, err
+		}
+	case application.EventOpinionDeleted:
+		m.Event = EventOpinionDeleted
+		m.public = true
+		data = opinionDeleted{ID: event.Opinion.ID}
+	case application.EventVoteSubmitted, application.EventVoteUpdated, application.EventVoteDeleted:
+		summary, found := h.tallies.Get(event.Vote.Opinion)
+		if !found {
+			return Message{}, false, nil
+		}
+		m.Event = EventVoteTallyChanged
+		m.subject = application.Event{Opinion: application.Opinion{ID: summary.ID, Owner: summary.Owner, Status: summary.Status}}
+		data = voteTallyChanged{OpinionID: summary.ID, Agreements: summary.Agreements, Disagreements: summary.Disagreements}
+	default:
+		return Message{}, false, nil
+	}
+
+	m.Data, err = json.Marshal(data)
+	if err != nil {
+		return Message{}, false, err
+	}
+	return m, true, nil
+}
+
+// Close ends the subscriptions, e.g. on shutdown, the clients resume with their Last-Event-ID on another server
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		h.remove(ch)
+	}
+}
+
+// remove closes the channel once, h.mu must be held
+func (h *Hub) remove(ch chan Message) {
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,292 @@
+package sse_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+var (
+	alice = application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}
+	bob   = application.AuthenticatedUser{Id: "bob", Roles: []application.Role{application.RoleUser}}
+)
+
+// ownAndPublished is the default visibility of the service for users
+func ownAndPublished(user application.AuthenticatedUser) application.OpinionVisibility {
+	return application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+		{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: string(application.OpinionPublished)}},
+		{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: string(user.Id)}},
+	}}
+}
+
+func opinionCreated(sequence uint64, id application.OpinionId, owner application.UserId, status application.OpinionStatus) application.Event {
+	return application.Event{
+		Sequence: sequence,
+		Type:     application.EventOpinionCreated,
+		Opinion:  application.Opinion{ID: id, Owner: owner, Statement: "streams are nice", Status: status},
+	}
+}
+
+func voteSubmitted(sequence uint64, opinion application.OpinionId, voter application.UserId, agreement bool) application.Event {
+	return application.Event{
+		Sequence: sequence,
+		Type:     application.EventVoteSubmitted,
+		Vote:     application.Vote{Opinion: opinion, Voter: voter, Agreement: agreement},
+	}
+}
+
+// publish passes the events to the projection before the hub, like the event bus with the subscription order of main
+func publish(t *testing.T, projection *projections.OpinionListProjection, hub *sse.Hub, events ...application.Event) {
+	t.Helper()
+	for _, event := range events {
+		assert.NoError(t, projection.HandleEvent(context.Background(), event))
+		assert.NoError(t, hub.HandleEvent(context.Background(), event))
+	}
+}
+
+func received(messages <-chan sse.Message) []sse.Message {
+	var list []sse.Message
+	for {
+		select {
+		case m, ok := <-messages:
+			if !ok {
+				return list
+			}
+			list = append(list, m)
+		default:
+			return list
+		}
+	}
+}
+
+func TestHub_HandleEvent(t *testing.T) {
+	t.Parallel()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 10)
+
+	aliceSub := hub.Subscribe(ownAndPublished(alice), 10)
+	defer aliceSub.Cancel()
+	bobSub := hub.Subscribe(ownAndPublished(bob), 10)
+	defer bobSub.Cancel()
+
+	publish(t, projection, hub,
+		opinionCreated(1, "published", "alice", application.OpinionPublished),
+		opinionCreated(2, "pending", "alice", application.OpinionPendingReview),
+		application.Event{Sequence: 3, Type: application.EventOpinionEdited, Opinion: application.Opinion{ID: "published", Owner: "alice", Status: application.OpinionPublished}},
+		voteSubmitted(4, "published", "bob", true),
+		voteSubmitted(5, "published", "carol", false),
+		voteSubmitted(6, "pending", "alice", true),
+		application.Event{Sequence: 7, Type: application.EventOpinionDeleted, Opinion: application.Opinion{ID: "pending"}},
+	)
+
+	messages := received(aliceSub.Messages)
+	if assert.Len(t, messages, 7) {
+		assert.Equal(t, sse.EventOpinionCreated, messages[0].Event)
+		assert.JSONEq(t, `{"id":"published","owner":"alice","createdAt":"0001-01-01T00:00:00Z","statement":"streams are nice","status":"published","version":0}`, string(messages[0].Data))
+		assert.Equal(t, uint64(2), messages[1].ID, "the owner receives the own opinions which are held for review")
+		assert.Equal(t, sse.EventOpinionUpdated, messages[2].Event)
+		assert.Equal(t, sse.EventVoteTallyChanged, messages[3].Event)
+		assert.JSONEq(t, `{"opinionId":"published","agreements":1,"disagreements":0}`, string(messages[3].Data))
+		assert.JSONEq(t, `{"opinionId":"published","agreements":1,"disagreements":1}`, string(messages[4].Data))
+		assert.JSONEq(t, `{"opinionId":"pending","agreements":1,"disagreements":0}`, string(messages[5].Data))
+		assert.Equal(t, sse.EventOpinionDeleted, messages[6].Event)
+		assert.JSONEq(t, `{"id":"pending"}`, string(messages[6].Data))
+	}
+
+	var ids []uint64
+	for _, m := range received(bobSub.Messages) {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []uint64{1, 3, 4, 5, 7}, ids, "the opinions which are held for review of other users are not streamed")
+}
+
+func TestHub_HandleEvent_review(t *testing.T) {
+	t.Parallel()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 10)
+
+	aliceSub := hub.Subscribe(ownAndPublished(alice), 10)
+	defer aliceSub.Cancel()
+	bobSub := hub.Subscribe(ownAndPublished(bob), 10)
+	defer bobSub.Cancel()
+
+	reviewed := func(sequence uint64, eventType application.EventType, id application.OpinionId, status application.OpinionStatus, previous application.OpinionStatus) application.Event {
+		return application.Event{Sequence: sequence, Type: eventType, Opinion: application.Opinion{ID: id, Owner: "alice", Status: status}, PreviousStatus: previous}
+	}
+	publish(t, projection, hub,
+		opinionCreated(1, "held", "alice", application.OpinionPendingReview),
+		reviewed(2, application.EventOpinionApproved, "held", application.OpinionPublished, application.OpinionPendingReview),
+		reviewed(3, application.EventOpinionHidden, "held", application.OpinionHidden, application.OpinionPublished),
+		opinionCreated(4, "spam", "alice", application.OpinionPendingReview),
+		reviewed(5, application.EventOpinionRejected, "spam", application.OpinionRejected, application.OpinionPendingReview),
+	)
+
+	type message struct {
+		ID    uint64
+		Event string
+	}
+	messagesOf := func(sub sse.Subscription) []message {
+		var list []message
+		for _, m := range received(sub.Messages) {
+			list = append(list, message{ID: m.ID, Event: m.Event})
+		}
+		return list
+	}
+
+	assert.Equal(t, []message{
+		{ID: 2, Event: sse.EventOpinionUpdated},
+		{ID: 3, Event: sse.EventOpinionRemoved},
+	}, messagesOf(bobSub), "the approval shows the opinion to the other users, hiding it removes it again")
+	assert.Equal(t, []message{
+		{ID: 1, Event: sse.EventOpinionCreated},
+		{ID: 2, Event: sse.EventOpinionUpdated},
+		{ID: 3, Event: sse.EventOpinionUpdated},
+		{ID: 4, Event: sse.EventOpinionCreated},
+		{ID: 5, Event: sse.EventOpinionUpdated},
+	}, messagesOf(aliceSub), "the owner still lists the own hidden and rejected opinions")
+
+	sub := hub.Resume(ownAndPublished(bob), 0, 10)
+	defer sub.Cancel()
+	if assert.Len(t, sub.Replay, 2) {
+		assert.JSONEq(t, `{"id":"held"}`, string(sub.Replay[1].Data), "the replay removes the hidden opinion as well")
+	}
+}
+
+func TestHub_HandleEvent_visibility(t *testing.T) {
+	t.Parallel()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 10)
+
+	moderatorSub := hub.Subscribe(application.AllOpinionsVisible, 10)
+	defer moderatorSub.Cancel()
+	noneSub := hub.Subscribe(application.OpinionVisibility{}, 10)
+	defer noneSub.Cancel()
+
+	publish(t, projection, hub,
+		opinionCreated(1, "pending", "alice", application.OpinionPendingReview),
+		voteSubmitted(2, "pending", "alice", true),
+		application.Event{Sequence: 3, Type: application.EventOpinionDeleted, Opinion: application.Opinion{ID: "pending"}},
+	)
+
+	var ids []uint64
+	for _, m := range received(moderatorSub.Messages) {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, ids, "the opinions which are held for review are streamed to the moderators")
+
+	ids = nil
+	for _, m := range received(noneSub.Messages) {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []uint64{3}, ids, "the deletions are streamed to every subscriber")
+}
+
+func TestHub_Resume(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		events      []string
+		ids         []uint64
+	}{
+		{
+			name:        "buffered messages are replayed",
+			lastEventID: 3,
+			events:      []string{sse.EventOpinionCreated, sse.EventOpinionCreated},
+			ids:         []uint64{4, 5},
+		},
+		{
+			name:        "up to date",
+			lastEventID: 5,
+		},
+		{
+			name:        "dropped messages reset the client",
+			lastEventID: 1,
+			events:      []string{sse.EventReset, sse.EventOpinionCreated, sse.EventOpinionCreated, sse.EventOpinionCreated},
+			ids:         []uint64{2, 3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			projection := projections.NewOpinionListProjection()
+			hub := sse.NewHub(projection, 3)
+			for sequence := uint64(1); sequence <= 5; sequence++ {
+				publish(t, projection, hub, opinionCreated(sequence, application.OpinionId(strconv.FormatUint(sequence, 10)), "alice", application.OpinionPublished))
+			}
+
+			sub := hub.Resume(ownAndPublished(bob), tt.lastEventID, 10)
+			defer sub.Cancel()
+
+			var events []string
+			var ids []uint64
+			for _, m := range sub.Replay {
+				events = append(events, m.Event)
+				ids = append(ids, m.ID)
+			}
+			assert.Equal(t, tt.events, events)
+			assert.Equal(t, tt.ids, ids)
+		})
+	}
+}
+
+func TestHub_Resume_afterStart(t *testing.T) {
+	t.Parallel()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 3)
+
+	sub := hub.Resume(ownAndPublished(bob), 41, 10)
+	sub.Cancel()
+	assert.Empty(t, sub.Replay, "a hub without events does not know about missing messages")
+
+	publish(t, projection, hub, opinionCreated(43, "a", "alice", application.OpinionPublished))
+
+	sub = hub.Resume(ownAndPublished(bob), 41, 10)
+	defer sub.Cancel()
+	if assert.Len(t, sub.Replay, 2) {
+		assert.Equal(t, sse.EventReset, sub.Replay[0].Event, "the events before the start of the hub are missing")
+		assert.Equal(t, uint64(42), sub.Replay[0].ID)
+		assert.Equal(t, uint64(43), sub.Replay[1].ID)
+	}
+}
+
+func TestHub_slowSubscriber(t *testing.T) {
+	t.Parallel()
+	projection := projections.NewOpinionListProjection()
+	hub := sse.NewHub(projection, 10)
+
+	slow := hub.Subscribe(ownAndPublished(bob), 1)
+	defer slow.Cancel()
+
+	publish(t, projection, hub,
+		opinionCreated(1, "a", "alice", application.OpinionPublished),
+		opinionCreated(2, "b", "alice", application.OpinionPublished),
+	)
+
+	assert.Equal(t, uint64(1), (<-slow.Messages).ID)
+	_, open := <-slow.Messages
+	assert.False(t, open, "the subscriber which fell behind is removed")
+
+	resumed := hub.Resume(ownAndPublished(bob), 1, 1)
+	defer resumed.Cancel()
+	if assert.Len(t, resumed.Replay, 1, "the subscriber catches up with the replay buffer") {
+		assert.Equal(t, uint64(2), resumed.Replay[0].ID)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	t.Parallel()
+	hub := sse.NewHub(projections.NewOpinionListProjection(), 10)
+	sub := hub.Subscribe(ownAndPublished(bob), 1)
+
+	hub.Close()
+	_, open := <-sub.Messages
+	assert.False(t, open)
+	sub.Cancel()
+}
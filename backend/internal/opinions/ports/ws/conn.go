@@ -0,0 +1,200 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/gorilla/websocket"
+	"sync"
+	"time"
+)
+
+// The types of the messages, every message is a JSON text frame
+const (
+	// TypeCreateVote and TypeUpdateVote are sent by the client to vote on an opinion
+	TypeCreateVote = "create-vote"
+	TypeUpdateVote = "update-vote"
+	// TypeAck answers a vote with the stored vote
+	TypeAck = "ack"
+	// TypeError answers a vote or an invalid message with the error of the command
+	TypeError = "error"
+	// TypeVoteTallyChanged is sent after a vote on an opinion which is visible to the user
+	TypeVoteTallyChanged = sse.EventVoteTallyChanged
+)
+
+// clientMessage is a command of the client, the ID is returned with the answer to correlate it
+type clientMessage struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	OpinionID string `json:"opinionId"`
+	Agreement bool   `json:"agreement"`
+	// Version is the last known version of the vote for updates, zero skips the version check
+	Version int64 `json:"version"`
+}
+
+type serverMessage struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Vote  *vote           `json:"vote,omitempty"`
+	Error *errorBody      `json:"error,omitempty"`
+	Tally json.RawMessage `json:"tally,omitempty"`
+}
+
+type vote struct {
+	OpinionID string    `json:"opinionId"`
+	Voter     string    `json:"voter"`
+	Agreement bool      `json:"agreement"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int64     `json:"version"`
+}
+
+type conn struct {
+	handler *Handler
+	user    application.AuthenticatedUser
+	// visibility selects the opinions whose tallies are sent, it is queried once when the connection is opened
+	visibility application.OpinionVisibility
+	ws         *websocket.Conn
+	send       chan serverMessage
+
+	closeOnce sync.Once
+	// closeMessage is the payload of the close frame, it is set before done is closed
+	closeMessage []byte
+	done         chan struct{}
+}
+
+// serve reads the commands of the client until the connection is closed by either side
+func (c *conn) serve(ctx context.Context) {
+	tallies := c.handler.tallies.Subscribe(c.visibility, tallyBuffer)
+	defer tallies.Cancel()
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.write(tallies.Messages)
+	}()
+
+	c.read(ctx)
+	c.closeWith(websocket.CloseNormalClosure, "")
+	<-written
+	_ = c.ws.Close()
+}
+
+// close ends the connection because the server shuts down
+func (c *conn) close() {
+	c.closeWith(websocket.CloseGoingAway, "server is shutting down")
+}
+
+// closeWith lets the writer send the close frame, only the first call has an effect
+func (c *conn) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeMessage = websocket.FormatCloseMessage(code, text)
+		close(c.done)
+	})
+}
+
+func (c *conn) read(ctx context.Context) {
+	pongWait := 2 * c.handler.limits.PingInterval
+	c.ws.SetReadLimit(maxMessageSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(serverMessage{Type: TypeError, Error: newErrorBody(application.NewValidationError(application.FieldError{Field: "message", Message: "must be a JSON object"}))})
+			continue
+		}
+		c.reply(c.dispatch(ctx, msg))
+	}
+}
+
+// dispatch passes the command to the service and returns the answer
+func (c *conn) dispatch(ctx context.Context, msg clientMessage) serverMessage {
+	dto := application.VoteCreateAndUpdateDTO{
+		Agreement: msg.Agreement,
+		Opinion:   application.OpinionId(msg.OpinionID),
+		Version:   msg.Version,
+	}
+
+	var v application.Vote
+	var err error
+	switch msg.Type {
+	case TypeCreateVote:
+		v, err = c.handler.service.CreateVoteCommand(ctx, c.user, dto)
+	case TypeUpdateVote:
+		v, err = c.handler.service.UpdateVoteCommand(ctx, c.user, dto)
+	default:
+		err = application.NewValidationError(application.FieldError{Field: "type", Message: "must be " + TypeCreateVote + " or " + TypeUpdateVote})
+	}
+	if err != nil {
+		return serverMessage{Type: TypeError, ID: msg.ID, Error: newErrorBody(err)}
+	}
+
+	return serverMessage{Type: TypeAck, ID: msg.ID, Vote: &vote{
+		OpinionID: string(v.Opinion),
+		Voter:     string(v.Voter),
+		Agreement: v.Agreement,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Version:   v.Version,
+	}}
+}
+
+// reply passes the message to the writer, it blocks while the client does not read its answers
+func (c *conn) reply(msg serverMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+// write is the only writer of the connection, it sends the answers, the tallies and the pings
+func (c *conn) write(tallies <-chan sse.Message) {
+	ticker := time.NewTicker(c.handler.limits.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-c.done:
+			_ = c.ws.WriteControl(websocket.CloseMessage, c.closeMessage, time.Now().Add(writeWait))
+			// the reader returns once the client answered the close frame or the deadline passed
+			_ = c.ws.SetReadDeadline(time.Now().Add(writeWait))
+			return
+		case msg := <-c.send:
+			err = c.writeJSON(msg)
+		case m, ok := <-tallies:
+			if !ok {
+				c.closeWith(websocket.CloseTryAgainLater, "connection fell behind")
+				tallies = nil
+				continue
+			}
+			if m.Event == sse.EventVoteTallyChanged {
+				err = c.writeJSON(serverMessage{Type: TypeVoteTallyChanged, Tally: m.Data})
+			}
+		case <-ticker.C:
+			err = c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		}
+
+		if err != nil {
+			// the reader fails on the closed connection and ends serve, a blocked reply is released by done
+			c.closeWith(websocket.CloseAbnormalClosure, "")
+			_ = c.ws.Close()
+			return
+		}
+	}
+}
+
+func (c *conn) writeJSON(msg serverMessage) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.ws.WriteJSON(msg)
+}
@@ -0,0 +1,171 @@
+// Package ws lets the browsers vote and receive the vote tallies over a single WebSocket connection.
+package ws
+
+import (
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/httperror"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TallySubscriber passes the live messages which match the opinion visibility, see sse.Hub
+type TallySubscriber interface {
+	Subscribe(visibility application.OpinionVisibility, buffer int) sse.Subscription
+}
+
+// Authenticator resolves the user of the upgrade request, see rest.HeaderAuthenticator
+type Authenticator interface {
+	Authenticate(r *http.Request) (application.AuthenticatedUser, error)
+}
+
+// Limits bound the resources of the open connections
+type Limits struct {
+	// Connections is the count of open connections of all users
+	Connections int
+	// ConnectionsPerUser is the count of open connections of a single user, e.g. browser tabs
+	ConnectionsPerUser int
+	// PingInterval is the interval of the pings, a connection without a pong for two intervals is closed
+	PingInterval time.Duration
+}
+
+// DefaultLimits allow a few tabs per user
+func DefaultLimits() Limits {
+	return Limits{
+		Connections:        1000,
+		ConnectionsPerUser: 5,
+		PingInterval:       30 * time.Second,
+	}
+}
+
+const (
+	// maxMessageSize is the size of the largest accepted client message in bytes
+	maxMessageSize = 4096
+	// sendBuffer is the count of messages a connection may fall behind before the commands of the client block
+	sendBuffer = 16
+	// tallyBuffer is the count of tallies a connection may fall behind before it is closed
+	tallyBuffer = 64
+	// writeWait is the time a write of a message may take
+	writeWait = 10 * time.Second
+)
+
+// NewHandler creates a handler which upgrades the authenticated requests to WebSocket connections.
+// The votes are passed to the service, so they pass the same policy checks and rate limits as the other APIs.
+func NewHandler(service application.Service, tallies TallySubscriber, authenticator Authenticator, limits Limits) *Handler {
+	if limits.PingInterval <= 0 {
+		limits.PingInterval = DefaultLimits().PingInterval
+	}
+	return &Handler{
+		service:       service,
+		tallies:       tallies,
+		authenticator: authenticator,
+		limits:        limits,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		conns:   make(map[*conn]struct{}),
+		perUser: make(map[application.UserId]int),
+	}
+}
+
+type Handler struct {
+	service       application.Service
+	tallies       TallySubscriber
+	authenticator Authenticator
+	limits        Limits
+	upgrader      websocket.Upgrader
+
+	mu      sync.Mutex
+	closed  bool
+	conns   map[*conn]struct{}
+	perUser map[application.UserId]int
+	wg      sync.WaitGroup
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	visibility, err := h.service.OpinionVisibilityQuery(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, err)
+		return
+	}
+
+	c := &conn{handler: h, user: user, visibility: visibility, send: make(chan serverMessage, sendBuffer), done: make(chan struct{})}
+	if status, err := h.add(c); err != nil {
+		httperror.WriteStatus(w, status, err)
+		return
+	}
+	defer h.remove(c)
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already wrote the error response
+		return
+	}
+	c.ws = ws
+	c.serve(r.Context())
+}
+
+// Close sends a close frame to every connection and waits until they are closed, new connections are rejected.
+// The http.Server does not track upgraded connections, so Close has to be registered with RegisterOnShutdown.
+func (h *Handler) Close() {
+	h.mu.Lock()
+	h.closed = true
+	for c := range h.conns {
+		c.close()
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+}
+
+// add registers the connection if the limits allow it
+func (h *Handler) add(c *conn) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return http.StatusServiceUnavailable, application.NewError(application.CodeInternal, "server is shutting down", nil)
+	}
+	if h.limits.Connections > 0 && len(h.conns) >= h.limits.Connections {
+		return http.StatusServiceUnavailable, application.NewError(application.CodeRateLimited, "too many connections", nil)
+	}
+	if h.limits.ConnectionsPerUser > 0 && h.perUser[c.user.Id] >= h.limits.ConnectionsPerUser {
+		return http.StatusTooManyRequests, application.NewError(application.CodeRateLimited, "too many connections of the user", nil)
+	}
+
+	h.conns[c] = struct{}{}
+	h.perUser[c.user.Id]++
+	h.wg.Add(1)
+	return 0, nil
+}
+
+func (h *Handler) remove(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.conns, c)
+	if h.perUser[c.user.Id]--; h.perUser[c.user.Id] <= 0 {
+		delete(h.perUser, c.user.Id)
+	}
+	h.wg.Done()
+}
+
+type errorBody struct {
+	httperror.Body
+	// RetryAfter is the count of seconds after which a rate limited command may be sent again
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// newErrorBody renders the application error like the REST API
+func newErrorBody(err error) *errorBody {
+	return &errorBody{Body: httperror.NewBody(err), RetryAfter: httperror.RetryAfter(err)}
+}
@@ -0,0 +1,276 @@
+package ws_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/infrastructure"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/rest"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/sse"
+	"github.com/fwiedmann/site/backend/internal/opinions/ports/ws"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	url     string
+	handler *ws.Handler
+	opinion application.Opinion
+}
+
+// newTestServer serves the handler with an opinion of alice which can be voted on
+func newTestServer(t *testing.T, limits ws.Limits) testServer {
+	t.Helper()
+	opinionList := projections.NewOpinionListProjection()
+	hub := sse.NewHub(opinionList, 10)
+	bus := infrastructure.NewEventBus(infrastructure.NewEventLogMemory(), nil)
+	bus.Subscribe(opinionList)
+	bus.Subscribe(hub)
+
+	s := application.NewOpinionService(
+		infrastructure.AuthenticatedUsersPolicyEnforcementPoint{},
+		infrastructure.NewOpinionsRepositoryMemory(),
+		bus,
+		infrastructure.RandomIdService{},
+		infrastructure.SystemTimeService{},
+	)
+	opinion, err := s.CreateOpinionCommand(context.Background(), application.AuthenticatedUser{Id: "alice", Roles: []application.Role{application.RoleUser}}, application.OpinionCreateDTO{Statement: "sockets are nice"})
+	if err != nil {
+		t.Fatalf("CreateOpinionCommand() returned error %s, but no error is expected", err)
+	}
+
+	handler := ws.NewHandler(s, hub, rest.HeaderAuthenticator{Header: "X-User-Id"}, limits)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Cleanup(handler.Close)
+
+	return testServer{url: "ws" + strings.TrimPrefix(server.URL, "http"), handler: handler, opinion: opinion}
+}
+
+func dial(t *testing.T, url string, user string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	header := http.Header{}
+	if user != "" {
+		header.Set("X-User-Id", user)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+type message struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Vote struct {
+		OpinionID string `json:"opinionId"`
+		Voter     string `json:"voter"`
+		Agreement bool   `json:"agreement"`
+		Version   int64  `json:"version"`
+	} `json:"vote"`
+	Error struct {
+		Code    application.ErrorCode `json:"code"`
+		Message string                `json:"message"`
+		Fields  []struct {
+			Field string `json:"field"`
+		} `json:"fields"`
+	} `json:"error"`
+	Tally struct {
+		OpinionID     string `json:"opinionId"`
+		Agreements    int    `json:"agreements"`
+		Disagreements int    `json:"disagreements"`
+	} `json:"tally"`
+}
+
+// readTypes reads count messages by type, the tallies and the answers of the commands are not ordered
+func readTypes(t *testing.T, conn *websocket.Conn, count int) map[string]message {
+	t.Helper()
+	messages := make(map[string]message)
+	for i := 0; i < count; i++ {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("message could not be read: %s", err)
+		}
+		messages[msg.Type] = msg
+	}
+	return messages
+}
+
+func TestHandler_votes(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, ws.DefaultLimits())
+	conn, _, err := dial(t, server.url, "bob")
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+
+	assert.NoError(t, conn.WriteJSON(map[string]any{"type": ws.TypeCreateVote, "id": "1", "opinionId": server.opinion.ID, "agreement": true}))
+	messages := readTypes(t, conn, 2)
+	ack := messages[ws.TypeAck]
+	assert.Equal(t, "1", ack.ID)
+	assert.Equal(t, "bob", ack.Vote.Voter)
+	assert.True(t, ack.Vote.Agreement)
+	assert.Equal(t, 1, messages[ws.TypeVoteTallyChanged].Tally.Agreements)
+
+	assert.NoError(t, conn.WriteJSON(map[string]any{"type": ws.TypeUpdateVote, "id": "2", "opinionId": server.opinion.ID, "agreement": false, "version": ack.Vote.Version}))
+	messages = readTypes(t, conn, 2)
+	assert.Equal(t, "2", messages[ws.TypeAck].ID)
+	assert.False(t, messages[ws.TypeAck].Vote.Agreement)
+	assert.Equal(t, 0, messages[ws.TypeVoteTallyChanged].Tally.Agreements)
+	assert.Equal(t, 1, messages[ws.TypeVoteTallyChanged].Tally.Disagreements)
+}
+
+func TestHandler_errors(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, ws.DefaultLimits())
+	conn, _, err := dial(t, server.url, "bob")
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		id      string
+		code    application.ErrorCode
+		field   string
+	}{
+		{
+			name:    "unknown opinion",
+			message: `{"type":"create-vote","id":"1","opinionId":"unknown","agreement":true}`,
+			id:      "1",
+			code:    application.CodeNotFound,
+		},
+		{
+			name:    "update without a vote",
+			message: `{"type":"update-vote","id":"2","opinionId":"` + string(server.opinion.ID) + `","agreement":true}`,
+			id:      "2",
+			code:    application.CodeNotFound,
+		},
+		{
+			name:    "unknown type",
+			message: `{"type":"delete-vote","id":"3"}`,
+			id:      "3",
+			code:    application.CodeValidation,
+			field:   "type",
+		},
+		{
+			name:    "no JSON",
+			message: `vote`,
+			code:    application.CodeValidation,
+			field:   "message",
+		},
+	}
+	// the subtests share the connection, so they run sequentially
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(tt.message)))
+
+			var msg message
+			assert.NoError(t, conn.ReadJSON(&msg))
+			assert.Equal(t, ws.TypeError, msg.Type)
+			assert.Equal(t, tt.id, msg.ID)
+			assert.Equal(t, tt.code, msg.Error.Code)
+			if tt.field != "" && assert.Len(t, msg.Error.Fields, 1) {
+				assert.Equal(t, tt.field, msg.Error.Fields[0].Field)
+			}
+		})
+	}
+}
+
+func TestHandler_limits(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, ws.Limits{Connections: 2, ConnectionsPerUser: 1})
+
+	_, resp, err := dial(t, server.url, "")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	first, _, err := dial(t, server.url, "bob")
+	assert.NoError(t, err)
+	_, resp, err = dial(t, server.url, "bob")
+	if assert.Error(t, err, "a user has one connection") {
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	_, _, err = dial(t, server.url, "carol")
+	assert.NoError(t, err)
+	_, resp, err = dial(t, server.url, "dave")
+	if assert.Error(t, err, "the server has two connections") {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	// a closed connection frees its slot once the server read the close frame
+	assert.NoError(t, first.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	_, _, err = first.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	assert.Eventually(t, func() bool {
+		_, _, err := dial(t, server.url, "dave")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHandler_ping(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, ws.Limits{PingInterval: 10 * time.Millisecond})
+	conn, _, err := dial(t, server.url, "bob")
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+
+	pings := make(chan struct{}, 10)
+	conn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-pings:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no ping was received")
+		}
+	}
+}
+
+func TestHandler_Close(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, ws.DefaultLimits())
+	conn, _, err := dial(t, server.url, "bob")
+	if err != nil {
+		t.Fatalf("Dial() returned error %s, but no error is expected", err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		server.handler.Close()
+	}()
+
+	// the default close handler of the client answers the close frame, which lets Close return
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "unexpected error %v", err)
+	<-closed
+
+	_, resp, err := dial(t, server.url, "bob")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
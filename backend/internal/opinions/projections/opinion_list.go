@@ -0,0 +1,145 @@
+package projections
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OpinionSummary is an opinion together with the current vote tally
+type OpinionSummary struct {
+	ID            application.OpinionId
+	Owner         application.UserId
+	CreatedAt     time.Time
+	Statement     string
+	Status        application.OpinionStatus
+	Agreements    int
+	Disagreements int
+	// OpenReports is the count of reports which are not resolved yet
+	OpenReports int
+}
+
+// NewOpinionListProjection creates an empty opinion list read model
+func NewOpinionListProjection() *OpinionListProjection {
+	p := &OpinionListProjection{}
+	p.Reset()
+	return p
+}
+
+// OpinionListProjection keeps all existing opinions with their vote counts
+type OpinionListProjection struct {
+	mu       sync.RWMutex
+	opinions map[application.OpinionId]*OpinionSummary
+	// votes holds the agreement of every voter per opinion, required to adjust the tally on updates
+	votes map[application.OpinionId]map[application.UserId]bool
+}
+
+func (p *OpinionListProjection) Name() string {
+	return "opinion-list"
+}
+
+func (p *OpinionListProjection) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opinions = make(map[application.OpinionId]*OpinionSummary)
+	p.votes = make(map[application.OpinionId]map[application.UserId]bool)
+}
+
+func (p *OpinionListProjection) HandleEvent(_ context.Context, event application.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event.Type {
+	case application.EventOpinionCreated:
+		p.opinions[event.Opinion.ID] = &OpinionSummary{
+			ID:        event.Opinion.ID,
+			Owner:     event.Opinion.Owner,
+			CreatedAt: event.Opinion.CreatedAt,
+			Statement: event.Opinion.Statement,
+			Status:    event.Opinion.Status,
+		}
+		p.votes[event.Opinion.ID] = make(map[application.UserId]bool)
+	case application.EventOpinionEdited:
+		if summary, ok := p.opinions[event.Opinion.ID]; ok {
+			summary.Statement = event.Opinion.Statement
+			summary.Status = event.Opinion.Status
+		}
+	case application.EventOpinionApproved, application.EventOpinionRejected, application.EventOpinionHidden:
+		if summary, ok := p.opinions[event.Opinion.ID]; ok {
+			summary.Status = event.Opinion.Status
+		}
+	case application.EventOpinionReported:
+		if summary, ok := p.opinions[event.Report.Opinion]; ok {
+			summary.OpenReports++
+		}
+	case application.EventReportsResolved:
+		if summary, ok := p.opinions[event.Report.Opinion]; ok {
+			summary.OpenReports = 0
+			summary.Status = event.Opinion.Status
+		}
+	case application.EventOpinionDeleted:
+		delete(p.opinions, event.Opinion.ID)
+		delete(p.votes, event.Opinion.ID)
+	case application.EventVoteSubmitted, application.EventVoteUpdated:
+		summary, ok := p.opinions[event.Vote.Opinion]
+		if !ok {
+			return nil
+		}
+		if previous, voted := p.votes[summary.ID][event.Vote.Voter]; voted {
+			count(summary, previous, -1)
+		}
+		p.votes[summary.ID][event.Vote.Voter] = event.Vote.Agreement
+		count(summary, event.Vote.Agreement, 1)
+	case application.EventVoteDeleted:
+		summary, ok := p.opinions[event.Vote.Opinion]
+		if !ok {
+			return nil
+		}
+		if previous, voted := p.votes[summary.ID][event.Vote.Voter]; voted {
+			count(summary, previous, -1)
+			delete(p.votes[summary.ID], event.Vote.Voter)
+		}
+	}
+	return nil
+}
+
+// List returns all opinions ordered by creation time, the oldest first
+func (p *OpinionListProjection) List() []OpinionSummary {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]OpinionSummary, 0, len(p.opinions))
+	for _, summary := range p.opinions {
+		list = append(list, *summary)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Get returns the summary of a single opinion
+func (p *OpinionListProjection) Get(id application.OpinionId) (OpinionSummary, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	summary, ok := p.opinions[id]
+	if !ok {
+		return OpinionSummary{}, false
+	}
+	return *summary, true
+}
+
+func count(summary *OpinionSummary, agreement bool, delta int) {
+	if agreement {
+		summary.Agreements += delta
+		return
+	}
+	summary.Disagreements += delta
+}
@@ -0,0 +1,149 @@
+package projections_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func opinionCreated(id application.OpinionId, owner application.UserId, at time.Time) application.Event {
+	return application.Event{
+		Type:       application.EventOpinionCreated,
+		OccurredAt: at,
+		Opinion: application.Opinion{
+			ID:        id,
+			Owner:     owner,
+			CreatedAt: at,
+			Statement: "copy and pasta is fine",
+		},
+	}
+}
+
+func voteEvent(eventType application.EventType, opinion application.OpinionId, voter application.UserId, agreement bool, at time.Time) application.Event {
+	return application.Event{
+		Type:       eventType,
+		OccurredAt: at,
+		Vote: application.Vote{
+			Agreement: agreement,
+			Opinion:   opinion,
+			Voter:     voter,
+			CreatedAt: at,
+			UpdatedAt: at,
+		},
+	}
+}
+
+func TestOpinionListProjection_HandleEvent(t *testing.T) {
+	t.Parallel()
+	testDate := time.Now()
+
+	tests := []struct {
+		name   string
+		events []application.Event
+		want   []projections.OpinionSummary
+	}{
+		{
+			name:   "Should be empty without events",
+			events: nil,
+			want:   []projections.OpinionSummary{},
+		},
+		{
+			name: "Should list opinions ordered by creation time",
+			events: []application.Event{
+				opinionCreated("2", "a", testDate.Add(time.Minute)),
+				opinionCreated("1", "b", testDate),
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "b", CreatedAt: testDate, Statement: "copy and pasta is fine"},
+				{ID: "2", Owner: "a", CreatedAt: testDate.Add(time.Minute), Statement: "copy and pasta is fine"},
+			},
+		},
+		{
+			name: "Should count agreements and disagreements",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "b", true, testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "c", true, testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "d", false, testDate),
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is fine", Agreements: 2, Disagreements: 1},
+			},
+		},
+		{
+			name: "Should move the vote on update and remove it on delete",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "b", true, testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "c", true, testDate),
+				voteEvent(application.EventVoteUpdated, "1", "b", false, testDate),
+				voteEvent(application.EventVoteDeleted, "1", "c", true, testDate),
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is fine", Agreements: 0, Disagreements: 1},
+			},
+		},
+		{
+			name: "Should replace the statement on edit and keep the votes",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				voteEvent(application.EventVoteSubmitted, "1", "b", true, testDate),
+				{Type: application.EventOpinionEdited, Opinion: application.Opinion{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is great"}},
+				{Type: application.EventOpinionEdited, Opinion: application.Opinion{ID: "2", Statement: "unknown"}},
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is great", Agreements: 1},
+			},
+		},
+		{
+			name: "Should track the moderation status",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				opinionCreated("2", "a", testDate.Add(time.Minute)),
+				{Type: application.EventOpinionApproved, Opinion: application.Opinion{ID: "1", Status: application.OpinionPublished}},
+				{Type: application.EventOpinionRejected, Opinion: application.Opinion{ID: "2", Status: application.OpinionRejected}},
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is fine", Status: application.OpinionPublished},
+				{ID: "2", Owner: "a", CreatedAt: testDate.Add(time.Minute), Statement: "copy and pasta is fine", Status: application.OpinionRejected},
+			},
+		},
+		{
+			name: "Should count open reports until they are resolved",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				{Type: application.EventOpinionReported, Report: application.Report{Opinion: "1", Reporter: "b"}},
+				{Type: application.EventOpinionReported, Report: application.Report{Opinion: "1", Reporter: "c"}},
+				{Type: application.EventOpinionHidden, Opinion: application.Opinion{ID: "1", Status: application.OpinionHidden}},
+				{Type: application.EventReportsResolved, Opinion: application.Opinion{ID: "1", Status: application.OpinionPublished}, Report: application.Report{Opinion: "1", Resolution: application.ReportDismissed}},
+				{Type: application.EventOpinionReported, Report: application.Report{Opinion: "1", Reporter: "d"}},
+			},
+			want: []projections.OpinionSummary{
+				{ID: "1", Owner: "a", CreatedAt: testDate, Statement: "copy and pasta is fine", Status: application.OpinionPublished, OpenReports: 1},
+			},
+		},
+		{
+			name: "Should drop deleted opinions and ignore votes for unknown opinions",
+			events: []application.Event{
+				opinionCreated("1", "a", testDate),
+				{Type: application.EventOpinionDeleted, Opinion: application.Opinion{ID: "1"}},
+				voteEvent(application.EventVoteSubmitted, "1", "b", true, testDate),
+			},
+			want: []projections.OpinionSummary{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := projections.NewOpinionListProjection()
+			for _, event := range tt.events {
+				if err := p.HandleEvent(context.Background(), event); err != nil {
+					t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+				}
+			}
+			assert.Equal(t, tt.want, p.List())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package projections contains the query side of the opinions module.
+// Read models are kept up to date from the domain events published by the application service
+// and can be rebuilt from the event log at any time, e.g. after their shape changed.
+package projections
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+)
+
+// Projection is a read model which is derived from domain events only
+type Projection interface {
+	application.EventHandler
+	// Name identifies the projection in errors
+	Name() string
+	// Reset drops the whole state of the projection before a rebuild
+	Reset()
+}
+
+// EventLoader reads the persisted event log
+type EventLoader interface {
+	Load(ctx context.Context, afterSequence uint64) ([]application.Event, error)
+}
+
+// NewProjector creates a projector which dispatches events to the given projections.
+// Subscribe it to the event bus to keep the projections up to date.
+func NewProjector(loader EventLoader, projections ...Projection) *Projector {
+	return &Projector{
+		loader:      loader,
+		projections: projections,
+	}
+}
+
+type Projector struct {
+	loader      EventLoader
+	projections []Projection
+
+	mu           sync.Mutex
+	lastSequence uint64
+}
+
+// HandleEvent applies a live event to all projections.
+// Events which were already applied during a rebuild are skipped.
+func (p *Projector) HandleEvent(ctx context.Context, event application.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Sequence != 0 && event.Sequence <= p.lastSequence {
+		return nil
+	}
+	return p.apply(ctx, event)
+}
+
+// Rebuild resets all projections and replays the complete event log.
+// Live events are blocked until the rebuild is finished.
+func (p *Projector) Rebuild(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events, err := p.loader.Load(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, projection := range p.projections {
+		projection.Reset()
+	}
+	p.lastSequence = 0
+
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.apply(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Projector) apply(ctx context.Context, event application.Event) error {
+	for _, projection := range p.projections {
+		if err := projection.HandleEvent(ctx, event); err != nil {
+			return fmt.Errorf("projection %s could not apply event %d: %w", projection.Name(), event.Sequence, err)
+		}
+	}
+	if event.Sequence > p.lastSequence {
+		p.lastSequence = event.Sequence
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package projections_test
+
+import (
+	"context"
+	"errors"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type staticLoader struct {
+	events []application.Event
+	err    error
+}
+
+func (s staticLoader) Load(_ context.Context, afterSequence uint64) ([]application.Event, error) {
+	events := make([]application.Event, 0)
+	for _, event := range s.events {
+		if event.Sequence > afterSequence {
+			events = append(events, event)
+		}
+	}
+	return events, s.err
+}
+
+func TestProjector_Rebuild(t *testing.T) {
+	t.Parallel()
+	testDate := time.Now()
+
+	first := opinionCreated("1", "owner", testDate)
+	first.Sequence = 1
+	second := opinionCreated("2", "owner", testDate)
+	second.Sequence = 2
+
+	list := projections.NewOpinionListProjection()
+	// state which is not part of the log has to be dropped by the rebuild
+	if err := list.HandleEvent(context.Background(), opinionCreated("stale", "owner", testDate)); err != nil {
+		t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+	}
+
+	projector := projections.NewProjector(staticLoader{events: []application.Event{first, second}}, list)
+	if err := projector.Rebuild(context.Background()); err != nil {
+		t.Errorf("Rebuild() returned error %s, but no error is expected", err)
+	}
+
+	got := list.List()
+	assert.Len(t, got, 2)
+	assert.Equal(t, application.OpinionId("1"), got[0].ID)
+	assert.Equal(t, application.OpinionId("2"), got[1].ID)
+
+	// already replayed events are skipped, new ones are applied
+	if err := projector.HandleEvent(context.Background(), second); err != nil {
+		t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+	}
+	vote := voteEvent(application.EventVoteSubmitted, "2", "voter", true, testDate)
+	vote.Sequence = 3
+	if err := projector.HandleEvent(context.Background(), vote); err != nil {
+		t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+	}
+
+	got = list.List()
+	assert.Len(t, got, 2)
+	assert.Equal(t, 1, got[1].Agreements)
+}
+
+func TestProjector_Rebuild_loader_error(t *testing.T) {
+	t.Parallel()
+	loaderError := errors.New("loader error")
+
+	projector := projections.NewProjector(staticLoader{err: loaderError}, projections.NewOpinionListProjection())
+	if err := projector.Rebuild(context.Background()); !errors.Is(err, loaderError) {
+		t.Errorf("Rebuild() error = %v, wantErr %v", err, loaderError)
+	}
+}
@@ -0,0 +1,119 @@
+package projections
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"sync"
+	"time"
+)
+
+// UserActivity summarizes what a single user currently contributes to the site
+type UserActivity struct {
+	User         application.UserId
+	Opinions     int
+	Votes        int
+	LastActivity time.Time
+}
+
+// NewUserActivityProjection creates an empty per user activity read model
+func NewUserActivityProjection() *UserActivityProjection {
+	p := &UserActivityProjection{}
+	p.Reset()
+	return p
+}
+
+// UserActivityProjection counts the existing opinions and votes per user
+type UserActivityProjection struct {
+	mu         sync.RWMutex
+	activities map[application.UserId]*UserActivity
+	// owners and voters are required to resolve deletions, because an EventOpinionDeleted only carries the id
+	owners map[application.OpinionId]application.UserId
+	voters map[application.OpinionId]map[application.UserId]struct{}
+}
+
+func (p *UserActivityProjection) Name() string {
+	return "user-activity"
+}
+
+func (p *UserActivityProjection) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.activities = make(map[application.UserId]*UserActivity)
+	p.owners = make(map[application.OpinionId]application.UserId)
+	p.voters = make(map[application.OpinionId]map[application.UserId]struct{})
+}
+
+func (p *UserActivityProjection) HandleEvent(_ context.Context, event application.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event.Type {
+	case application.EventOpinionCreated:
+		p.owners[event.Opinion.ID] = event.Opinion.Owner
+		p.voters[event.Opinion.ID] = make(map[application.UserId]struct{})
+		p.activity(event.Opinion.Owner, event.OccurredAt).Opinions++
+	case application.EventOpinionEdited:
+		if owner, ok := p.owners[event.Opinion.ID]; ok {
+			p.activity(owner, event.OccurredAt)
+		}
+	case application.EventOpinionDeleted:
+		owner, ok := p.owners[event.Opinion.ID]
+		if !ok {
+			return nil
+		}
+		p.activities[owner].Opinions--
+		for voter := range p.voters[event.Opinion.ID] {
+			p.activities[voter].Votes--
+		}
+		delete(p.owners, event.Opinion.ID)
+		delete(p.voters, event.Opinion.ID)
+	case application.EventVoteSubmitted:
+		voters, ok := p.voters[event.Vote.Opinion]
+		if !ok {
+			return nil
+		}
+		if _, voted := voters[event.Vote.Voter]; !voted {
+			voters[event.Vote.Voter] = struct{}{}
+			p.activity(event.Vote.Voter, event.OccurredAt).Votes++
+		}
+	case application.EventVoteUpdated:
+		if _, ok := p.voters[event.Vote.Opinion]; ok {
+			p.activity(event.Vote.Voter, event.OccurredAt)
+		}
+	case application.EventVoteDeleted:
+		voters, ok := p.voters[event.Vote.Opinion]
+		if !ok {
+			return nil
+		}
+		if _, voted := voters[event.Vote.Voter]; voted {
+			delete(voters, event.Vote.Voter)
+			p.activity(event.Vote.Voter, event.OccurredAt).Votes--
+		}
+	}
+	return nil
+}
+
+// Get returns the activity of the given user
+func (p *UserActivityProjection) Get(user application.UserId) (UserActivity, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	activity, ok := p.activities[user]
+	if !ok {
+		return UserActivity{}, false
+	}
+	return *activity, true
+}
+
+// activity returns the mutable activity of the user and marks the given time as the last activity
+func (p *UserActivityProjection) activity(user application.UserId, at time.Time) *UserActivity {
+	activity, ok := p.activities[user]
+	if !ok {
+		activity = &UserActivity{User: user}
+		p.activities[user] = activity
+	}
+	if at.After(activity.LastActivity) {
+		activity.LastActivity = at
+	}
+	return activity
+}
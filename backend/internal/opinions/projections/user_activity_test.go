@@ -0,0 +1,54 @@
+package projections_test
+
+import (
+	"context"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/fwiedmann/site/backend/internal/opinions/projections"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUserActivityProjection_HandleEvent(t *testing.T) {
+	t.Parallel()
+	testDate := time.Now()
+
+	p := projections.NewUserActivityProjection()
+	events := []application.Event{
+		opinionCreated("1", "owner", testDate),
+		opinionCreated("2", "owner", testDate.Add(time.Minute)),
+		voteEvent(application.EventVoteSubmitted, "1", "voter", true, testDate.Add(2*time.Minute)),
+		voteEvent(application.EventVoteSubmitted, "2", "voter", false, testDate.Add(3*time.Minute)),
+		voteEvent(application.EventVoteUpdated, "2", "voter", true, testDate.Add(4*time.Minute)),
+		{Type: application.EventOpinionDeleted, OccurredAt: testDate.Add(5 * time.Minute), Opinion: application.Opinion{ID: "1"}},
+	}
+	for _, event := range events {
+		if err := p.HandleEvent(context.Background(), event); err != nil {
+			t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+		}
+	}
+
+	owner, ok := p.Get("owner")
+	assert.True(t, ok)
+	assert.Equal(t, projections.UserActivity{User: "owner", Opinions: 1, Votes: 0, LastActivity: testDate.Add(time.Minute)}, owner)
+
+	voter, ok := p.Get("voter")
+	assert.True(t, ok)
+	assert.Equal(t, projections.UserActivity{User: "voter", Opinions: 0, Votes: 1, LastActivity: testDate.Add(4 * time.Minute)}, voter)
+
+	_, ok = p.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestUserActivityProjection_Reset(t *testing.T) {
+	t.Parallel()
+	p := projections.NewUserActivityProjection()
+	if err := p.HandleEvent(context.Background(), opinionCreated("1", "owner", time.Now())); err != nil {
+		t.Errorf("HandleEvent() returned error %s, but no error is expected", err)
+	}
+
+	p.Reset()
+
+	_, ok := p.Get("owner")
+	assert.False(t, ok)
+}
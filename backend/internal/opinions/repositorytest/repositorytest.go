@@ -0,0 +1,688 @@
+// Package repositorytest contains the contract every application.Repository implementation has to fulfill.
+// Adapters call Run from their own tests:
+//
+//	func TestMyRepository_contract(t *testing.T) {
+//		repositorytest.Run(t, func(t *testing.T) application.Repository {
+//			return NewMyRepository()
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"github.com/fwiedmann/site/backend/internal/opinions/application"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns an empty repository, it is called once per contract case.
+// Cleanup of the repository should be registered with t.Cleanup.
+type Factory func(t *testing.T) application.Repository
+
+// concurrentWriters is the number of goroutines used by the concurrency cases
+const concurrentWriters = 20
+
+// Run executes all contract cases as sub tests of t
+func Run(t *testing.T, factory Factory) {
+	// SQL adapters store the time with second precision
+	testTime := time.Now().Truncate(time.Second)
+
+	t.Run("create and list opinions ordered by creation time", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("2", testTime.Add(time.Minute))))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+
+		list, err := repo.ListOpinions(ctx, application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 2) {
+			assert.Equal(t, application.OpinionId("1"), list[0].ID)
+			assert.Equal(t, application.OpinionId("2"), list[1].ID)
+			assert.True(t, testTime.Equal(list[0].CreatedAt))
+			assert.Equal(t, application.UserId("123"), list[0].Owner)
+			assert.Equal(t, "copy and pasta is fine", list[0].Statement)
+		}
+	})
+
+	t.Run("list opinions of empty repository", func(t *testing.T) {
+		repo := factory(t)
+
+		list, err := repo.ListOpinions(context.Background(), application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		assert.NotNil(t, list)
+		assert.Empty(t, list)
+
+		votes, err := repo.ListVotes(context.Background())
+		assert.NoError(t, err)
+		assert.NotNil(t, votes)
+		assert.Empty(t, votes)
+	})
+
+	t.Run("list opinions which match the visibility", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		pending := newOpinion("2", testTime.Add(time.Minute))
+		pending.Status = application.OpinionPendingReview
+		foreign := newOpinion("3", testTime.Add(2*time.Minute))
+		foreign.Owner = "456"
+		foreign.Status = application.OpinionHidden
+		for _, o := range []application.Opinion{newOpinion("1", testTime), pending, foreign} {
+			assert.NoError(t, repo.CreateOpinion(ctx, o))
+		}
+
+		tests := []struct {
+			name       string
+			visibility application.OpinionVisibility
+			want       []application.OpinionId
+		}{
+			{
+				name: "clauses are combined with OR",
+				visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+					{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+					{{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: "456"}},
+				}},
+				want: []application.OpinionId{"1", "3"},
+			},
+			{
+				name: "conditions are combined with AND",
+				visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{
+					{Field: application.OpinionFieldOwner, Operator: application.OperatorEqual, Value: "123"},
+					{Field: application.OpinionFieldStatus, Operator: application.OperatorNotEqual, Value: "published"},
+				}}},
+				want: []application.OpinionId{"2"},
+			},
+			{
+				name: "values are not interpreted as SQL",
+				visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+					{{Field: application.OpinionFieldID, Operator: application.OperatorEqual, Value: "1' OR '1' = '1"}},
+				}},
+				want: []application.OpinionId{},
+			},
+			{
+				name: "lists and null checks are supported",
+				visibility: application.OpinionVisibility{Clauses: [][]application.OpinionCondition{{
+					{Field: application.OpinionFieldStatus, Operator: application.OperatorIn, Value: []any{"published", "hidden"}},
+					{Field: application.OpinionFieldOwner, Operator: application.OperatorNotEqual, Value: nil},
+				}}},
+				want: []application.OpinionId{"1", "3"},
+			},
+			{
+				name:       "no clauses match no opinion",
+				visibility: application.OpinionVisibility{},
+				want:       []application.OpinionId{},
+			},
+		}
+		for _, tt := range tests {
+			list, err := repo.ListOpinions(ctx, tt.visibility)
+			assert.NoError(t, err, tt.name)
+
+			ids := make([]application.OpinionId, 0, len(list))
+			for _, o := range list {
+				ids = append(ids, o.ID)
+			}
+			assert.Equal(t, tt.want, ids, tt.name)
+		}
+
+		_, err := repo.ListOpinions(ctx, application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+			{{Field: "statement; DROP TABLE opinions", Operator: application.OperatorEqual, Value: "1"}},
+		}})
+		assert.ErrorIs(t, err, application.InvalidOpinionConditionError)
+	})
+
+	t.Run("list and count pages of opinions", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		pending := newOpinion("4", testTime.Add(time.Minute))
+		pending.Status = application.OpinionPendingReview
+		for _, o := range []application.Opinion{newOpinion("1", testTime), newOpinion("2", testTime), newOpinion("3", testTime.Add(time.Minute)), pending, newOpinion("5", testTime.Add(2*time.Minute))} {
+			assert.NoError(t, repo.CreateOpinion(ctx, o))
+		}
+		published := application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+			{{Field: application.OpinionFieldStatus, Operator: application.OperatorEqual, Value: "published"}},
+		}}
+
+		ids := func(page application.OpinionPage) []application.OpinionId {
+			t.Helper()
+			list, err := repo.ListOpinionsPage(ctx, published, page)
+			assert.NoError(t, err)
+			ids := make([]application.OpinionId, 0, len(list))
+			for _, o := range list {
+				ids = append(ids, o.ID)
+			}
+			return ids
+		}
+
+		assert.Equal(t, []application.OpinionId{"1", "2"}, ids(application.OpinionPage{Limit: 2}))
+		assert.Equal(t, []application.OpinionId{"2", "3", "5"}, ids(application.OpinionPage{After: application.OpinionCursor{CreatedAt: testTime, ID: "1"}, Limit: 10}),
+			"the opinions of the same creation time are ordered by id")
+		assert.Equal(t, []application.OpinionId{"5"}, ids(application.OpinionPage{After: application.OpinionCursor{CreatedAt: testTime.Add(time.Minute), ID: "4"}, Limit: 10}),
+			"the cursor of an opinion which is not listed is a position as well")
+
+		assert.NoError(t, repo.DeleteOpinion(ctx, "3"))
+		assert.Equal(t, []application.OpinionId{"5"}, ids(application.OpinionPage{After: application.OpinionCursor{CreatedAt: testTime.Add(time.Minute), ID: "3"}, Limit: 10}),
+			"the cursor of a deleted opinion stays valid")
+		assert.Empty(t, ids(application.OpinionPage{Limit: 0}))
+
+		count, err := repo.CountOpinions(ctx, published)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		count, err = repo.CountOpinions(ctx, application.OpinionVisibility{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("duplicate opinion", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.ErrorIs(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)), application.OpinionAlreadyExistsError)
+	})
+
+	t.Run("delete opinion", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("2", testTime)))
+		assert.NoError(t, repo.DeleteOpinion(ctx, "1"))
+
+		list, err := repo.ListOpinions(ctx, application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, application.OpinionId("2"), list[0].ID)
+		}
+	})
+
+	t.Run("delete unknown opinion", func(t *testing.T) {
+		repo := factory(t)
+		assert.ErrorIs(t, repo.DeleteOpinion(context.Background(), "1"), application.OpinionNotFoundError)
+	})
+
+	t.Run("delete opinions by filter", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		for i, owner := range []application.UserId{"123", "456", "123", "456"} {
+			opinion := newOpinion(application.OpinionId(fmt.Sprint(i)), testTime.Add(time.Duration(i)*time.Hour))
+			opinion.Owner = owner
+			assert.NoError(t, repo.CreateOpinion(ctx, opinion))
+		}
+		assert.NoError(t, repo.CreateVote(ctx, newVote("2", "456", true, testTime)))
+		assert.NoError(t, repo.CreateReport(ctx, newReport("2", "456", testTime)))
+
+		ids, err := repo.DeleteOpinions(ctx, application.OpinionFilter{Owner: "123", CreatedFrom: testTime.Add(time.Hour)})
+		assert.NoError(t, err)
+		assert.Equal(t, []application.OpinionId{"2"}, ids)
+
+		ids, err = repo.DeleteOpinions(ctx, application.OpinionFilter{CreatedBefore: testTime.Add(2 * time.Hour)})
+		assert.NoError(t, err)
+		assert.Equal(t, []application.OpinionId{"0", "1"}, ids)
+
+		ids, err = repo.DeleteOpinions(ctx, application.OpinionFilter{Owner: "789"})
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		list, err := repo.ListOpinions(ctx, application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, application.OpinionId("3"), list[0].ID)
+		}
+
+		_, err = repo.GetVote(ctx, "2", "456")
+		assert.ErrorIs(t, err, application.VoteNotFoundError)
+
+		reports, err := repo.ListOpenReports(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, reports)
+	})
+
+	t.Run("get and update opinion", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		created := newOpinion("1", testTime)
+		assert.NoError(t, repo.CreateOpinion(ctx, created))
+
+		got, err := repo.GetOpinion(ctx, "1")
+		assert.NoError(t, err)
+		assert.True(t, created.CreatedAt.Equal(got.CreatedAt))
+		assert.Equal(t, created.Statement, got.Statement)
+		assert.Equal(t, created.Owner, got.Owner)
+		assert.Equal(t, application.OpinionPublished, got.Status)
+		assert.Equal(t, int64(1), got.Version)
+
+		edited := got
+		edited.Statement = "copy and pasta is great"
+		edited.Status = application.OpinionPendingReview
+		edited.Owner = "456"
+		assert.NoError(t, repo.UpdateOpinion(ctx, edited))
+
+		got, err = repo.GetOpinion(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "copy and pasta is great", got.Statement)
+		assert.Equal(t, application.OpinionPendingReview, got.Status)
+		assert.Equal(t, created.Owner, got.Owner)
+		assert.Equal(t, int64(2), got.Version)
+
+		list, err := repo.ListOpinions(ctx, application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, application.OpinionPendingReview, list[0].Status)
+		}
+	})
+
+	t.Run("get and update unknown opinion", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		_, err := repo.GetOpinion(ctx, "1")
+		assert.ErrorIs(t, err, application.OpinionNotFoundError)
+		assert.ErrorIs(t, repo.UpdateOpinion(ctx, newOpinion("1", testTime)), application.OpinionNotFoundError)
+	})
+
+	t.Run("update opinion with outdated version", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+
+		edited := newOpinion("1", testTime)
+		edited.Statement = "copy and pasta is great"
+		assert.NoError(t, repo.UpdateOpinion(ctx, edited))
+
+		outdated := newOpinion("1", testTime)
+		outdated.Statement = "copy and pasta is bad"
+		assert.ErrorIs(t, repo.UpdateOpinion(ctx, outdated), application.OpinionVersionConflictError)
+
+		got, err := repo.GetOpinion(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "copy and pasta is great", got.Statement)
+		assert.Equal(t, int64(2), got.Version)
+	})
+
+	t.Run("vote lifecycle", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "456", true, testTime)))
+
+		stored, err := repo.GetVote(ctx, "1", "456")
+		assert.NoError(t, err)
+		assert.Equal(t, application.OpinionId("1"), stored.Opinion)
+		assert.Equal(t, application.UserId("456"), stored.Voter)
+		assert.True(t, stored.Agreement)
+		assert.True(t, testTime.Equal(stored.CreatedAt))
+
+		updated := newVote("1", "456", false, testTime)
+		updated.UpdatedAt = testTime.Add(time.Minute)
+		assert.NoError(t, repo.UpdateVote(ctx, updated))
+
+		stored, err = repo.GetVote(ctx, "1", "456")
+		assert.NoError(t, err)
+		assert.False(t, stored.Agreement)
+		assert.True(t, testTime.Equal(stored.CreatedAt))
+		assert.True(t, updated.UpdatedAt.Equal(stored.UpdatedAt))
+		assert.Equal(t, int64(2), stored.Version)
+
+		votes, err := repo.ListVotes(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, votes, 1)
+
+		assert.NoError(t, repo.DeleteVote(ctx, "1", "456"))
+		_, err = repo.GetVote(ctx, "1", "456")
+		assert.ErrorIs(t, err, application.VoteNotFoundError)
+	})
+
+	t.Run("vote errors", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+
+		assert.ErrorIs(t, repo.CreateVote(ctx, newVote("unknown", "456", true, testTime)), application.OpinionNotFoundError)
+
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "456", true, testTime)))
+		assert.ErrorIs(t, repo.CreateVote(ctx, newVote("1", "456", false, testTime)), application.VoteAlreadyExistsError)
+
+		_, err := repo.GetVote(ctx, "1", "789")
+		assert.ErrorIs(t, err, application.VoteNotFoundError)
+		assert.ErrorIs(t, repo.UpdateVote(ctx, newVote("1", "789", true, testTime)), application.VoteNotFoundError)
+		assert.ErrorIs(t, repo.DeleteVote(ctx, "1", "789"), application.VoteNotFoundError)
+	})
+
+	t.Run("list votes ordered by creation time", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "b", true, testTime.Add(time.Minute))))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "a", true, testTime.Add(time.Minute))))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "c", true, testTime)))
+
+		votes, err := repo.ListVotes(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, votes, 3) {
+			assert.Equal(t, application.UserId("c"), votes[0].Voter)
+			assert.Equal(t, application.UserId("a"), votes[1].Voter)
+			assert.Equal(t, application.UserId("b"), votes[2].Voter)
+		}
+	})
+
+	t.Run("count votes of opinions", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("2", testTime)))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("3", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "a", true, testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "b", true, testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "c", false, testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("2", "a", false, testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("3", "a", true, testTime)))
+
+		counts, err := repo.CountVotes(ctx, application.AllOpinionsVisible, []application.OpinionId{"1", "2", "unknown"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[application.OpinionId]application.VoteCount{
+			"1": {Agreements: 2, Disagreements: 1},
+			"2": {Agreements: 0, Disagreements: 1},
+		}, counts)
+
+		counts, err = repo.CountVotes(ctx, application.OpinionVisibility{Clauses: [][]application.OpinionCondition{
+			{{Field: application.OpinionFieldID, Operator: application.OperatorNotEqual, Value: "2"}},
+		}}, []application.OpinionId{"1", "2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[application.OpinionId]application.VoteCount{"1": {Agreements: 2, Disagreements: 1}}, counts,
+			"the opinions which do not match the visibility are not counted")
+
+		counts, err = repo.CountVotes(ctx, application.AllOpinionsVisible, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, counts)
+	})
+
+	t.Run("delete opinion removes its votes", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("2", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "456", true, testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("2", "456", true, testTime)))
+
+		assert.NoError(t, repo.DeleteOpinion(ctx, "1"))
+
+		votes, err := repo.ListVotes(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, votes, 1) {
+			assert.Equal(t, application.OpinionId("2"), votes[0].Opinion)
+		}
+
+		_, err = repo.GetVote(ctx, "1", "456")
+		assert.ErrorIs(t, err, application.VoteNotFoundError)
+
+		// a recreated opinion must not inherit the votes of the deleted one
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "456", false, testTime)))
+	})
+
+	t.Run("report lifecycle", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("2", testTime)))
+
+		assert.ErrorIs(t, repo.CreateReport(ctx, newReport("unknown", "a", testTime)), application.OpinionNotFoundError)
+		assert.NoError(t, repo.CreateReport(ctx, newReport("1", "b", testTime.Add(time.Minute))))
+		assert.NoError(t, repo.CreateReport(ctx, newReport("1", "a", testTime.Add(time.Minute))))
+		assert.NoError(t, repo.CreateReport(ctx, newReport("2", "c", testTime)))
+		assert.ErrorIs(t, repo.CreateReport(ctx, newReport("1", "a", testTime)), application.ReportAlreadyExistsError)
+
+		count, err := repo.CountOpenReports(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		reports, err := repo.ListOpenReports(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, reports, 3) {
+			assert.Equal(t, application.UserId("c"), reports[0].Reporter)
+			assert.Equal(t, application.UserId("a"), reports[1].Reporter)
+			assert.Equal(t, application.UserId("b"), reports[2].Reporter)
+			assert.Equal(t, application.ReportReasonSpam, reports[0].Reason)
+			assert.True(t, testTime.Equal(reports[0].CreatedAt))
+		}
+
+		assert.NoError(t, repo.ResolveReports(ctx, "1", application.ReportDismissed, testTime))
+		assert.ErrorIs(t, repo.ResolveReports(ctx, "1", application.ReportDismissed, testTime), application.ReportNotFoundError)
+
+		count, err = repo.CountOpenReports(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		// a resolved report still counts as the one report of the user
+		assert.ErrorIs(t, repo.CreateReport(ctx, newReport("1", "a", testTime)), application.ReportAlreadyExistsError)
+
+		reports, err = repo.ListOpenReports(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, reports, 1) {
+			assert.Equal(t, application.OpinionId("2"), reports[0].Opinion)
+		}
+	})
+
+	t.Run("delete opinion removes its reports", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateReport(ctx, newReport("1", "a", testTime)))
+		assert.NoError(t, repo.DeleteOpinion(ctx, "1"))
+
+		reports, err := repo.ListOpenReports(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, reports)
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateReport(ctx, newReport("1", "a", testTime)))
+	})
+
+	t.Run("idempotency records", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		record := application.IdempotencyRecord{
+			User:        "123",
+			Key:         "key",
+			RequestHash: "hash",
+			Opinion:     newOpinion("1", testTime),
+			ExpiresAt:   testTime.Add(time.Hour),
+		}
+
+		_, err := repo.GetIdempotencyRecord(ctx, "123", "key", testTime)
+		assert.ErrorIs(t, err, application.IdempotencyRecordNotFoundError)
+
+		assert.NoError(t, repo.CreateIdempotencyRecord(ctx, record, testTime))
+		assert.ErrorIs(t, repo.CreateIdempotencyRecord(ctx, record, testTime.Add(time.Minute)), application.IdempotencyRecordAlreadyExistsError)
+
+		// keys are scoped per user
+		other := record
+		other.User = "456"
+		assert.NoError(t, repo.CreateIdempotencyRecord(ctx, other, testTime))
+
+		got, err := repo.GetIdempotencyRecord(ctx, "123", "key", testTime.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, "hash", got.RequestHash)
+		assert.Equal(t, record.Opinion.ID, got.Opinion.ID)
+		assert.Equal(t, record.Opinion.Statement, got.Opinion.Statement)
+		assert.Equal(t, record.Opinion.Status, got.Opinion.Status)
+		assert.Equal(t, record.Opinion.Owner, got.Opinion.Owner)
+		assert.Equal(t, record.Opinion.Version, got.Opinion.Version)
+		assert.True(t, record.Opinion.CreatedAt.Equal(got.Opinion.CreatedAt))
+		assert.True(t, record.ExpiresAt.Equal(got.ExpiresAt))
+
+		// expired records are not returned and can be replaced
+		_, err = repo.GetIdempotencyRecord(ctx, "123", "key", testTime.Add(time.Hour))
+		assert.ErrorIs(t, err, application.IdempotencyRecordNotFoundError)
+
+		replaced := record
+		replaced.RequestHash = "other"
+		replaced.ExpiresAt = testTime.Add(3 * time.Hour)
+		assert.NoError(t, repo.CreateIdempotencyRecord(ctx, replaced, testTime.Add(2*time.Hour)))
+
+		got, err = repo.GetIdempotencyRecord(ctx, "123", "key", testTime.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, "other", got.RequestHash)
+
+		deleted, err := repo.DeleteExpiredIdempotencyRecords(ctx, testTime.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		assert.NoError(t, repo.DeleteIdempotencyRecord(ctx, "123", "key"))
+		assert.ErrorIs(t, repo.DeleteIdempotencyRecord(ctx, "123", "key"), application.IdempotencyRecordNotFoundError)
+	})
+
+	t.Run("concurrent votes of different users", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrentWriters; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				assert.NoError(t, repo.CreateVote(ctx, newVote("1", application.UserId(fmt.Sprint(i)), true, testTime)))
+			}(i)
+		}
+		wg.Wait()
+
+		votes, err := repo.ListVotes(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, votes, concurrentWriters)
+	})
+
+	t.Run("concurrent duplicate votes", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for i := 0; i < concurrentWriters; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.CreateVote(ctx, newVote("1", "456", true, testTime))
+				if err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, application.VoteAlreadyExistsError)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("concurrent updates of one vote", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		assert.NoError(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)))
+		assert.NoError(t, repo.CreateVote(ctx, newVote("1", "456", true, testTime)))
+
+		// all writers read version 1, therefore only the first update is applied
+		var mu sync.Mutex
+		updated := 0
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrentWriters; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := repo.UpdateVote(ctx, newVote("1", "456", i%2 == 0, testTime))
+				if err == nil {
+					mu.Lock()
+					updated++
+					mu.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, application.VoteVersionConflictError)
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, updated)
+
+		stored, err := repo.GetVote(ctx, "1", "456")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Version)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		repo := factory(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, repo.CreateOpinion(ctx, newOpinion("1", testTime)), context.Canceled)
+		_, err := repo.ListOpinions(ctx, application.AllOpinionsVisible)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, repo.DeleteOpinion(ctx, "1"), context.Canceled)
+		assert.ErrorIs(t, repo.CreateVote(ctx, newVote("1", "456", true, testTime)), context.Canceled)
+		_, err = repo.GetVote(ctx, "1", "456")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, repo.UpdateVote(ctx, newVote("1", "456", true, testTime)), context.Canceled)
+		assert.ErrorIs(t, repo.DeleteVote(ctx, "1", "456"), context.Canceled)
+		_, err = repo.ListVotes(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		list, err := repo.ListOpinions(context.Background(), application.AllOpinionsVisible)
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+func newOpinion(id application.OpinionId, created time.Time) application.Opinion {
+	return application.Opinion{
+		ID:        id,
+		Owner:     "123",
+		CreatedAt: created,
+		Statement: "copy and pasta is fine",
+		Status:    application.OpinionPublished,
+		Version:   1,
+	}
+}
+
+func newVote(opinion application.OpinionId, voter application.UserId, agreement bool, created time.Time) application.Vote {
+	return application.Vote{
+		Agreement: agreement,
+		Opinion:   opinion,
+		Voter:     voter,
+		CreatedAt: created,
+		UpdatedAt: created,
+		Version:   1,
+	}
+}
+
+func newReport(opinion application.OpinionId, reporter application.UserId, created time.Time) application.Report {
+	return application.Report{
+		Opinion:   opinion,
+		Reporter:  reporter,
+		Reason:    application.ReportReasonSpam,
+		CreatedAt: created,
+	}
+}